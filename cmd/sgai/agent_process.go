@@ -48,12 +48,13 @@ func buildAgentEnv(cfg agentRunConfig, wfState state.Workflow, modelSpec string)
 		agentIdentity = cfg.agent + "|" + model + "|" + variant
 	}
 
-	return buildManagedOpenCodeEnv(cfg.dir, cfg.mcpURL, agentIdentity, interactiveEnv)
+	env := buildManagedOpenCodeEnv(cfg.dir, cfg.mcpURL, agentIdentity, interactiveEnv, cfg.agentModels)
+	return append(env, secretEnv(cfg.userConfigDir, cfg.dir, cfg.secrets.agentRefs())...)
 }
 
-func executeAgentProcess(ctx context.Context, cfg agentRunConfig, agentArgs []string, agentMsg, prefix string, outputCapture *ringWriter, wfState state.Workflow, modelSpec string) (state.Workflow, string, *state.Workflow) {
-	stderrOut := buildAgentOutputWriter(os.Stderr, cfg.logWriter, cfg.stderrLog)
-	stdoutOut := buildAgentOutputWriter(os.Stdout, cfg.logWriter, cfg.stdoutLog)
+func executeAgentProcess(ctx context.Context, cfg agentRunConfig, agentArgs []string, agentMsg, prefix string, outputCapture *ringWriter, wfState state.Workflow, modelSpec string) (state.Workflow, string, agentFailureKind, *state.Workflow) {
+	stderrOut := buildAgentOutputWriter(cfg.redactor, os.Stderr, cfg.logWriter, cfg.stderrLog)
+	stdoutOut := buildAgentOutputWriter(cfg.redactor, os.Stdout, cfg.logWriter, cfg.stdoutLog)
 	stderrWriter := &prefixWriter{prefix: prefix + " ", w: stderrOut}
 	stdoutWriter := &prefixWriter{prefix: prefix + " ", w: stdoutOut}
 	stdoutPassthrough := io.MultiWriter(stdoutWriter, outputCapture)
@@ -68,7 +69,8 @@ func executeAgentProcess(ctx context.Context, cfg agentRunConfig, agentArgs []st
 	cmd.SysProcAttr = commandProcessGroupAttr()
 	cmd.Env = buildAgentEnv(cfg, wfState, modelSpec)
 	cmd.Stdin = strings.NewReader(agentMsg)
-	cmd.Stderr = io.MultiWriter(stderrWriter, outputCapture)
+	stderrCapture := newRingWriter()
+	cmd.Stderr = io.MultiWriter(stderrWriter, outputCapture, stderrCapture)
 	cmd.Stdout = sessionIDCapture
 
 	if errStart := cmd.Start(); errStart != nil {
@@ -81,10 +83,10 @@ func executeAgentProcess(ctx context.Context, cfg agentRunConfig, agentArgs []st
 		}
 		fmt.Fprintln(os.Stderr, "agent", cfg.agent, "marked as agent-done due to start failure")
 		result := cfg.coord.State()
-		return state.Workflow{}, "", &result
+		return state.Workflow{}, "", failureOther, &result
 	}
 	cfg.coord.SetLogFunc(func(message string) {
-		if _, errWrite := fmt.Fprintln(stdoutOut, formatLogTimestamp(time.Now())+prefix+"  → "+message); errWrite != nil {
+		if _, errWrite := fmt.Fprintln(stdoutOut, formatLogTimestamp(time.Now())+prefix+"  → "+maskSecrets(message)); errWrite != nil {
 			log.Println("write failed:", errWrite)
 		}
 	})
@@ -97,14 +99,23 @@ func executeAgentProcess(ctx context.Context, cfg agentRunConfig, agentArgs []st
 	close(processExited)
 	cfg.coord.Stop()
 	agentCancel()
+	sessionIDCapture.Flush()
+	for _, w := range []*prefixWriter{stdoutWriter, stderrWriter} {
+		if errFlush := w.Flush(); errFlush != nil {
+			log.Println("write failed:", errFlush)
+		}
+	}
+	if cfg.redactor != nil {
+		recordRedactions(cfg.coord, cfg.redactor.drain())
+	}
 
 	if errWait != nil {
 		if ctx.Err() != nil {
 			fmt.Println("["+cfg.paddedsgai+"]", "interrupted during agent execution")
-			return state.Workflow{}, "", &wfState
+			return state.Workflow{}, "", failureNone, &wfState
 		}
 		fmt.Fprintln(os.Stderr, "\n=== RAW AGENT OUTPUT (last 1000 lines) ===")
-		outputCapture.dump(os.Stderr)
+		outputCapture.dump(buildAgentOutputWriter(cfg.redactor.uncounted(), os.Stderr))
 		fmt.Fprintln(os.Stderr, "=== END RAW AGENT OUTPUT ===")
 		if errUpdate := cfg.coord.UpdateState(func(wf *state.Workflow) {
 			wf.Status = state.StatusAgentDone
@@ -113,11 +124,10 @@ func executeAgentProcess(ctx context.Context, cfg agentRunConfig, agentArgs []st
 		}
 		fmt.Fprintln(os.Stderr, "agent", cfg.agent, "marked as agent-done due to error:", errWait)
 		result := cfg.coord.State()
-		return state.Workflow{}, "", &result
+		return state.Workflow{}, "", classifyAgentFailure(exitCodeFromError(errWait), stderrCapture.String()), &result
 	}
 
-	sessionIDCapture.Flush()
-	return cfg.coord.State(), sessionIDCapture.sessionID, nil
+	return cfg.coord.State(), sessionIDCapture.sessionID, failureNone, nil
 }
 
 func exportAgentSession(cfg agentRunConfig, sessionID string, iteration int) {
@@ -127,6 +137,7 @@ func exportAgentSession(cfg agentRunConfig, sessionID string, iteration int) {
 	if errExport != nil {
 		log.Fatalln("failed to export session:", errExport)
 	}
+	output = []byte(maskSecrets(string(output)))
 	if errMkdir := os.MkdirAll(filepath.Dir(sessionFile), 0755); errMkdir != nil {
 		log.Fatalln("failed to export session:", errMkdir)
 	}
@@ -22,11 +22,17 @@ type agentRunConfig struct {
 	coord            *state.Coordinator
 	retrospectiveDir string
 	goalAgents       []string
+	agentModels      map[string]string
 	paddedsgai       string
 	mcpURL           string
 	logWriter        io.Writer
 	stdoutLog        io.Writer
 	stderrLog        io.Writer
+	steeringNote     string
+	onGateFailure    func(output string)
+	secrets          *secretsConfig
+	userConfigDir    string
+	redactor         *redactor
 }
 
 func buildIterationPrefix(dir string, iteration int) string {
@@ -107,6 +113,14 @@ CRITICALLY IMPORTANT: use as many Task subagents as possible at all times. Your
 		msg += fmt.Sprintf("\nYou have %d pending TODO items. Please complete them before marking agent-done.\n", pendingTodosCount)
 	}
 
+	if cfg.steeringNote != "" {
+		msg += formatSteeringNote(cfg.steeringNote)
+	}
+
+	if trigger := pipelineTrigger(wfState.Trigger); trigger != nil {
+		msg += formatPipelineTrigger(trigger)
+	}
+
 	snippets := parseAgentSnippets(cfg.dir, cfg.agent)
 	if len(snippets) > 0 {
 		snippetsStr := strings.Join(snippets, ", ")
@@ -149,7 +163,12 @@ func blockCompletionOnPendingTodos(cfg agentRunConfig, newState, wfState state.W
 	}
 	fmt.Println("["+cfg.paddedsgai+"]", "coordinator cannot complete workflow, there are pending TODO items")
 	newState.Status = state.StatusWorking
-	if errAppend := appendProjectManagementSection(cfg.dir, "Pending TODO Items", fmt.Sprintf("You have %d pending TODO items. Please complete them before marking workflow complete.", count)); errAppend != nil {
+	if errAppend := appendProjectManagementSection(cfg.dir, ledgerEntry{
+		Type:  ledgerBlocker,
+		Title: "Pending TODO Items",
+		Agent: ledgerSystemAgent,
+		Body:  fmt.Sprintf("You have %d pending TODO items. Please complete them before marking workflow complete.", count),
+	}); errAppend != nil {
 		log.Println("failed to append pending TODO blocker to PROJECT_MANAGEMENT.md:", errAppend)
 	}
 	saveState(cfg.coord, newState)
@@ -163,23 +182,35 @@ func blockCompletionOnGateScript(ctx context.Context, cfg agentRunConfig, newSta
 	fmt.Println("["+cfg.paddedsgai+"]", "running completionGateScript:", metadata.CompletionGateScript)
 	newState.Task = "running completionGateScript: " + metadata.CompletionGateScript
 	saveState(cfg.coord, newState)
-	output, errScript := runCompletionGateScript(ctx, cfg.dir, metadata.CompletionGateScript)
+	gateEnv := secretEnv(cfg.userConfigDir, cfg.dir, cfg.secrets.completionGateRefs())
+	output, errScript := runCompletionGateScript(ctx, cfg.dir, metadata.CompletionGateScript, gateEnv)
 	if errScript == nil {
 		return nil
 	}
 	fmt.Println("["+cfg.paddedsgai+"]", "completionGateScript failed, blocking completion")
+	if cfg.onGateFailure != nil {
+		cfg.onGateFailure(output)
+	}
 	newState.Status = state.StatusWorking
-	if errAppend := appendProjectManagementSection(cfg.dir, "Completion Gate Failure", formatCompletionGateScriptFailureMessage(metadata.CompletionGateScript, output)); errAppend != nil {
+	if errAppend := appendProjectManagementSection(cfg.dir, ledgerEntry{
+		Type:  ledgerGateFailure,
+		Title: "Completion Gate Failure",
+		Agent: ledgerSystemAgent,
+		Body:  formatCompletionGateScriptFailureMessage(metadata.CompletionGateScript, output),
+	}); errAppend != nil {
 		log.Println("failed to append completion gate failure to PROJECT_MANAGEMENT.md:", errAppend)
 	}
 	saveState(cfg.coord, newState)
 	return &newState
 }
 
-func runCompletionGateScript(ctx context.Context, dir, script string) (string, error) {
+func runCompletionGateScript(ctx context.Context, dir, script string, secretEnv []string) (string, error) {
 	cmd := exec.CommandContext(ctx, "sh", "-c", script)
 	cmd.Dir = dir
 	cmd.SysProcAttr = commandProcessGroupAttr()
+	if len(secretEnv) > 0 {
+		cmd.Env = append(os.Environ(), secretEnv...)
+	}
 
 	var buf bytes.Buffer
 	cmd.Stdout = &buf
@@ -195,7 +226,7 @@ func runCompletionGateScript(ctx context.Context, dir, script string) (string, e
 
 	errWait := cmd.Wait()
 	close(processExited)
-	return buf.String(), errWait
+	return maskSecrets(buf.String()), errWait
 }
 
 func formatCompletionGateScriptFailureMessage(script, output string) string {
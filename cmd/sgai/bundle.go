@@ -0,0 +1,397 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	bundleFormatVersion = 1
+	bundleManifestName  = "manifest.json"
+	bundleSessionsDir   = "sessions"
+	archivedMarkerName  = "archived.json"
+	maxBundleFileSize   = 512 << 20
+	maxBundleTotalSize  = 2 << 30
+	maxBundleEntries    = 10000
+)
+
+var (
+	errArchivedWorkspace   = errors.New("workspace is an archived bundle and is read-only")
+	errInvalidBundle       = errors.New("invalid bundle")
+	errUnsupportedBundle   = errors.New("unsupported bundle version")
+	errBundleEmptyManifest = errors.New("bundle has no manifest")
+)
+
+// bundleManifest is stored as manifest.json at the top of every bundle.
+type bundleManifest struct {
+	Version    int             `json:"version"`
+	Workspace  string          `json:"workspace"`
+	CreatedAt  string          `json:"createdAt"`
+	Status     string          `json:"status,omitempty"`
+	Retro      string          `json:"retrospective,omitempty"`
+	Files      []string        `json:"files"`
+	Sessions   []bundleSession `json:"sessions"`
+	ImportedAt string          `json:"importedAt,omitempty"`
+}
+
+type bundleSession struct {
+	ID    string `json:"id"`
+	Agent string `json:"agent,omitempty"`
+	File  string `json:"file,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type sessionExporter func(dir, sessionID string) ([]byte, error)
+
+type bundleFile struct {
+	name string
+	path string
+	data []byte
+}
+
+func isArchivedWorkspace(dir string) bool {
+	_, errStat := os.Stat(filepath.Join(dir, ".sgai", archivedMarkerName))
+	return errStat == nil
+}
+
+func readArchivedManifest(dir string) (bundleManifest, error) {
+	data, errRead := os.ReadFile(filepath.Join(dir, ".sgai", archivedMarkerName))
+	if errRead != nil {
+		return bundleManifest{}, errRead
+	}
+	var manifest bundleManifest
+	if errJSON := json.Unmarshal(data, &manifest); errJSON != nil {
+		return bundleManifest{}, fmt.Errorf("parsing %s: %w", archivedMarkerName, errJSON)
+	}
+	return manifest, nil
+}
+
+func archivedManifestForAPI(dir string) *bundleManifest {
+	if !isArchivedWorkspace(dir) {
+		return nil
+	}
+	manifest, errRead := readArchivedManifest(dir)
+	if errRead != nil {
+		log.Println("failed to read archived manifest:", errRead)
+		return nil
+	}
+	return &manifest
+}
+
+func bundleFileName(workspace string, now time.Time) string {
+	return workspace + "-" + now.UTC().Format("20060102-150405") + ".sgai-bundle.tar.gz"
+}
+
+// exportBundle writes GOAL.md, the .sgai run state, the current retrospective
+// directory and opencode session exports to w as a gzipped tar archive.
+func exportBundle(workspacePath string, w io.Writer, exportSession sessionExporter, now time.Time) (bundleManifest, error) {
+	manifest := bundleManifest{
+		Version:   bundleFormatVersion,
+		Workspace: filepath.Base(workspacePath),
+		CreatedAt: now.UTC().Format(time.RFC3339),
+		Files:     []string{},
+		Sessions:  []bundleSession{},
+	}
+
+	var files []bundleFile
+	addFile := func(rel string) {
+		absPath := filepath.Join(workspacePath, filepath.FromSlash(rel))
+		if info, errStat := os.Stat(absPath); errStat == nil && info.Mode().IsRegular() {
+			files = append(files, bundleFile{name: rel, path: absPath})
+		}
+	}
+	addFile("GOAL.md")
+	addFile(".sgai/PROJECT_MANAGEMENT.md")
+	addFile(".sgai/state.json")
+	addFile(".sgai/sessions.jsonl")
+	addFile(".sgai/delegations.jsonl")
+
+	if wf, errState := readRetroState(filepath.Join(workspacePath, ".sgai")); errState == nil {
+		manifest.Status = wf.Status
+	}
+
+	retroRel := extractRetrospectiveDirFromProjectManagement(filepath.Join(workspacePath, ".sgai", "PROJECT_MANAGEMENT.md"))
+	if retroRel != "" {
+		retroDir := filepath.Join(workspacePath, filepath.FromSlash(retroRel))
+		errWalk := filepath.WalkDir(retroDir, func(p string, d os.DirEntry, errEntry error) error {
+			if errEntry != nil || !d.Type().IsRegular() {
+				return errEntry
+			}
+			rel, errRel := filepath.Rel(workspacePath, p)
+			if errRel != nil {
+				return errRel
+			}
+			files = append(files, bundleFile{name: filepath.ToSlash(rel), path: p})
+			return nil
+		})
+		if errWalk != nil && !os.IsNotExist(errWalk) {
+			return bundleManifest{}, fmt.Errorf("collecting retrospective: %w", errWalk)
+		}
+		manifest.Retro = filepath.ToSlash(retroRel)
+	}
+
+	entries, errSessions := readSessionEntries(filepath.Join(workspacePath, ".sgai", "sessions.jsonl"))
+	if errSessions != nil && !errors.Is(errSessions, os.ErrNotExist) {
+		return bundleManifest{}, errSessions
+	}
+	for _, entry := range entries {
+		session := bundleSession{ID: entry.SessionID, Agent: entry.Agent}
+		data, errExport := exportSession(workspacePath, entry.SessionID)
+		if errExport != nil {
+			log.Println("failed to export session", entry.SessionID+":", errExport)
+			session.Error = errExport.Error()
+		} else {
+			session.File = path.Join(bundleSessionsDir, strings.ReplaceAll(entry.SessionID, "/", "_")+".json")
+			files = append(files, bundleFile{name: session.File, data: data})
+		}
+		manifest.Sessions = append(manifest.Sessions, session)
+	}
+
+	for _, file := range files {
+		manifest.Files = append(manifest.Files, file.name)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	manifestData, errJSON := json.MarshalIndent(manifest, "", "  ")
+	if errJSON != nil {
+		return bundleManifest{}, errJSON
+	}
+	if errWrite := writeTarEntry(tw, bundleManifestName, manifestData, now); errWrite != nil {
+		return bundleManifest{}, errWrite
+	}
+	for _, file := range files {
+		data := file.data
+		if file.path != "" {
+			var errRead error
+			if data, errRead = os.ReadFile(file.path); errRead != nil {
+				return bundleManifest{}, fmt.Errorf("reading %s: %w", file.name, errRead)
+			}
+		}
+		if errWrite := writeTarEntry(tw, file.name, data, now); errWrite != nil {
+			return bundleManifest{}, errWrite
+		}
+	}
+	if errClose := tw.Close(); errClose != nil {
+		return bundleManifest{}, fmt.Errorf("finishing bundle: %w", errClose)
+	}
+	if errClose := gz.Close(); errClose != nil {
+		return bundleManifest{}, fmt.Errorf("finishing bundle: %w", errClose)
+	}
+	return manifest, nil
+}
+
+func writeTarEntry(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if errHeader := tw.WriteHeader(header); errHeader != nil {
+		return fmt.Errorf("writing %s: %w", name, errHeader)
+	}
+	if _, errWrite := tw.Write(data); errWrite != nil {
+		return fmt.Errorf("writing %s: %w", name, errWrite)
+	}
+	return nil
+}
+
+func readSessionEntries(path string) ([]sessionEntry, error) {
+	data, errRead := os.ReadFile(path)
+	if errRead != nil {
+		return nil, errRead
+	}
+	var entries []sessionEntry
+	seen := make(map[string]bool)
+	for line := range strings.SplitSeq(string(data), "\n") {
+		var entry sessionEntry
+		if errJSON := json.Unmarshal([]byte(strings.TrimSpace(line)), &entry); errJSON != nil || entry.SessionID == "" {
+			continue
+		}
+		if seen[entry.SessionID] {
+			continue
+		}
+		seen[entry.SessionID] = true
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// bundleImportBudget bounds what an import may unpack, so a small compressed
+// archive cannot fill the disk with many large or many tiny entries.
+type bundleImportBudget struct {
+	maxEntries int
+	maxBytes   int64
+	entries    int
+	bytes      int64
+}
+
+func (b *bundleImportBudget) admit(header *tar.Header) error {
+	b.entries++
+	if b.entries > b.maxEntries {
+		return fmt.Errorf("%w: more than %d entries", errInvalidBundle, b.maxEntries)
+	}
+	if header.Typeflag != tar.TypeReg {
+		return nil
+	}
+	if header.Size < 0 || header.Size > maxBundleFileSize {
+		return fmt.Errorf("%w: %s is too large", errInvalidBundle, header.Name)
+	}
+	b.bytes += header.Size
+	if b.bytes > b.maxBytes {
+		return fmt.Errorf("%w: unpacked size exceeds %d bytes", errInvalidBundle, b.maxBytes)
+	}
+	return nil
+}
+
+// writeBundleEntry streams one archive entry to dest without buffering it.
+func writeBundleEntry(dest string, r io.Reader, size int64) error {
+	if errMkdir := os.MkdirAll(filepath.Dir(dest), 0o755); errMkdir != nil {
+		return errMkdir
+	}
+	file, errOpen := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o444)
+	if errOpen != nil {
+		return errOpen
+	}
+	_, errCopy := io.CopyN(file, r, size)
+	if errClose := file.Close(); errClose != nil && errCopy == nil {
+		return errClose
+	}
+	if errCopy != nil {
+		return fmt.Errorf("%w: %w", errInvalidBundle, errCopy)
+	}
+	return nil
+}
+
+// importBundle unpacks a bundle into rootDir/name and marks the result as an
+// archived, read-only workspace. An empty name is derived from the manifest.
+func importBundle(r io.Reader, rootDir, name string, now time.Time) (string, bundleManifest, error) {
+	if name != "" {
+		if _, errStat := os.Stat(filepath.Join(rootDir, name)); errStat == nil {
+			return "", bundleManifest{}, fmt.Errorf("%w: %s", errDirectoryExists, name)
+		}
+	}
+
+	gz, errGzip := gzip.NewReader(r)
+	if errGzip != nil {
+		return "", bundleManifest{}, fmt.Errorf("%w: %w", errInvalidBundle, errGzip)
+	}
+	defer func() {
+		if errClose := gz.Close(); errClose != nil {
+			log.Println("failed to close bundle reader:", errClose)
+		}
+	}()
+
+	stagingDir, errStage := os.MkdirTemp(rootDir, ".sgai-bundle-import-*")
+	if errStage != nil {
+		return "", bundleManifest{}, fmt.Errorf("creating staging directory: %w", errStage)
+	}
+	defer func() {
+		if errRemove := os.RemoveAll(stagingDir); errRemove != nil {
+			log.Println("failed to remove bundle staging directory:", errRemove)
+		}
+	}()
+
+	var manifest *bundleManifest
+	budget := bundleImportBudget{maxEntries: maxBundleEntries, maxBytes: maxBundleTotalSize}
+	tr := tar.NewReader(gz)
+	for {
+		header, errNext := tr.Next()
+		if errors.Is(errNext, io.EOF) {
+			break
+		}
+		if errNext != nil {
+			return "", bundleManifest{}, fmt.Errorf("%w: %w", errInvalidBundle, errNext)
+		}
+		if errBudget := budget.admit(header); errBudget != nil {
+			return "", bundleManifest{}, errBudget
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if header.Name == bundleManifestName {
+			var parsed bundleManifest
+			if errJSON := json.NewDecoder(tr).Decode(&parsed); errJSON != nil {
+				return "", bundleManifest{}, fmt.Errorf("%w: parsing manifest: %w", errInvalidBundle, errJSON)
+			}
+			if parsed.Version < 1 || parsed.Version > bundleFormatVersion {
+				return "", bundleManifest{}, fmt.Errorf("%w %d", errUnsupportedBundle, parsed.Version)
+			}
+			manifest = &parsed
+			continue
+		}
+		dest, errPath := bundleEntryPath(stagingDir, header.Name)
+		if errPath != nil {
+			return "", bundleManifest{}, errPath
+		}
+		if errWrite := writeBundleEntry(dest, tr, header.Size); errWrite != nil {
+			return "", bundleManifest{}, errWrite
+		}
+	}
+	if manifest == nil {
+		return "", bundleManifest{}, fmt.Errorf("%w: %w", errInvalidBundle, errBundleEmptyManifest)
+	}
+
+	manifest.ImportedAt = now.UTC().Format(time.RFC3339)
+	markerData, errJSON := json.MarshalIndent(manifest, "", "  ")
+	if errJSON != nil {
+		return "", bundleManifest{}, errJSON
+	}
+	if errMkdir := os.MkdirAll(filepath.Join(stagingDir, ".sgai"), 0o755); errMkdir != nil {
+		return "", bundleManifest{}, errMkdir
+	}
+	if errWrite := os.WriteFile(filepath.Join(stagingDir, ".sgai", archivedMarkerName), markerData, 0o444); errWrite != nil {
+		return "", bundleManifest{}, errWrite
+	}
+	if errChmod := os.Chmod(stagingDir, 0o755); errChmod != nil {
+		return "", bundleManifest{}, errChmod
+	}
+	if name == "" {
+		name = archivedWorkspaceName(manifest.Workspace, now)
+	}
+	targetDir := filepath.Join(rootDir, name)
+	if _, errStat := os.Stat(targetDir); errStat == nil {
+		return "", bundleManifest{}, fmt.Errorf("%w: %s", errDirectoryExists, name)
+	}
+	if errRename := os.Rename(stagingDir, targetDir); errRename != nil {
+		return "", bundleManifest{}, fmt.Errorf("moving bundle into place: %w", errRename)
+	}
+	return targetDir, *manifest, nil
+}
+
+func bundleEntryPath(root, name string) (string, error) {
+	cleaned := path.Clean(name)
+	if path.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("%w: unsafe path %q", errInvalidBundle, name)
+	}
+	return filepath.Join(root, filepath.FromSlash(cleaned)), nil
+}
+
+func archivedWorkspaceName(workspace string, now time.Time) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		default:
+			return '-'
+		}
+	}, workspace)
+	name = strings.Trim(name, "-")
+	if name == "" {
+		name = "workspace"
+	}
+	return name + "-archived-" + now.UTC().Format("20060102-150405")
+}
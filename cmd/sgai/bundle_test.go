@@ -0,0 +1,207 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errFakeExport = errors.New("session not found")
+
+func fakeSessionExporter(_, sessionID string) ([]byte, error) {
+	if sessionID == "ses_missing" {
+		return nil, errFakeExport
+	}
+	return []byte(`{"info":{"id":"` + sessionID + `"}}`), nil
+}
+
+func setupBundleWorkspace(t *testing.T, dir string) {
+	t.Helper()
+	retroDir := filepath.Join(dir, ".sgai", "retrospectives", "2026-03-01-10-00.aaaa")
+	require.NoError(t, os.MkdirAll(retroDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "GOAL.md"), []byte("# Goal\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".sgai", "PROJECT_MANAGEMENT.md"), []byte("---\nRetrospective Session: .sgai/retrospectives/2026-03-01-10-00.aaaa\n---\n\n## Notes\nhello\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".sgai", "state.json"), []byte(`{"status":"complete"}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".sgai", "sessions.jsonl"), []byte("{\"sessionID\":\"ses_1\",\"agent\":\"coordinator\"}\n{\"sessionID\":\"ses_1\",\"agent\":\"coordinator\"}\n{\"sessionID\":\"ses_missing\",\"agent\":\"go\"}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(retroDir, "stdout.log"), []byte("log line\n"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".sgai", "retrospectives", "2026-02-01-10-00.old"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".sgai", "retrospectives", "2026-02-01-10-00.old", "stdout.log"), []byte("old\n"), 0o644))
+}
+
+func TestExportImportBundleRoundTrip(t *testing.T) {
+	workspace := filepath.Join(t.TempDir(), "demo")
+	setupBundleWorkspace(t, workspace)
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	manifest, err := exportBundle(workspace, &buf, fakeSessionExporter, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, bundleFormatVersion, manifest.Version)
+	assert.Equal(t, "demo", manifest.Workspace)
+	assert.Equal(t, "complete", manifest.Status)
+	assert.Equal(t, ".sgai/retrospectives/2026-03-01-10-00.aaaa", manifest.Retro)
+	assert.Equal(t, []string{
+		"GOAL.md",
+		".sgai/PROJECT_MANAGEMENT.md",
+		".sgai/state.json",
+		".sgai/sessions.jsonl",
+		".sgai/retrospectives/2026-03-01-10-00.aaaa/stdout.log",
+		"sessions/ses_1.json",
+	}, manifest.Files)
+	require.Len(t, manifest.Sessions, 2)
+	assert.Equal(t, "sessions/ses_1.json", manifest.Sessions[0].File)
+	assert.Equal(t, errFakeExport.Error(), manifest.Sessions[1].Error)
+
+	root := t.TempDir()
+	targetDir, imported, errImport := importBundle(bytes.NewReader(buf.Bytes()), root, "", now)
+
+	require.NoError(t, errImport)
+	assert.Equal(t, filepath.Join(root, "demo-archived-20260302-120000"), targetDir)
+	assert.Equal(t, now.Format(time.RFC3339), imported.ImportedAt)
+	assert.True(t, isArchivedWorkspace(targetDir))
+	assert.FileExists(t, filepath.Join(targetDir, "sessions", "ses_1.json"))
+	assert.FileExists(t, filepath.Join(targetDir, ".sgai", "retrospectives", "2026-03-01-10-00.aaaa", "stdout.log"))
+	assert.NoDirExists(t, filepath.Join(targetDir, ".sgai", "retrospectives", "2026-02-01-10-00.old"))
+
+	stored, errRead := readArchivedManifest(targetDir)
+	require.NoError(t, errRead)
+	assert.Equal(t, imported, stored)
+
+	_, _, errAgain := importBundle(bytes.NewReader(buf.Bytes()), root, "demo-archived-20260302-120000", now)
+	assert.ErrorIs(t, errAgain, errDirectoryExists)
+}
+
+func buildTestBundle(t *testing.T, entries map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range entries {
+		require.NoError(t, writeTarEntry(tw, name, []byte(content), time.Now()))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestImportBundleRejectsInvalidArchives(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{
+			name:    "notGzip",
+			data:    []byte("plain text"),
+			wantErr: errInvalidBundle,
+		},
+		{
+			name:    "missingManifest",
+			data:    buildTestBundle(t, map[string]string{"GOAL.md": "# Goal"}),
+			wantErr: errBundleEmptyManifest,
+		},
+		{
+			name:    "futureVersion",
+			data:    buildTestBundle(t, map[string]string{bundleManifestName: `{"version":99}`}),
+			wantErr: errUnsupportedBundle,
+		},
+		{
+			name:    "pathTraversal",
+			data:    buildTestBundle(t, map[string]string{bundleManifestName: `{"version":1}`, "../escape.txt": "x"}),
+			wantErr: errInvalidBundle,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			_, _, err := importBundle(bytes.NewReader(tt.data), root, "target", time.Now())
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.NoDirExists(t, filepath.Join(root, "target"))
+			assert.NoFileExists(t, filepath.Join(filepath.Dir(root), "escape.txt"))
+		})
+	}
+}
+
+func TestBundleImportBudget(t *testing.T) {
+	file := func(name string, size int64) *tar.Header {
+		return &tar.Header{Name: name, Typeflag: tar.TypeReg, Size: size}
+	}
+	cases := []struct {
+		name    string
+		headers []*tar.Header
+		wantErr bool
+	}{
+		{"withinBudget", []*tar.Header{file("a", 40), {Name: "d/", Typeflag: tar.TypeDir}, file("b", 60)}, false},
+		{"tooManyEntries", []*tar.Header{file("a", 1), file("b", 1), file("c", 1), file("d", 1)}, true},
+		{"totalTooLarge", []*tar.Header{file("a", 60), file("b", 60)}, true},
+		{"fileTooLarge", []*tar.Header{file("a", maxBundleFileSize+1)}, true},
+		{"negativeSize", []*tar.Header{file("a", -1)}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			budget := bundleImportBudget{maxEntries: 3, maxBytes: 100}
+			var errAdmit error
+			for _, header := range tc.headers {
+				if errAdmit = budget.admit(header); errAdmit != nil {
+					break
+				}
+			}
+			if tc.wantErr {
+				assert.ErrorIs(t, errAdmit, errInvalidBundle)
+				return
+			}
+			assert.NoError(t, errAdmit)
+		})
+	}
+}
+
+func TestArchivedWorkspaceName(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, "my-proj-archived-20260302-120000", archivedWorkspaceName("My_Proj", now))
+	assert.Equal(t, "workspace-archived-20260302-120000", archivedWorkspaceName("", now))
+}
+
+func TestHandleAPIBundleExportImport(t *testing.T) {
+	server, rootDir := setupTestServer(t)
+	wsDir := setupTestWorkspace(t, rootDir, "bundle-ws")
+	require.NoError(t, os.WriteFile(filepath.Join(wsDir, ".sgai", "state.json"), []byte(`{"status":"complete"}`), 0o644))
+
+	w := serveHTTP(server, "GET", "/api/v1/workspaces/bundle-ws/bundle", "")
+	require.Equal(t, 200, w.Code)
+	assert.Equal(t, "application/gzip", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "bundle-ws-")
+	bundle := w.Body.Bytes()
+
+	w = serveHTTP(server, "POST", "/api/v1/bundles/import?name=bundle-copy", string(bundle))
+	require.Equal(t, 201, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"name":"bundle-copy"`)
+	assert.True(t, isArchivedWorkspace(filepath.Join(rootDir, "bundle-copy")))
+
+	w = serveHTTP(server, "POST", "/api/v1/bundles/import?name=bundle-copy", string(bundle))
+	assert.Equal(t, 409, w.Code)
+
+	w = serveHTTP(server, "POST", "/api/v1/bundles/import", "junk")
+	assert.Equal(t, 400, w.Code)
+
+	w = serveHTTP(server, "POST", "/api/v1/workspaces/bundle-copy/start", `{}`)
+	assert.Equal(t, 409, w.Code)
+	w = serveHTTP(server, "PUT", "/api/v1/workspaces/bundle-copy/goal", `{"content":"# new"}`)
+	assert.Equal(t, 409, w.Code)
+
+	w = serveHTTP(server, "GET", "/api/v1/state", "")
+	require.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"archived":{"version":1,"workspace":"bundle-ws"`)
+
+	result := server.startSession(filepath.Join(rootDir, "bundle-copy"))
+	assert.ErrorIs(t, result.startError, errArchivedWorkspace)
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+)
+
+const (
+	checkpointVCSJJ   = "jj"
+	checkpointVCSGit  = "git"
+	checkpointVCSNone = "none"
+
+	checkpointRefPrefix = "refs/sgai/checkpoints/"
+	maxCheckpoints      = 200
+
+	// checkpointGitExclude and checkpointJJFileset keep .sgai/ out of
+	// snapshots and restores: it holds the checkpoint records themselves and
+	// state.json, which a rewind restores from the record instead.
+	checkpointGitExclude = ":(exclude).sgai"
+	checkpointJJFileset  = `~".sgai"`
+)
+
+var (
+	errCheckpointNotFound = errors.New("checkpoint not found")
+	errInvalidCheckpoint  = errors.New("invalid checkpoint id")
+)
+
+// checkpoint ties a coordinator iteration to a VCS snapshot of the working
+// copy. For jj it is the operation ID and the working-copy commit; for git it
+// is a commit kept alive under refs/sgai/checkpoints/ that HEAD never points
+// at.
+type checkpoint struct {
+	ID        string `json:"id"`
+	Iteration int    `json:"iteration"`
+	Prefix    string `json:"prefix"`
+	Timestamp string `json:"timestamp"`
+	Agent     string `json:"agent"`
+	Task      string `json:"task,omitempty"`
+	Status    string `json:"status,omitempty"`
+	VCS       string `json:"vcs"`
+	Operation string `json:"operation,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+}
+
+type checkpointFile struct {
+	checkpoint
+	State state.Workflow `json:"state"`
+}
+
+func checkpointsDir(dir string) string {
+	return filepath.Join(dir, ".sgai", "checkpoints")
+}
+
+func checkpointPath(dir, id string) string {
+	return filepath.Join(checkpointsDir(dir), id+".json")
+}
+
+func validCheckpointID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\`) && !strings.HasPrefix(id, ".")
+}
+
+func detectCheckpointVCS(dir string) string {
+	jjRoot := exec.Command("jj", "root")
+	jjRoot.Dir = dir
+	if output, errRoot := jjRoot.Output(); errRoot == nil && strings.TrimSpace(string(output)) != "" {
+		return checkpointVCSJJ
+	}
+	if errGit := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Run(); errGit == nil {
+		return checkpointVCSGit
+	}
+	return checkpointVCSNone
+}
+
+func runCheckpointCommand(dir string, env []string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, errRun := cmd.Output()
+	if errRun != nil {
+		return "", fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), errRun, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func snapshotJJ(dir string) (operation, commit string, err error) {
+	commit, err = runCheckpointCommand(dir, nil, "jj", "log", "-r", "@", "--no-graph", "-T", "commit_id")
+	if err != nil {
+		return "", "", err
+	}
+	operation, err = runCheckpointCommand(dir, nil, "jj", "op", "log", "--no-graph", "--limit", "1", "-T", "id")
+	if err != nil {
+		return "", "", err
+	}
+	return operation, commit, nil
+}
+
+// snapshotGit commits the working tree, except .sgai/, through a temporary
+// index so the real index, HEAD and branches are left alone.
+func snapshotGit(dir, id, message string) (string, error) {
+	indexFile, errTemp := os.CreateTemp("", "sgai-checkpoint-index-*")
+	if errTemp != nil {
+		return "", errTemp
+	}
+	indexPath := indexFile.Name()
+	_ = indexFile.Close()
+	_ = os.Remove(indexPath)
+	defer func() { _ = os.Remove(indexPath) }()
+
+	env := []string{
+		"GIT_INDEX_FILE=" + indexPath,
+		"GIT_AUTHOR_NAME=sgai", "GIT_AUTHOR_EMAIL=sgai@localhost",
+		"GIT_COMMITTER_NAME=sgai", "GIT_COMMITTER_EMAIL=sgai@localhost",
+	}
+	if _, errAdd := runCheckpointCommand(dir, env, "git", "add", "-A", "--", ".", checkpointGitExclude); errAdd != nil {
+		return "", errAdd
+	}
+	tree, errTree := runCheckpointCommand(dir, env, "git", "write-tree")
+	if errTree != nil {
+		return "", errTree
+	}
+	commitArgs := []string{"commit-tree", tree, "-m", message}
+	if head, errHead := runCheckpointCommand(dir, nil, "git", "rev-parse", "--verify", "--quiet", "HEAD"); errHead == nil && head != "" {
+		commitArgs = append(commitArgs, "-p", head)
+	}
+	commit, errCommit := runCheckpointCommand(dir, env, "git", commitArgs...)
+	if errCommit != nil {
+		return "", errCommit
+	}
+	if _, errRef := runCheckpointCommand(dir, nil, "git", "update-ref", checkpointRefPrefix+id, commit); errRef != nil {
+		return "", errRef
+	}
+	return commit, nil
+}
+
+// recordCheckpoint snapshots the working copy and the workflow state after a
+// coordinator iteration.
+func recordCheckpoint(dir string, iteration int, prefix, agent string, wfState state.Workflow, now time.Time) (checkpoint, error) {
+	cp := checkpoint{
+		ID:        fmt.Sprintf("%s-%04d", now.UTC().Format("20060102T150405.000Z"), iteration),
+		Iteration: iteration,
+		Prefix:    prefix,
+		Timestamp: now.UTC().Format(time.RFC3339),
+		Agent:     agent,
+		Task:      wfState.Task,
+		Status:    wfState.Status,
+		VCS:       detectCheckpointVCS(dir),
+	}
+	switch cp.VCS {
+	case checkpointVCSJJ:
+		operation, commit, errSnapshot := snapshotJJ(dir)
+		if errSnapshot != nil {
+			return checkpoint{}, fmt.Errorf("snapshotting jj working copy: %w", errSnapshot)
+		}
+		cp.Operation, cp.Commit = operation, commit
+	case checkpointVCSGit:
+		commit, errSnapshot := snapshotGit(dir, cp.ID, "sgai checkpoint "+prefix)
+		if errSnapshot != nil {
+			return checkpoint{}, fmt.Errorf("snapshotting git working tree: %w", errSnapshot)
+		}
+		cp.Commit = commit
+	}
+
+	data, errJSON := json.MarshalIndent(checkpointFile{checkpoint: cp, State: wfState}, "", "  ")
+	if errJSON != nil {
+		return checkpoint{}, errJSON
+	}
+	if errMkdir := os.MkdirAll(checkpointsDir(dir), 0755); errMkdir != nil {
+		return checkpoint{}, errMkdir
+	}
+	if errWrite := os.WriteFile(checkpointPath(dir, cp.ID), data, 0644); errWrite != nil {
+		return checkpoint{}, errWrite
+	}
+	pruneCheckpoints(dir)
+	return cp, nil
+}
+
+func loadCheckpoint(dir, id string) (checkpointFile, error) {
+	if !validCheckpointID(id) {
+		return checkpointFile{}, fmt.Errorf("%w: %q", errInvalidCheckpoint, id)
+	}
+	data, errRead := os.ReadFile(checkpointPath(dir, id))
+	if errors.Is(errRead, os.ErrNotExist) {
+		return checkpointFile{}, fmt.Errorf("%w: %s", errCheckpointNotFound, id)
+	}
+	if errRead != nil {
+		return checkpointFile{}, errRead
+	}
+	var file checkpointFile
+	if errJSON := json.Unmarshal(data, &file); errJSON != nil {
+		return checkpointFile{}, fmt.Errorf("parsing checkpoint %s: %w", id, errJSON)
+	}
+	return file, nil
+}
+
+// listCheckpoints returns the workspace checkpoints, oldest first.
+func listCheckpoints(dir string) ([]checkpoint, error) {
+	entries, errRead := os.ReadDir(checkpointsDir(dir))
+	if errors.Is(errRead, os.ErrNotExist) {
+		return nil, nil
+	}
+	if errRead != nil {
+		return nil, errRead
+	}
+	var checkpoints []checkpoint
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok {
+			continue
+		}
+		file, errLoad := loadCheckpoint(dir, id)
+		if errLoad != nil {
+			log.Println("skipping checkpoint:", errLoad)
+			continue
+		}
+		checkpoints = append(checkpoints, file.checkpoint)
+	}
+	slices.SortFunc(checkpoints, func(a, b checkpoint) int { return strings.Compare(a.ID, b.ID) })
+	return checkpoints, nil
+}
+
+func pruneCheckpoints(dir string) {
+	checkpoints, errList := listCheckpoints(dir)
+	if errList != nil || len(checkpoints) <= maxCheckpoints {
+		return
+	}
+	for _, cp := range checkpoints[:len(checkpoints)-maxCheckpoints] {
+		if errRemove := os.Remove(checkpointPath(dir, cp.ID)); errRemove != nil {
+			log.Println("failed to prune checkpoint:", errRemove)
+		}
+		if cp.VCS == checkpointVCSGit {
+			if _, errRef := runCheckpointCommand(dir, nil, "git", "update-ref", "-d", checkpointRefPrefix+cp.ID); errRef != nil {
+				log.Println("failed to delete checkpoint ref:", errRef)
+			}
+		}
+	}
+}
+
+func restoreGitCheckpoint(dir, current, target string) error {
+	indexFile, errTemp := os.CreateTemp("", "sgai-rewind-index-*")
+	if errTemp != nil {
+		return errTemp
+	}
+	indexPath := indexFile.Name()
+	_ = indexFile.Close()
+	_ = os.Remove(indexPath)
+	defer func() { _ = os.Remove(indexPath) }()
+
+	env := []string{"GIT_INDEX_FILE=" + indexPath}
+	if _, errRead := runCheckpointCommand(dir, env, "git", "read-tree", current); errRead != nil {
+		return errRead
+	}
+	_, errReset := runCheckpointCommand(dir, env, "git", "read-tree", "-u", "--reset", target)
+	return errReset
+}
+
+// rewindToCheckpoint restores the working copy and state.json to the given
+// checkpoint. A checkpoint of the current state is recorded first so the
+// rewind itself can be undone.
+func rewindToCheckpoint(dir, id string) (target checkpoint, safety checkpoint, err error) {
+	file, errLoad := loadCheckpoint(dir, id)
+	if errLoad != nil {
+		return checkpoint{}, checkpoint{}, errLoad
+	}
+	statePath := filepath.Join(dir, ".sgai", "state.json")
+	current, errState := state.NewCoordinator(statePath)
+	var currentState state.Workflow
+	if errState == nil {
+		currentState = current.State()
+	}
+	safety, errSafety := recordCheckpoint(dir, 0, "pre-rewind", "sgai", currentState, time.Now())
+	if errSafety != nil {
+		return checkpoint{}, checkpoint{}, fmt.Errorf("recording pre-rewind checkpoint: %w", errSafety)
+	}
+
+	switch file.VCS {
+	case checkpointVCSJJ:
+		if _, errRestore := runCheckpointCommand(dir, nil, "jj", "restore", "--from", file.Commit, checkpointJJFileset); errRestore != nil {
+			return checkpoint{}, checkpoint{}, fmt.Errorf("restoring jj working copy: %w", errRestore)
+		}
+	case checkpointVCSGit:
+		if safety.VCS != checkpointVCSGit {
+			return checkpoint{}, checkpoint{}, fmt.Errorf("checkpoint %s was taken with git, but the workspace is no longer a git repository", id)
+		}
+		if errRestore := restoreGitCheckpoint(dir, safety.Commit, file.Commit); errRestore != nil {
+			return checkpoint{}, checkpoint{}, fmt.Errorf("restoring git working tree: %w", errRestore)
+		}
+	}
+
+	if _, errWrite := state.NewCoordinatorWith(statePath, file.State); errWrite != nil {
+		return checkpoint{}, checkpoint{}, fmt.Errorf("restoring state.json: %w", errWrite)
+	}
+	return file.checkpoint, safety, nil
+}
+
+func (r *workflowRunner) recordIterationCheckpoint(agent, prefix string, wfState state.Workflow) {
+	if _, errCheckpoint := recordCheckpoint(r.dir, r.iterationCounter, prefix, agent, wfState, time.Now()); errCheckpoint != nil {
+		log.Println("failed to record checkpoint:", errCheckpoint)
+	}
+}
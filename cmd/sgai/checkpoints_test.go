@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readStateTask(t *testing.T, dir string) string {
+	t.Helper()
+	coord, errCoord := state.NewCoordinator(filepath.Join(dir, ".sgai", "state.json"))
+	require.NoError(t, errCoord)
+	return coord.State().Task
+}
+
+func TestCheckpointRewindGit(t *testing.T) {
+	if _, errLook := exec.LookPath("git"); errLook != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("v1\n"), 0644))
+	runGit(t, dir, "add", "a.txt")
+	runGit(t, dir, "commit", "-qm", "initial")
+	head := runGit(t, dir, "rev-parse", "HEAD")
+
+	first, errFirst := recordCheckpoint(dir, 1, "[ws:0001]", "coordinator", state.Workflow{Status: state.StatusWorking, Task: "first"}, time.Now())
+	require.NoError(t, errFirst)
+	assert.Equal(t, checkpointVCSGit, first.VCS)
+	assert.NotEmpty(t, first.Commit)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("v2\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("new\n"), 0644))
+	_, errSecond := recordCheckpoint(dir, 2, "[ws:0002]", "coordinator", state.Workflow{Status: state.StatusWorking, Task: "second"}, time.Now().Add(time.Second))
+	require.NoError(t, errSecond)
+	_, errState := state.NewCoordinatorWith(filepath.Join(dir, ".sgai", "state.json"), state.Workflow{Status: state.StatusWorking, Task: "latest"})
+	require.NoError(t, errState)
+
+	target, safety, errRewind := rewindToCheckpoint(dir, first.ID)
+	require.NoError(t, errRewind)
+
+	assert.Equal(t, first.ID, target.ID)
+	content, errRead := os.ReadFile(filepath.Join(dir, "a.txt"))
+	require.NoError(t, errRead)
+	assert.Equal(t, "v1\n", string(content))
+	assert.NoFileExists(t, filepath.Join(dir, "b.txt"))
+	assert.Equal(t, "first", readStateTask(t, dir))
+	assert.Equal(t, head, runGit(t, dir, "rev-parse", "HEAD"), "HEAD is never moved")
+	assert.Empty(t, strings.TrimSpace(runGit(t, dir, "status", "--porcelain", "--", ".", checkpointGitExclude)))
+
+	_, _, errUndo := rewindToCheckpoint(dir, safety.ID)
+	require.NoError(t, errUndo)
+	content, errRead = os.ReadFile(filepath.Join(dir, "a.txt"))
+	require.NoError(t, errRead)
+	assert.Equal(t, "v2\n", string(content))
+	assert.FileExists(t, filepath.Join(dir, "b.txt"))
+	assert.Equal(t, "latest", readStateTask(t, dir))
+
+	checkpoints, errList := listCheckpoints(dir)
+	require.NoError(t, errList)
+	require.Len(t, checkpoints, 4)
+	assert.Equal(t, first.ID, checkpoints[0].ID)
+}
+
+func TestCheckpointWithoutVCS(t *testing.T) {
+	dir := t.TempDir()
+	cp, errRecord := recordCheckpoint(dir, 3, "[ws:0003]", "coordinator", state.Workflow{Status: state.StatusWorking, Task: "plain"}, time.Now())
+	require.NoError(t, errRecord)
+	assert.Equal(t, checkpointVCSNone, cp.VCS)
+
+	_, _, errRewind := rewindToCheckpoint(dir, cp.ID)
+	require.NoError(t, errRewind)
+	assert.Equal(t, "plain", readStateTask(t, dir))
+}
+
+func TestLoadCheckpointErrors(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		name    string
+		id      string
+		wantErr error
+	}{
+		{"missing", "20260101T000000.000Z-0001", errCheckpointNotFound},
+		{"pathTraversal", "../state", errInvalidCheckpoint},
+		{"empty", "", errInvalidCheckpoint},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, errLoad := loadCheckpoint(dir, tc.id)
+			require.ErrorIs(t, errLoad, tc.wantErr)
+		})
+	}
+}
+
+func TestHandleAPICheckpoints(t *testing.T) {
+	server, rootDir := setupTestServer(t)
+	wsDir := setupTestWorkspace(t, rootDir, "rewindable")
+	cp, errRecord := recordCheckpoint(wsDir, 1, "[rewindable:0001]", "coordinator", state.Workflow{Status: state.StatusWorking, Task: "checkpointed"}, time.Now())
+	require.NoError(t, errRecord)
+
+	t.Run("list", func(t *testing.T) {
+		w := serveHTTP(server, http.MethodGet, "/api/v1/workspaces/rewindable/checkpoints", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp listCheckpointsResult
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Checkpoints, 1)
+		assert.Equal(t, cp.ID, resp.Checkpoints[0].ID)
+		assert.Equal(t, 1, resp.Checkpoints[0].Iteration)
+	})
+
+	t.Run("rewindWhileRunning", func(t *testing.T) {
+		server.mu.Lock()
+		server.sessions[wsDir] = &session{running: true}
+		server.mu.Unlock()
+		t.Cleanup(func() {
+			server.mu.Lock()
+			delete(server.sessions, wsDir)
+			server.mu.Unlock()
+		})
+
+		w := serveHTTP(server, http.MethodPost, "/api/v1/workspaces/rewindable/checkpoints/"+cp.ID+"/rewind", "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("rewindUnknown", func(t *testing.T) {
+		w := serveHTTP(server, http.MethodPost, "/api/v1/workspaces/rewindable/checkpoints/20200101T000000.000Z-0009/rewind", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("rewind", func(t *testing.T) {
+		w := serveHTTP(server, http.MethodPost, "/api/v1/workspaces/rewindable/checkpoints/"+cp.ID+"/rewind", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp rewindCheckpointResult
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, resp.Rewound)
+		assert.Equal(t, cp.ID, resp.Checkpoint.ID)
+		assert.NotEmpty(t, resp.Safety.ID)
+		assert.Equal(t, "checkpointed", readStateTask(t, wsDir))
+	})
+}
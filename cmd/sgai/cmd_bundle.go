@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func cmdBundle(args []string) {
+	if len(args) < 1 {
+		printBundleUsage()
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "export":
+		cmdBundleExport(args[1:])
+	case "import":
+		cmdBundleImport(args[1:])
+	default:
+		printBundleUsage()
+		os.Exit(2)
+	}
+}
+
+func printBundleUsage() {
+	fmt.Println("usage: sgai bundle export [--output file] <workspace-path>")
+	fmt.Println("       sgai bundle import [--root dir] [--name name] <bundle.tar.gz>")
+}
+
+func cmdBundleExport(args []string) {
+	fs := flag.NewFlagSet("bundle export", flag.ExitOnError)
+	output := fs.String("output", "", "bundle file to write (default: <workspace>-<timestamp>.sgai-bundle.tar.gz)")
+	fs.Usage = func() {
+		fmt.Println("sgai bundle export [--output file] <workspace-path>")
+		fmt.Println("")
+		fmt.Println("Packages GOAL.md, PROJECT_MANAGEMENT.md, state.json, sessions.jsonl, the")
+		fmt.Println("current retrospective directory and opencode session exports into a")
+		fmt.Println("versioned tar.gz with a manifest.")
+		fmt.Println("")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	workspacePath, errAbs := filepath.Abs(fs.Arg(0))
+	if errAbs != nil {
+		log.Fatalln("cannot resolve workspace path:", errAbs)
+	}
+	if !hassgaiDirectory(workspacePath) {
+		log.Fatalln("not an sgai workspace:", workspacePath)
+	}
+
+	now := time.Now()
+	outputPath := *output
+	if outputPath == "" {
+		outputPath = bundleFileName(filepath.Base(workspacePath), now)
+	}
+	file, errCreate := os.Create(outputPath)
+	if errCreate != nil {
+		log.Fatalln("cannot create bundle:", errCreate)
+	}
+	manifest, errExport := exportBundle(workspacePath, file, exportSessionBytes, now)
+	if errClose := file.Close(); errClose != nil && errExport == nil {
+		errExport = errClose
+	}
+	if errExport != nil {
+		if errRemove := os.Remove(outputPath); errRemove != nil {
+			log.Println("failed to remove partial bundle:", errRemove)
+		}
+		log.Fatalln("cannot export bundle:", errExport)
+	}
+	fmt.Printf("wrote %s (%d files, %d sessions)\n", outputPath, len(manifest.Files), len(manifest.Sessions))
+}
+
+func cmdBundleImport(args []string) {
+	fs := flag.NewFlagSet("bundle import", flag.ExitOnError)
+	root := fs.String("root", ".", "serve root to unpack the archived workspace into")
+	name := fs.String("name", "", "workspace name (default: <workspace>-archived-<timestamp>)")
+	fs.Usage = func() {
+		fmt.Println("sgai bundle import [--root dir] [--name name] <bundle.tar.gz>")
+		fmt.Println("")
+		fmt.Println("Unpacks a bundle into a read-only archived workspace that the dashboard")
+		fmt.Println("can browse.")
+		fmt.Println("")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	file, errOpen := os.Open(fs.Arg(0))
+	if errOpen != nil {
+		log.Fatalln("cannot open bundle:", errOpen)
+	}
+	defer func() {
+		if errClose := file.Close(); errClose != nil {
+			log.Println("failed to close bundle:", errClose)
+		}
+	}()
+
+	result, errImport := importBundleIntoRoot(*root, *name, file, time.Now())
+	if errImport != nil {
+		log.Fatalln("cannot import bundle:", errImport)
+	}
+	fmt.Println("imported", result.Manifest.Workspace, "as", result.Dir)
+}
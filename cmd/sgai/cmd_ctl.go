@@ -0,0 +1,588 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+)
+
+var errCtlUsage = errors.New("invalid usage")
+
+type ctlOptions struct {
+	client   *ctlClient
+	jsonOut  bool
+	stdin    *bufio.Reader
+	stdout   io.Writer
+	editFile func(path string) error
+}
+
+type ctlWorkspaceSummary struct {
+	Name       string `json:"name"`
+	Dir        string `json:"dir"`
+	Status     string `json:"status"`
+	Running    bool   `json:"running"`
+	NeedsInput bool   `json:"needsInput"`
+	IsRoot     bool   `json:"isRoot"`
+	IsFork     bool   `json:"isFork"`
+	Task       string `json:"task"`
+}
+
+type repeatedFlag []string
+
+func (f *repeatedFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *repeatedFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func cmdCtl(args []string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	errRun := runCtl(ctx, args, os.Stdin, os.Stdout, runEditor)
+	switch {
+	case errRun == nil:
+	case errors.Is(errRun, errCtlUsage):
+		printCtlUsage()
+		os.Exit(2)
+	default:
+		log.Fatalln(errRun)
+	}
+}
+
+func printCtlUsage() {
+	fmt.Println(`usage: sgai ctl [--server url] [--json] <command> [args]
+
+Commands:
+  list                                  List workspaces
+  status <workspace>                    Show status, task, todos and pending question
+  start [--auto] <workspace>            Start a session
+  stop <workspace>                      Stop a session
+  respond [--answer text] [--choice c]... <workspace>
+                                        Answer the pending question (interactive without flags)
+  tail [-f] <workspace>                 Print the session log; -f follows new lines
+  fork [--goal file] <workspace>        Fork a root workspace
+  goal get <workspace>                  Print GOAL.md
+  goal edit <workspace>                 Edit GOAL.md in $EDITOR and save it
+
+The server defaults to $SGAI_SERVER or ` + defaultCtlServer + `.`)
+}
+
+func runCtl(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer, editFile func(path string) error) error {
+	fs := flag.NewFlagSet("ctl", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	server := fs.String("server", envOr("SGAI_SERVER", defaultCtlServer), "sgai serve base URL")
+	jsonOut := fs.Bool("json", false, "print JSON instead of text")
+	if errParse := fs.Parse(args); errParse != nil {
+		return fmt.Errorf("%w: %w", errCtlUsage, errParse)
+	}
+	if fs.NArg() < 1 {
+		return errCtlUsage
+	}
+
+	opts := ctlOptions{
+		client:   newCtlClient(*server),
+		jsonOut:  *jsonOut,
+		stdin:    bufio.NewReader(stdin),
+		stdout:   stdout,
+		editFile: editFile,
+	}
+	rest := fs.Args()[1:]
+	switch fs.Arg(0) {
+	case "list":
+		return ctlList(ctx, opts)
+	case "status":
+		return ctlStatus(ctx, opts, rest)
+	case "start":
+		return ctlStart(ctx, opts, rest)
+	case "stop":
+		return ctlStop(ctx, opts, rest)
+	case "respond":
+		return ctlRespond(ctx, opts, rest)
+	case "tail":
+		return ctlTail(ctx, opts, rest)
+	case "fork":
+		return ctlFork(ctx, opts, rest)
+	case "goal":
+		return ctlGoal(ctx, opts, rest)
+	default:
+		return fmt.Errorf("%w: unknown command %q", errCtlUsage, fs.Arg(0))
+	}
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func parseCtlArgs(name string, args []string, define func(fs *flag.FlagSet)) (string, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if define != nil {
+		define(fs)
+	}
+	if errParse := fs.Parse(args); errParse != nil {
+		return "", fmt.Errorf("%w: %w", errCtlUsage, errParse)
+	}
+	if fs.NArg() != 1 {
+		return "", fmt.Errorf("%w: %s needs exactly one workspace", errCtlUsage, name)
+	}
+	return fs.Arg(0), nil
+}
+
+func (o ctlOptions) printJSON(v any) error {
+	encoder := json.NewEncoder(o.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func ctlList(ctx context.Context, opts ctlOptions) error {
+	factory, errState := opts.client.state(ctx)
+	if errState != nil {
+		return errState
+	}
+	summaries := make([]ctlWorkspaceSummary, 0, len(factory.Workspaces))
+	for _, ws := range factory.Workspaces {
+		summaries = append(summaries, ctlWorkspaceSummary{
+			Name:       ws.Name,
+			Dir:        ws.Dir,
+			Status:     ws.Status,
+			Running:    ws.Running,
+			NeedsInput: ws.NeedsInput,
+			IsRoot:     ws.IsRoot,
+			IsFork:     ws.IsFork,
+			Task:       ws.Task,
+		})
+	}
+	if opts.jsonOut {
+		return opts.printJSON(summaries)
+	}
+
+	tw := tabwriter.NewWriter(opts.stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tSTATUS\tRUNNING\tINPUT\tTASK")
+	for _, ws := range summaries {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", ws.Name, cmp.Or(ws.Status, "-"), yesNo(ws.Running), yesNo(ws.NeedsInput), ws.Task)
+	}
+	return tw.Flush()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func ctlStatus(ctx context.Context, opts ctlOptions, args []string) error {
+	name, errArgs := parseCtlArgs("status", args, nil)
+	if errArgs != nil {
+		return errArgs
+	}
+	ws, errWorkspace := opts.client.workspace(ctx, name)
+	if errWorkspace != nil {
+		return errWorkspace
+	}
+	if opts.jsonOut {
+		return opts.printJSON(ws)
+	}
+
+	out := opts.stdout
+	_, _ = fmt.Fprintln(out, "workspace:", ws.Name)
+	_, _ = fmt.Fprintln(out, "directory:", ws.Dir)
+	_, _ = fmt.Fprintln(out, "status:   ", cmp.Or(ws.Status, "-"))
+	_, _ = fmt.Fprintln(out, "running:  ", yesNo(ws.Running))
+	if ws.Task != "" {
+		_, _ = fmt.Fprintln(out, "task:     ", ws.Task)
+	}
+	if ws.LatestProgress != "" {
+		_, _ = fmt.Fprintln(out, "progress: ", ws.LatestProgress)
+	}
+	if ws.TotalExecTime != "" {
+		_, _ = fmt.Fprintln(out, "elapsed:  ", ws.TotalExecTime)
+	}
+	if len(ws.AgentTodos) > 0 {
+		_, _ = fmt.Fprintln(out, "todos:")
+		for _, todo := range ws.AgentTodos {
+			_, _ = fmt.Fprintf(out, "  [%s] %s\n", todo.Status, todo.Content)
+		}
+	}
+	if ws.PendingQuestion != nil {
+		_, _ = fmt.Fprintln(out, "")
+		_, _ = fmt.Fprintln(out, "pending question (answer with: sgai ctl respond "+ws.Name+"):")
+		renderPendingQuestion(out, ws.PendingQuestion)
+	}
+	return nil
+}
+
+func ctlStart(ctx context.Context, opts ctlOptions, args []string) error {
+	var auto bool
+	name, errArgs := parseCtlArgs("start", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&auto, "auto", false, "run without asking for human input")
+	})
+	if errArgs != nil {
+		return errArgs
+	}
+	result, errStart := opts.client.start(ctx, name, auto)
+	if errStart != nil {
+		return errStart
+	}
+	return opts.printSessionAction(result)
+}
+
+func ctlStop(ctx context.Context, opts ctlOptions, args []string) error {
+	name, errArgs := parseCtlArgs("stop", args, nil)
+	if errArgs != nil {
+		return errArgs
+	}
+	result, errStop := opts.client.stop(ctx, name)
+	if errStop != nil {
+		return errStop
+	}
+	return opts.printSessionAction(result)
+}
+
+func (o ctlOptions) printSessionAction(result apiSessionActionResponse) error {
+	if o.jsonOut {
+		return o.printJSON(result)
+	}
+	_, errWrite := fmt.Fprintln(o.stdout, result.Name+":", result.Message)
+	return errWrite
+}
+
+func ctlRespond(ctx context.Context, opts ctlOptions, args []string) error {
+	var answer, decision string
+	var choices, comments repeatedFlag
+	name, errArgs := parseCtlArgs("respond", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&answer, "answer", "", "free-text answer")
+		fs.Var(&choices, "choice", "selected choice (repeatable)")
+		fs.StringVar(&decision, "decision", "", "work-gate decision: approve, approve-with-comments or request-changes")
+		fs.Var(&comments, "comment", "work-gate section comment as section=text (repeatable)")
+	})
+	if errArgs != nil {
+		return errArgs
+	}
+	ws, errWorkspace := opts.client.workspace(ctx, name)
+	if errWorkspace != nil {
+		return errWorkspace
+	}
+	if ws.PendingQuestion == nil {
+		return fmt.Errorf("%s has no pending question", name)
+	}
+
+	req := apiRespondRequest{QuestionID: ws.PendingQuestion.QuestionID, Answer: answer, SelectedChoices: choices}
+	if decision != "" {
+		review, errReview := parseCtlWorkGateReview(decision, answer, comments)
+		if errReview != nil {
+			return errReview
+		}
+		req = apiRespondRequest{QuestionID: ws.PendingQuestion.QuestionID, WorkGate: &review}
+	} else if answer == "" && len(choices) == 0 {
+		var errPrompt error
+		req, errPrompt = promptForResponse(opts.stdin, opts.stdout, ws.PendingQuestion)
+		if errPrompt != nil {
+			return errPrompt
+		}
+	}
+	result, errRespond := opts.client.respond(ctx, name, req)
+	if errRespond != nil {
+		return errRespond
+	}
+	if opts.jsonOut {
+		return opts.printJSON(result)
+	}
+	_, errWrite := fmt.Fprintln(opts.stdout, result.Message)
+	return errWrite
+}
+
+func parseCtlWorkGateReview(decision, comment string, comments []string) (apiWorkGateReviewRequest, error) {
+	review := apiWorkGateReviewRequest{Decision: decision, Comment: comment}
+	for _, raw := range comments {
+		section, text, ok := strings.Cut(raw, "=")
+		if !ok || strings.TrimSpace(section) == "" {
+			return apiWorkGateReviewRequest{}, fmt.Errorf("%w: --comment must be section=text, got %q", errCtlUsage, raw)
+		}
+		review.Comments = append(review.Comments, state.WorkGateComment{Section: strings.TrimSpace(section), Comment: text})
+	}
+	return review, nil
+}
+
+func renderPendingQuestion(out io.Writer, q *apiPendingQuestionResponse) {
+	if q.Message != "" {
+		_, _ = fmt.Fprintln(out, q.Message)
+	}
+	for _, section := range q.Sections {
+		_, _ = fmt.Fprintf(out, "[%s] %s\n", section.ID, section.Title)
+	}
+	for i, item := range q.Questions {
+		renderQuestionItem(out, i, item)
+	}
+}
+
+func renderQuestionItem(out io.Writer, index int, item apiQuestionItem) {
+	_, _ = fmt.Fprintln(out, "")
+	_, _ = fmt.Fprintf(out, "%d. %s\n", index+1, item.Question)
+	for j, choice := range item.Choices {
+		_, _ = fmt.Fprintf(out, "   %d) %s\n", j+1, choice)
+	}
+}
+
+// promptForResponse renders a pending question and reads the selections and
+// an optional free-text answer from in.
+func promptForResponse(in *bufio.Reader, out io.Writer, q *apiPendingQuestionResponse) (apiRespondRequest, error) {
+	req := apiRespondRequest{QuestionID: q.QuestionID}
+	if q.Message != "" {
+		_, _ = fmt.Fprintln(out, q.Message)
+	}
+	for i, item := range q.Questions {
+		renderQuestionItem(out, i, item)
+		selected, errSelect := promptForChoices(in, out, item)
+		if errSelect != nil {
+			return apiRespondRequest{}, errSelect
+		}
+		req.SelectedChoices = append(req.SelectedChoices, selected...)
+	}
+
+	label := "answer: "
+	if len(q.Questions) > 0 {
+		label = "additional comments (optional): "
+	}
+	_, _ = fmt.Fprint(out, label)
+	line, errRead := in.ReadString('\n')
+	if errRead != nil && !errors.Is(errRead, io.EOF) {
+		return apiRespondRequest{}, fmt.Errorf("reading answer: %w", errRead)
+	}
+	req.Answer = strings.TrimSpace(line)
+	if req.Answer == "" && len(req.SelectedChoices) == 0 {
+		return apiRespondRequest{}, errors.New("response cannot be empty")
+	}
+	return req, nil
+}
+
+func promptForChoices(in *bufio.Reader, out io.Writer, item apiQuestionItem) ([]string, error) {
+	if len(item.Choices) == 0 {
+		return nil, nil
+	}
+	for {
+		if item.MultiSelect {
+			_, _ = fmt.Fprintf(out, "select one or more [1-%d, comma separated, empty to skip]: ", len(item.Choices))
+		} else {
+			_, _ = fmt.Fprintf(out, "select one [1-%d, empty to skip]: ", len(item.Choices))
+		}
+		line, errRead := in.ReadString('\n')
+		if errRead != nil && !errors.Is(errRead, io.EOF) {
+			return nil, fmt.Errorf("reading selection: %w", errRead)
+		}
+		selected, errParse := parseChoiceSelection(line, item)
+		if errParse == nil {
+			return selected, nil
+		}
+		if errors.Is(errRead, io.EOF) {
+			return nil, errParse
+		}
+		_, _ = fmt.Fprintln(out, errParse)
+	}
+}
+
+func parseChoiceSelection(line string, item apiQuestionItem) ([]string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, nil
+	}
+	fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' })
+	if !item.MultiSelect && len(fields) > 1 {
+		return nil, errors.New("select a single choice")
+	}
+	var selected []string
+	for _, field := range fields {
+		n, errAtoi := strconv.Atoi(field)
+		if errAtoi != nil || n < 1 || n > len(item.Choices) {
+			return nil, fmt.Errorf("invalid choice %q", field)
+		}
+		selected = append(selected, item.Choices[n-1])
+	}
+	return selected, nil
+}
+
+func ctlTail(ctx context.Context, opts ctlOptions, args []string) error {
+	var follow bool
+	name, errArgs := parseCtlArgs("tail", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&follow, "f", false, "follow new log lines")
+	})
+	if errArgs != nil {
+		return errArgs
+	}
+	ws, errWorkspace := opts.client.workspace(ctx, name)
+	if errWorkspace != nil {
+		return errWorkspace
+	}
+	if errPrint := opts.printLog(ws.Log); errPrint != nil {
+		return errPrint
+	}
+	if !follow {
+		return nil
+	}
+
+	seen := ws.Log
+	return opts.client.watchSignals(ctx, func() error {
+		current, errCurrent := opts.client.workspace(ctx, name)
+		if errCurrent != nil {
+			return errCurrent
+		}
+		if errPrint := opts.printLog(newLogEntries(seen, current.Log)); errPrint != nil {
+			return errPrint
+		}
+		seen = current.Log
+		return nil
+	})
+}
+
+func (o ctlOptions) printLog(entries []apiLogEntry) error {
+	for _, entry := range entries {
+		var errWrite error
+		if o.jsonOut {
+			errWrite = json.NewEncoder(o.stdout).Encode(entry)
+		} else {
+			_, errWrite = fmt.Fprintln(o.stdout, entry.Prefix+entry.Text)
+		}
+		if errWrite != nil {
+			return errWrite
+		}
+	}
+	return nil
+}
+
+func ctlFork(ctx context.Context, opts ctlOptions, args []string) error {
+	var goalFile string
+	name, errArgs := parseCtlArgs("fork", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&goalFile, "goal", "", "GOAL.md for the fork (default: the root's fork template)")
+	})
+	if errArgs != nil {
+		return errArgs
+	}
+	var goalContent string
+	if goalFile != "" {
+		data, errRead := os.ReadFile(goalFile)
+		if errRead != nil {
+			return fmt.Errorf("reading goal file: %w", errRead)
+		}
+		goalContent = string(data)
+	}
+	result, errFork := opts.client.fork(ctx, name, goalContent)
+	if errFork != nil {
+		return errFork
+	}
+	if opts.jsonOut {
+		return opts.printJSON(result)
+	}
+	_, errWrite := fmt.Fprintln(opts.stdout, "forked", result.Parent, "as", result.Name, "in", result.Dir)
+	return errWrite
+}
+
+func ctlGoal(ctx context.Context, opts ctlOptions, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("%w: goal needs get or edit", errCtlUsage)
+	}
+	action := args[0]
+	if action != "get" && action != "edit" {
+		return fmt.Errorf("%w: unknown goal action %q", errCtlUsage, action)
+	}
+	name, errArgs := parseCtlArgs("goal "+action, args[1:], nil)
+	if errArgs != nil {
+		return errArgs
+	}
+	content, errGoal := opts.client.goal(ctx, name)
+	if errGoal != nil {
+		return errGoal
+	}
+
+	if action == "get" {
+		if opts.jsonOut {
+			return opts.printJSON(apiGoalResponse{Content: content})
+		}
+		_, errWrite := fmt.Fprint(opts.stdout, content)
+		return errWrite
+	}
+
+	edited, errEdit := editInTempFile(content, opts.editFile)
+	if errEdit != nil {
+		return errEdit
+	}
+	if edited == content {
+		if opts.jsonOut {
+			return opts.printJSON(apiUpdateGoalResponse{Updated: false, Workspace: name})
+		}
+		_, errWrite := fmt.Fprintln(opts.stdout, "GOAL.md unchanged")
+		return errWrite
+	}
+	result, errUpdate := opts.client.updateGoal(ctx, name, edited)
+	if errUpdate != nil {
+		return errUpdate
+	}
+	if opts.jsonOut {
+		return opts.printJSON(result)
+	}
+	_, errWrite := fmt.Fprintln(opts.stdout, "GOAL.md updated for", result.Workspace)
+	return errWrite
+}
+
+func editInTempFile(content string, editFile func(path string) error) (string, error) {
+	file, errCreate := os.CreateTemp("", "sgai-goal-*.md")
+	if errCreate != nil {
+		return "", fmt.Errorf("creating temporary file: %w", errCreate)
+	}
+	path := file.Name()
+	defer func() {
+		if errRemove := os.Remove(path); errRemove != nil {
+			log.Println("failed to remove temporary file:", errRemove)
+		}
+	}()
+	_, errWrite := file.WriteString(content)
+	if errClose := file.Close(); errClose != nil && errWrite == nil {
+		errWrite = errClose
+	}
+	if errWrite != nil {
+		return "", fmt.Errorf("writing temporary file: %w", errWrite)
+	}
+	if errEdit := editFile(path); errEdit != nil {
+		return "", fmt.Errorf("running editor: %w", errEdit)
+	}
+	edited, errRead := os.ReadFile(path)
+	if errRead != nil {
+		return "", fmt.Errorf("reading edited goal: %w", errRead)
+	}
+	return string(edited), nil
+}
+
+// runEditor opens path in $VISUAL or $EDITOR (default vi) and waits for it to
+// exit.
+func runEditor(path string) error {
+	editor := envOr("VISUAL", envOr("EDITOR", "vi"))
+	parts := strings.Fields(editor)
+	if len(parts) == 0 {
+		return errors.New("no editor configured")
+	}
+	cmd := exec.Command(parts[0], append(parts[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startCtlTestServer(t *testing.T) (string, string) {
+	t.Helper()
+	server, rootDir := setupTestServer(t)
+	mux := http.NewServeMux()
+	server.registerAPIRoutes(mux)
+	httpServer := httptest.NewServer(mux)
+	t.Cleanup(httpServer.Close)
+	return httpServer.URL, rootDir
+}
+
+func runCtlForTest(t *testing.T, serverURL string, stdin string, args ...string) (string, error) {
+	t.Helper()
+	var stdout bytes.Buffer
+	editFile := func(string) error { return errors.New("unexpected editor") }
+	errRun := runCtl(context.Background(), append([]string{"--server", serverURL}, args...), strings.NewReader(stdin), &stdout, editFile)
+	return stdout.String(), errRun
+}
+
+func TestCtlListAndStatus(t *testing.T) {
+	serverURL, rootDir := startCtlTestServer(t)
+	wsDir := setupTestWorkspace(t, rootDir, "alpha")
+	require.NoError(t, os.WriteFile(filepath.Join(wsDir, "GOAL.md"), []byte("# Alpha\n"), 0o644))
+
+	t.Run("listText", func(t *testing.T) {
+		out, errRun := runCtlForTest(t, serverURL, "", "list")
+		require.NoError(t, errRun)
+		assert.Contains(t, out, "NAME")
+		assert.Contains(t, out, "alpha")
+	})
+
+	t.Run("listJSON", func(t *testing.T) {
+		out, errRun := runCtlForTest(t, serverURL, "", "--json", "list")
+		require.NoError(t, errRun)
+		var summaries []ctlWorkspaceSummary
+		require.NoError(t, json.Unmarshal([]byte(out), &summaries))
+		require.NotEmpty(t, summaries)
+		names := make([]string, 0, len(summaries))
+		for _, s := range summaries {
+			names = append(names, s.Name)
+		}
+		assert.Contains(t, names, "alpha")
+	})
+
+	t.Run("statusText", func(t *testing.T) {
+		out, errRun := runCtlForTest(t, serverURL, "", "status", "alpha")
+		require.NoError(t, errRun)
+		assert.Contains(t, out, "workspace: alpha")
+		assert.Contains(t, out, "running:   no")
+	})
+
+	t.Run("statusUnknownWorkspace", func(t *testing.T) {
+		_, errRun := runCtlForTest(t, serverURL, "", "status", "missing")
+		require.ErrorIs(t, errRun, errCtlWorkspaceNotFound)
+	})
+}
+
+func TestCtlGoal(t *testing.T) {
+	serverURL, rootDir := startCtlTestServer(t)
+	wsDir := setupTestWorkspace(t, rootDir, "alpha")
+	require.NoError(t, os.WriteFile(filepath.Join(wsDir, "GOAL.md"), []byte("# Alpha\n"), 0o644))
+
+	t.Run("get", func(t *testing.T) {
+		out, errRun := runCtlForTest(t, serverURL, "", "goal", "get", "alpha")
+		require.NoError(t, errRun)
+		assert.Equal(t, "# Alpha\n", out)
+	})
+
+	t.Run("editSavesChanges", func(t *testing.T) {
+		var stdout bytes.Buffer
+		editFile := func(path string) error {
+			return os.WriteFile(path, []byte("# Alpha v2\n"), 0o644)
+		}
+		errRun := runCtl(context.Background(), []string{"--server", serverURL, "goal", "edit", "alpha"}, strings.NewReader(""), &stdout, editFile)
+		require.NoError(t, errRun)
+		assert.Contains(t, stdout.String(), "GOAL.md updated for alpha")
+		data, errRead := os.ReadFile(filepath.Join(wsDir, "GOAL.md"))
+		require.NoError(t, errRead)
+		assert.Equal(t, "# Alpha v2\n", string(data))
+	})
+
+	t.Run("editWithoutChanges", func(t *testing.T) {
+		var stdout bytes.Buffer
+		errRun := runCtl(context.Background(), []string{"--server", serverURL, "goal", "edit", "alpha"}, strings.NewReader(""), &stdout, func(string) error { return nil })
+		require.NoError(t, errRun)
+		assert.Contains(t, stdout.String(), "GOAL.md unchanged")
+	})
+}
+
+func TestCtlUsageErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+	}{
+		{"noCommand", nil},
+		{"unknownCommand", []string{"explode"}},
+		{"statusWithoutWorkspace", []string{"status"}},
+		{"goalWithoutAction", []string{"goal"}},
+		{"goalUnknownAction", []string{"goal", "print", "alpha"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, errRun := runCtlForTest(t, "http://127.0.0.1:0", "", tc.args...)
+			require.ErrorIs(t, errRun, errCtlUsage)
+		})
+	}
+}
+
+func TestCtlRespondWithoutPendingQuestion(t *testing.T) {
+	serverURL, rootDir := startCtlTestServer(t)
+	setupTestWorkspace(t, rootDir, "alpha")
+
+	_, errRun := runCtlForTest(t, serverURL, "", "respond", "--answer", "yes", "alpha")
+	require.Error(t, errRun)
+	assert.Contains(t, errRun.Error(), "no pending question")
+}
+
+func TestCtlAPIErrorIncludesStatus(t *testing.T) {
+	serverURL, _ := startCtlTestServer(t)
+
+	_, errRun := runCtlForTest(t, serverURL, "", "stop", "missing")
+	var apiErr *ctlAPIError
+	require.ErrorAs(t, errRun, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+}
+
+func TestPromptForResponse(t *testing.T) {
+	question := &apiPendingQuestionResponse{
+		QuestionID: "q1",
+		Type:       "multi-choice",
+		Questions: []apiQuestionItem{
+			{Question: "Which database?", Choices: []string{"Postgres", "SQLite"}},
+			{Question: "Which extras?", Choices: []string{"Metrics", "Tracing", "Logs"}, MultiSelect: true},
+		},
+	}
+
+	cases := []struct {
+		name        string
+		question    *apiPendingQuestionResponse
+		input       string
+		wantChoices []string
+		wantAnswer  string
+		wantErr     bool
+	}{
+		{"singleAndMulti", question, "2\n1,3\nship it\n", []string{"SQLite", "Metrics", "Logs"}, "ship it", false},
+		{"retriesInvalidChoice", question, "9\n1\n\n\n", []string{"Postgres"}, "", false},
+		{"freeText", &apiPendingQuestionResponse{QuestionID: "q2", Type: "free-text", Message: "What next?"}, "keep going\n", nil, "keep going", false},
+		{"emptyResponse", &apiPendingQuestionResponse{QuestionID: "q3", Type: "free-text", Message: "What next?"}, "\n", nil, "", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			req, errPrompt := promptForResponse(bufio.NewReader(strings.NewReader(tc.input)), &out, tc.question)
+			if tc.wantErr {
+				require.Error(t, errPrompt)
+				return
+			}
+			require.NoError(t, errPrompt)
+			assert.Equal(t, tc.question.QuestionID, req.QuestionID)
+			assert.Equal(t, tc.wantChoices, req.SelectedChoices)
+			assert.Equal(t, tc.wantAnswer, req.Answer)
+		})
+	}
+}
+
+func TestNewLogEntries(t *testing.T) {
+	a := apiLogEntry{Prefix: "[x] ", Text: "a"}
+	b := apiLogEntry{Prefix: "[x] ", Text: "b"}
+	c := apiLogEntry{Prefix: "[x] ", Text: "c"}
+	d := apiLogEntry{Prefix: "[x] ", Text: "d"}
+
+	cases := []struct {
+		name     string
+		previous []apiLogEntry
+		current  []apiLogEntry
+		want     []apiLogEntry
+	}{
+		{"nothingSeen", nil, []apiLogEntry{a, b}, []apiLogEntry{a, b}},
+		{"appended", []apiLogEntry{a, b}, []apiLogEntry{a, b, c}, []apiLogEntry{c}},
+		{"ringRotated", []apiLogEntry{a, b, c}, []apiLogEntry{b, c, d}, []apiLogEntry{d}},
+		{"unchanged", []apiLogEntry{a, b}, []apiLogEntry{a, b}, []apiLogEntry{}},
+		{"noOverlap", []apiLogEntry{a}, []apiLogEntry{c, d}, []apiLogEntry{c, d}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, newLogEntries(tc.previous, tc.current))
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func cmdDoctor(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Usage = func() {
+		fmt.Println("sgai doctor [--json] [dir]")
+		fmt.Println("")
+		fmt.Println("Checks opencode, jj and git, the opencode database, the model catalog,")
+		fmt.Println("MCP server commands from .sgai/opencode.jsonc and sgai.json in the")
+		fmt.Println("workspace (default: the current directory when it is an sgai workspace),")
+		fmt.Println("the sgai config directories and the embedded webapp. Exits with status 1")
+		fmt.Println("when any check fails.")
+		fmt.Println("")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args) //nolint:errcheck // ExitOnError FlagSet exits on error, never returns non-nil
+
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	workspacePath, errAbs := filepath.Abs(dir)
+	if errAbs != nil {
+		log.Fatalln("cannot resolve workspace path:", errAbs)
+	}
+	if !hassgaiDirectory(workspacePath) {
+		if fs.NArg() > 0 {
+			log.Fatalln("not an sgai workspace:", workspacePath)
+		}
+		workspacePath = ""
+	}
+
+	report := runDoctor(doctorOptions{
+		workspacePath:  workspacePath,
+		opencodeDBPath: resolveOpencodeDBPath(),
+		configDirs:     []string{defaultUserConfigDir()},
+	})
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if errEncode := enc.Encode(report); errEncode != nil {
+			log.Fatalln("cannot encode report:", errEncode)
+		}
+	} else {
+		fmt.Print(formatDoctorReport(report))
+	}
+	if report.Status == doctorFail {
+		os.Exit(1)
+	}
+}
+
+func formatDoctorReport(report doctorReport) string {
+	var buf strings.Builder
+	width := 0
+	for _, check := range report.Checks {
+		width = max(width, len(check.Name))
+	}
+	for _, check := range report.Checks {
+		fmt.Fprintf(&buf, "[%s] %-*s  %s\n", check.Status, width, check.Name, check.Detail)
+	}
+	fmt.Fprintf(&buf, "\noverall: %s\n", report.Status)
+	return buf.String()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+func cmdRetro(args []string) {
+	if len(args) < 1 || args[0] != "report" {
+		fmt.Println("usage: sgai retro report [--format markdown|html|json] [--since T] [--until T] [--output file] [dir]")
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("retro report", flag.ExitOnError)
+	format := fs.String("format", retroFormatMarkdown, "output format: markdown, html or json")
+	since := fs.String("since", "", "only include runs started at or after this time (RFC3339, YYYY-MM-DD or a duration such as 168h)")
+	until := fs.String("until", "", "only include runs started before this time (RFC3339, YYYY-MM-DD or a duration such as 168h)")
+	output := fs.String("output", "", "write the report to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Println("sgai retro report [--format markdown|html|json] [--since T] [--until T] [--output file] [dir]")
+		fmt.Println("")
+		fmt.Println("Aggregates every run under .sgai/retrospectives in the workspace (default:")
+		fmt.Println("the current directory): duration, iterations, gate failures, questions,")
+		fmt.Println("tokens, agents used and recurring blocker themes.")
+		fmt.Println("")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args[1:])
+
+	if !slices.Contains([]string{retroFormatMarkdown, retroFormatHTML, retroFormatJSON}, *format) {
+		fmt.Fprintln(os.Stderr, "unknown format:", *format)
+		os.Exit(2)
+	}
+	window, errWindow := parseUsageTimeRange(*since, *until, time.Now())
+	if errWindow != nil {
+		fmt.Fprintln(os.Stderr, errWindow)
+		os.Exit(2)
+	}
+
+	dir := "."
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	if absDir, errAbs := filepath.Abs(dir); errAbs == nil {
+		dir = absDir
+	}
+
+	report, errReport := buildRetroReport(dir, defaultUserConfigDir(), window, time.Now())
+	if errReport != nil {
+		log.Fatalln("cannot build retrospective report:", errReport)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, errCreate := os.Create(*output)
+		if errCreate != nil {
+			log.Fatalln("cannot create report file:", errCreate)
+		}
+		defer func() {
+			if errClose := file.Close(); errClose != nil {
+				log.Println("failed to close report file:", errClose)
+			}
+		}()
+		w = file
+	}
+	if errWrite := writeRetroReport(w, report, *format); errWrite != nil {
+		log.Fatalln("cannot write retrospective report:", errWrite)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+func cmdSecrets(args []string) {
+	if len(args) < 1 {
+		printSecretsUsage()
+		os.Exit(2)
+	}
+	fs := flag.NewFlagSet("secrets "+args[0], flag.ExitOnError)
+	user := fs.Bool("user", false, "use the user secrets in the sgai config directory instead of the workspace secrets")
+	dir := fs.String("dir", ".", "workspace directory")
+	fs.Usage = printSecretsUsage
+	_ = fs.Parse(args[1:])
+
+	scope := secretScopeWorkspace
+	if *user {
+		scope = secretScopeUser
+	}
+	workspacePath, errAbs := filepath.Abs(*dir)
+	if errAbs != nil {
+		log.Fatalln("cannot resolve workspace path:", errAbs)
+	}
+	if scope == secretScopeWorkspace && !hassgaiDirectory(workspacePath) {
+		log.Fatalln("not an sgai workspace:", workspacePath)
+	}
+	userConfigDir := defaultUserConfigDir()
+	path, errPath := secretsFilePath(scope, userConfigDir, workspacePath)
+	if errPath != nil {
+		log.Fatalln(errPath)
+	}
+
+	switch args[0] {
+	case "list":
+		secrets, errLoad := loadSecretsFile(path, userConfigDir)
+		if errLoad != nil {
+			log.Fatalln("cannot load secrets:", errLoad)
+		}
+		for _, name := range slices.Sorted(maps.Keys(secrets)) {
+			fmt.Println(name)
+		}
+	case "set":
+		name := secretNameArg(fs)
+		value, errValue := readSecretValue(os.Stdin, name)
+		if errValue != nil {
+			log.Fatalln("cannot read secret value:", errValue)
+		}
+		updateSecretsFile(path, userConfigDir, func(secrets map[string]string) {
+			secrets[name] = value
+		})
+		fmt.Println("stored", name, "in", path)
+	case "unset":
+		name := secretNameArg(fs)
+		updateSecretsFile(path, userConfigDir, func(secrets map[string]string) {
+			delete(secrets, name)
+		})
+		fmt.Println("removed", name, "from", path)
+	default:
+		printSecretsUsage()
+		os.Exit(2)
+	}
+}
+
+func printSecretsUsage() {
+	fmt.Println("usage: sgai secrets list [--user] [--dir path]")
+	fmt.Println("       sgai secrets set [--user] [--dir path] NAME   (value read from stdin)")
+	fmt.Println("       sgai secrets unset [--user] [--dir path] NAME")
+	fmt.Println("")
+	fmt.Println("Secrets are encrypted with " + secretsPassphraseEnv + " when it is set, or with")
+	fmt.Println("a generated key file in the sgai config directory otherwise.")
+}
+
+func secretNameArg(fs *flag.FlagSet) string {
+	if fs.NArg() != 1 {
+		printSecretsUsage()
+		os.Exit(2)
+	}
+	name := fs.Arg(0)
+	if !secretNamePattern.MatchString(name) {
+		log.Fatalln(errInvalidSecretName)
+	}
+	return name
+}
+
+// readSecretValue reads the first line of r so values never have to appear
+// on the command line or in shell history.
+func readSecretValue(r io.Reader, name string) (string, error) {
+	if file, ok := r.(*os.File); ok {
+		if info, errStat := file.Stat(); errStat == nil && info.Mode()&os.ModeCharDevice != 0 {
+			fmt.Fprint(os.Stderr, "value for ", name, ": ")
+		}
+	}
+	line, errRead := bufio.NewReader(r).ReadString('\n')
+	if errRead != nil && errRead != io.EOF {
+		return "", errRead
+	}
+	value := strings.TrimRight(line, "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("empty value for %s", name)
+	}
+	return value, nil
+}
+
+func updateSecretsFile(path, userConfigDir string, update func(map[string]string)) {
+	secrets, errLoad := loadSecretsFile(path, userConfigDir)
+	if errLoad != nil {
+		log.Fatalln("cannot load secrets:", errLoad)
+	}
+	update(secrets)
+	if errSave := saveSecretsFile(path, userConfigDir, secrets); errSave != nil {
+		log.Fatalln("cannot save secrets:", errSave)
+	}
+}
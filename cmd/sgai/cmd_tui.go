@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var errTUINotTerminal = errors.New("sgai tui needs an interactive terminal")
+
+const tuiReconnectDelay = 2 * time.Second
+
+func cmdTUI(args []string) {
+	fs := flag.NewFlagSet("tui", flag.ExitOnError)
+	server := fs.String("server", envOr("SGAI_SERVER", defaultCtlServer), "sgai serve base URL")
+	embed := fs.Bool("embed", false, "run the sgai server inside the dashboard instead of connecting to one")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: sgai tui [--server url] [--embed [dir]]
+
+Terminal dashboard for the workspaces of a running sgai serve. With --embed
+the server runs in-process over dir (default: current directory) and its
+output goes to a log file instead of the terminal.`)
+		fs.PrintDefaults()
+	}
+	if errParse := fs.Parse(args); errParse != nil {
+		log.Fatalln(errParse)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	tty := os.Stdout
+	baseURL := *server
+	if *embed {
+		embeddedURL, logPath, errEmbed := startEmbeddedServer(ctx, fs.Arg(0))
+		if errEmbed != nil {
+			log.Fatalln(errEmbed)
+		}
+		baseURL = embeddedURL
+		defer fmt.Fprintln(tty, "embedded server log:", logPath)
+	}
+
+	if errRun := runTUI(ctx, newCtlClient(baseURL), os.Stdin, tty); errRun != nil {
+		log.Fatalln(errRun)
+	}
+}
+
+// startEmbeddedServer serves the API for rootDir on a loopback port. Agent
+// and server output is redirected to a log file so it cannot draw over the
+// dashboard.
+func startEmbeddedServer(ctx context.Context, rootDir string) (baseURL, logPath string, err error) {
+	if _, errLook := exec.LookPath("opencode"); errLook != nil {
+		return "", "", fmt.Errorf("opencode is required for --embed: %w", errLook)
+	}
+	if rootDir == "" {
+		cwd, errCwd := os.Getwd()
+		if errCwd != nil {
+			return "", "", fmt.Errorf("failed to get working directory: %w", errCwd)
+		}
+		rootDir = cwd
+	}
+
+	listener, errListen := net.Listen("tcp4", "127.0.0.1:0")
+	if errListen != nil {
+		return "", "", fmt.Errorf("failed to listen: %w", errListen)
+	}
+	logFile, errLog := os.CreateTemp("", "sgai-tui-*.log")
+	if errLog != nil {
+		_ = listener.Close()
+		return "", "", fmt.Errorf("creating server log: %w", errLog)
+	}
+	os.Stdout = logFile
+	os.Stderr = logFile
+	log.SetOutput(logFile)
+
+	srv := NewServer(rootDir)
+	srv.shutdownCtx = ctx
+	srv.loadPersistedSettings()
+	srv.startStateWatcher()
+	go srv.warmStateCache()
+
+	mux := http.NewServeMux()
+	srv.registerAPIRoutes(mux)
+	httpServer := &http.Server{Handler: mux}
+	go func() {
+		if errServe := httpServer.Serve(listener); errServe != nil && !errors.Is(errServe, http.ErrServerClosed) {
+			log.Println("embedded server error:", errServe)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		if errClose := httpServer.Close(); errClose != nil {
+			log.Println("embedded server close:", errClose)
+		}
+	}()
+	return dashboardBaseURL(listener.Addr().String()), logFile.Name(), nil
+}
+
+// runTUI drives the dashboard until the user quits or ctx is cancelled.
+// State is refetched whenever the server signals a reload; commands run in
+// the background and report back through events so the loop never blocks
+// on the network.
+func runTUI(ctx context.Context, client *ctlClient, in *os.File, out io.Writer) error {
+	term, errRaw := enterRawMode(int(in.Fd()))
+	if errRaw != nil {
+		return errRaw
+	}
+	defer func() {
+		if errRestore := term.restore(); errRestore != nil {
+			log.Println("failed to restore terminal:", errRestore)
+		}
+	}()
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan func(*tuiModel))
+	post := func(event func(*tuiModel)) {
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+	}
+	postStatus := func(message string, err error) {
+		post(func(m *tuiModel) {
+			if err != nil {
+				m.status = err.Error()
+				return
+			}
+			m.status = message
+		})
+	}
+	refresh := func() {
+		go func() {
+			factory, errState := client.state(ctx)
+			post(func(m *tuiModel) {
+				if errState != nil {
+					m.status = errState.Error()
+					return
+				}
+				m.setState(factory)
+			})
+		}()
+	}
+
+	keys := make(chan []byte)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, errRead := in.Read(buf)
+			if errRead != nil {
+				close(keys)
+				return
+			}
+			select {
+			case keys <- append([]byte(nil), buf[:n]...):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		for ctx.Err() == nil {
+			errWatch := client.watchSignals(ctx, func() error {
+				refresh()
+				return nil
+			})
+			if errWatch != nil {
+				postStatus("", fmt.Errorf("signal stream: %w (reconnecting)", errWatch))
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(tuiReconnectDelay):
+				refresh()
+			}
+		}
+	}()
+
+	run := func(cmd tuiCommand) {
+		go func() {
+			switch cmd.kind {
+			case tuiCommandStart:
+				result, errStart := client.start(ctx, cmd.workspace, cmd.auto)
+				postStatus(cmd.workspace+": "+result.Message, errStart)
+			case tuiCommandStop:
+				result, errStop := client.stop(ctx, cmd.workspace)
+				postStatus(cmd.workspace+": "+result.Message, errStop)
+			case tuiCommandRespond:
+				result, errRespond := client.respond(ctx, cmd.workspace, cmd.respond)
+				postStatus(cmd.workspace+": "+result.Message, errRespond)
+			case tuiCommandDiff:
+				diff, errDiff := client.diff(ctx, cmd.workspace)
+				post(func(m *tuiModel) {
+					if errDiff != nil {
+						m.status = errDiff.Error()
+						return
+					}
+					m.diff, m.diffFor = diff, cmd.workspace
+				})
+			case tuiCommandRefresh:
+				refresh()
+			}
+		}()
+	}
+
+	model := &tuiModel{server: client.baseURL}
+	refresh()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		width, height := term.size()
+		fmt.Fprint(out, "\x1b[H"+strings.Join(model.render(width, height), "\r\n"))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case data, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, key := range parseTUIKeys(data) {
+				cmd := model.handleKey(key)
+				if cmd.kind == tuiCommandQuit {
+					return nil
+				}
+				run(cmd)
+			}
+		case event := <-events:
+			event(model)
+		case <-ticker.C:
+		}
+	}
+}
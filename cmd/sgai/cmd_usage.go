@@ -3,14 +3,21 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"cmp"
 	"database/sql"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -21,11 +28,19 @@ func cmdTokenStats(args []string) {
 	fs := flag.NewFlagSet("token-stats", flag.ExitOnError)
 	listenAddr := fs.String("listen-addr", "127.0.0.1:0", "listen address (unused)")
 	_ = listenAddr
+	format := fs.String("format", "table", "output format: table, json or csv")
+	since := fs.String("since", "", "only include sessions started at or after this time (RFC3339, YYYY-MM-DD or a duration such as 24h)")
+	until := fs.String("until", "", "only include sessions started before this time (RFC3339, YYYY-MM-DD or a duration such as 24h)")
+	all := fs.Bool("all", false, "treat the path as a serve root and aggregate every workspace under it")
+	bySession := fs.Bool("sessions", false, "list individual sessions instead of agent/model totals (table and csv)")
 	fs.Usage = func() {
-		fmt.Println("sgai token-stats <workspace-path>")
+		fmt.Println("sgai token-stats [--format table|json|csv] [--since T] [--until T] [--all] [--sessions] <workspace-path>")
 		fmt.Println("")
 		fmt.Println("Reads .sgai/sessions.jsonl in the workspace and aggregates token usage")
-		fmt.Println("from the opencode database, broken down by agent and model.")
+		fmt.Println("from the opencode database, broken down by agent and model. Costs are")
+		fmt.Println("computed from the pricing table in sgai.json and the user config.")
+		fmt.Println("")
+		fs.PrintDefaults()
 	}
 	_ = fs.Parse(args)
 
@@ -33,27 +48,155 @@ func cmdTokenStats(args []string) {
 		fs.Usage()
 		os.Exit(2)
 	}
+	if !slices.Contains([]string{"table", "json", "csv"}, *format) {
+		fmt.Fprintln(os.Stderr, "unknown format:", *format)
+		os.Exit(2)
+	}
+	window, errWindow := parseUsageTimeRange(*since, *until, time.Now())
+	if errWindow != nil {
+		fmt.Fprintln(os.Stderr, errWindow)
+		os.Exit(2)
+	}
+
 	workspacePath := fs.Arg(0)
 	absWorkspace, errAbs := filepath.Abs(workspacePath)
 	if errAbs == nil {
 		workspacePath = absWorkspace
 	}
 
+	dbPath := resolveOpencodeDBPath()
+	var usage tokenUsage
+	if *all {
+		var errAggregate error
+		usage, errAggregate = aggregateServeRootTokenUsage(dbPath, defaultUserConfigDir(), workspacePath, window)
+		if errAggregate != nil {
+			log.Fatalln("cannot aggregate token usage:", errAggregate)
+		}
+	} else {
+		sessionsPath := filepath.Join(workspacePath, ".sgai", "sessions.jsonl")
+		var errUsage error
+		usage, errUsage = loadWorkspaceTokenUsage(dbPath, defaultUserConfigDir(), workspacePath, window)
+		if errors.Is(errUsage, errNoRecordedSessions) {
+			fmt.Println("no sessions recorded in", sessionsPath)
+			return
+		}
+		if errUsage != nil {
+			log.Fatalln("cannot load token usage:", errUsage)
+		}
+	}
+
+	if errWrite := writeTokenUsage(os.Stdout, usage, *format, *bySession); errWrite != nil {
+		log.Fatalln("cannot write token usage:", errWrite)
+	}
+}
+
+var errNoRecordedSessions = errors.New("no sessions recorded")
+
+type usageTimeRange struct {
+	Since time.Time
+	Until time.Time
+}
+
+func (r usageTimeRange) isZero() bool {
+	return r.Since.IsZero() && r.Until.IsZero()
+}
+
+func (r usageTimeRange) contains(t time.Time) bool {
+	if !r.Since.IsZero() && t.Before(r.Since) {
+		return false
+	}
+	if !r.Until.IsZero() && !t.Before(r.Until) {
+		return false
+	}
+	return true
+}
+
+func parseUsageTimeRange(since, until string, now time.Time) (usageTimeRange, error) {
+	var window usageTimeRange
+	var errParse error
+	if window.Since, errParse = parseUsageTime(since, now); errParse != nil {
+		return usageTimeRange{}, fmt.Errorf("invalid since: %w", errParse)
+	}
+	if window.Until, errParse = parseUsageTime(until, now); errParse != nil {
+		return usageTimeRange{}, fmt.Errorf("invalid until: %w", errParse)
+	}
+	if !window.Since.IsZero() && !window.Until.IsZero() && !window.Since.Before(window.Until) {
+		return usageTimeRange{}, fmt.Errorf("since must be before until")
+	}
+	return window, nil
+}
+
+func parseUsageTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, errRFC := time.Parse(time.RFC3339, value); errRFC == nil {
+		return t, nil
+	}
+	if t, errDate := time.ParseInLocation(time.DateOnly, value, now.Location()); errDate == nil {
+		return t, nil
+	}
+	if d, errDuration := time.ParseDuration(value); errDuration == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not an RFC3339 time, a YYYY-MM-DD date or a duration", value)
+}
+
+func loadWorkspaceTokenUsage(dbPath, userConfigDir, workspacePath string, window usageTimeRange) (tokenUsage, error) {
 	sessionIDs, errSessions := readSessionsJSONL(filepath.Join(workspacePath, ".sgai", "sessions.jsonl"))
 	if errSessions != nil {
-		log.Fatalln("cannot read sessions.jsonl:", errSessions)
+		if errors.Is(errSessions, os.ErrNotExist) {
+			return tokenUsage{}, errNoRecordedSessions
+		}
+		return tokenUsage{}, errSessions
 	}
 	if len(sessionIDs) == 0 {
-		fmt.Println("no sessions recorded in", filepath.Join(workspacePath, ".sgai", "sessions.jsonl"))
-		return
+		return tokenUsage{}, errNoRecordedSessions
 	}
 
-	dbPath := resolveOpencodeDBPath()
-	usage, errQuery := queryTokenUsage(dbPath, sessionIDs)
+	pricing, errPricing := loadPricingTable(userConfigDir, workspacePath)
+	if errPricing != nil {
+		return tokenUsage{}, errPricing
+	}
+
+	sessions, errQuery := querySessionUsage(dbPath, sessionIDs)
 	if errQuery != nil {
-		log.Fatalln("cannot query opencode database:", errQuery)
+		return tokenUsage{}, errQuery
+	}
+	return summarizeTokenUsage(sessions, window, pricing), nil
+}
+
+func aggregateServeRootTokenUsage(dbPath, userConfigDir, rootDir string, window usageTimeRange) (tokenUsage, error) {
+	projects, errScan := scanForProjects(rootDir)
+	if errScan != nil {
+		return tokenUsage{}, fmt.Errorf("scanning serve root: %w", errScan)
 	}
-	printTokenUsage(usage)
+	var dirs []string
+	for _, proj := range projects {
+		if proj.HasWorkspace {
+			dirs = append(dirs, proj.Directory)
+		}
+	}
+	return aggregateWorkspacesTokenUsage(dbPath, userConfigDir, dirs, window)
+}
+
+func aggregateWorkspacesTokenUsage(dbPath, userConfigDir string, dirs []string, window usageTimeRange) (tokenUsage, error) {
+	usages := make(map[string]tokenUsage, len(dirs))
+	for _, dir := range dirs {
+		if absDir, errAbs := filepath.Abs(dir); errAbs == nil {
+			dir = absDir
+		}
+		usage, errUsage := loadWorkspaceTokenUsage(dbPath, userConfigDir, dir, window)
+		if errors.Is(errUsage, errNoRecordedSessions) {
+			continue
+		}
+		if errUsage != nil {
+			return tokenUsage{}, fmt.Errorf("workspace %s: %w", filepath.Base(dir), errUsage)
+		}
+		usages[dir] = usage
+	}
+	return mergeWorkspaceTokenUsage(usages), nil
 }
 
 type sessionEntry struct {
@@ -131,26 +274,54 @@ func resolveOpencodeDataDir() string {
 }
 
 type tokenUsageRow struct {
-	Agent        string `json:"agent"`
-	Model        string `json:"model"`
-	Input        int64  `json:"input"`
-	Output       int64  `json:"output"`
-	CacheRead    int64  `json:"cacheRead"`
-	CacheWrite   int64  `json:"cacheWrite"`
-	Reasoning    int64  `json:"reasoning"`
-	Other        int64  `json:"other"`
-	Total        int64  `json:"total"`
-	SessionCount int64  `json:"sessionCount"`
+	Workspace    string  `json:"workspace,omitempty"`
+	WorkspaceDir string  `json:"workspaceDir,omitempty"`
+	SessionID    string  `json:"sessionID,omitempty"`
+	Started      string  `json:"started,omitempty"`
+	Agent        string  `json:"agent"`
+	Model        string  `json:"model"`
+	Input        int64   `json:"input"`
+	Output       int64   `json:"output"`
+	CacheRead    int64   `json:"cacheRead"`
+	CacheWrite   int64   `json:"cacheWrite"`
+	Reasoning    int64   `json:"reasoning"`
+	Other        int64   `json:"other"`
+	Total        int64   `json:"total"`
+	SessionCount int64   `json:"sessionCount"`
+	Cost         float64 `json:"cost"`
+	Unpriced     bool    `json:"unpriced,omitempty"`
 }
 
 type tokenUsage struct {
-	Rows   []tokenUsageRow `json:"rows"`
-	Totals tokenUsageRow   `json:"totals"`
+	Rows       []tokenUsageRow `json:"rows"`
+	Sessions   []tokenUsageRow `json:"sessions"`
+	Workspaces []tokenUsageRow `json:"workspaces,omitempty"`
+	Totals     tokenUsageRow   `json:"totals"`
+}
+
+func emptyTokenUsage() tokenUsage {
+	return tokenUsage{Rows: []tokenUsageRow{}, Sessions: []tokenUsageRow{}}
 }
 
-func queryTokenUsage(dbPath string, sessionIDs []string) (tokenUsage, error) {
+func (r *tokenUsageRow) add(other tokenUsageRow) {
+	r.Input += other.Input
+	r.Output += other.Output
+	r.CacheRead += other.CacheRead
+	r.CacheWrite += other.CacheWrite
+	r.Reasoning += other.Reasoning
+	r.Other += other.Other
+	r.Total += other.Total
+	r.SessionCount += other.SessionCount
+	r.Cost += other.Cost
+	r.Unpriced = r.Unpriced || other.Unpriced
+}
+
+func querySessionUsage(dbPath string, sessionIDs []string) ([]tokenUsageRow, error) {
 	if len(sessionIDs) == 0 {
-		return tokenUsage{Rows: []tokenUsageRow{}}, nil
+		return nil, nil
+	}
+	if _, errStat := os.Stat(dbPath); errStat != nil {
+		return nil, fmt.Errorf("opencode database unavailable: %w", errStat)
 	}
 	dsn := "file:" + dbPath + "?mode=ro&_pragma=busy_timeout(5000)"
 	placeholders := make([]any, len(sessionIDs))
@@ -161,25 +332,25 @@ func queryTokenUsage(dbPath string, sessionIDs []string) (tokenUsage, error) {
 	}
 	query := `
 		SELECT
+			id,
 			COALESCE(agent, '') AS agent,
 			COALESCE(model, '') AS model,
-			SUM(tokens_input)       AS input,
-			SUM(tokens_output)      AS output,
-			SUM(tokens_cache_read)  AS cache_read,
-			SUM(tokens_cache_write) AS cache_write,
-			SUM(tokens_reasoning)   AS reasoning,
-			COUNT(*)                AS sessions
+			COALESCE(tokens_input, 0)       AS input,
+			COALESCE(tokens_output, 0)      AS output,
+			COALESCE(tokens_cache_read, 0)  AS cache_read,
+			COALESCE(tokens_cache_write, 0) AS cache_write,
+			COALESCE(tokens_reasoning, 0)   AS reasoning,
+			COALESCE(time_created, 0)       AS created
 		FROM session
 		WHERE id IN (` + strings.Join(placeList, ", ") + `)
-		GROUP BY agent, model
-		ORDER BY agent, model
+		ORDER BY created, id
 	`
 	var rows *sql.Rows
 	var errQuery error
 	for attempt := 0; attempt < 5; attempt++ {
 		db, errOpen := sql.Open("sqlite", dsn)
 		if errOpen != nil {
-			return tokenUsage{}, fmt.Errorf("opening opencode database: %w", errOpen)
+			return nil, fmt.Errorf("opening opencode database: %w", errOpen)
 		}
 		rows, errQuery = db.Query(query, placeholders...)
 		if errQuery == nil {
@@ -200,49 +371,164 @@ func queryTokenUsage(dbPath string, sessionIDs []string) (tokenUsage, error) {
 		time.Sleep(time.Second)
 	}
 	if errQuery != nil {
-		return tokenUsage{}, fmt.Errorf("querying token usage: %w", errQuery)
+		return nil, fmt.Errorf("querying token usage: %w", errQuery)
 	}
 
-	var usage tokenUsage
+	var sessions []tokenUsageRow
 	for rows.Next() {
 		var r tokenUsageRow
-		if errScan := rows.Scan(&r.Agent, &r.Model, &r.Input, &r.Output, &r.CacheRead, &r.CacheWrite, &r.Reasoning, &r.SessionCount); errScan != nil {
-			return tokenUsage{}, fmt.Errorf("scanning usage row: %w", errScan)
+		var createdMillis int64
+		if errScan := rows.Scan(&r.SessionID, &r.Agent, &r.Model, &r.Input, &r.Output, &r.CacheRead, &r.CacheWrite, &r.Reasoning, &createdMillis); errScan != nil {
+			return nil, fmt.Errorf("scanning usage row: %w", errScan)
+		}
+		if createdMillis > 0 {
+			r.Started = time.UnixMilli(createdMillis).UTC().Format(time.RFC3339)
 		}
 		r.Other = r.Reasoning
 		r.Total = r.Input + r.Output + r.CacheRead + r.CacheWrite + r.Reasoning
-		usage.Rows = append(usage.Rows, r)
-		usage.Totals.Input += r.Input
-		usage.Totals.Output += r.Output
-		usage.Totals.CacheRead += r.CacheRead
-		usage.Totals.CacheWrite += r.CacheWrite
-		usage.Totals.Reasoning += r.Reasoning
-		usage.Totals.Other += r.Other
-		usage.Totals.Total += r.Total
-		usage.Totals.SessionCount += r.SessionCount
+		r.SessionCount = 1
+		sessions = append(sessions, r)
 	}
 	if errRows := rows.Err(); errRows != nil {
-		return tokenUsage{}, fmt.Errorf("reading usage rows: %w", errRows)
+		return nil, fmt.Errorf("reading usage rows: %w", errRows)
+	}
+	return sessions, nil
+}
+
+func summarizeTokenUsage(sessions []tokenUsageRow, window usageTimeRange, pricing pricingTable) tokenUsage {
+	usage := emptyTokenUsage()
+	index := make(map[[2]string]int)
+	for _, session := range sessions {
+		if !window.isZero() {
+			started, errParse := time.Parse(time.RFC3339, session.Started)
+			if errParse != nil || !window.contains(started) {
+				continue
+			}
+		}
+		cost, priced := pricing.cost(session)
+		session.Cost = cost
+		session.Unpriced = !priced
+		usage.Sessions = append(usage.Sessions, session)
+
+		key := [2]string{session.Agent, session.Model}
+		i, exists := index[key]
+		if !exists {
+			i = len(usage.Rows)
+			index[key] = i
+			usage.Rows = append(usage.Rows, tokenUsageRow{Agent: session.Agent, Model: session.Model})
+		}
+		usage.Rows[i].add(session)
+		usage.Totals.add(session)
 	}
-	return usage, nil
+	slices.SortStableFunc(usage.Rows, func(a, b tokenUsageRow) int {
+		return cmp.Or(cmp.Compare(a.Agent, b.Agent), cmp.Compare(a.Model, b.Model))
+	})
+	return usage
 }
 
-func printTokenUsage(usage tokenUsage) {
-	if len(usage.Rows) == 0 {
-		fmt.Println("no token usage found for the recorded sessions")
+// mergeWorkspaceTokenUsage merges usages keyed by absolute workspace
+// directory. Workspaces are labelled by directory name, or by their full path
+// when several share one.
+func mergeWorkspaceTokenUsage(usages map[string]tokenUsage) tokenUsage {
+	baseNames := make(map[string]int, len(usages))
+	for dir := range usages {
+		baseNames[filepath.Base(dir)]++
+	}
+	merged := emptyTokenUsage()
+	merged.Workspaces = []tokenUsageRow{}
+	for _, dir := range slices.Sorted(maps.Keys(usages)) {
+		usage := usages[dir]
+		name := filepath.Base(dir)
+		if baseNames[name] > 1 {
+			name = dir
+		}
+		for _, row := range usage.Rows {
+			row.Workspace, row.WorkspaceDir = name, dir
+			merged.Rows = append(merged.Rows, row)
+		}
+		for _, session := range usage.Sessions {
+			session.Workspace, session.WorkspaceDir = name, dir
+			merged.Sessions = append(merged.Sessions, session)
+		}
+		workspaceTotals := usage.Totals
+		workspaceTotals.Workspace, workspaceTotals.WorkspaceDir = name, dir
+		merged.Workspaces = append(merged.Workspaces, workspaceTotals)
+		merged.Totals.add(usage.Totals)
+	}
+	return merged
+}
+
+func writeTokenUsage(w io.Writer, usage tokenUsage, format string, bySession bool) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(usage)
+	case "csv":
+		return writeTokenUsageCSV(w, usage, bySession)
+	default:
+		printTokenUsage(w, usage, bySession)
+		return nil
+	}
+}
+
+func writeTokenUsageCSV(w io.Writer, usage tokenUsage, bySession bool) error {
+	writer := csv.NewWriter(w)
+	if errHeader := writer.Write([]string{"workspace", "session", "started", "agent", "model", "input", "output", "cache_read", "cache_write", "reasoning", "total", "sessions", "cost_usd", "unpriced"}); errHeader != nil {
+		return errHeader
+	}
+	rows := usage.Rows
+	if bySession {
+		rows = usage.Sessions
+	}
+	for _, r := range rows {
+		record := []string{
+			r.Workspace, r.SessionID, r.Started, r.Agent, modelDisplay(r.Model),
+			strconv.FormatInt(r.Input, 10), strconv.FormatInt(r.Output, 10),
+			strconv.FormatInt(r.CacheRead, 10), strconv.FormatInt(r.CacheWrite, 10),
+			strconv.FormatInt(r.Reasoning, 10), strconv.FormatInt(r.Total, 10),
+			strconv.FormatInt(r.SessionCount, 10), strconv.FormatFloat(r.Cost, 'f', 4, 64),
+			strconv.FormatBool(r.Unpriced),
+		}
+		if errWrite := writer.Write(record); errWrite != nil {
+			return errWrite
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func printTokenUsage(w io.Writer, usage tokenUsage, bySession bool) {
+	rows := usage.Rows
+	if bySession {
+		rows = usage.Sessions
+	}
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "no token usage found for the recorded sessions")
 		return
 	}
-	fmt.Printf("%-20s %-45s %12s %12s %14s %15s %12s %12s %15s %10s\n",
-		"AGENT", "MODEL", "INPUT", "OUTPUT", "CACHED INPUT", "CACHED OUTPUT", "OTHER", "REASONING", "TOTAL", "SESSIONS")
-	fmt.Println(strings.Repeat("-", 168))
-	for _, r := range usage.Rows {
-		fmt.Printf("%-20s %-45s %12d %12d %14d %15d %12d %12d %15d %10d\n",
-			r.Agent, modelDisplay(r.Model), r.Input, r.Output, r.CacheRead, r.CacheWrite, r.Other, r.Reasoning, r.Total, r.SessionCount)
+	fmt.Fprintf(w, "%-20s %-20s %-45s %12s %12s %14s %15s %12s %12s %15s %10s %12s\n",
+		"WORKSPACE", "AGENT", "MODEL", "INPUT", "OUTPUT", "CACHED INPUT", "CACHED OUTPUT", "OTHER", "REASONING", "TOTAL", "SESSIONS", "COST (USD)")
+	fmt.Fprintln(w, strings.Repeat("-", 202))
+	for _, r := range rows {
+		fmt.Fprintf(w, "%-20s %-20s %-45s %12d %12d %14d %15d %12d %12d %15d %10d %12s\n",
+			r.Workspace, r.Agent, modelDisplay(r.Model), r.Input, r.Output, r.CacheRead, r.CacheWrite, r.Other, r.Reasoning, r.Total, r.SessionCount, formatCost(r))
 	}
-	fmt.Println(strings.Repeat("-", 168))
+	fmt.Fprintln(w, strings.Repeat("-", 202))
 	t := usage.Totals
-	fmt.Printf("%-20s %-45s %12d %12d %14d %15d %12d %12d %15d %10d\n",
-		"TOTAL", "", t.Input, t.Output, t.CacheRead, t.CacheWrite, t.Other, t.Reasoning, t.Total, t.SessionCount)
+	fmt.Fprintf(w, "%-20s %-20s %-45s %12d %12d %14d %15d %12d %12d %15d %10d %12s\n",
+		"TOTAL", "", "", t.Input, t.Output, t.CacheRead, t.CacheWrite, t.Other, t.Reasoning, t.Total, t.SessionCount, formatCost(t))
+	if t.Unpriced {
+		fmt.Fprintln(w, "* no pricing configured for at least one model; add it under \"pricing\" in sgai.json")
+	}
+}
+
+func formatCost(r tokenUsageRow) string {
+	cost := fmt.Sprintf("%.4f", r.Cost)
+	if r.Unpriced {
+		return cost + "*"
+	}
+	return cost
 }
 
 type modelDescriptor struct {
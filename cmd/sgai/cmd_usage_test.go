@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOpencodeSession struct {
+	id         string
+	agent      string
+	model      string
+	input      int64
+	output     int64
+	cacheRead  int64
+	cacheWrite int64
+	reasoning  int64
+	created    time.Time
+}
+
+func setupFakeOpencodeDB(t *testing.T, sessions []fakeOpencodeSession) string {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "opencode.db")
+	db, errOpen := sql.Open("sqlite", "file:"+dbPath)
+	require.NoError(t, errOpen)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	_, errCreate := db.Exec(`CREATE TABLE session (
+		id TEXT PRIMARY KEY,
+		agent TEXT,
+		model TEXT,
+		tokens_input INTEGER,
+		tokens_output INTEGER,
+		tokens_cache_read INTEGER,
+		tokens_cache_write INTEGER,
+		tokens_reasoning INTEGER,
+		time_created INTEGER
+	)`)
+	require.NoError(t, errCreate)
+	for _, s := range sessions {
+		_, errInsert := db.Exec(`INSERT INTO session VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			s.id, s.agent, s.model, s.input, s.output, s.cacheRead, s.cacheWrite, s.reasoning, s.created.UnixMilli())
+		require.NoError(t, errInsert)
+	}
+	return dbPath
+}
+
+func writeSessionsJSONL(t *testing.T, workspaceDir string, ids ...string) {
+	t.Helper()
+	var sb strings.Builder
+	for _, id := range ids {
+		data, errMarshal := json.Marshal(sessionEntry{SessionID: id, Agent: "coordinator"})
+		require.NoError(t, errMarshal)
+		sb.Write(data)
+		sb.WriteByte('\n')
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(workspaceDir, ".sgai"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(workspaceDir, ".sgai", "sessions.jsonl"), []byte(sb.String()), 0o644))
+}
+
+var fakeUsageSessions = []fakeOpencodeSession{
+	{
+		id:      "ses_1",
+		agent:   "coordinator",
+		model:   `{"id":"gpt-5.5","providerID":"openai","variant":"xhigh"}`,
+		input:   1_000_000,
+		output:  100_000,
+		created: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+	},
+	{
+		id:        "ses_2",
+		agent:     "go-developer",
+		model:     `{"id":"claude-sonnet-4.5","providerID":"anthropic"}`,
+		input:     200_000,
+		output:    50_000,
+		cacheRead: 1_000_000,
+		created:   time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC),
+	},
+	{
+		id:      "ses_3",
+		agent:   "coordinator",
+		model:   `{"id":"gpt-5.5","providerID":"openai","variant":"xhigh"}`,
+		input:   500_000,
+		output:  50_000,
+		created: time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC),
+	},
+}
+
+func TestParseUsageTimeRange(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		since     string
+		until     string
+		wantSince time.Time
+		wantUntil time.Time
+		wantErr   bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:      "rfc3339",
+			since:     "2026-03-01T00:00:00Z",
+			wantSince: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "dateOnly",
+			until:     "2026-03-05",
+			wantUntil: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "relativeDuration",
+			since:     "48h",
+			wantSince: now.Add(-48 * time.Hour),
+		},
+		{
+			name:    "invalid",
+			since:   "last tuesday",
+			wantErr: true,
+		},
+		{
+			name:    "inverted",
+			since:   "2026-03-05",
+			until:   "2026-03-01",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, err := parseUsageTimeRange(tt.since, tt.until, now)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.wantSince.Equal(window.Since))
+			assert.True(t, tt.wantUntil.Equal(window.Until))
+		})
+	}
+}
+
+func TestLoadWorkspaceTokenUsageComputesCosts(t *testing.T) {
+	dbPath := setupFakeOpencodeDB(t, fakeUsageSessions)
+	workspaceDir := t.TempDir()
+	writeSessionsJSONL(t, workspaceDir, "ses_1", "ses_2", "ses_3", "ses_missing")
+	require.NoError(t, os.WriteFile(filepath.Join(workspaceDir, configFileName), []byte(`{
+		"pricing": {"openai/gpt-5.5": {"input": 2, "output": 10}}
+	}`), 0o644))
+
+	usage, err := loadWorkspaceTokenUsage(dbPath, t.TempDir(), workspaceDir, usageTimeRange{})
+
+	require.NoError(t, err)
+	require.Len(t, usage.Sessions, 3)
+	assert.Equal(t, "ses_1", usage.Sessions[0].SessionID)
+	assert.Equal(t, "2026-03-01T10:00:00Z", usage.Sessions[0].Started)
+	assert.InDelta(t, 3.0, usage.Sessions[0].Cost, 1e-9)
+
+	require.Len(t, usage.Rows, 2)
+	coordinator := usage.Rows[0]
+	assert.Equal(t, "coordinator", coordinator.Agent)
+	assert.Equal(t, int64(2), coordinator.SessionCount)
+	assert.Equal(t, int64(1_500_000), coordinator.Input)
+	assert.InDelta(t, 4.5, coordinator.Cost, 1e-9)
+	assert.False(t, coordinator.Unpriced)
+
+	developer := usage.Rows[1]
+	assert.Equal(t, "go-developer", developer.Agent)
+	assert.True(t, developer.Unpriced)
+	assert.Zero(t, developer.Cost)
+
+	assert.Equal(t, int64(3), usage.Totals.SessionCount)
+	assert.InDelta(t, 4.5, usage.Totals.Cost, 1e-9)
+	assert.True(t, usage.Totals.Unpriced)
+}
+
+func TestLoadWorkspaceTokenUsageFiltersByTime(t *testing.T) {
+	dbPath := setupFakeOpencodeDB(t, fakeUsageSessions)
+	workspaceDir := t.TempDir()
+	writeSessionsJSONL(t, workspaceDir, "ses_1", "ses_2", "ses_3")
+
+	usage, err := loadWorkspaceTokenUsage(dbPath, t.TempDir(), workspaceDir, usageTimeRange{
+		Since: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+		Until: time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, usage.Sessions, 1)
+	assert.Equal(t, "ses_2", usage.Sessions[0].SessionID)
+	assert.Equal(t, int64(1), usage.Totals.SessionCount)
+}
+
+func TestLoadWorkspaceTokenUsageWithoutSessions(t *testing.T) {
+	_, err := loadWorkspaceTokenUsage(filepath.Join(t.TempDir(), "opencode.db"), t.TempDir(), t.TempDir(), usageTimeRange{})
+	assert.ErrorIs(t, err, errNoRecordedSessions)
+}
+
+func TestAggregateServeRootTokenUsage(t *testing.T) {
+	dbPath := setupFakeOpencodeDB(t, fakeUsageSessions)
+	rootDir := t.TempDir()
+	writeSessionsJSONL(t, filepath.Join(rootDir, "alpha"), "ses_1")
+	writeSessionsJSONL(t, filepath.Join(rootDir, "beta"), "ses_2", "ses_3")
+	require.NoError(t, os.MkdirAll(filepath.Join(rootDir, "gamma", ".sgai"), 0o755))
+
+	usage, err := aggregateServeRootTokenUsage(dbPath, t.TempDir(), rootDir, usageTimeRange{})
+
+	require.NoError(t, err)
+	require.Len(t, usage.Workspaces, 2)
+	assert.Equal(t, "alpha", usage.Workspaces[0].Workspace)
+	assert.Equal(t, int64(1), usage.Workspaces[0].SessionCount)
+	assert.Equal(t, "beta", usage.Workspaces[1].Workspace)
+	assert.Equal(t, int64(2), usage.Workspaces[1].SessionCount)
+	assert.Len(t, usage.Rows, 3)
+	assert.Len(t, usage.Sessions, 3)
+	assert.Equal(t, int64(3), usage.Totals.SessionCount)
+}
+
+func TestAggregateWorkspacesTokenUsageKeepsSameNamedWorkspacesApart(t *testing.T) {
+	dbPath := setupFakeOpencodeDB(t, fakeUsageSessions)
+	first := filepath.Join(t.TempDir(), "api")
+	second := filepath.Join(t.TempDir(), "api")
+	writeSessionsJSONL(t, first, "ses_1")
+	writeSessionsJSONL(t, second, "ses_2", "ses_3")
+
+	usage, err := aggregateWorkspacesTokenUsage(dbPath, t.TempDir(), []string{first, second}, usageTimeRange{})
+
+	require.NoError(t, err)
+	require.Len(t, usage.Workspaces, 2)
+	counts := make(map[string]int64)
+	for _, workspace := range usage.Workspaces {
+		assert.Equal(t, workspace.WorkspaceDir, workspace.Workspace)
+		counts[workspace.WorkspaceDir] = workspace.SessionCount
+	}
+	assert.Equal(t, map[string]int64{first: 1, second: 2}, counts)
+	assert.Equal(t, int64(3), usage.Totals.SessionCount)
+}
+
+func TestSummarizeTokenUsageWindowExcludesUndatedSessions(t *testing.T) {
+	sessions := []tokenUsageRow{
+		{SessionID: "dated", Started: "2026-03-02T10:00:00Z", SessionCount: 1},
+		{SessionID: "empty", SessionCount: 1},
+		{SessionID: "garbled", Started: "yesterday", SessionCount: 1},
+	}
+
+	all := summarizeTokenUsage(sessions, usageTimeRange{}, pricingTable{})
+	windowed := summarizeTokenUsage(sessions, usageTimeRange{Since: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)}, pricingTable{})
+
+	assert.Len(t, all.Sessions, 3)
+	require.Len(t, windowed.Sessions, 1)
+	assert.Equal(t, "dated", windowed.Sessions[0].SessionID)
+}
+
+func TestWriteTokenUsageFormats(t *testing.T) {
+	usage := summarizeTokenUsage([]tokenUsageRow{
+		{SessionID: "ses_1", Agent: "coordinator", Model: "openai/gpt-5.5", Input: 1_000_000, Total: 1_000_000, SessionCount: 1},
+	}, usageTimeRange{}, pricingTable{"openai/gpt-5.5": {Input: 2}})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeTokenUsage(&buf, usage, "json", false))
+		var decoded tokenUsage
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.InDelta(t, 2.0, decoded.Totals.Cost, 1e-9)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeTokenUsage(&buf, usage, "csv", true))
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		assert.True(t, strings.HasPrefix(lines[0], "workspace,session,started,agent,model"))
+		assert.Equal(t, ",ses_1,,coordinator,openai/gpt-5.5,1000000,0,0,0,0,1000000,1,2.0000,false", lines[1])
+	})
+
+	t.Run("table", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeTokenUsage(&buf, usage, "table", false))
+		assert.Contains(t, buf.String(), "COST (USD)")
+		assert.Contains(t, buf.String(), "2.0000")
+	})
+}
+
+func TestHandleAPITokenStatsIncludesCosts(t *testing.T) {
+	dbPath := setupFakeOpencodeDB(t, fakeUsageSessions)
+	t.Setenv("OPENCODE_DB", dbPath)
+	server, rootDir := setupTestServer(t)
+	server.externalConfigDir = t.TempDir()
+	wsDir := setupTestWorkspace(t, rootDir, "costly")
+	writeSessionsJSONL(t, wsDir, "ses_1", "ses_3")
+	require.NoError(t, os.WriteFile(filepath.Join(server.externalConfigDir, userConfigFileName), []byte(`{
+		"pricing": {"openai/gpt-5.5": {"input": 2, "output": 10}}
+	}`), 0o644))
+
+	w := serveHTTP(server, http.MethodGet, "/api/v1/workspaces/costly/token-stats?since=2026-03-02", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var usage tokenUsage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &usage))
+	assert.Equal(t, int64(1), usage.Totals.SessionCount)
+	assert.InDelta(t, 1.5, usage.Totals.Cost, 1e-9)
+
+	w = serveHTTP(server, http.MethodGet, "/api/v1/workspaces/costly/token-stats?since=yesterday-ish", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveHTTP(server, http.MethodGet, "/api/v1/token-stats", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var aggregate tokenUsage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &aggregate))
+	require.Len(t, aggregate.Workspaces, 1)
+	assert.Equal(t, "costly", aggregate.Workspaces[0].Workspace)
+	assert.InDelta(t, 4.5, aggregate.Totals.Cost, 1e-9)
+
+	assert.Nil(t, server.workspaceTokenTotals(wsDir), "the first lookup must not wait for the query")
+	require.Eventually(t, func() bool { return server.workspaceTokenTotals(wsDir) != nil }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(2), server.workspaceTokenTotals(wsDir).SessionCount)
+	assert.Nil(t, server.workspaceTokenTotals(setupTestWorkspace(t, rootDir, "idle")))
+}
@@ -16,6 +16,7 @@ type composerState struct {
 	Retrospective  bool                `json:"retrospective"`
 	Agents         []composerAgentConf `json:"agents"`
 	Model          string              `json:"model"`
+	Interactive    string              `json:"interactive,omitempty"`
 	Tasks          string              `json:"tasks"`
 }
 
@@ -65,6 +66,7 @@ func loadComposerStateFromDisk(dir string) composerState {
 		SafetyAnalysis: bodyHasSafetyAnalysis(bodyContent),
 		Retrospective:  retrospectiveEnabled(metadata),
 		Model:          metadata.Model,
+		Interactive:    metadata.Interactive,
 		Tasks:          extractTasksFromBody(bodyContent),
 	}
 
@@ -199,6 +201,12 @@ func buildGOALContent(st composerState) string {
 		buf.WriteString("\n")
 	}
 
+	if st.Interactive != "" {
+		buf.WriteString("interactive: ")
+		buf.WriteString(st.Interactive)
+		buf.WriteString("\n")
+	}
+
 	if st.Retrospective {
 		buf.WriteString("retrospective: true\n")
 	}
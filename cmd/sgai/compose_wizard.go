@@ -1,11 +1,18 @@
 package main
 
 type workflowTemplate struct {
-	ID          string
-	Name        string
-	Description string
-	Icon        string
-	Agents      []composerAgentConf
+	ID             string
+	Name           string
+	Description    string
+	Icon           string
+	Agents         []composerAgentConf
+	Model          string
+	CompletionGate string
+	Interactive    string
+	Tasks          string
+	Body           string
+	TechStack      []techStackItem
+	Source         string
 }
 
 const defaultCoordinatorModel = "openai/gpt-5.5 (xhigh)"
@@ -6,9 +6,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/adrg/xdg"
 )
 
-const configFileName = "sgai.json"
+const (
+	configFileName     = "sgai.json"
+	userConfigFileName = "config.json"
+)
 
 func defaultActionConfigs() []actionConfig {
 	return []actionConfig{
@@ -40,13 +45,38 @@ type actionConfig struct {
 	Description string `json:"description,omitempty"`
 }
 
+// projectActions returns the actions from the workspace sgai.json, or the
+// defaults when it defines none.
+func projectActions(workspacePath string) []actionConfig {
+	config, errLoad := loadProjectConfig(workspacePath)
+	if errLoad != nil || config == nil || config.Actions == nil {
+		return defaultActionConfigs()
+	}
+	return config.Actions
+}
+
+func findProjectAction(workspacePath, name string) (actionConfig, bool) {
+	for _, action := range projectActions(workspacePath) {
+		if action.Name == name {
+			return action, true
+		}
+	}
+	return actionConfig{}, false
+}
+
 // projectConfig represents the sgai.json configuration file.
 // The configuration file must be located at the project root, as a sibling to the .sgai directory.
 type projectConfig struct {
-	DefaultModel string                     `json:"defaultModel,omitempty"`
-	MCP          map[string]json.RawMessage `json:"mcp,omitempty"`
-	Editor       string                     `json:"editor,omitempty"`
-	Actions      []actionConfig             `json:"actions,omitempty"`
+	DefaultModel    string                     `json:"defaultModel,omitempty"`
+	FallbackModels  []string                   `json:"fallbackModels,omitempty"`
+	MCP             map[string]json.RawMessage `json:"mcp,omitempty"`
+	Editor          string                     `json:"editor,omitempty"`
+	Actions         []actionConfig             `json:"actions,omitempty"`
+	Pricing         pricingTable               `json:"pricing,omitempty"`
+	Secrets         *secretsConfig             `json:"secrets,omitempty"`
+	Redaction       *redactionConfig           `json:"redaction,omitempty"`
+	ToolPermissions toolPermissionsConfig      `json:"toolPermissions,omitempty"`
+	Pipeline        *pipelineConfig            `json:"pipeline,omitempty"`
 }
 
 func loadProjectConfig(dir string) (*projectConfig, error) {
@@ -79,12 +109,41 @@ func loadProjectConfig(dir string) (*projectConfig, error) {
 	return &config, nil
 }
 
+// userConfig represents the user-level config.json in the sgai config directory.
+type userConfig struct {
+	Pricing   pricingTable     `json:"pricing,omitempty"`
+	Roots     []serveRoot      `json:"roots,omitempty"`
+	Knowledge *knowledgeConfig `json:"knowledge,omitempty"`
+}
+
+// defaultUserConfigDir is the sgai config directory, which holds config.json
+// and the other user-level state files.
+func defaultUserConfigDir() string {
+	return filepath.Join(xdg.ConfigHome, "sgai")
+}
+
+func loadUserConfig(configDir string) (*userConfig, error) {
+	configPath := filepath.Join(configDir, userConfigFileName)
+	data, errRead := os.ReadFile(configPath)
+	if errRead != nil {
+		if os.IsNotExist(errRead) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading user config %s: %w", configPath, errRead)
+	}
+	var config userConfig
+	if errUnmarshal := json.Unmarshal(data, &config); errUnmarshal != nil {
+		return nil, fmt.Errorf("parsing user config %s: %w", configPath, errUnmarshal)
+	}
+	return &config, nil
+}
+
 func validateProjectConfig(config *projectConfig) error {
 	if config == nil {
 		return nil
 	}
 
-	if config.DefaultModel == "" {
+	if config.DefaultModel == "" && len(config.FallbackModels) == 0 {
 		return nil
 	}
 
@@ -93,21 +152,56 @@ func validateProjectConfig(config *projectConfig) error {
 		return fmt.Errorf("validating defaultModel in config file: %w", errModels)
 	}
 
-	if errValidate := validateModelSpec(catalog, config.DefaultModel); errValidate != nil {
-		return fmt.Errorf("invalid defaultModel in config file: %w", errValidate)
+	if config.DefaultModel != "" {
+		if errValidate := validateModelSpec(catalog, config.DefaultModel); errValidate != nil {
+			return fmt.Errorf("invalid defaultModel in config file: %w", errValidate)
+		}
+	}
+
+	for _, fallback := range config.FallbackModels {
+		if errValidate := validateModelSpec(catalog, fallback); errValidate != nil {
+			return fmt.Errorf("invalid fallbackModels in config file: %w", errValidate)
+		}
+	}
+
+	return nil
+}
+
+func validateGoalModels(metadata GoalMetadata) error {
+	if len(metadata.Models) == 0 && len(metadata.FallbackModels) == 0 {
+		return nil
+	}
+
+	catalog, errModels := fetchValidModels()
+	if errModels != nil {
+		return fmt.Errorf("validating models in GOAL.md: %w", errModels)
+	}
+
+	if errValidate := validateAgentModels(catalog, metadata.Models); errValidate != nil {
+		return fmt.Errorf("invalid models in GOAL.md: %w", errValidate)
+	}
+
+	for _, fallback := range metadata.FallbackModels {
+		if errValidate := validateModelSpec(catalog, fallback); errValidate != nil {
+			return fmt.Errorf("invalid fallbackModels in GOAL.md: %w", errValidate)
+		}
 	}
 
 	return nil
 }
 
 func applyConfigDefaults(config *projectConfig, metadata *GoalMetadata) {
-	if config == nil || config.DefaultModel == "" {
+	if config == nil {
 		return
 	}
 
 	if metadata.Model == "" {
 		metadata.Model = config.DefaultModel
 	}
+
+	if len(metadata.FallbackModels) == 0 {
+		metadata.FallbackModels = config.FallbackModels
+	}
 }
 
 func applyCustomMCPs(dir string, config *projectConfig) error {
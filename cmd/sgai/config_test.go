@@ -152,11 +152,27 @@ func TestValidateProjectConfig(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "validFallbackModels",
+			config: &projectConfig{
+				DefaultModel:   "openai/gpt-5.5 (xhigh)",
+				FallbackModels: []string{"anthropic/claude-sonnet-4.5"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalidFallbackModel",
+			config: &projectConfig{
+				FallbackModels: []string{"openai/gpt-missing"},
+			},
+			wantErr:     true,
+			errContains: "invalid fallbackModels in config file",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.config != nil && tt.config.DefaultModel != "" {
+			if tt.config != nil && (tt.config.DefaultModel != "" || len(tt.config.FallbackModels) > 0) {
 				setupFakeOpenCode(t, fakeModelsVerboseOutput, 0)
 			}
 
@@ -220,6 +236,27 @@ func TestApplyConfigDefaults(t *testing.T) {
 				assert.Equal(t, "default-model", m.Model)
 			},
 		},
+		{
+			name: "appliesFallbackModels",
+			config: &projectConfig{
+				FallbackModels: []string{"fallback-a", "fallback-b"},
+			},
+			metadata: &GoalMetadata{Model: "model1"},
+			validate: func(t *testing.T, m *GoalMetadata) {
+				assert.Equal(t, "model1", m.Model)
+				assert.Equal(t, []string{"fallback-a", "fallback-b"}, m.FallbackModels)
+			},
+		},
+		{
+			name: "keepsGoalFallbackModels",
+			config: &projectConfig{
+				FallbackModels: []string{"fallback-a"},
+			},
+			metadata: &GoalMetadata{FallbackModels: []string{"goal-fallback"}},
+			validate: func(t *testing.T, m *GoalMetadata) {
+				assert.Equal(t, []string{"goal-fallback"}, m.FallbackModels)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -11,7 +11,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/adhocore/gronx"
 	"github.com/sandgardenhq/sgai/pkg/state"
 )
 
@@ -49,7 +48,8 @@ func runContinuousModePrompt(ctx context.Context, dir string, prompt string, mcp
 
 		cmd := exec.CommandContext(ctx, "opencode", "run", "--title", "continuous-mode-prompt")
 		cmd.Dir = dir
-		cmd.Env = buildManagedOpenCodeEnv(dir, mcpURL, "continuous-mode", "auto")
+		cmd.Env = buildManagedOpenCodeEnv(dir, mcpURL, "continuous-mode", "auto", nil)
+		cmd.Env = append(cmd.Env, secretEnv(defaultUserConfigDir(), dir, projectSecretsConfig(dir).agentRefs())...)
 		cmd.Stdin = strings.NewReader(prompt)
 
 		if errRun := cmd.Run(); errRun != nil {
@@ -65,20 +65,16 @@ func runContinuousModePrompt(ctx context.Context, dir string, prompt string, mcp
 	updateContinuousModeProgress(coord, "continuous mode prompt failed after all retries, proceeding to watch loop")
 }
 
-func watchForTrigger(ctx context.Context, dir string, lastChecksum string, autoDuration time.Duration, cronExpr string) triggerKind {
+func watchForTrigger(ctx context.Context, dir string, lastChecksum string, autoDuration time.Duration, cron *cronScheduler, triggers *continuousTriggers) triggerEvent {
 	goalPath := filepath.Join(dir, "GOAL.md")
 
-	var deadline time.Time
+	event, scheduled, deadline := cron.plan(time.Now())
+	if event != nil {
+		return *event
+	}
 	var deadlineTrigger triggerKind
-
-	if cronExpr != "" {
-		nextTick, errNext := gronx.NextTick(cronExpr, false)
-		if errNext != nil {
-			log.Println("failed to parse cron expression:", errNext)
-		} else {
-			deadline = nextTick
-			deadlineTrigger = triggerCron
-		}
+	if !deadline.IsZero() {
+		deadlineTrigger = triggerCron
 	}
 
 	now := time.Now()
@@ -87,10 +83,14 @@ func watchForTrigger(ctx context.Context, dir string, lastChecksum string, autoD
 		deadlineTrigger = triggerAuto
 	}
 
+	watch := newTriggerWatch(ctx, dir, triggers)
+
 	for {
 		select {
 		case <-ctx.Done():
-			return triggerNone
+			return triggerEvent{Kind: triggerNone}
+		case event := <-watch.webhookEvents():
+			return event
 		case <-time.After(continuousModePollInterval):
 		}
 
@@ -99,11 +99,18 @@ func watchForTrigger(ctx context.Context, dir string, lastChecksum string, autoD
 			continue
 		}
 		if currentChecksum != lastChecksum {
-			return triggerGoal
+			return triggerEvent{Kind: triggerGoal, Payload: "GOAL.md changed"}
+		}
+
+		if event, fired := watch.poll(ctx); fired {
+			return event
 		}
 
 		if !deadline.IsZero() && time.Now().After(deadline) {
-			return deadlineTrigger
+			if deadlineTrigger == triggerCron {
+				return cron.fired(scheduled, time.Now())
+			}
+			return triggerEvent{Kind: deadlineTrigger, Payload: deadline.UTC().Format(time.RFC3339)}
 		}
 	}
 }
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adhocore/gronx"
+)
+
+const (
+	cronPolicySkip    = "skip"
+	cronPolicyRunOnce = "run-once"
+	cronPolicyRunAll  = "run-all"
+	cronPolicyQueue   = "queue"
+
+	// maxCronCatchUp bounds how many missed fire times are replayed.
+	maxCronCatchUp = 100
+)
+
+var (
+	errInvalidCronExpression = errors.New("invalid cron expression")
+	errInvalidCronPolicy     = errors.New("invalid cron policy")
+)
+
+// cronSchedule is the continuous-mode cron configuration from GOAL.md.
+type cronSchedule struct {
+	Expr     string
+	Location *time.Location
+	Missed   string
+	Overlap  string
+	Jitter   time.Duration
+}
+
+// cronScheduleState is persisted in .sgai/schedule.json so fire times missed
+// while the server was down can be caught up on the next start.
+type cronScheduleState struct {
+	LastRun       time.Time `json:"lastRun,omitzero"`
+	LastScheduled time.Time `json:"lastScheduled,omitzero"`
+}
+
+func cronSchedulePath(dir string) string {
+	return filepath.Join(dir, ".sgai", "schedule.json")
+}
+
+func parseCronSchedule(metadata GoalMetadata) (cronSchedule, error) {
+	schedule := cronSchedule{
+		Expr:     strings.TrimSpace(metadata.ContinuousModeCron),
+		Location: time.Local,
+		Missed:   cmp.Or(metadata.ContinuousModeCronMissed, cronPolicySkip),
+		Overlap:  cmp.Or(metadata.ContinuousModeCronOverlap, cronPolicySkip),
+	}
+	if schedule.Expr == "" {
+		return schedule, nil
+	}
+	if !gronx.IsValid(schedule.Expr) {
+		return cronSchedule{}, fmt.Errorf("%w: %q", errInvalidCronExpression, schedule.Expr)
+	}
+	if metadata.ContinuousModeCronTimezone != "" {
+		loc, errLoc := time.LoadLocation(metadata.ContinuousModeCronTimezone)
+		if errLoc != nil {
+			return cronSchedule{}, fmt.Errorf("invalid continuousModeCronTimezone: %w", errLoc)
+		}
+		schedule.Location = loc
+	}
+	switch schedule.Missed {
+	case cronPolicySkip, cronPolicyRunOnce, cronPolicyRunAll:
+	default:
+		return cronSchedule{}, fmt.Errorf("%w: continuousModeCronMissed must be skip, run-once or run-all, got %q", errInvalidCronPolicy, schedule.Missed)
+	}
+	switch schedule.Overlap {
+	case cronPolicySkip, cronPolicyQueue:
+	default:
+		return cronSchedule{}, fmt.Errorf("%w: continuousModeCronOverlap must be skip or queue, got %q", errInvalidCronPolicy, schedule.Overlap)
+	}
+	if metadata.ContinuousModeCronJitter != "" {
+		jitter, errJitter := time.ParseDuration(metadata.ContinuousModeCronJitter)
+		if errJitter != nil || jitter < 0 {
+			return cronSchedule{}, fmt.Errorf("invalid continuousModeCronJitter %q", metadata.ContinuousModeCronJitter)
+		}
+		schedule.Jitter = jitter
+	}
+	return schedule, nil
+}
+
+func readCronSchedule(workspacePath string) (cronSchedule, error) {
+	metadata, errParse := parseYAMLFrontmatterFromFile(filepath.Join(workspacePath, "GOAL.md"))
+	if errParse != nil {
+		return cronSchedule{}, errParse
+	}
+	return parseCronSchedule(metadata)
+}
+
+func (c cronSchedule) next(after time.Time) (time.Time, error) {
+	return gronx.NextTickAfter(c.Expr, after.In(c.Location), false)
+}
+
+// between returns the fire times in (from, to], at most limit of them.
+func (c cronSchedule) between(from, to time.Time, limit int) []time.Time {
+	var ticks []time.Time
+	cursor := from
+	for len(ticks) < limit {
+		tick, errNext := c.next(cursor)
+		if errNext != nil || tick.After(to) {
+			break
+		}
+		ticks = append(ticks, tick)
+		cursor = tick
+	}
+	return ticks
+}
+
+func (c cronSchedule) upcoming(now time.Time, count int) []time.Time {
+	return c.between(now, now.AddDate(10, 0, 0), count)
+}
+
+func loadCronScheduleState(dir string) cronScheduleState {
+	data, errRead := os.ReadFile(cronSchedulePath(dir))
+	if errRead != nil {
+		return cronScheduleState{}
+	}
+	var st cronScheduleState
+	if errJSON := json.Unmarshal(data, &st); errJSON != nil {
+		log.Println("failed to parse schedule.json:", errJSON)
+		return cronScheduleState{}
+	}
+	return st
+}
+
+func saveCronScheduleState(dir string, st cronScheduleState) {
+	data, errJSON := json.MarshalIndent(st, "", "  ")
+	if errJSON != nil {
+		log.Println("failed to encode schedule.json:", errJSON)
+		return
+	}
+	if errMkdir := os.MkdirAll(filepath.Dir(cronSchedulePath(dir)), 0755); errMkdir != nil {
+		log.Println("failed to save schedule.json:", errMkdir)
+		return
+	}
+	if errWrite := os.WriteFile(cronSchedulePath(dir), data, 0644); errWrite != nil {
+		log.Println("failed to save schedule.json:", errWrite)
+	}
+}
+
+// cronScheduler decides when the continuous-mode cron fires. Fire times that
+// passed before the scheduler started were missed while sgai was not running;
+// fire times that passed later overlapped a running cycle. Each kind is
+// handled by its own policy.
+type cronScheduler struct {
+	dir       string
+	schedule  cronSchedule
+	startedAt time.Time
+	jitter    func(max time.Duration) time.Duration
+	pending   []time.Time
+}
+
+func newCronScheduler(dir string, schedule cronSchedule, startedAt time.Time) *cronScheduler {
+	return &cronScheduler{
+		dir:       dir,
+		schedule:  schedule,
+		startedAt: startedAt,
+		jitter: func(max time.Duration) time.Duration {
+			if max <= 0 {
+				return 0
+			}
+			return rand.N(max)
+		},
+	}
+}
+
+// reload picks up cron changes made to GOAL.md between cycles. An invalid
+// configuration disables the cron trigger until it is fixed.
+func (s *cronScheduler) reload() {
+	schedule, errSchedule := readCronSchedule(s.dir)
+	if errSchedule != nil {
+		log.Println("continuous mode cron disabled:", errSchedule)
+		schedule = cronSchedule{}
+	}
+	if schedule.Expr != s.schedule.Expr || schedule.Location.String() != s.schedule.Location.String() {
+		s.pending = nil
+	}
+	s.schedule = schedule
+}
+
+// plan returns a trigger that is already due because of catch-up, or the next
+// fire time together with the jittered deadline at which it should run. It
+// returns neither when no cron is set.
+func (s *cronScheduler) plan(now time.Time) (*triggerEvent, time.Time, time.Time) {
+	if s == nil || s.schedule.Expr == "" {
+		return nil, time.Time{}, time.Time{}
+	}
+	st := loadCronScheduleState(s.dir)
+
+	if len(s.pending) == 0 && !st.LastScheduled.IsZero() {
+		passed := s.schedule.between(st.LastScheduled, now, maxCronCatchUp)
+		var missed, overlapped []time.Time
+		for _, tick := range passed {
+			if tick.Before(s.startedAt) {
+				missed = append(missed, tick)
+			} else {
+				overlapped = append(overlapped, tick)
+			}
+		}
+		s.pending = append(s.pending, applyCronPolicy(s.schedule.Missed, missed)...)
+		s.pending = append(s.pending, applyCronPolicy(s.schedule.Overlap, overlapped)...)
+		if len(passed) > 0 && len(s.pending) == 0 {
+			st.LastScheduled = passed[len(passed)-1]
+			saveCronScheduleState(s.dir, st)
+		}
+	}
+
+	if len(s.pending) > 0 {
+		due := s.pending[0]
+		s.pending = s.pending[1:]
+		event := s.fire(st, due, now, "catch-up for")
+		return &event, time.Time{}, time.Time{}
+	}
+
+	from := now
+	if st.LastScheduled.After(from) {
+		from = st.LastScheduled
+	}
+	nextTick, errNext := s.schedule.next(from)
+	if errNext != nil {
+		log.Println("failed to compute next cron tick:", errNext)
+		return nil, time.Time{}, time.Time{}
+	}
+	return nil, nextTick, nextTick.Add(s.jitter(s.schedule.Jitter))
+}
+
+// applyCronPolicy returns the fire times that should still run: none for
+// skip, the latest for run-once and queue, every one for run-all.
+func applyCronPolicy(policy string, ticks []time.Time) []time.Time {
+	if len(ticks) == 0 {
+		return nil
+	}
+	switch policy {
+	case cronPolicyRunAll:
+		return ticks
+	case cronPolicyRunOnce, cronPolicyQueue:
+		return ticks[len(ticks)-1:]
+	default:
+		return nil
+	}
+}
+
+// fired records that the cron fire time scheduled ran at now.
+func (s *cronScheduler) fired(scheduled, now time.Time) triggerEvent {
+	return s.fire(loadCronScheduleState(s.dir), scheduled, now, "scheduled for")
+}
+
+func (s *cronScheduler) fire(st cronScheduleState, scheduled, now time.Time, label string) triggerEvent {
+	st.LastRun = now.UTC()
+	if scheduled.After(st.LastScheduled) {
+		st.LastScheduled = scheduled
+	}
+	saveCronScheduleState(s.dir, st)
+	return triggerEvent{Kind: triggerCron, Payload: label + " " + scheduled.In(s.schedule.Location).Format(time.RFC3339)}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	cases := []struct {
+		name     string
+		metadata GoalMetadata
+		wantErr  bool
+	}{
+		{"empty", GoalMetadata{}, false},
+		{"defaults", GoalMetadata{ContinuousModeCron: "0 9 * * *"}, false},
+		{"fullyConfigured", GoalMetadata{ContinuousModeCron: "0 9 * * *", ContinuousModeCronTimezone: "Europe/Berlin", ContinuousModeCronMissed: "run-all", ContinuousModeCronOverlap: "queue", ContinuousModeCronJitter: "5m"}, false},
+		{"invalidExpression", GoalMetadata{ContinuousModeCron: "not cron"}, true},
+		{"invalidTimezone", GoalMetadata{ContinuousModeCron: "0 9 * * *", ContinuousModeCronTimezone: "Mars/Olympus"}, true},
+		{"invalidMissedPolicy", GoalMetadata{ContinuousModeCron: "0 9 * * *", ContinuousModeCronMissed: "queue"}, true},
+		{"invalidOverlapPolicy", GoalMetadata{ContinuousModeCron: "0 9 * * *", ContinuousModeCronOverlap: "run-all"}, true},
+		{"invalidJitter", GoalMetadata{ContinuousModeCron: "0 9 * * *", ContinuousModeCronJitter: "-1m"}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			schedule, errParse := parseCronSchedule(tc.metadata)
+			if tc.wantErr {
+				require.Error(t, errParse)
+				return
+			}
+			require.NoError(t, errParse)
+			assert.Equal(t, tc.metadata.ContinuousModeCron, schedule.Expr)
+			assert.NotEmpty(t, schedule.Missed)
+			assert.NotEmpty(t, schedule.Overlap)
+		})
+	}
+}
+
+func TestCronScheduleUsesTimezone(t *testing.T) {
+	loc, errLoc := time.LoadLocation("America/New_York")
+	require.NoError(t, errLoc)
+	schedule := cronSchedule{Expr: "0 9 * * *", Location: loc}
+
+	upcoming := schedule.upcoming(time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC), 3)
+
+	require.Len(t, upcoming, 3)
+	for _, tick := range upcoming {
+		assert.Equal(t, 9, tick.In(loc).Hour())
+	}
+	assert.Equal(t, 14, upcoming[0].UTC().Hour())
+	assert.Equal(t, 13, upcoming[2].UTC().Hour(), "daylight saving time starts on 2026-03-08")
+}
+
+func TestCronSchedulerCatchUp(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 30, 0, 0, time.UTC)
+	lastScheduled := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	beforeMissedTicks := time.Date(2026, 1, 1, 9, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		name       string
+		missed     string
+		overlap    string
+		startedAt  time.Time
+		wantEvents int
+	}{
+		{"missedSkip", cronPolicySkip, cronPolicyQueue, now, 0},
+		{"missedRunOnce", cronPolicyRunOnce, cronPolicySkip, now, 1},
+		{"missedRunAll", cronPolicyRunAll, cronPolicySkip, now, 3},
+		{"overlapSkip", cronPolicyRunAll, cronPolicySkip, beforeMissedTicks, 0},
+		{"overlapQueue", cronPolicySkip, cronPolicyQueue, beforeMissedTicks, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			saveCronScheduleState(dir, cronScheduleState{LastScheduled: lastScheduled})
+			schedule := cronSchedule{Expr: "0 * * * *", Location: time.UTC, Missed: tc.missed, Overlap: tc.overlap}
+			scheduler := newCronScheduler(dir, schedule, tc.startedAt)
+
+			var events []triggerEvent
+			for range 5 {
+				event, scheduled, _ := scheduler.plan(now)
+				if event == nil {
+					assert.Equal(t, time.Date(2026, 1, 1, 13, 0, 0, 0, time.UTC), scheduled)
+					break
+				}
+				events = append(events, *event)
+			}
+
+			require.Len(t, events, tc.wantEvents)
+			for _, event := range events {
+				assert.Equal(t, triggerCron, event.Kind)
+			}
+			if tc.wantEvents > 0 {
+				assert.Equal(t, "catch-up for 2026-01-01T12:00:00Z", events[len(events)-1].Payload)
+			}
+			persisted := loadCronScheduleState(dir)
+			assert.Equal(t, time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC), persisted.LastScheduled.UTC())
+		})
+	}
+}
+
+func TestCronSchedulerJitterAndFired(t *testing.T) {
+	dir := t.TempDir()
+	schedule := cronSchedule{Expr: "0 * * * *", Location: time.UTC, Missed: cronPolicySkip, Overlap: cronPolicySkip, Jitter: time.Minute}
+	scheduler := newCronScheduler(dir, schedule, time.Now())
+	scheduler.jitter = func(max time.Duration) time.Duration { return max / 2 }
+	now := time.Date(2026, 1, 1, 12, 30, 0, 0, time.UTC)
+
+	event, scheduled, deadline := scheduler.plan(now)
+
+	assert.Nil(t, event)
+	assert.Equal(t, time.Date(2026, 1, 1, 13, 0, 0, 0, time.UTC), scheduled)
+	assert.Equal(t, scheduled.Add(30*time.Second), deadline)
+
+	fired := scheduler.fired(scheduled, deadline)
+	assert.Equal(t, "scheduled for 2026-01-01T13:00:00Z", fired.Payload)
+	persisted := loadCronScheduleState(dir)
+	assert.True(t, persisted.LastScheduled.Equal(scheduled))
+	assert.True(t, persisted.LastRun.Equal(deadline))
+}
+
+func TestCronSchedulerReloadDisablesInvalidCron(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "GOAL.md"), []byte("---\ncontinuousModeCron: \"0 9 * * *\"\ncontinuousModeCronMissed: sometimes\n---\n# Goal\n"), 0644))
+	scheduler := newCronScheduler(dir, cronSchedule{Expr: "0 * * * *", Location: time.UTC}, time.Now())
+
+	scheduler.reload()
+
+	event, scheduled, deadline := scheduler.plan(time.Now())
+	assert.Nil(t, event)
+	assert.True(t, scheduled.IsZero())
+	assert.True(t, deadline.IsZero())
+}
+
+func TestHandleAPISchedule(t *testing.T) {
+	server, rootDir := setupTestServer(t)
+
+	t.Run("upcomingAndOverdue", func(t *testing.T) {
+		wsDir := setupTestWorkspace(t, rootDir, "scheduled")
+		goal := "---\ncontinuousModeCron: \"0 * * * *\"\ncontinuousModeCronTimezone: UTC\ncontinuousModeCronMissed: run-once\ncontinuousModeCronJitter: 2m\n---\n# Goal\n"
+		require.NoError(t, os.WriteFile(filepath.Join(wsDir, "GOAL.md"), []byte(goal), 0644))
+		saveCronScheduleState(wsDir, cronScheduleState{LastScheduled: time.Now().Add(-150 * time.Minute).Truncate(time.Hour)})
+
+		w := serveHTTP(server, http.MethodGet, "/api/v1/workspaces/scheduled/schedule?count=3", "")
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp apiScheduleResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "0 * * * *", resp.Cron)
+		assert.Equal(t, "UTC", resp.Timezone)
+		assert.Equal(t, cronPolicyRunOnce, resp.Missed)
+		assert.Equal(t, cronPolicySkip, resp.Overlap)
+		assert.Equal(t, "2m0s", resp.Jitter)
+		assert.Len(t, resp.Upcoming, 3)
+		assert.NotEmpty(t, resp.LastScheduled)
+		assert.GreaterOrEqual(t, len(resp.Overdue), 2)
+	})
+
+	t.Run("notConfigured", func(t *testing.T) {
+		wsDir := setupTestWorkspace(t, rootDir, "plain")
+		require.NoError(t, os.WriteFile(filepath.Join(wsDir, "GOAL.md"), []byte("# Goal\n"), 0644))
+
+		w := serveHTTP(server, http.MethodGet, "/api/v1/workspaces/plain/schedule", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("invalidCount", func(t *testing.T) {
+		w := serveHTTP(server, http.MethodGet, "/api/v1/workspaces/scheduled/schedule?count=0", "")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
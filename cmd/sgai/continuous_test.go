@@ -192,7 +192,7 @@ func TestWatchForTriggerCancelledContext(t *testing.T) {
 	require.NoError(t, os.MkdirAll(sgaiDir, 0755))
 	require.NoError(t, os.WriteFile(filepath.Join(dir, "GOAL.md"), []byte("# Goal"), 0644))
 
-	result := watchForTrigger(ctx, dir, "checksum123", 0, "")
+	result := watchForTrigger(ctx, dir, "checksum123", 0, nil, nil).Kind
 	assert.Equal(t, triggerNone, result)
 }
 
@@ -206,7 +206,7 @@ func TestWatchForTriggerGoalChanged(t *testing.T) {
 	goalPath := filepath.Join(dir, "GOAL.md")
 	require.NoError(t, os.WriteFile(goalPath, []byte("# Goal version 2"), 0644))
 
-	result := watchForTrigger(ctx, dir, "stale-checksum", 0, "")
+	result := watchForTrigger(ctx, dir, "stale-checksum", 0, nil, nil).Kind
 	assert.Equal(t, triggerGoal, result)
 }
 
@@ -223,7 +223,7 @@ func TestWatchForTriggerAutoTimer(t *testing.T) {
 	checksum, errChecksum := computeGoalChecksum(goalPath)
 	require.NoError(t, errChecksum)
 
-	result := watchForTrigger(ctx, dir, checksum, 1*time.Millisecond, "")
+	result := watchForTrigger(ctx, dir, checksum, 1*time.Millisecond, nil, nil).Kind
 	assert.Equal(t, triggerAuto, result)
 }
 
@@ -240,7 +240,7 @@ func TestWatchForTriggerCronWithAutoFallback(t *testing.T) {
 	checksum, errChecksum := computeGoalChecksum(goalPath)
 	require.NoError(t, errChecksum)
 
-	result := watchForTrigger(ctx, dir, checksum, 1*time.Millisecond, "* * * * *")
+	result := watchForTrigger(ctx, dir, checksum, 1*time.Millisecond, newCronScheduler(dir, cronSchedule{Expr: "* * * * *", Location: time.UTC}, time.Now()), nil).Kind
 	assert.Equal(t, triggerAuto, result)
 }
 
@@ -257,7 +257,13 @@ func TestWatchForTriggerInvalidCronExpression(t *testing.T) {
 	checksum, errChecksum := computeGoalChecksum(goalPath)
 	require.NoError(t, errChecksum)
 
-	result := watchForTrigger(ctx, dir, checksum, 1*time.Millisecond, "invalid cron")
+	cron := newCronScheduler(dir, cronSchedule{}, time.Now())
+	require.NoError(t, os.WriteFile(goalPath, []byte("---\ncontinuousModeCron: \"invalid cron\"\n---\n# Goal"), 0644))
+	cron.reload()
+	checksum, errChecksum = computeGoalChecksum(goalPath)
+	require.NoError(t, errChecksum)
+
+	result := watchForTrigger(ctx, dir, checksum, 1*time.Millisecond, cron, nil).Kind
 	assert.Equal(t, triggerAuto, result)
 }
 
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+)
+
+const (
+	triggerFiles   triggerKind = "files-changed"
+	triggerGitRef  triggerKind = "git-ref"
+	triggerWebhook triggerKind = "webhook"
+)
+
+const (
+	defaultWebhookSecretEnv = "SGAI_WEBHOOK_SECRET"
+	maxTriggerPayloadBytes  = 64 * 1024
+	maxGitTriggerCommits    = 20
+)
+
+// continuousTriggers configures extra wake-up sources for continuous mode,
+// set under continuousModeTriggers in GOAL.md frontmatter.
+type continuousTriggers struct {
+	Files   []string        `json:"files,omitempty"`
+	GitRefs []gitRefTrigger `json:"gitRefs,omitempty"`
+	Webhook *webhookTrigger `json:"webhook,omitempty"`
+}
+
+type gitRefTrigger struct {
+	Repo string `json:"repo"`
+	Ref  string `json:"ref"`
+}
+
+type webhookTrigger struct {
+	SecretEnv string `json:"secretEnv,omitempty"`
+}
+
+type triggerEvent struct {
+	Kind    triggerKind
+	Payload string
+}
+
+var (
+	webhookInboxesMu sync.Mutex
+	webhookInboxes   = make(map[string]chan triggerEvent)
+)
+
+// webhookInbox holds at most one pending webhook per workspace; deliveries
+// that arrive while one is pending are coalesced into it.
+func webhookInbox(dir string) chan triggerEvent {
+	webhookInboxesMu.Lock()
+	defer webhookInboxesMu.Unlock()
+	key := filepath.Clean(dir)
+	inbox, ok := webhookInboxes[key]
+	if !ok {
+		inbox = make(chan triggerEvent, 1)
+		webhookInboxes[key] = inbox
+	}
+	return inbox
+}
+
+func deliverWebhookTrigger(dir, payload string) bool {
+	select {
+	case webhookInbox(dir) <- triggerEvent{Kind: triggerWebhook, Payload: payload}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (t *continuousTriggers) webhookSecret() string {
+	if t == nil || t.Webhook == nil {
+		return ""
+	}
+	return os.Getenv(cmp.Or(t.Webhook.SecretEnv, defaultWebhookSecretEnv))
+}
+
+// verifyWebhookRequest accepts either "Authorization: Bearer <secret>" or a
+// GitHub-style "X-Hub-Signature-256: sha256=<hmac of body>".
+func verifyWebhookRequest(secret, authorization, signature string, body []byte) bool {
+	if secret == "" {
+		return false
+	}
+	if token, ok := strings.CutPrefix(authorization, "Bearer "); ok {
+		return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(secret)) == 1
+	}
+	if hexSig, ok := strings.CutPrefix(signature, "sha256="); ok {
+		got, errDecode := hex.DecodeString(hexSig)
+		if errDecode != nil {
+			return false
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		return hmac.Equal(got, mac.Sum(nil))
+	}
+	return false
+}
+
+func readContinuousModeTriggers(workspacePath string) *continuousTriggers {
+	metadata, errParse := parseYAMLFrontmatterFromFile(filepath.Join(workspacePath, "GOAL.md"))
+	if errParse != nil {
+		return nil
+	}
+	return metadata.ContinuousModeTriggers
+}
+
+// matchGlob matches a slash-separated relative path against a pattern where
+// "**" spans any number of directories and other segments use path.Match.
+func matchGlob(pattern, name string) bool {
+	return matchGlobSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := len(name); i >= 0; i-- {
+				if matchGlobSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		matched, errMatch := path.Match(pattern[0], name[0])
+		if errMatch != nil || !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// globCanMatchUnder reports whether pattern can match some path inside the
+// directory dir, so directories no pattern reaches are not walked.
+func globCanMatchUnder(pattern, dir string) bool {
+	segments := strings.Split(pattern, "/")
+	for _, part := range strings.Split(dir, "/") {
+		if len(segments) == 0 {
+			return false
+		}
+		if segments[0] == "**" {
+			return true
+		}
+		matched, errMatch := path.Match(segments[0], part)
+		if errMatch != nil || !matched {
+			return false
+		}
+		segments = segments[1:]
+	}
+	return len(segments) > 0
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func snapshotGlobFiles(dir string, patterns []string) map[string]fileStamp {
+	snapshot := make(map[string]fileStamp)
+	if len(patterns) == 0 {
+		return snapshot
+	}
+	errWalk := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, errRel := filepath.Rel(dir, p)
+		if errRel != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			switch d.Name() {
+			case ".sgai", ".git", ".jj", "node_modules":
+				return filepath.SkipDir
+			}
+			if rel != "." && !slices.ContainsFunc(patterns, func(pattern string) bool { return globCanMatchUnder(pattern, rel) }) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !slices.ContainsFunc(patterns, func(pattern string) bool { return matchGlob(pattern, rel) }) {
+			return nil
+		}
+		info, errInfo := d.Info()
+		if errInfo != nil {
+			return nil
+		}
+		snapshot[rel] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	if errWalk != nil {
+		log.Println("failed to scan trigger files:", errWalk)
+	}
+	return snapshot
+}
+
+func diffFileSnapshots(before, after map[string]fileStamp) []string {
+	var changes []string
+	for name, stamp := range after {
+		previous, existed := before[name]
+		switch {
+		case !existed:
+			changes = append(changes, "added "+name)
+		case previous != stamp:
+			changes = append(changes, "modified "+name)
+		}
+	}
+	for name := range before {
+		if _, exists := after[name]; !exists {
+			changes = append(changes, "removed "+name)
+		}
+	}
+	slices.SortFunc(changes, func(a, b string) int {
+		_, nameA, _ := strings.Cut(a, " ")
+		_, nameB, _ := strings.Cut(b, " ")
+		return strings.Compare(nameA, nameB)
+	})
+	return changes
+}
+
+func resolveGitTriggerRepo(dir, repo string) string {
+	if repo == "" {
+		return dir
+	}
+	if filepath.IsAbs(repo) {
+		return repo
+	}
+	return filepath.Join(dir, repo)
+}
+
+func gitRefRevision(ctx context.Context, repo, ref string) string {
+	output, errRun := exec.CommandContext(ctx, "git", "-C", repo, "rev-parse", "--verify", "--quiet", ref+"^{commit}").Output()
+	if errRun != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func gitRefPayload(ctx context.Context, repo string, trigger gitRefTrigger, before, after string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s in %s moved from %s to %s\n", trigger.Ref, cmp.Or(trigger.Repo, "."), cmp.Or(before, "(none)"), after)
+	revRange := after
+	if before != "" {
+		revRange = before + ".." + after
+	}
+	output, errLog := exec.CommandContext(ctx, "git", "-C", repo, "log", "--oneline", fmt.Sprintf("-n%d", maxGitTriggerCommits), revRange).Output()
+	if errLog != nil {
+		log.Println("failed to list commits for git trigger:", errLog)
+		return sb.String()
+	}
+	sb.Write(output)
+	return sb.String()
+}
+
+// triggerWatch holds the baselines taken when a watch starts, so only changes
+// made after the cycle finished wake the workspace again.
+type triggerWatch struct {
+	dir      string
+	triggers *continuousTriggers
+	files    map[string]fileStamp
+	gitRevs  []string
+}
+
+func newTriggerWatch(ctx context.Context, dir string, triggers *continuousTriggers) *triggerWatch {
+	watch := &triggerWatch{dir: dir, triggers: triggers}
+	if triggers == nil {
+		return watch
+	}
+	watch.files = snapshotGlobFiles(dir, triggers.Files)
+	for _, gitTrigger := range triggers.GitRefs {
+		watch.gitRevs = append(watch.gitRevs, gitRefRevision(ctx, resolveGitTriggerRepo(dir, gitTrigger.Repo), gitTrigger.Ref))
+	}
+	return watch
+}
+
+func (w *triggerWatch) webhookEvents() <-chan triggerEvent {
+	if w.triggers == nil || w.triggers.Webhook == nil {
+		return nil
+	}
+	return webhookInbox(w.dir)
+}
+
+func (w *triggerWatch) poll(ctx context.Context) (triggerEvent, bool) {
+	if w.triggers == nil {
+		return triggerEvent{}, false
+	}
+	if len(w.triggers.Files) > 0 {
+		current := snapshotGlobFiles(w.dir, w.triggers.Files)
+		if changes := diffFileSnapshots(w.files, current); len(changes) > 0 {
+			return triggerEvent{Kind: triggerFiles, Payload: strings.Join(changes, "\n")}, true
+		}
+	}
+	for i, gitTrigger := range w.triggers.GitRefs {
+		repo := resolveGitTriggerRepo(w.dir, gitTrigger.Repo)
+		current := gitRefRevision(ctx, repo, gitTrigger.Ref)
+		if current == "" || current == w.gitRevs[i] {
+			continue
+		}
+		return triggerEvent{Kind: triggerGitRef, Payload: gitRefPayload(ctx, repo, gitTrigger, w.gitRevs[i], current)}, true
+	}
+	return triggerEvent{}, false
+}
+
+func truncateTriggerPayload(payload string) string {
+	if len(payload) <= maxTriggerPayloadBytes {
+		return payload
+	}
+	return payload[:maxTriggerPayloadBytes] + "\n[truncated]"
+}
+
+// recordTrigger persists the trigger for the dashboard and returns it so the
+// cycle it woke can pass it to the continuous-mode prompt in memory.
+func recordTrigger(coord *state.Coordinator, event triggerEvent) *state.Trigger {
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	payload := truncateTriggerPayload(event.Payload)
+	description := "cycle triggered by " + string(event.Kind)
+	if summary, _, _ := strings.Cut(strings.TrimSpace(payload), "\n"); summary != "" {
+		if len(summary) > 200 {
+			summary = summary[:200] + "..."
+		}
+		description += ": " + summary
+	}
+	trigger := &state.Trigger{Kind: string(event.Kind), Payload: payload, Timestamp: timestamp}
+	if errUpdate := coord.UpdateState(func(wf *state.Workflow) {
+		wf.Trigger = trigger
+		wf.Progress = append(wf.Progress, state.ProgressEntry{
+			Timestamp:   timestamp,
+			Agent:       "continuous-mode",
+			Description: description,
+		})
+	}); errUpdate != nil {
+		log.Println("failed to record continuous mode trigger:", errUpdate)
+	}
+	return trigger
+}
+
+// clearConsumedTrigger drops the persisted trigger once its cycle has run, so
+// a restart does not replay it as if it had just fired.
+func clearConsumedTrigger(coord *state.Coordinator) {
+	if coord.State().Trigger == nil {
+		return
+	}
+	if errUpdate := coord.UpdateState(func(wf *state.Workflow) {
+		wf.Trigger = nil
+	}); errUpdate != nil {
+		log.Println("failed to clear continuous mode trigger:", errUpdate)
+	}
+}
+
+func continuousPromptWithTrigger(prompt string, trigger *state.Trigger) string {
+	if trigger == nil || trigger.Kind == "" {
+		return prompt
+	}
+	var sb strings.Builder
+	sb.WriteString(prompt)
+	sb.WriteString("\n\n## Trigger\n\nThis cycle was triggered by: ")
+	sb.WriteString(trigger.Kind)
+	sb.WriteString("\n")
+	if trigger.Payload != "" {
+		sb.WriteString("\nPayload:\n\n```\n")
+		sb.WriteString(strings.TrimRight(trigger.Payload, "\n"))
+		sb.WriteString("\n```\n")
+	}
+	return sb.String()
+}
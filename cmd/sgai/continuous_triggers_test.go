@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		name    string
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"exactFile", "README.md", "README.md", true},
+		{"singleSegmentWildcard", "*.md", "README.md", true},
+		{"wildcardDoesNotCrossDirectories", "*.md", "docs/guide.md", false},
+		{"doubleStarAnyDepth", "src/**/*.go", "src/a/b/main.go", true},
+		{"doubleStarZeroDirectories", "src/**/*.go", "src/main.go", true},
+		{"doubleStarPrefix", "**/*.go", "main.go", true},
+		{"doubleStarSuffix", "docs/**", "docs/a/b.md", true},
+		{"noMatch", "src/**/*.go", "docs/main.go", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, matchGlob(tc.pattern, tc.path))
+		})
+	}
+}
+
+func TestGlobCanMatchUnder(t *testing.T) {
+	cases := []struct {
+		name    string
+		pattern string
+		dir     string
+		want    bool
+	}{
+		{"prefixMatches", "src/pkg/*.go", "src", true},
+		{"fullDirectoryMatches", "src/pkg/*.go", "src/pkg", true},
+		{"fileLevelPattern", "src/pkg/*.go", "src/pkg/sub", false},
+		{"otherDirectory", "src/**/*.go", "docs", false},
+		{"doubleStarReachesDeeper", "src/**/*.go", "src/a/b", true},
+		{"leadingDoubleStar", "**/*.go", "vendor", true},
+		{"topLevelFilePattern", "*.md", "docs", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, globCanMatchUnder(tc.pattern, tc.dir))
+		})
+	}
+}
+
+func TestTriggerWatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src", "pkg"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".sgai"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte("package main"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0o644))
+
+	watch := newTriggerWatch(context.Background(), dir, &continuousTriggers{Files: []string{"src/**/*.go"}})
+
+	_, fired := watch.poll(context.Background())
+	assert.False(t, fired)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("more notes"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".sgai", "state.go"), []byte("ignored"), 0o644))
+	_, fired = watch.poll(context.Background())
+	assert.False(t, fired, "files outside the globs and .sgai are ignored")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "pkg", "new.go"), []byte("package pkg"), 0o644))
+	require.NoError(t, os.Remove(filepath.Join(dir, "src", "main.go")))
+	event, fired := watch.poll(context.Background())
+	require.True(t, fired)
+	assert.Equal(t, triggerFiles, event.Kind)
+	assert.Equal(t, "removed src/main.go\nadded src/pkg/new.go", event.Payload)
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+	)
+	output, errRun := cmd.CombinedOutput()
+	require.NoError(t, errRun, string(output))
+	return string(output)
+}
+
+func TestTriggerWatchGitRef(t *testing.T) {
+	if _, errLook := exec.LookPath("git"); errLook != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	bare := filepath.Join(t.TempDir(), "upstream.git")
+	clone := filepath.Join(t.TempDir(), "clone")
+	runGit(t, filepath.Dir(bare), "init", "--bare", "--initial-branch=main", bare)
+	runGit(t, filepath.Dir(clone), "clone", bare, clone)
+	runGit(t, clone, "commit", "--allow-empty", "-m", "initial commit")
+	runGit(t, clone, "push", "origin", "HEAD:refs/heads/main")
+
+	triggers := &continuousTriggers{GitRefs: []gitRefTrigger{{Repo: bare, Ref: "refs/heads/main"}}}
+	watch := newTriggerWatch(context.Background(), dir, triggers)
+	_, fired := watch.poll(context.Background())
+	assert.False(t, fired)
+
+	runGit(t, clone, "commit", "--allow-empty", "-m", "add billing endpoint")
+	runGit(t, clone, "push", "origin", "HEAD:refs/heads/main")
+
+	event, fired := watch.poll(context.Background())
+	require.True(t, fired)
+	assert.Equal(t, triggerGitRef, event.Kind)
+	assert.Contains(t, event.Payload, "refs/heads/main in "+bare+" moved from")
+	assert.Contains(t, event.Payload, "add billing endpoint")
+	assert.NotContains(t, event.Payload, "initial commit")
+}
+
+func TestWatchForTriggerWebhook(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	dir := t.TempDir()
+	goalPath := filepath.Join(dir, "GOAL.md")
+	require.NoError(t, os.WriteFile(goalPath, []byte("# Goal"), 0o644))
+	checksum, errChecksum := computeGoalChecksum(goalPath)
+	require.NoError(t, errChecksum)
+
+	require.True(t, deliverWebhookTrigger(dir, `{"action":"opened"}`))
+	assert.False(t, deliverWebhookTrigger(dir, `{"action":"closed"}`), "a second delivery is coalesced while one is pending")
+
+	event := watchForTrigger(ctx, dir, checksum, 0, nil, &continuousTriggers{Webhook: &webhookTrigger{}})
+	assert.Equal(t, triggerWebhook, event.Kind)
+	assert.Equal(t, `{"action":"opened"}`, event.Payload)
+}
+
+func TestVerifyWebhookRequest(t *testing.T) {
+	body := []byte(`{"ref":"main"}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	validSignature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	cases := []struct {
+		name          string
+		secret        string
+		authorization string
+		signature     string
+		want          bool
+	}{
+		{"bearerToken", "s3cret", "Bearer s3cret", "", true},
+		{"wrongBearerToken", "s3cret", "Bearer nope", "", false},
+		{"hmacSignature", "s3cret", "", validSignature, true},
+		{"wrongSignature", "s3cret", "", "sha256=00ff", false},
+		{"malformedSignature", "s3cret", "", "sha256=zz", false},
+		{"noCredentials", "s3cret", "", "", false},
+		{"noSecretConfigured", "", "Bearer ", "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, verifyWebhookRequest(tc.secret, tc.authorization, tc.signature, body))
+		})
+	}
+}
+
+func TestHandleAPITrigger(t *testing.T) {
+	const webhookGoal = "---\ncontinuousModePrompt: triage new issues\ncontinuousModeTriggers:\n  webhook:\n    secretEnv: TEST_SGAI_WEBHOOK_SECRET\n---\n# Goal\n"
+
+	postTrigger := func(server *Server, name, authorization, body string) *httptest.ResponseRecorder {
+		mux := http.NewServeMux()
+		server.registerAPIRoutes(mux)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/workspaces/"+name+"/trigger", strings.NewReader(body))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("notEnabled", func(t *testing.T) {
+		server, rootDir := setupTestServer(t)
+		wsDir := setupTestWorkspace(t, rootDir, "ws")
+		require.NoError(t, os.WriteFile(filepath.Join(wsDir, "GOAL.md"), []byte("# Goal\n"), 0o644))
+		assert.Equal(t, http.StatusNotFound, postTrigger(server, "ws", "Bearer x", "{}").Code)
+	})
+
+	t.Run("secretMissing", func(t *testing.T) {
+		t.Setenv("TEST_SGAI_WEBHOOK_SECRET", "")
+		server, rootDir := setupTestServer(t)
+		wsDir := setupTestWorkspace(t, rootDir, "ws")
+		require.NoError(t, os.WriteFile(filepath.Join(wsDir, "GOAL.md"), []byte(webhookGoal), 0o644))
+		assert.Equal(t, http.StatusForbidden, postTrigger(server, "ws", "Bearer x", "{}").Code)
+	})
+
+	t.Run("badCredentials", func(t *testing.T) {
+		t.Setenv("TEST_SGAI_WEBHOOK_SECRET", "s3cret")
+		server, rootDir := setupTestServer(t)
+		wsDir := setupTestWorkspace(t, rootDir, "ws")
+		require.NoError(t, os.WriteFile(filepath.Join(wsDir, "GOAL.md"), []byte(webhookGoal), 0o644))
+		assert.Equal(t, http.StatusUnauthorized, postTrigger(server, "ws", "Bearer wrong", "{}").Code)
+	})
+
+	t.Run("notRunning", func(t *testing.T) {
+		t.Setenv("TEST_SGAI_WEBHOOK_SECRET", "s3cret")
+		server, rootDir := setupTestServer(t)
+		wsDir := setupTestWorkspace(t, rootDir, "ws")
+		require.NoError(t, os.WriteFile(filepath.Join(wsDir, "GOAL.md"), []byte(webhookGoal), 0o644))
+		assert.Equal(t, http.StatusConflict, postTrigger(server, "ws", "Bearer s3cret", "{}").Code)
+	})
+
+	t.Run("queued", func(t *testing.T) {
+		t.Setenv("TEST_SGAI_WEBHOOK_SECRET", "s3cret")
+		server, rootDir := setupTestServer(t)
+		wsDir := setupTestWorkspace(t, rootDir, "ws")
+		require.NoError(t, os.WriteFile(filepath.Join(wsDir, "GOAL.md"), []byte(webhookGoal), 0o644))
+		coord, errCoord := state.NewCoordinatorWith(filepath.Join(wsDir, ".sgai", "state.json"), state.Workflow{InteractionMode: state.ModeContinuous})
+		require.NoError(t, errCoord)
+		server.mu.Lock()
+		server.sessions[wsDir] = &session{running: true, coord: coord}
+		server.mu.Unlock()
+
+		w := postTrigger(server, "ws", "Bearer s3cret", `{"issue":42}`)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Contains(t, w.Body.String(), `"queued":true`)
+
+		w = postTrigger(server, "ws", "Bearer s3cret", `{"issue":43}`)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Contains(t, w.Body.String(), `"queued":false`)
+
+		event := <-webhookInbox(wsDir)
+		assert.Equal(t, `{"issue":42}`, event.Payload)
+	})
+}
+
+func TestRecordTriggerAndPrompt(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	coord, errCoord := state.NewCoordinatorWith(stateFile, state.Workflow{Status: state.StatusWorking})
+	require.NoError(t, errCoord)
+
+	trigger := recordTrigger(coord, triggerEvent{Kind: triggerFiles, Payload: "added src/a.go\nmodified src/b.go"})
+
+	snapshot := coord.State()
+	require.NotNil(t, snapshot.Trigger)
+	assert.Equal(t, trigger, snapshot.Trigger)
+	assert.Equal(t, string(triggerFiles), snapshot.Trigger.Kind)
+	assert.Equal(t, "added src/a.go\nmodified src/b.go", snapshot.Trigger.Payload)
+	require.Len(t, snapshot.Progress, 1)
+	assert.Equal(t, "cycle triggered by files-changed: added src/a.go", snapshot.Progress[0].Description)
+
+	prompt := continuousPromptWithTrigger("Review the changes.", snapshot.Trigger)
+	assert.Equal(t, "Review the changes.\n\n## Trigger\n\nThis cycle was triggered by: files-changed\n\nPayload:\n\n```\nadded src/a.go\nmodified src/b.go\n```\n", prompt)
+	assert.Equal(t, "Review the changes.", continuousPromptWithTrigger("Review the changes.", nil))
+
+	clearConsumedTrigger(coord)
+	reloaded, errReload := state.NewCoordinator(stateFile)
+	require.NoError(t, errReload)
+	assert.Nil(t, reloaded.State().Trigger, "a restart must not replay a consumed trigger")
+}
+
+func TestReadContinuousModeTriggers(t *testing.T) {
+	dir := t.TempDir()
+	goal := `---
+continuousModePrompt: "keep the docs current"
+continuousModeTriggers:
+  files:
+    - "docs/**/*.md"
+  gitRefs:
+    - repo: ../upstream.git
+      ref: refs/heads/main
+  webhook: {}
+---
+# Goal`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "GOAL.md"), []byte(goal), 0o644))
+
+	triggers := readContinuousModeTriggers(dir)
+	require.NotNil(t, triggers)
+	assert.Equal(t, []string{"docs/**/*.md"}, triggers.Files)
+	assert.Equal(t, []gitRefTrigger{{Repo: "../upstream.git", Ref: "refs/heads/main"}}, triggers.GitRefs)
+	require.NotNil(t, triggers.Webhook)
+	assert.Equal(t, filepath.Join(dir, "../upstream.git"), resolveGitTriggerRepo(dir, triggers.GitRefs[0].Repo))
+
+	assert.Nil(t, readContinuousModeTriggers(t.TempDir()))
+}
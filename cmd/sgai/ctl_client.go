@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultCtlServer = "http://127.0.0.1:8080"
+
+var errCtlWorkspaceNotFound = errors.New("workspace not found")
+
+// ctlClient talks to the REST API of a running sgai serve.
+type ctlClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+type ctlAPIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *ctlAPIError) Error() string {
+	return fmt.Sprintf("%s %s: %s (%d)", e.Method, e.Path, e.Message, e.StatusCode)
+}
+
+func newCtlClient(server string) *ctlClient {
+	if !strings.Contains(server, "://") {
+		server = "http://" + server
+	}
+	return &ctlClient{
+		baseURL: strings.TrimRight(server, "/"),
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *ctlClient) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, errJSON := json.Marshal(body)
+		if errJSON != nil {
+			return fmt.Errorf("encoding request: %w", errJSON)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, errReq := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if errReq != nil {
+		return fmt.Errorf("building request: %w", errReq)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, errDo := c.http.Do(req)
+	if errDo != nil {
+		return fmt.Errorf("contacting sgai server at %s: %w", c.baseURL, errDo)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &ctlAPIError{Method: method, Path: path, StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(message))}
+	}
+	if out == nil {
+		return nil
+	}
+	if errDecode := json.NewDecoder(resp.Body).Decode(out); errDecode != nil {
+		return fmt.Errorf("decoding response from %s %s: %w", method, path, errDecode)
+	}
+	return nil
+}
+
+func workspaceAPIPath(name, suffix string) string {
+	return "/api/v1/workspaces/" + url.PathEscape(name) + suffix
+}
+
+func (c *ctlClient) state(ctx context.Context) (apiFactoryState, error) {
+	var result apiFactoryState
+	errDo := c.do(ctx, http.MethodGet, "/api/v1/state", nil, &result)
+	return result, errDo
+}
+
+func (c *ctlClient) workspace(ctx context.Context, name string) (apiWorkspaceFullState, error) {
+	factory, errState := c.state(ctx)
+	if errState != nil {
+		return apiWorkspaceFullState{}, errState
+	}
+	for _, ws := range factory.Workspaces {
+		if ws.Name == name {
+			return ws, nil
+		}
+	}
+	return apiWorkspaceFullState{}, fmt.Errorf("%w: %s", errCtlWorkspaceNotFound, name)
+}
+
+func (c *ctlClient) start(ctx context.Context, name string, auto bool) (apiSessionActionResponse, error) {
+	var result apiSessionActionResponse
+	errDo := c.do(ctx, http.MethodPost, workspaceAPIPath(name, "/start"), apiStartSessionRequest{Auto: auto}, &result)
+	return result, errDo
+}
+
+func (c *ctlClient) stop(ctx context.Context, name string) (apiSessionActionResponse, error) {
+	var result apiSessionActionResponse
+	errDo := c.do(ctx, http.MethodPost, workspaceAPIPath(name, "/stop"), nil, &result)
+	return result, errDo
+}
+
+func (c *ctlClient) respond(ctx context.Context, name string, req apiRespondRequest) (apiRespondResponse, error) {
+	var result apiRespondResponse
+	errDo := c.do(ctx, http.MethodPost, workspaceAPIPath(name, "/respond"), req, &result)
+	return result, errDo
+}
+
+func (c *ctlClient) fork(ctx context.Context, name, goalContent string) (apiForkResponse, error) {
+	var result apiForkResponse
+	errDo := c.do(ctx, http.MethodPost, workspaceAPIPath(name, "/fork"), apiForkRequest{GoalContent: goalContent}, &result)
+	return result, errDo
+}
+
+func (c *ctlClient) goal(ctx context.Context, name string) (string, error) {
+	var result apiGoalResponse
+	errDo := c.do(ctx, http.MethodGet, workspaceAPIPath(name, "/goal"), nil, &result)
+	return result.Content, errDo
+}
+
+func (c *ctlClient) updateGoal(ctx context.Context, name, content string) (apiUpdateGoalResponse, error) {
+	var result apiUpdateGoalResponse
+	errDo := c.do(ctx, http.MethodPut, workspaceAPIPath(name, "/goal"), apiUpdateGoalRequest{Content: content}, &result)
+	return result, errDo
+}
+
+func (c *ctlClient) diff(ctx context.Context, name string) (string, error) {
+	var result apiWorkspaceDiffResponse
+	errDo := c.do(ctx, http.MethodGet, workspaceAPIPath(name, "/diff"), nil, &result)
+	return result.Diff, errDo
+}
+
+// watchSignals calls onReload for every reload event on the dashboard signal
+// stream until ctx is cancelled or the server closes the stream.
+func (c *ctlClient) watchSignals(ctx context.Context, onReload func() error) error {
+	req, errReq := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/v1/signal", nil)
+	if errReq != nil {
+		return fmt.Errorf("building request: %w", errReq)
+	}
+	streamClient := &http.Client{}
+	resp, errDo := streamClient.Do(req)
+	if errDo != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("contacting sgai server at %s: %w", c.baseURL, errDo)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return &ctlAPIError{Method: http.MethodGet, Path: "/api/v1/signal", StatusCode: resp.StatusCode, Message: resp.Status}
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) != "event: reload" {
+			continue
+		}
+		if errReload := onReload(); errReload != nil {
+			return errReload
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return scanner.Err()
+}
+
+// newLogEntries returns the entries of current that were not in previous. The
+// server only keeps a bounded tail of the log, so previous and current are
+// aligned on the longest suffix of previous that starts current.
+func newLogEntries(previous, current []apiLogEntry) []apiLogEntry {
+	for overlap := min(len(previous), len(current)); overlap > 0; overlap-- {
+		if logEntriesEqual(previous[len(previous)-overlap:], current[:overlap]) {
+			return current[overlap:]
+		}
+	}
+	return current
+}
+
+func logEntriesEqual(a, b []apiLogEntry) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
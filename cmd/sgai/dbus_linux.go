@@ -0,0 +1,937 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This file holds a minimal D-Bus client: enough of the wire protocol to call
+// methods, export objects and receive signals on the session bus without
+// cgo or a third-party library.
+
+const (
+	dbusMethodCall   byte = 1
+	dbusMethodReturn byte = 2
+	dbusErrorReply   byte = 3
+	dbusSignal       byte = 4
+
+	dbusFlagNoReplyExpected byte = 0x1
+
+	dbusFieldPath        byte = 1
+	dbusFieldInterface   byte = 2
+	dbusFieldMember      byte = 3
+	dbusFieldErrorName   byte = 4
+	dbusFieldReplySerial byte = 5
+	dbusFieldDestination byte = 6
+	dbusFieldSender      byte = 7
+	dbusFieldSignature   byte = 8
+
+	dbusBusName      = "org.freedesktop.DBus"
+	dbusBusPath      = "/org/freedesktop/DBus"
+	dbusMaxMessage   = 1 << 27
+	dbusMaxArray     = 1 << 26
+	dbusMaxSignature = 255
+	dbusMaxDepth     = 64
+	dbusHelloTimeout = 5 * time.Second
+)
+
+var (
+	errDBusNoSessionBus = errors.New("no D-Bus session bus")
+	errDBusAuth         = errors.New("D-Bus authentication failed")
+	errDBusClosed       = errors.New("D-Bus connection closed")
+	errDBusSignature    = errors.New("invalid D-Bus signature")
+	errDBusValue        = errors.New("value does not match D-Bus signature")
+	errDBusMessage      = errors.New("malformed D-Bus message")
+)
+
+type dbusObjectPath string
+
+type dbusSignature string
+
+type dbusVariant struct {
+	sig   string
+	value any
+}
+
+// dbusStruct holds the fields of a struct or dict entry in signature order.
+type dbusStruct []any
+
+type dbusMessage struct {
+	kind        byte
+	flags       byte
+	serial      uint32
+	replySerial uint32
+	path        string
+	iface       string
+	member      string
+	errorName   string
+	destination string
+	sender      string
+	signature   string
+	body        []any
+}
+
+// dbusError is an error reply. Handlers return it to choose the error name
+// sent back to the caller.
+type dbusError struct {
+	name    string
+	message string
+}
+
+func (e *dbusError) Error() string {
+	if e.message == "" {
+		return e.name
+	}
+	return e.name + ": " + e.message
+}
+
+type dbusMethodKey struct {
+	path   string
+	iface  string
+	member string
+}
+
+// dbusHandler serves an exported method and returns the reply signature and
+// values.
+type dbusHandler func(msg *dbusMessage) (string, []any, error)
+
+type dbusExport struct {
+	signature string
+	handler   dbusHandler
+}
+
+type dbusConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	name   string
+	closed chan struct{}
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	serial   uint32
+	pending  map[uint32]chan *dbusMessage
+	handlers map[dbusMethodKey]dbusExport
+	onSignal func(*dbusMessage)
+}
+
+// dialSessionBus connects to $DBUS_SESSION_BUS_ADDRESS, falling back to the
+// systemd user bus socket.
+func dialSessionBus() (*dbusConn, error) {
+	address := os.Getenv("DBUS_SESSION_BUS_ADDRESS")
+	if address == "" && os.Getenv("XDG_RUNTIME_DIR") != "" {
+		address = "unix:path=" + os.Getenv("XDG_RUNTIME_DIR") + "/bus"
+	}
+	if address == "" {
+		return nil, fmt.Errorf("%w: DBUS_SESSION_BUS_ADDRESS is not set", errDBusNoSessionBus)
+	}
+	return dialDBus(address)
+}
+
+func dialDBus(address string) (*dbusConn, error) {
+	var errs []error
+	for entry := range strings.SplitSeq(address, ";") {
+		socket, ok := dbusUnixSocket(entry)
+		if !ok {
+			continue
+		}
+		conn, errDial := net.Dial("unix", socket)
+		if errDial != nil {
+			errs = append(errs, errDial)
+			continue
+		}
+		c, errConn := newDBusConn(conn)
+		if errConn != nil {
+			if errClose := conn.Close(); errClose != nil {
+				log.Println("failed to close D-Bus socket:", errClose)
+			}
+			errs = append(errs, errConn)
+			continue
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("%w at %s: %w", errDBusNoSessionBus, address, errors.Join(errs...))
+}
+
+// dbusUnixSocket returns the socket of a unix: address entry. Abstract
+// sockets are returned with the leading @ that net.Dial expects.
+func dbusUnixSocket(entry string) (string, bool) {
+	transport, params, ok := strings.Cut(entry, ":")
+	if !ok || transport != "unix" {
+		return "", false
+	}
+	for param := range strings.SplitSeq(params, ",") {
+		key, value, _ := strings.Cut(param, "=")
+		unescaped, errUnescape := url.PathUnescape(value)
+		if errUnescape != nil {
+			continue
+		}
+		switch key {
+		case "path":
+			return unescaped, true
+		case "abstract":
+			return "@" + unescaped, true
+		}
+	}
+	return "", false
+}
+
+func newDBusConn(conn net.Conn) (*dbusConn, error) {
+	uid := hex.EncodeToString([]byte(strconv.Itoa(os.Getuid())))
+	if _, errWrite := io.WriteString(conn, "\x00AUTH EXTERNAL "+uid+"\r\n"); errWrite != nil {
+		return nil, fmt.Errorf("%w: %w", errDBusAuth, errWrite)
+	}
+	reader := bufio.NewReader(conn)
+	line, errRead := reader.ReadString('\n')
+	if errRead != nil {
+		return nil, fmt.Errorf("%w: %w", errDBusAuth, errRead)
+	}
+	if !strings.HasPrefix(line, "OK ") {
+		return nil, fmt.Errorf("%w: %s", errDBusAuth, strings.TrimSpace(line))
+	}
+	if _, errWrite := io.WriteString(conn, "BEGIN\r\n"); errWrite != nil {
+		return nil, fmt.Errorf("%w: %w", errDBusAuth, errWrite)
+	}
+
+	c := &dbusConn{
+		conn:     conn,
+		reader:   reader,
+		closed:   make(chan struct{}),
+		pending:  make(map[uint32]chan *dbusMessage),
+		handlers: make(map[dbusMethodKey]dbusExport),
+	}
+	go c.readLoop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbusHelloTimeout)
+	defer cancel()
+	reply, errHello := c.busCall(ctx, "Hello", "")
+	if errHello != nil {
+		if errClose := c.close(); errClose != nil {
+			log.Println("failed to close D-Bus connection:", errClose)
+		}
+		return nil, fmt.Errorf("D-Bus Hello: %w", errHello)
+	}
+	name, ok := dbusFirst[string](reply)
+	if !ok {
+		if errClose := c.close(); errClose != nil {
+			log.Println("failed to close D-Bus connection:", errClose)
+		}
+		return nil, fmt.Errorf("%w: Hello returned %v", errDBusMessage, reply)
+	}
+	c.name = name
+	return c, nil
+}
+
+func (c *dbusConn) close() error {
+	return c.conn.Close()
+}
+
+// export registers a method handler for calls whose arguments match
+// signature, so handlers can assert argument types. Calls that omit the
+// interface are matched by path and member alone.
+func (c *dbusConn) export(path, iface, member, signature string, handler dbusHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[dbusMethodKey{path: path, iface: iface, member: member}] = dbusExport{signature: signature, handler: handler}
+}
+
+// setSignalHandler installs the callback for incoming signals. It runs on
+// the read loop, so it must not wait for method replies.
+func (c *dbusConn) setSignalHandler(handler func(*dbusMessage)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onSignal = handler
+}
+
+func (c *dbusConn) call(ctx context.Context, destination, path, iface, member, sig string, args ...any) ([]any, error) {
+	msg := &dbusMessage{kind: dbusMethodCall, destination: destination, path: path, iface: iface, member: member, signature: sig, body: args}
+	replyCh := make(chan *dbusMessage, 1)
+	c.mu.Lock()
+	c.serial++
+	msg.serial = c.serial
+	c.pending[msg.serial] = replyCh
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, msg.serial)
+		c.mu.Unlock()
+	}()
+
+	if errSend := c.send(msg); errSend != nil {
+		return nil, errSend
+	}
+	select {
+	case reply := <-replyCh:
+		if reply.kind == dbusErrorReply {
+			message, _ := dbusFirst[string](reply.body)
+			return nil, &dbusError{name: reply.errorName, message: message}
+		}
+		return reply.body, nil
+	case <-c.closed:
+		return nil, errDBusClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *dbusConn) busCall(ctx context.Context, member, sig string, args ...any) ([]any, error) {
+	return c.call(ctx, dbusBusName, dbusBusPath, dbusBusName, member, sig, args...)
+}
+
+func (c *dbusConn) emit(path, iface, member, sig string, args ...any) error {
+	return c.send(&dbusMessage{kind: dbusSignal, path: path, iface: iface, member: member, signature: sig, body: args})
+}
+
+func (c *dbusConn) send(msg *dbusMessage) error {
+	if msg.serial == 0 {
+		c.mu.Lock()
+		c.serial++
+		msg.serial = c.serial
+		c.mu.Unlock()
+	}
+	data, errEncode := encodeDBusMessage(msg)
+	if errEncode != nil {
+		return errEncode
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, errWrite := c.conn.Write(data); errWrite != nil {
+		return fmt.Errorf("%w: %w", errDBusClosed, errWrite)
+	}
+	return nil
+}
+
+func (c *dbusConn) readLoop() {
+	defer close(c.closed)
+	for {
+		msg, errRead := readDBusMessage(c.reader)
+		if errRead != nil {
+			return
+		}
+		switch msg.kind {
+		case dbusMethodReturn, dbusErrorReply:
+			c.mu.Lock()
+			replyCh := c.pending[msg.replySerial]
+			c.mu.Unlock()
+			if replyCh != nil {
+				select {
+				case replyCh <- msg:
+				default:
+				}
+			}
+		case dbusMethodCall:
+			go c.serve(msg)
+		case dbusSignal:
+			c.mu.Lock()
+			onSignal := c.onSignal
+			c.mu.Unlock()
+			if onSignal != nil {
+				onSignal(msg)
+			}
+		}
+	}
+}
+
+func (c *dbusConn) serve(msg *dbusMessage) {
+	exported, found := c.lookupHandler(msg)
+	var (
+		sig     string
+		body    []any
+		errCall error
+	)
+	switch {
+	case found && exported.signature != msg.signature:
+		errCall = &dbusError{name: "org.freedesktop.DBus.Error.InvalidArgs", message: fmt.Sprintf("%s expects %q, got %q", msg.member, exported.signature, msg.signature)}
+	case found:
+		sig, body, errCall = exported.handler(msg)
+	case msg.iface == "org.freedesktop.DBus.Peer" && msg.member == "Ping":
+	default:
+		errCall = &dbusError{name: "org.freedesktop.DBus.Error.UnknownMethod", message: msg.iface + "." + msg.member + " is not exported at " + msg.path}
+	}
+	if msg.flags&dbusFlagNoReplyExpected != 0 {
+		return
+	}
+
+	reply := &dbusMessage{kind: dbusMethodReturn, replySerial: msg.serial, destination: msg.sender, signature: sig, body: body}
+	if errCall != nil {
+		var dbusErr *dbusError
+		if !errors.As(errCall, &dbusErr) {
+			dbusErr = &dbusError{name: "org.freedesktop.DBus.Error.Failed", message: errCall.Error()}
+		}
+		reply = &dbusMessage{kind: dbusErrorReply, replySerial: msg.serial, destination: msg.sender, errorName: dbusErr.name, signature: "s", body: []any{dbusErr.message}}
+	}
+	if errSend := c.send(reply); errSend != nil {
+		log.Println("dbus: failed to reply to", msg.member+":", errSend)
+	}
+}
+
+func (c *dbusConn) lookupHandler(msg *dbusMessage) (dbusExport, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if msg.iface != "" {
+		exported, ok := c.handlers[dbusMethodKey{path: msg.path, iface: msg.iface, member: msg.member}]
+		return exported, ok
+	}
+	for key, exported := range c.handlers {
+		if key.path == msg.path && key.member == msg.member {
+			return exported, true
+		}
+	}
+	return dbusExport{}, false
+}
+
+// dbusFirst returns the first value of a message body as T.
+func dbusFirst[T any](body []any) (T, bool) {
+	var zero T
+	if len(body) == 0 {
+		return zero, false
+	}
+	value, ok := body[0].(T)
+	return value, ok
+}
+
+func dbusProps(props map[string]dbusVariant) []any {
+	entries := make([]any, 0, len(props))
+	for _, key := range slices.Sorted(maps.Keys(props)) {
+		entries = append(entries, dbusStruct{key, props[key]})
+	}
+	return entries
+}
+
+func encodeDBusMessage(msg *dbusMessage) ([]byte, error) {
+	body := &dbusEncoder{}
+	types, errSplit := splitDBusSignature(msg.signature)
+	if errSplit != nil {
+		return nil, errSplit
+	}
+	if len(types) != len(msg.body) {
+		return nil, fmt.Errorf("%w: signature %q has %d types for %d values", errDBusValue, msg.signature, len(types), len(msg.body))
+	}
+	for i, typ := range types {
+		if errEncode := body.encode(typ, msg.body[i], 0); errEncode != nil {
+			return nil, errEncode
+		}
+	}
+
+	var fields []any
+	addField := func(code byte, sig string, value any) {
+		fields = append(fields, dbusStruct{code, dbusVariant{sig: sig, value: value}})
+	}
+	if msg.path != "" {
+		addField(dbusFieldPath, "o", dbusObjectPath(msg.path))
+	}
+	if msg.iface != "" {
+		addField(dbusFieldInterface, "s", msg.iface)
+	}
+	if msg.member != "" {
+		addField(dbusFieldMember, "s", msg.member)
+	}
+	if msg.errorName != "" {
+		addField(dbusFieldErrorName, "s", msg.errorName)
+	}
+	if msg.replySerial != 0 {
+		addField(dbusFieldReplySerial, "u", msg.replySerial)
+	}
+	if msg.destination != "" {
+		addField(dbusFieldDestination, "s", msg.destination)
+	}
+	if msg.signature != "" {
+		addField(dbusFieldSignature, "g", dbusSignature(msg.signature))
+	}
+
+	header := &dbusEncoder{buf: []byte{'l', msg.kind, msg.flags, 1}}
+	header.uint32(uint32(len(body.buf)))
+	header.uint32(msg.serial)
+	if errEncode := header.encode("a(yv)", fields, 0); errEncode != nil {
+		return nil, errEncode
+	}
+	header.align(8)
+	return append(header.buf, body.buf...), nil
+}
+
+func readDBusMessage(r io.Reader) (*dbusMessage, error) {
+	fixed := make([]byte, 16)
+	if _, errRead := io.ReadFull(r, fixed); errRead != nil {
+		return nil, errRead
+	}
+	var order binary.ByteOrder
+	switch fixed[0] {
+	case 'l':
+		order = binary.LittleEndian
+	case 'B':
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("%w: unknown byte order %q", errDBusMessage, fixed[0])
+	}
+	bodyLen := order.Uint32(fixed[4:8])
+	fieldsLen := order.Uint32(fixed[12:16])
+	if bodyLen > dbusMaxMessage || fieldsLen > dbusMaxArray {
+		return nil, fmt.Errorf("%w: message too large", errDBusMessage)
+	}
+	headerLen := 16 + int(fieldsLen)
+	headerLen += (8 - headerLen%8) % 8
+	if headerLen+int(bodyLen) > dbusMaxMessage {
+		return nil, fmt.Errorf("%w: message too large", errDBusMessage)
+	}
+	data := make([]byte, headerLen+int(bodyLen))
+	copy(data, fixed)
+	if _, errRead := io.ReadFull(r, data[16:]); errRead != nil {
+		return nil, errRead
+	}
+
+	msg := &dbusMessage{kind: fixed[1], flags: fixed[2], serial: order.Uint32(fixed[8:12])}
+	header := &dbusDecoder{buf: data[:headerLen], pos: 12, order: order}
+	fields, errFields := header.decode("a(yv)", 0)
+	if errFields != nil {
+		return nil, errFields
+	}
+	for _, field := range fields.([]any) {
+		entry := field.(dbusStruct)
+		variant := entry[1].(dbusVariant)
+		switch entry[0].(byte) {
+		case dbusFieldPath:
+			msg.path, _ = variant.value.(string)
+		case dbusFieldInterface:
+			msg.iface, _ = variant.value.(string)
+		case dbusFieldMember:
+			msg.member, _ = variant.value.(string)
+		case dbusFieldErrorName:
+			msg.errorName, _ = variant.value.(string)
+		case dbusFieldReplySerial:
+			msg.replySerial, _ = variant.value.(uint32)
+		case dbusFieldDestination:
+			msg.destination, _ = variant.value.(string)
+		case dbusFieldSender:
+			msg.sender, _ = variant.value.(string)
+		case dbusFieldSignature:
+			msg.signature, _ = variant.value.(string)
+		}
+	}
+
+	types, errSplit := splitDBusSignature(msg.signature)
+	if errSplit != nil {
+		return nil, errSplit
+	}
+	body := &dbusDecoder{buf: data[headerLen:], order: order}
+	for _, typ := range types {
+		value, errDecode := body.decode(typ, 0)
+		if errDecode != nil {
+			return nil, errDecode
+		}
+		msg.body = append(msg.body, value)
+	}
+	return msg, nil
+}
+
+// splitDBusSignature splits a signature into its complete types.
+func splitDBusSignature(sig string) ([]string, error) {
+	if len(sig) > dbusMaxSignature {
+		return nil, fmt.Errorf("%w: longer than %d bytes", errDBusSignature, dbusMaxSignature)
+	}
+	var types []string
+	for sig != "" {
+		n, errNext := nextDBusType(sig)
+		if errNext != nil {
+			return nil, errNext
+		}
+		types = append(types, sig[:n])
+		sig = sig[n:]
+	}
+	return types, nil
+}
+
+// nextDBusType returns the length of the first complete type in sig.
+func nextDBusType(sig string) (int, error) {
+	if sig == "" {
+		return 0, errDBusSignature
+	}
+	switch sig[0] {
+	case 'y', 'b', 'n', 'q', 'i', 'u', 'x', 't', 'd', 's', 'o', 'g', 'h', 'v':
+		return 1, nil
+	case 'a':
+		n, errElem := nextDBusType(sig[1:])
+		return n + 1, errElem
+	case '(', '{':
+		closing := byte(')')
+		if sig[0] == '{' {
+			closing = '}'
+		}
+		pos := 1
+		for pos < len(sig) && sig[pos] != closing {
+			n, errField := nextDBusType(sig[pos:])
+			if errField != nil {
+				return 0, errField
+			}
+			pos += n
+		}
+		if pos >= len(sig) || pos == 1 {
+			return 0, fmt.Errorf("%w: %q", errDBusSignature, sig)
+		}
+		return pos + 1, nil
+	default:
+		return 0, fmt.Errorf("%w: %q", errDBusSignature, sig)
+	}
+}
+
+func dbusAlignment(code byte) int {
+	switch code {
+	case 'n', 'q':
+		return 2
+	case 'b', 'i', 'u', 'h', 's', 'o', 'a':
+		return 4
+	case 'x', 't', 'd', '(', '{':
+		return 8
+	default:
+		return 1
+	}
+}
+
+type dbusEncoder struct {
+	buf []byte
+}
+
+func (e *dbusEncoder) align(n int) {
+	for len(e.buf)%n != 0 {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *dbusEncoder) uint32(v uint32) {
+	e.align(4)
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, v)
+}
+
+func (e *dbusEncoder) string(v string) {
+	e.uint32(uint32(len(v)))
+	e.buf = append(append(e.buf, v...), 0)
+}
+
+func (e *dbusEncoder) encode(sig string, value any, depth int) error {
+	if depth > dbusMaxDepth {
+		return fmt.Errorf("%w: nested too deeply", errDBusValue)
+	}
+	mismatch := func() error {
+		return fmt.Errorf("%w: %T for %q", errDBusValue, value, sig)
+	}
+	switch sig[0] {
+	case 'y':
+		v, ok := value.(byte)
+		if !ok {
+			return mismatch()
+		}
+		e.buf = append(e.buf, v)
+	case 'b':
+		v, ok := value.(bool)
+		if !ok {
+			return mismatch()
+		}
+		var b uint32
+		if v {
+			b = 1
+		}
+		e.uint32(b)
+	case 'n', 'q':
+		var v uint16
+		switch typed := value.(type) {
+		case int16:
+			v = uint16(typed)
+		case uint16:
+			v = typed
+		default:
+			return mismatch()
+		}
+		e.align(2)
+		e.buf = binary.LittleEndian.AppendUint16(e.buf, v)
+	case 'i':
+		v, ok := value.(int32)
+		if !ok {
+			return mismatch()
+		}
+		e.uint32(uint32(v))
+	case 'u', 'h':
+		v, ok := value.(uint32)
+		if !ok {
+			return mismatch()
+		}
+		e.uint32(v)
+	case 'x', 't', 'd':
+		var v uint64
+		switch typed := value.(type) {
+		case int64:
+			v = uint64(typed)
+		case uint64:
+			v = typed
+		case float64:
+			v = math.Float64bits(typed)
+		default:
+			return mismatch()
+		}
+		e.align(8)
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, v)
+	case 's', 'o':
+		switch typed := value.(type) {
+		case string:
+			e.string(typed)
+		case dbusObjectPath:
+			e.string(string(typed))
+		default:
+			return mismatch()
+		}
+	case 'g':
+		var v string
+		switch typed := value.(type) {
+		case string:
+			v = typed
+		case dbusSignature:
+			v = string(typed)
+		default:
+			return mismatch()
+		}
+		e.buf = append(append(append(e.buf, byte(len(v))), v...), 0)
+	case 'v':
+		v, ok := value.(dbusVariant)
+		if !ok {
+			return mismatch()
+		}
+		if n, errNext := nextDBusType(v.sig); errNext != nil || n != len(v.sig) {
+			return fmt.Errorf("%w: variant %q", errDBusSignature, v.sig)
+		}
+		e.buf = append(append(append(e.buf, byte(len(v.sig))), v.sig...), 0)
+		return e.encode(v.sig, v.value, depth+1)
+	case 'a':
+		var elems []any
+		switch typed := value.(type) {
+		case []any:
+			elems = typed
+		case []string:
+			for _, s := range typed {
+				elems = append(elems, s)
+			}
+		default:
+			return mismatch()
+		}
+		e.uint32(0)
+		lengthAt := len(e.buf) - 4
+		e.align(dbusAlignment(sig[1]))
+		start := len(e.buf)
+		for _, elem := range elems {
+			if errElem := e.encode(sig[1:], elem, depth+1); errElem != nil {
+				return errElem
+			}
+		}
+		binary.LittleEndian.PutUint32(e.buf[lengthAt:], uint32(len(e.buf)-start))
+	case '(', '{':
+		fields, ok := value.(dbusStruct)
+		if !ok {
+			return mismatch()
+		}
+		types, errSplit := splitDBusSignature(sig[1 : len(sig)-1])
+		if errSplit != nil {
+			return errSplit
+		}
+		if len(types) != len(fields) {
+			return mismatch()
+		}
+		e.align(8)
+		for i, typ := range types {
+			if errField := e.encode(typ, fields[i], depth+1); errField != nil {
+				return errField
+			}
+		}
+	default:
+		return fmt.Errorf("%w: %q", errDBusSignature, sig)
+	}
+	return nil
+}
+
+type dbusDecoder struct {
+	buf   []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+func (d *dbusDecoder) align(n int) error {
+	pad := (n - d.pos%n) % n
+	if d.pos+pad > len(d.buf) {
+		return fmt.Errorf("%w: truncated", errDBusMessage)
+	}
+	d.pos += pad
+	return nil
+}
+
+func (d *dbusDecoder) read(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.buf) {
+		return nil, fmt.Errorf("%w: truncated", errDBusMessage)
+	}
+	data := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return data, nil
+}
+
+func (d *dbusDecoder) uint32() (uint32, error) {
+	if errAlign := d.align(4); errAlign != nil {
+		return 0, errAlign
+	}
+	data, errRead := d.read(4)
+	if errRead != nil {
+		return 0, errRead
+	}
+	return d.order.Uint32(data), nil
+}
+
+func (d *dbusDecoder) string(lengthBytes int) (string, error) {
+	var n int
+	if lengthBytes == 1 {
+		data, errRead := d.read(1)
+		if errRead != nil {
+			return "", errRead
+		}
+		n = int(data[0])
+	} else {
+		length, errLen := d.uint32()
+		if errLen != nil {
+			return "", errLen
+		}
+		n = int(length)
+	}
+	if n > len(d.buf)-d.pos {
+		return "", fmt.Errorf("%w: truncated", errDBusMessage)
+	}
+	data, errRead := d.read(n + 1)
+	if errRead != nil {
+		return "", errRead
+	}
+	if data[n] != 0 {
+		return "", fmt.Errorf("%w: string is not nul-terminated", errDBusMessage)
+	}
+	return string(data[:n]), nil
+}
+
+func (d *dbusDecoder) decode(sig string, depth int) (any, error) {
+	if depth > dbusMaxDepth {
+		return nil, fmt.Errorf("%w: nested too deeply", errDBusMessage)
+	}
+	switch sig[0] {
+	case 'y':
+		data, errRead := d.read(1)
+		if errRead != nil {
+			return nil, errRead
+		}
+		return data[0], nil
+	case 'b':
+		v, errRead := d.uint32()
+		return v != 0, errRead
+	case 'n', 'q':
+		if errAlign := d.align(2); errAlign != nil {
+			return nil, errAlign
+		}
+		data, errRead := d.read(2)
+		if errRead != nil {
+			return nil, errRead
+		}
+		if sig[0] == 'n' {
+			return int16(d.order.Uint16(data)), nil
+		}
+		return d.order.Uint16(data), nil
+	case 'i':
+		v, errRead := d.uint32()
+		return int32(v), errRead
+	case 'u', 'h':
+		return d.uint32()
+	case 'x', 't', 'd':
+		if errAlign := d.align(8); errAlign != nil {
+			return nil, errAlign
+		}
+		data, errRead := d.read(8)
+		if errRead != nil {
+			return nil, errRead
+		}
+		v := d.order.Uint64(data)
+		switch sig[0] {
+		case 'x':
+			return int64(v), nil
+		case 'd':
+			return math.Float64frombits(v), nil
+		default:
+			return v, nil
+		}
+	case 's', 'o':
+		return d.string(4)
+	case 'g':
+		return d.string(1)
+	case 'v':
+		variantSig, errSig := d.string(1)
+		if errSig != nil {
+			return nil, errSig
+		}
+		if n, errNext := nextDBusType(variantSig); errNext != nil || n != len(variantSig) {
+			return nil, fmt.Errorf("%w: variant %q", errDBusSignature, variantSig)
+		}
+		value, errValue := d.decode(variantSig, depth+1)
+		return dbusVariant{sig: variantSig, value: value}, errValue
+	case 'a':
+		length, errLen := d.uint32()
+		if errLen != nil {
+			return nil, errLen
+		}
+		if length > dbusMaxArray {
+			return nil, fmt.Errorf("%w: array too large", errDBusMessage)
+		}
+		if errAlign := d.align(dbusAlignment(sig[1])); errAlign != nil {
+			return nil, errAlign
+		}
+		end := d.pos + int(length)
+		if end > len(d.buf) {
+			return nil, fmt.Errorf("%w: truncated", errDBusMessage)
+		}
+		elems := []any{}
+		for d.pos < end {
+			elem, errElem := d.decode(sig[1:], depth+1)
+			if errElem != nil {
+				return nil, errElem
+			}
+			elems = append(elems, elem)
+		}
+		if d.pos != end {
+			return nil, fmt.Errorf("%w: array length does not match its elements", errDBusMessage)
+		}
+		return elems, nil
+	case '(', '{':
+		if errAlign := d.align(8); errAlign != nil {
+			return nil, errAlign
+		}
+		types, errSplit := splitDBusSignature(sig[1 : len(sig)-1])
+		if errSplit != nil {
+			return nil, errSplit
+		}
+		fields := make(dbusStruct, 0, len(types))
+		for _, typ := range types {
+			field, errField := d.decode(typ, depth+1)
+			if errField != nil {
+				return nil, errField
+			}
+			fields = append(fields, field)
+		}
+		return fields, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", errDBusSignature, sig)
+	}
+}
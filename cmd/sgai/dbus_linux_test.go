@@ -0,0 +1,312 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitDBusSignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		signature string
+		expected  []string
+		wantErr   bool
+	}{
+		{name: "empty", signature: "", expected: nil},
+		{name: "basic", signature: "susssasa{sv}i", expected: []string{"s", "u", "s", "s", "s", "as", "a{sv}", "i"}},
+		{name: "nested", signature: "u(ia{sv}av)", expected: []string{"u", "(ia{sv}av)"}},
+		{name: "unclosedStruct", signature: "(is", wantErr: true},
+		{name: "emptyStruct", signature: "()", wantErr: true},
+		{name: "unknownType", signature: "z", wantErr: true},
+		{name: "bareArray", signature: "a", wantErr: true},
+		{name: "tooLong", signature: strings.Repeat("i", dbusMaxSignature+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := splitDBusSignature(tt.signature)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errDBusSignature)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestDBusMessageRoundTrip(t *testing.T) {
+	msg := &dbusMessage{
+		kind:        dbusMethodCall,
+		serial:      42,
+		path:        "/MenuBar",
+		iface:       dbusMenuInterface,
+		member:      "Event",
+		destination: ":1.7",
+		signature:   "ybnqiuxtdsogv(isvu)a{sv}aas",
+		body: []any{
+			byte(7), true, int16(-2), uint16(3), int32(-4), uint32(5), int64(-6), uint64(7), 1.5,
+			"clicked", dbusObjectPath("/StatusNotifierItem"), dbusSignature("a{sv}"),
+			dbusVariant{sig: "as", value: []any{"a", "b"}},
+			dbusStruct{int32(3), "clicked", dbusVariant{sig: "s", value: ""}, uint32(9)},
+			[]any{dbusStruct{"label", dbusVariant{sig: "s", value: "Quit"}}},
+			[]any{[]any{}, []any{"x"}},
+		},
+	}
+
+	data, errEncode := encodeDBusMessage(msg)
+	require.NoError(t, errEncode)
+
+	decoded, errRead := readDBusMessage(bytes.NewReader(data))
+	require.NoError(t, errRead)
+	assert.Equal(t, msg.kind, decoded.kind)
+	assert.Equal(t, msg.serial, decoded.serial)
+	assert.Equal(t, msg.path, decoded.path)
+	assert.Equal(t, msg.iface, decoded.iface)
+	assert.Equal(t, msg.member, decoded.member)
+	assert.Equal(t, msg.destination, decoded.destination)
+	assert.Equal(t, msg.signature, decoded.signature)
+	assert.Equal(t, []any{
+		byte(7), true, int16(-2), uint16(3), int32(-4), uint32(5), int64(-6), uint64(7), 1.5,
+		"clicked", "/StatusNotifierItem", "a{sv}",
+		dbusVariant{sig: "as", value: []any{"a", "b"}},
+		dbusStruct{int32(3), "clicked", dbusVariant{sig: "s", value: ""}, uint32(9)},
+		[]any{dbusStruct{"label", dbusVariant{sig: "s", value: "Quit"}}},
+		[]any{[]any{}, []any{"x"}},
+	}, decoded.body)
+}
+
+func TestEncodeDBusMessageRejectsMismatch(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *dbusMessage
+	}{
+		{name: "wrongType", msg: &dbusMessage{kind: dbusSignal, signature: "u", body: []any{"one"}}},
+		{name: "wrongCount", msg: &dbusMessage{kind: dbusSignal, signature: "ss", body: []any{"one"}}},
+		{name: "structArity", msg: &dbusMessage{kind: dbusSignal, signature: "(is)", body: []any{dbusStruct{int32(1)}}}},
+		{name: "variantSignature", msg: &dbusMessage{kind: dbusSignal, signature: "v", body: []any{dbusVariant{sig: "ss", value: "x"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := encodeDBusMessage(tt.msg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestReadDBusMessageRejectsTruncated(t *testing.T) {
+	data, errEncode := encodeDBusMessage(&dbusMessage{kind: dbusSignal, serial: 1, path: "/x", iface: "a.b", member: "C", signature: "s", body: []any{"hello"}})
+	require.NoError(t, errEncode)
+
+	_, errRead := readDBusMessage(bytes.NewReader(data[:len(data)-3]))
+
+	assert.Error(t, errRead)
+}
+
+func TestReadDBusMessageRejectsOversized(t *testing.T) {
+	header := func(bodyLen, fieldsLen uint32) []byte {
+		data := []byte{'l', dbusSignal, 0, 1}
+		data = binary.LittleEndian.AppendUint32(data, bodyLen)
+		data = binary.LittleEndian.AppendUint32(data, 1)
+		return binary.LittleEndian.AppendUint32(data, fieldsLen)
+	}
+	bodyArray, errEncode := encodeDBusMessage(&dbusMessage{kind: dbusSignal, serial: 1, path: "/x", iface: "a.b", member: "C", signature: "au", body: []any{[]any{}}})
+	require.NoError(t, errEncode)
+	binary.LittleEndian.PutUint32(bodyArray[len(bodyArray)-4:], dbusMaxArray+1)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "headerArray", data: header(0, dbusMaxArray+1)},
+		{name: "totalSize", data: header(dbusMaxMessage, 16)},
+		{name: "bodyArray", data: bodyArray},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readDBusMessage(bytes.NewReader(tt.data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func FuzzReadDBusMessage(f *testing.F) {
+	seeds := []*dbusMessage{
+		{kind: dbusSignal, serial: 1, path: "/x", iface: "a.b", member: "C", signature: "s", body: []any{"hello"}},
+		{kind: dbusMethodCall, serial: 2, path: "/MenuBar", member: "Event", signature: "ia{sv}av", body: []any{
+			int32(1), []any{dbusStruct{"label", dbusVariant{sig: "s", value: "Quit"}}}, []any{dbusVariant{sig: "u", value: uint32(3)}},
+		}},
+		{kind: dbusMethodReturn, serial: 3, replySerial: 2, signature: "(ia{sv}av)", body: []any{
+			dbusStruct{int32(0), []any{}, []any{}},
+		}},
+	}
+	for _, msg := range seeds {
+		data, errEncode := encodeDBusMessage(msg)
+		require.NoError(f, errEncode)
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := readDBusMessage(bytes.NewReader(data))
+		if err == nil {
+			assert.NotNil(t, msg)
+		}
+	})
+}
+
+func TestDBusUnixSocket(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    string
+		expected string
+		ok       bool
+	}{
+		{name: "path", entry: "unix:path=/run/user/1000/bus", expected: "/run/user/1000/bus", ok: true},
+		{name: "abstractWithGUID", entry: "unix:abstract=/tmp/dbus-x,guid=abc", expected: "@/tmp/dbus-x", ok: true},
+		{name: "escaped", entry: "unix:path=/tmp/with%20space", expected: "/tmp/with space", ok: true},
+		{name: "tcp", entry: "tcp:host=localhost,port=1234", ok: false},
+		{name: "noSocket", entry: "unix:tmpdir=/tmp", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			socket, ok := dbusUnixSocket(tt.entry)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, socket)
+		})
+	}
+}
+
+// startTestDBus runs a private session bus so the tests never touch the
+// desktop of the machine running them.
+func startTestDBus(t *testing.T) string {
+	t.Helper()
+	if _, errLook := exec.LookPath("dbus-daemon"); errLook != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+	cmd := exec.Command("dbus-daemon", "--session", "--nofork", "--print-address=1")
+	stdout, errPipe := cmd.StdoutPipe()
+	require.NoError(t, errPipe)
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	address, errRead := bufio.NewReader(stdout).ReadString('\n')
+	if errRead != nil {
+		t.Skip("dbus-daemon did not start:", errRead)
+	}
+	return strings.TrimSpace(address)
+}
+
+func TestLinuxMenuBarOverDBus(t *testing.T) {
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", startTestDBus(t))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	desktop, errDesktop := dialSessionBus()
+	require.NoError(t, errDesktop)
+	t.Cleanup(func() { _ = desktop.close() })
+	notified := make(chan []any, 4)
+	desktop.export(notificationsPath, notificationsService, "Notify", "susssasa{sv}i", func(msg *dbusMessage) (string, []any, error) {
+		notified <- msg.body
+		return "u", []any{uint32(7)}, nil
+	})
+	registered := make(chan string, 1)
+	desktop.export(sniWatcherPath, sniWatcherService, "RegisterStatusNotifierItem", "s", func(msg *dbusMessage) (string, []any, error) {
+		registered <- msg.body[0].(string)
+		return "", nil, nil
+	})
+	for _, name := range []string{notificationsService, sniWatcherService} {
+		_, errName := desktop.busCall(ctx, "RequestName", "su", name, uint32(0))
+		require.NoError(t, errName)
+	}
+
+	app, errApp := dialSessionBus()
+	require.NoError(t, errApp)
+	t.Cleanup(func() { _ = app.close() })
+	opened := make(chan string, 4)
+	quitCtx, quit := context.WithCancel(ctx)
+	state := newLinuxMenuBarState(app, "http://127.0.0.1:8080", quit, func(target string) error {
+		opened <- target
+		return nil
+	})
+
+	require.NoError(t, state.publish(ctx))
+	assert.Equal(t, state.itemName, <-registered)
+
+	state.update(ctx, []menuBarItem{{name: "ws", running: true}})
+	state.update(ctx, []menuBarItem{{name: "ws", needsInput: true}})
+	select {
+	case body := <-notified:
+		assert.Equal(t, "sgai", body[0])
+		assert.Equal(t, "ws needs input", body[3])
+	case <-ctx.Done():
+		t.Fatal("no notification was sent")
+	}
+
+	t.Run("itemProperties", func(t *testing.T) {
+		reply, errGet := desktop.call(ctx, state.itemName, sniItemPath, dbusPropertiesInterface, "Get", "ss", sniItemInterface, "Status")
+		require.NoError(t, errGet)
+		assert.Equal(t, dbusVariant{sig: "s", value: "NeedsAttention"}, reply[0])
+
+		_, errUnknown := desktop.call(ctx, state.itemName, sniItemPath, dbusPropertiesInterface, "Get", "ss", sniItemInterface, "Nope")
+		var dbusErr *dbusError
+		require.ErrorAs(t, errUnknown, &dbusErr)
+		assert.Equal(t, "org.freedesktop.DBus.Error.UnknownProperty", dbusErr.name)
+	})
+
+	t.Run("menuLayout", func(t *testing.T) {
+		reply, errLayout := desktop.call(ctx, state.itemName, dbusMenuPath, dbusMenuInterface, "GetLayout", "iias", int32(0), int32(-1), []any{})
+		require.NoError(t, errLayout)
+		root := reply[1].(dbusStruct)
+		var labels []string
+		for _, child := range root[2].([]any) {
+			props := child.(dbusVariant).value.(dbusStruct)[1].([]any)
+			for _, prop := range props {
+				entry := prop.(dbusStruct)
+				if entry[0] == "label" {
+					labels = append(labels, entry[1].(dbusVariant).value.(string))
+				}
+			}
+		}
+		assert.Equal(t, []string{"Open Dashboard", "⚠ ws (Needs Input)", "Quit"}, labels)
+	})
+
+	t.Run("menuClick", func(t *testing.T) {
+		_, errEvent := desktop.call(ctx, state.itemName, dbusMenuPath, dbusMenuInterface, "Event", "isvu", int32(3), "clicked", dbusVariant{sig: "s", value: ""}, uint32(0))
+		require.NoError(t, errEvent)
+		assert.Equal(t, "http://127.0.0.1:8080/workspaces/ws/respond", <-opened)
+	})
+
+	t.Run("wrongSignature", func(t *testing.T) {
+		_, errEvent := desktop.call(ctx, state.itemName, dbusMenuPath, dbusMenuInterface, "Event", "s", "clicked")
+		var dbusErr *dbusError
+		require.ErrorAs(t, errEvent, &dbusErr)
+		assert.Equal(t, "org.freedesktop.DBus.Error.InvalidArgs", dbusErr.name)
+	})
+
+	t.Run("notificationAction", func(t *testing.T) {
+		require.NoError(t, desktop.emit(notificationsPath, notificationsService, "ActionInvoked", "us", uint32(7), "default"))
+		assert.Equal(t, "http://127.0.0.1:8080/workspaces/ws/respond", <-opened)
+	})
+
+	t.Run("quit", func(t *testing.T) {
+		_, errEvent := desktop.call(ctx, state.itemName, dbusMenuPath, dbusMenuInterface, "Event", "isvu", int32(5), "clicked", dbusVariant{sig: "s", value: ""}, uint32(0))
+		require.NoError(t, errEvent)
+		<-quitCtx.Done()
+		assert.ErrorIs(t, quitCtx.Err(), context.Canceled)
+	})
+}
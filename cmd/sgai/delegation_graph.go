@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	delegationStatusCompleted = "completed"
+	delegationStatusError     = "error"
+
+	delegationNodeWidth   = 180
+	delegationNodeHeight  = 48
+	delegationColumnGap   = 40
+	delegationLayerGap    = 90
+	delegationMargin      = 20
+	graphvizRenderTimeout = 10 * time.Second
+)
+
+// delegationRecord is one finished Task delegation, appended to
+// .sgai/delegations.jsonl by the workbench plugin from opencode events.
+type delegationRecord struct {
+	CallID         string `json:"callID"`
+	SessionID      string `json:"sessionID"`
+	ChildSessionID string `json:"childSessionID,omitempty"`
+	Agent          string `json:"agent"`
+	Description    string `json:"description,omitempty"`
+	Status         string `json:"status"`
+	Start          int64  `json:"start,omitempty"`
+	End            int64  `json:"end,omitempty"`
+}
+
+type delegationNode struct {
+	Agent    string   `json:"agent"`
+	Sessions []string `json:"sessions"`
+}
+
+type delegationEdge struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Count      int    `json:"count"`
+	Completed  int    `json:"completed"`
+	Failed     int    `json:"failed"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// delegationGraph has a node per agent seen in sessions.jsonl or configured
+// in GOAL.md, and an edge per delegating and delegated agent pair.
+type delegationGraph struct {
+	Nodes []delegationNode `json:"nodes"`
+	Edges []delegationEdge `json:"edges"`
+}
+
+func readDelegationRecords(path string) ([]delegationRecord, error) {
+	data, errRead := os.ReadFile(path)
+	if errRead != nil {
+		if os.IsNotExist(errRead) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading delegations file: %w", errRead)
+	}
+	var records []delegationRecord
+	seen := make(map[string]bool)
+	for line := range strings.SplitSeq(string(data), "\n") {
+		var record delegationRecord
+		if errJSON := json.Unmarshal([]byte(strings.TrimSpace(line)), &record); errJSON != nil || record.CallID == "" || record.Agent == "" {
+			continue
+		}
+		if seen[record.CallID] {
+			continue
+		}
+		seen[record.CallID] = true
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func loadDelegationGraph(workspacePath string, configured []string) (delegationGraph, error) {
+	sessions, errSessions := readSessionEntries(filepath.Join(workspacePath, ".sgai", "sessions.jsonl"))
+	if errSessions != nil && !os.IsNotExist(errSessions) {
+		return delegationGraph{}, fmt.Errorf("reading sessions file: %w", errSessions)
+	}
+	records, errRecords := readDelegationRecords(filepath.Join(workspacePath, ".sgai", "delegations.jsonl"))
+	if errRecords != nil {
+		return delegationGraph{}, errRecords
+	}
+	return buildDelegationGraph(configured, sessions, records), nil
+}
+
+func buildDelegationGraph(configured []string, sessions []sessionEntry, records []delegationRecord) delegationGraph {
+	graph := delegationGraph{Nodes: []delegationNode{}, Edges: []delegationEdge{}}
+	nodeIndex := make(map[string]int)
+	addNode := func(agent string) int {
+		if i, ok := nodeIndex[agent]; ok {
+			return i
+		}
+		nodeIndex[agent] = len(graph.Nodes)
+		graph.Nodes = append(graph.Nodes, delegationNode{Agent: agent, Sessions: []string{}})
+		return nodeIndex[agent]
+	}
+	addSession := func(agent, sessionID string) {
+		i := addNode(agent)
+		if sessionID != "" && !slices.Contains(graph.Nodes[i].Sessions, sessionID) {
+			graph.Nodes[i].Sessions = append(graph.Nodes[i].Sessions, sessionID)
+		}
+	}
+
+	if slices.ContainsFunc(sessions, func(entry sessionEntry) bool { return entry.Agent == "coordinator" }) {
+		addNode("coordinator")
+	}
+	sessionAgents := make(map[string]string, len(sessions))
+	for _, entry := range sessions {
+		if entry.Agent == "" {
+			continue
+		}
+		sessionAgents[entry.SessionID] = entry.Agent
+		addSession(entry.Agent, entry.SessionID)
+	}
+
+	edgeIndex := make(map[[2]string]int)
+	for _, record := range records {
+		from := sessionAgents[record.SessionID]
+		if from == "" {
+			from = "unknown"
+		}
+		addNode(from)
+		addSession(record.Agent, record.ChildSessionID)
+		key := [2]string{from, record.Agent}
+		i, ok := edgeIndex[key]
+		if !ok {
+			i = len(graph.Edges)
+			edgeIndex[key] = i
+			graph.Edges = append(graph.Edges, delegationEdge{From: from, To: record.Agent})
+		}
+		edge := &graph.Edges[i]
+		edge.Count++
+		switch record.Status {
+		case delegationStatusCompleted:
+			edge.Completed++
+		case delegationStatusError:
+			edge.Failed++
+		}
+		if record.Start > 0 && record.End > record.Start {
+			edge.DurationMs += record.End - record.Start
+		}
+	}
+
+	for _, agent := range configured {
+		addNode(agent)
+	}
+	return graph
+}
+
+func delegationNodeLabel(node delegationNode) string {
+	switch len(node.Sessions) {
+	case 0:
+		return "no sessions yet"
+	case 1:
+		return "1 session"
+	default:
+		return fmt.Sprintf("%d sessions", len(node.Sessions))
+	}
+}
+
+func delegationEdgeLabel(edge delegationEdge) string {
+	parts := []string{fmt.Sprintf("%d×", edge.Count)}
+	if edge.DurationMs > 0 {
+		parts = append(parts, formatDuration(time.Duration(edge.DurationMs)*time.Millisecond))
+	}
+	outcome := fmt.Sprintf("%d ok", edge.Completed)
+	if edge.Failed > 0 {
+		outcome += fmt.Sprintf(" / %d failed", edge.Failed)
+	}
+	parts = append(parts, outcome)
+	return strings.Join(parts, " · ")
+}
+
+// renderDelegationSVG renders graph with Graphviz when dotPath is set, and
+// with the built-in layered layout otherwise or when Graphviz fails.
+func renderDelegationSVG(graph delegationGraph, dotPath string) string {
+	if dotPath != "" {
+		svg, errDot := renderDelegationGraphviz(graph, dotPath)
+		if errDot == nil {
+			return svg
+		}
+		log.Println("graphviz rendering failed, using built-in layout:", errDot)
+	}
+	return layoutDelegationSVG(graph)
+}
+
+func delegationGraphDOT(graph delegationGraph) string {
+	var sb strings.Builder
+	sb.WriteString("digraph delegation {\n")
+	sb.WriteString("  rankdir=TB;\n")
+	sb.WriteString("  node [shape=box, style=rounded, fontname=\"Helvetica\", fontsize=12];\n")
+	sb.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(&sb, "  %s [label=%s];\n", dotQuote(node.Agent), dotQuote(node.Agent+"\n"+delegationNodeLabel(node)))
+	}
+	for _, edge := range graph.Edges {
+		color := "#4a5568"
+		if edge.Failed > 0 {
+			color = "#c53030"
+		}
+		fmt.Fprintf(&sb, "  %s -> %s [label=%s, color=%s];\n", dotQuote(edge.From), dotQuote(edge.To), dotQuote(delegationEdgeLabel(edge)), dotQuote(color))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func dotQuote(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+func renderDelegationGraphviz(graph delegationGraph, dotPath string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), graphvizRenderTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, dotPath, "-Tsvg")
+	cmd.Stdin = strings.NewReader(delegationGraphDOT(graph))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, errRun := cmd.Output()
+	if errRun != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("running dot: %w: %s", errRun, msg)
+		}
+		return "", fmt.Errorf("running dot: %w", errRun)
+	}
+	return string(output), nil
+}
+
+// delegationLayers assigns each node the breadth-first depth from the nodes
+// nobody delegates to; nodes only reachable through cycles stay on top.
+func delegationLayers(graph delegationGraph) map[string]int {
+	hasIncoming := make(map[string]bool)
+	children := make(map[string][]string)
+	for _, edge := range graph.Edges {
+		if edge.From != edge.To {
+			hasIncoming[edge.To] = true
+		}
+		children[edge.From] = append(children[edge.From], edge.To)
+	}
+	layers := make(map[string]int, len(graph.Nodes))
+	var queue []string
+	for _, node := range graph.Nodes {
+		if !hasIncoming[node.Agent] {
+			layers[node.Agent] = 0
+			queue = append(queue, node.Agent)
+		}
+	}
+	for len(queue) > 0 {
+		agent := queue[0]
+		queue = queue[1:]
+		for _, child := range children[agent] {
+			if _, ok := layers[child]; !ok {
+				layers[child] = layers[agent] + 1
+				queue = append(queue, child)
+			}
+		}
+	}
+	for _, node := range graph.Nodes {
+		if _, ok := layers[node.Agent]; !ok {
+			layers[node.Agent] = 0
+		}
+	}
+	return layers
+}
+
+func layoutDelegationSVG(graph delegationGraph) string {
+	type point struct{ x, y int }
+	layers := delegationLayers(graph)
+	columns := make(map[int]int)
+	positions := make(map[string]point, len(graph.Nodes))
+	maxColumns, maxLayer := 0, 0
+	for _, node := range graph.Nodes {
+		layer := layers[node.Agent]
+		column := columns[layer]
+		columns[layer] = column + 1
+		maxColumns = max(maxColumns, column+1)
+		maxLayer = max(maxLayer, layer)
+		positions[node.Agent] = point{
+			x: delegationMargin + column*(delegationNodeWidth+delegationColumnGap),
+			y: delegationMargin + layer*(delegationNodeHeight+delegationLayerGap),
+		}
+	}
+	width := 2*delegationMargin + maxColumns*(delegationNodeWidth+delegationColumnGap) - delegationColumnGap
+	height := 2*delegationMargin + (maxLayer+1)*(delegationNodeHeight+delegationLayerGap) - delegationLayerGap
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif">`, width, height, width, height)
+	sb.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="#4a5568"/></marker></defs>`)
+	for _, edge := range graph.Edges {
+		from, to := positions[edge.From], positions[edge.To]
+		x1, y1 := from.x+delegationNodeWidth/2, from.y+delegationNodeHeight
+		x2, y2 := to.x+delegationNodeWidth/2, to.y
+		cx, cy := (x1+x2)/2, (y1+y2)/2
+		if layers[edge.To] <= layers[edge.From] {
+			x1, y1 = from.x+delegationNodeWidth, from.y+delegationNodeHeight/2
+			x2, y2 = to.x+delegationNodeWidth, to.y+delegationNodeHeight/2
+			cx, cy = max(x1, x2)+delegationColumnGap, (y1+y2)/2
+		}
+		color := "#4a5568"
+		if edge.Failed > 0 {
+			color = "#c53030"
+		}
+		fmt.Fprintf(&sb, `<path d="M %d %d Q %d %d %d %d" fill="none" stroke="%s" stroke-width="1.5" marker-end="url(#arrow)"/>`, x1, y1, cx, cy, x2, y2, color)
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" font-size="11" text-anchor="middle" fill="%s">%s</text>`, (x1+x2+2*cx)/4, (y1+y2+2*cy)/4-4, color, escapeSVGText(delegationEdgeLabel(edge)))
+	}
+	for _, node := range graph.Nodes {
+		pos := positions[node.Agent]
+		fmt.Fprintf(&sb, `<g><rect x="%d" y="%d" width="%d" height="%d" rx="6" fill="#ffffff" stroke="#4a5568"/>`, pos.x, pos.y, delegationNodeWidth, delegationNodeHeight)
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" font-size="13" text-anchor="middle" fill="#1a202c">%s</text>`, pos.x+delegationNodeWidth/2, pos.y+20, escapeSVGText(node.Agent))
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" font-size="10" text-anchor="middle" fill="#718096">%s</text></g>`, pos.x+delegationNodeWidth/2, pos.y+37, escapeSVGText(delegationNodeLabel(node)))
+	}
+	sb.WriteString(`</svg>`)
+	return sb.String()
+}
+
+func escapeSVGText(text string) string {
+	var buf bytes.Buffer
+	if errEscape := xml.EscapeText(&buf, []byte(text)); errEscape != nil {
+		return ""
+	}
+	return buf.String()
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSessionsJSONL = `{"sessionID":"ses_c","agent":"coordinator"}
+{"sessionID":"ses_g1","agent":"go-developer"}
+{"sessionID":"ses_g2","agent":"go-developer"}
+{"sessionID":"ses_r","agent":"go-reviewer"}
+`
+
+const testDelegationsJSONL = `{"callID":"call_1","sessionID":"ses_c","childSessionID":"ses_g1","agent":"go-developer","status":"completed","start":1000,"end":61000}
+{"callID":"call_2","sessionID":"ses_c","childSessionID":"ses_g2","agent":"go-developer","status":"error","start":70000,"end":100000}
+{"callID":"call_2","sessionID":"ses_c","childSessionID":"ses_g2","agent":"go-developer","status":"error","start":70000,"end":100000}
+{"callID":"call_3","sessionID":"ses_g1","childSessionID":"ses_r","agent":"go-reviewer","status":"completed"}
+{"callID":"call_4","sessionID":"ses_other","agent":"explore","status":"completed","start":5,"end":10}
+not json
+{"callID":"","sessionID":"ses_c","agent":"go-developer","status":"completed"}
+`
+
+func writeDelegationWorkspace(t *testing.T, dir, goal string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".sgai"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".sgai", "sessions.jsonl"), []byte(testSessionsJSONL), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".sgai", "delegations.jsonl"), []byte(testDelegationsJSONL), 0644))
+	if goal != "" {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "GOAL.md"), []byte(goal), 0644))
+	}
+}
+
+func TestReadDelegationRecords(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "delegations.jsonl")
+
+	records, errMissing := readDelegationRecords(path)
+	require.NoError(t, errMissing)
+	assert.Empty(t, records)
+
+	require.NoError(t, os.WriteFile(path, []byte(testDelegationsJSONL), 0644))
+	records, errRead := readDelegationRecords(path)
+	require.NoError(t, errRead)
+	var callIDs []string
+	for _, record := range records {
+		callIDs = append(callIDs, record.CallID)
+	}
+	assert.Equal(t, []string{"call_1", "call_2", "call_3", "call_4"}, callIDs)
+}
+
+func TestLoadDelegationGraph(t *testing.T) {
+	dir := t.TempDir()
+	writeDelegationWorkspace(t, dir, "")
+
+	graph, errLoad := loadDelegationGraph(dir, []string{"go-developer", "docs-writer"})
+	require.NoError(t, errLoad)
+
+	assert.Equal(t, []delegationNode{
+		{Agent: "coordinator", Sessions: []string{"ses_c"}},
+		{Agent: "go-developer", Sessions: []string{"ses_g1", "ses_g2"}},
+		{Agent: "go-reviewer", Sessions: []string{"ses_r"}},
+		{Agent: "unknown", Sessions: []string{}},
+		{Agent: "explore", Sessions: []string{}},
+		{Agent: "docs-writer", Sessions: []string{}},
+	}, graph.Nodes)
+	assert.Equal(t, []delegationEdge{
+		{From: "coordinator", To: "go-developer", Count: 2, Completed: 1, Failed: 1, DurationMs: 90000},
+		{From: "go-developer", To: "go-reviewer", Count: 1, Completed: 1},
+		{From: "unknown", To: "explore", Count: 1, Completed: 1, DurationMs: 5},
+	}, graph.Edges)
+}
+
+func TestDelegationEdgeLabel(t *testing.T) {
+	cases := []struct {
+		name string
+		edge delegationEdge
+		want string
+	}{
+		{"completedWithDuration", delegationEdge{Count: 2, Completed: 2, DurationMs: 150000}, "2× · 2m 30s · 2 ok"},
+		{"failures", delegationEdge{Count: 3, Completed: 1, Failed: 2, DurationMs: 4000}, "3× · 4s · 1 ok / 2 failed"},
+		{"withoutDuration", delegationEdge{Count: 1, Completed: 1}, "1× · 1 ok"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, delegationEdgeLabel(tc.edge))
+		})
+	}
+}
+
+func TestDelegationGraphDOT(t *testing.T) {
+	graph := delegationGraph{
+		Nodes: []delegationNode{{Agent: "coordinator", Sessions: []string{"ses_c"}}, {Agent: `odd "name"`}},
+		Edges: []delegationEdge{{From: "coordinator", To: `odd "name"`, Count: 1, Failed: 1}},
+	}
+
+	dot := delegationGraphDOT(graph)
+
+	assert.Contains(t, dot, `"coordinator" [label="coordinator\n1 session"];`)
+	assert.Contains(t, dot, `"odd \"name\"" [label="odd \"name\"\nno sessions yet"];`)
+	assert.Contains(t, dot, `"coordinator" -> "odd \"name\"" [label="1× · 0 ok / 1 failed", color="#c53030"];`)
+}
+
+func assertWellFormedSVG(t *testing.T, svg string) {
+	t.Helper()
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		_, errToken := decoder.Token()
+		if errors.Is(errToken, io.EOF) {
+			return
+		}
+		require.NoError(t, errToken)
+	}
+}
+
+func TestLayoutDelegationSVG(t *testing.T) {
+	dir := t.TempDir()
+	writeDelegationWorkspace(t, dir, "")
+	graph, errLoad := loadDelegationGraph(dir, []string{"research <review> & verify"})
+	require.NoError(t, errLoad)
+	graph.Edges = append(graph.Edges, delegationEdge{From: "go-reviewer", To: "coordinator", Count: 1, Completed: 1})
+
+	svg := renderDelegationSVG(graph, "")
+
+	assertWellFormedSVG(t, svg)
+	assert.True(t, strings.HasPrefix(svg, "<svg"))
+	assert.Contains(t, svg, ">coordinator</text>")
+	assert.Contains(t, svg, ">2 sessions</text>")
+	assert.Contains(t, svg, "2× · 1m 30s · 1 ok / 1 failed")
+	assert.Contains(t, svg, "research &lt;review&gt; &amp; verify")
+	assert.Equal(t, len(graph.Edges), strings.Count(svg, `marker-end="url(#arrow)"`))
+}
+
+func TestRenderDelegationSVGFallsBackWhenGraphvizFails(t *testing.T) {
+	graph := delegationGraph{Nodes: []delegationNode{{Agent: "go"}}}
+
+	svg := renderDelegationSVG(graph, filepath.Join(t.TempDir(), "missing-dot"))
+
+	assert.Equal(t, layoutDelegationSVG(graph), svg)
+}
+
+func TestRenderDelegationSVGWithGraphviz(t *testing.T) {
+	dotPath, errLook := exec.LookPath("dot")
+	if errLook != nil {
+		t.Skip("graphviz is not installed")
+	}
+	graph := delegationGraph{
+		Nodes: []delegationNode{{Agent: "coordinator"}, {Agent: "go"}},
+		Edges: []delegationEdge{{From: "coordinator", To: "go", Count: 2, Completed: 2}},
+	}
+
+	svg, errRender := renderDelegationGraphviz(graph, dotPath)
+
+	require.NoError(t, errRender)
+	assert.Contains(t, svg, "<svg")
+	assert.Contains(t, svg, "2× · 2 ok")
+}
+
+func TestHandleAPIDelegationGraph(t *testing.T) {
+	server, rootDir := setupTestServer(t)
+	wsDir := setupTestWorkspace(t, rootDir, "delegation-ws")
+	writeDelegationWorkspace(t, wsDir, "---\nagents:\n  - coordinator\n  - go-developer\n  - go-reviewer\n---\n# Goal")
+
+	w := serveHTTP(server, http.MethodGet, "/api/v1/workspaces/delegation-ws/delegation-graph", "")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var graph delegationGraph
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
+	require.Len(t, graph.Nodes, 5)
+	assert.Equal(t, "coordinator", graph.Nodes[0].Agent)
+	require.Len(t, graph.Edges, 3)
+	assert.Equal(t, 2, graph.Edges[0].Count)
+}
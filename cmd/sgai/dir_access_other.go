@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+func checkDirWritable(dir string) error {
+	info, errStat := os.Stat(dir)
+	if errStat != nil {
+		return errStat
+	}
+	if info.Mode().Perm()&0o200 == 0 {
+		return errors.New("permission denied")
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build linux || darwin
+
+package main
+
+import "golang.org/x/sys/unix"
+
+func checkDirWritable(dir string) error {
+	return unix.Access(dir, unix.W_OK|unix.X_OK)
+}
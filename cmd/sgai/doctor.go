@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	doctorPass = "pass"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+)
+
+// doctorCheck is one line of the diagnostics report.
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// doctorReport is the result of runDoctor. Status is the worst status of
+// its checks.
+type doctorReport struct {
+	Status string        `json:"status"`
+	Checks []doctorCheck `json:"checks"`
+}
+
+// doctorOptions selects what runDoctor inspects. workspacePath is optional;
+// without it the MCP and sgai.json checks are skipped. modelCatalog replaces
+// checkModelCatalog, so callers can cache the `opencode models` run.
+type doctorOptions struct {
+	workspacePath  string
+	opencodeDBPath string
+	configDirs     []string
+	modelCatalog   func() doctorCheck
+}
+
+// runDoctor checks everything sgai needs before a run starts: external
+// tools, the opencode database and model catalog, MCP server commands,
+// writable config directories and the embedded webapp.
+func runDoctor(opts doctorOptions) doctorReport {
+	var checks []doctorCheck
+	opencode := checkToolVersion("opencode", doctorFail, "--version")
+	checks = append(checks, opencode,
+		checkToolVersion("jj", doctorFail, "--version"),
+		checkToolVersion("git", doctorWarn, "--version"),
+		checkOpencodeDB(opts.opencodeDBPath),
+	)
+	if opencode.Status == doctorPass {
+		modelCatalog := opts.modelCatalog
+		if modelCatalog == nil {
+			modelCatalog = checkModelCatalog
+		}
+		checks = append(checks, modelCatalog())
+	} else {
+		checks = append(checks, doctorCheck{Name: "model catalog", Status: doctorFail, Detail: "skipped: opencode not found in PATH"})
+	}
+	if opts.workspacePath != "" {
+		checks = append(checks, checkMCPCommands(opts.workspacePath)...)
+	}
+	seenDirs := make(map[string]bool)
+	for _, dir := range opts.configDirs {
+		dir = filepath.Clean(dir)
+		if seenDirs[dir] {
+			continue
+		}
+		seenDirs[dir] = true
+		checks = append(checks, checkWritableDir(dir))
+	}
+	checks = append(checks, checkWebappEmbed(webappDist))
+	return newDoctorReport(checks)
+}
+
+func newDoctorReport(checks []doctorCheck) doctorReport {
+	status := doctorPass
+	for _, check := range checks {
+		if doctorSeverity(check.Status) > doctorSeverity(status) {
+			status = check.Status
+		}
+	}
+	return doctorReport{Status: status, Checks: checks}
+}
+
+func doctorSeverity(status string) int {
+	switch status {
+	case doctorFail:
+		return 2
+	case doctorWarn:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// checkToolVersion reports the first line of `name args...`. A missing
+// binary is reported with missingStatus.
+func checkToolVersion(name, missingStatus string, args ...string) doctorCheck {
+	check := doctorCheck{Name: name}
+	path, errLook := exec.LookPath(name)
+	if errLook != nil {
+		check.Status = missingStatus
+		check.Detail = "not found in PATH"
+		return check
+	}
+	output, errRun := exec.Command(path, args...).CombinedOutput()
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	if errRun != nil {
+		check.Status = doctorWarn
+		check.Detail = fmt.Sprintf("%s: version check failed: %v", path, errRun)
+		return check
+	}
+	check.Status = doctorPass
+	check.Detail = strings.TrimSpace(path + " " + firstLine)
+	return check
+}
+
+// checkOpencodeDB opens the opencode database read-only and reads its
+// session table. A missing database is only a warning: opencode creates it
+// on the first run, and until then token statistics are empty.
+func checkOpencodeDB(dbPath string) doctorCheck {
+	check := doctorCheck{Name: "opencode database"}
+	if dbPath == "" {
+		check.Status = doctorWarn
+		check.Detail = "database path could not be resolved"
+		return check
+	}
+	if _, errStat := os.Stat(dbPath); errStat != nil {
+		check.Status = doctorWarn
+		check.Detail = fmt.Sprintf("%s: %v", dbPath, errStat)
+		return check
+	}
+	db, errOpen := sql.Open("sqlite", "file:"+dbPath+"?mode=ro&_pragma=busy_timeout(5000)")
+	if errOpen != nil {
+		check.Status = doctorFail
+		check.Detail = fmt.Sprintf("%s: %v", dbPath, errOpen)
+		return check
+	}
+	defer func() {
+		if errClose := db.Close(); errClose != nil {
+			log.Println("failed to close opencode database:", errClose)
+		}
+	}()
+	var sessions int
+	if errQuery := db.QueryRow("SELECT COUNT(*) FROM session").Scan(&sessions); errQuery != nil {
+		check.Status = doctorFail
+		check.Detail = fmt.Sprintf("%s: %v", dbPath, errQuery)
+		return check
+	}
+	check.Status = doctorPass
+	check.Detail = fmt.Sprintf("%s (%d sessions)", dbPath, sessions)
+	return check
+}
+
+func checkModelCatalog() doctorCheck {
+	check := doctorCheck{Name: "model catalog"}
+	catalog, errModels := fetchValidModels()
+	if errModels != nil {
+		check.Status = doctorFail
+		check.Detail = errModels.Error()
+		return check
+	}
+	check.Status = doctorPass
+	check.Detail = fmt.Sprintf("%d models available", len(catalog))
+	return check
+}
+
+type doctorMCPServer struct {
+	Type    string   `json:"type"`
+	Command []string `json:"command"`
+	URL     string   `json:"url"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// checkMCPCommands parses the MCP servers of the workspace opencode.jsonc
+// and sgai.json and checks that every enabled local server's command is
+// installed.
+func checkMCPCommands(workspacePath string) []doctorCheck {
+	servers := make(map[string]json.RawMessage)
+	var checks []doctorCheck
+
+	opencodePath := filepath.Join(workspacePath, ".sgai", "opencode.jsonc")
+	data, errRead := os.ReadFile(opencodePath)
+	switch {
+	case errors.Is(errRead, fs.ErrNotExist):
+	case errRead != nil:
+		checks = append(checks, doctorCheck{Name: "opencode.jsonc", Status: doctorFail, Detail: errRead.Error()})
+	default:
+		section, errParse := parseOpencodeMCPSection(data)
+		if errParse != nil {
+			checks = append(checks, doctorCheck{Name: "opencode.jsonc", Status: doctorFail, Detail: fmt.Sprintf("%s: %v", opencodePath, errParse)})
+		} else {
+			checks = append(checks, doctorCheck{Name: "opencode.jsonc", Status: doctorPass, Detail: opencodePath})
+			maps.Copy(servers, section)
+		}
+	}
+
+	config, errConfig := loadProjectConfig(workspacePath)
+	switch {
+	case errConfig != nil:
+		checks = append(checks, doctorCheck{Name: configFileName, Status: doctorFail, Detail: errConfig.Error()})
+	case config != nil:
+		checks = append(checks, doctorCheck{Name: configFileName, Status: doctorPass, Detail: filepath.Join(workspacePath, configFileName)})
+		for name, value := range config.MCP {
+			if _, exists := servers[name]; !exists {
+				servers[name] = value
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(servers)) {
+		checks = append(checks, checkMCPServer(name, servers[name]))
+	}
+	return checks
+}
+
+func parseOpencodeMCPSection(data []byte) (map[string]json.RawMessage, error) {
+	var oc map[string]json.RawMessage
+	if errUnmarshal := json.Unmarshal(data, &oc); errUnmarshal != nil {
+		return nil, errUnmarshal
+	}
+	return extractMCPSection(oc)
+}
+
+func checkMCPServer(name string, raw json.RawMessage) doctorCheck {
+	check := doctorCheck{Name: "mcp " + name}
+	var server doctorMCPServer
+	if errUnmarshal := json.Unmarshal(raw, &server); errUnmarshal != nil {
+		check.Status = doctorFail
+		check.Detail = "invalid server definition: " + errUnmarshal.Error()
+		return check
+	}
+	switch {
+	case server.Enabled != nil && !*server.Enabled:
+		check.Status = doctorPass
+		check.Detail = "disabled"
+	case server.Type == "remote":
+		check.Status = doctorPass
+		check.Detail = "remote " + server.URL
+	case len(server.Command) == 0:
+		check.Status = doctorFail
+		check.Detail = "local server without a command"
+	default:
+		path, errLook := exec.LookPath(server.Command[0])
+		if errLook != nil {
+			check.Status = doctorFail
+			check.Detail = server.Command[0] + " not found in PATH"
+			return check
+		}
+		check.Status = doctorPass
+		check.Detail = path
+	}
+	return check
+}
+
+// checkWritableDir checks that dir, or the nearest existing parent that sgai
+// would create it under, is a directory the current user can write to. It
+// does not create or write anything.
+func checkWritableDir(dir string) doctorCheck {
+	check := doctorCheck{Name: "config dir " + dir}
+	existing := dir
+	for {
+		info, errStat := os.Stat(existing)
+		if errStat == nil {
+			if !info.IsDir() {
+				check.Status = doctorFail
+				check.Detail = existing + " is not a directory"
+				return check
+			}
+			break
+		}
+		parent := filepath.Dir(existing)
+		if !errors.Is(errStat, fs.ErrNotExist) || parent == existing {
+			check.Status = doctorFail
+			check.Detail = errStat.Error()
+			return check
+		}
+		existing = parent
+	}
+	if errAccess := checkDirWritable(existing); errAccess != nil {
+		check.Status = doctorFail
+		check.Detail = fmt.Sprintf("%s: %v", existing, errAccess)
+		return check
+	}
+	check.Status = doctorPass
+	check.Detail = "writable"
+	if existing != dir {
+		check.Detail = "missing, will be created under " + existing
+	}
+	return check
+}
+
+func checkWebappEmbed(dist fs.FS) doctorCheck {
+	check := doctorCheck{Name: "webapp"}
+	index, errRead := fs.ReadFile(dist, "webapp/dist/index.html")
+	if errRead != nil {
+		check.Status = doctorFail
+		check.Detail = "webapp/dist/index.html is not embedded; run the webapp build before go build"
+		return check
+	}
+	if !bytes.Contains(index, []byte("<script")) {
+		check.Status = doctorWarn
+		check.Detail = "embedded index.html has no script bundle"
+		return check
+	}
+	check.Status = doctorPass
+	check.Detail = "embedded"
+	return check
+}
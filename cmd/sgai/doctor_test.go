@@ -0,0 +1,266 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDoctorReportStatus(t *testing.T) {
+	cases := []struct {
+		name     string
+		statuses []string
+		want     string
+	}{
+		{"empty", nil, doctorPass},
+		{"allPass", []string{doctorPass, doctorPass}, doctorPass},
+		{"warnWins", []string{doctorPass, doctorWarn}, doctorWarn},
+		{"failWins", []string{doctorWarn, doctorFail, doctorPass}, doctorFail},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var checks []doctorCheck
+			for _, status := range tc.statuses {
+				checks = append(checks, doctorCheck{Name: "check", Status: status})
+			}
+
+			assert.Equal(t, tc.want, newDoctorReport(checks).Status)
+		})
+	}
+}
+
+func TestCheckToolVersion(t *testing.T) {
+	binDir := t.TempDir()
+	writeExecutable(t, filepath.Join(binDir, "fake-tool"), "#!/bin/sh\necho 'fake-tool 1.2.3'\necho 'extra line'\n")
+	writeExecutable(t, filepath.Join(binDir, "broken-tool"), "#!/bin/sh\nexit 3\n")
+	t.Setenv("PATH", binDir)
+
+	cases := []struct {
+		name          string
+		tool          string
+		missingStatus string
+		wantStatus    string
+		wantDetail    string
+	}{
+		{"found", "fake-tool", doctorFail, doctorPass, "fake-tool 1.2.3"},
+		{"versionFails", "broken-tool", doctorFail, doctorWarn, "version check failed"},
+		{"missingRequired", "missing-tool", doctorFail, doctorFail, "not found in PATH"},
+		{"missingOptional", "missing-tool", doctorWarn, doctorWarn, "not found in PATH"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			check := checkToolVersion(tc.tool, tc.missingStatus, "--version")
+
+			assert.Equal(t, tc.wantStatus, check.Status)
+			assert.Contains(t, check.Detail, tc.wantDetail)
+			assert.NotContains(t, check.Detail, "extra line")
+		})
+	}
+}
+
+func TestCheckOpencodeDB(t *testing.T) {
+	dir := t.TempDir()
+	validPath := filepath.Join(dir, "valid.db")
+	db, errOpen := sql.Open("sqlite", validPath)
+	require.NoError(t, errOpen)
+	_, errCreate := db.Exec("CREATE TABLE session (id TEXT); INSERT INTO session VALUES ('a'), ('b')")
+	require.NoError(t, errCreate)
+	require.NoError(t, db.Close())
+	noTablePath := filepath.Join(dir, "notable.db")
+	require.NoError(t, os.WriteFile(noTablePath, nil, 0o644))
+
+	cases := []struct {
+		name       string
+		path       string
+		wantStatus string
+		wantDetail string
+	}{
+		{"valid", validPath, doctorPass, "(2 sessions)"},
+		{"missing", filepath.Join(dir, "missing.db"), doctorWarn, "missing.db"},
+		{"noSessionTable", noTablePath, doctorFail, "session"},
+		{"unresolved", "", doctorWarn, "could not be resolved"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			check := checkOpencodeDB(tc.path)
+
+			assert.Equal(t, tc.wantStatus, check.Status)
+			assert.Contains(t, check.Detail, tc.wantDetail)
+		})
+	}
+}
+
+func TestCheckMCPCommands(t *testing.T) {
+	binDir := t.TempDir()
+	writeExecutable(t, filepath.Join(binDir, "npx"), "#!/bin/sh\n")
+	t.Setenv("PATH", binDir)
+
+	cases := []struct {
+		name     string
+		opencode string
+		sgaiJSON string
+		want     map[string]string
+	}{
+		{
+			name:     "opencodeAndSgaiJSON",
+			opencode: `{"mcp": {"playwright": {"type": "local", "command": ["npx", "@playwright/mcp"]}, "docs": {"type": "remote", "url": "https://example.com/mcp"}, "off": {"type": "local", "command": ["gone"], "enabled": false}}}`,
+			sgaiJSON: `{"mcp": {"custom": {"type": "local", "command": ["custom-mcp"]}, "playwright": {"type": "local", "command": ["ignored"]}}}`,
+			want: map[string]string{
+				"opencode.jsonc": doctorPass,
+				configFileName:   doctorPass,
+				"mcp custom":     doctorFail,
+				"mcp docs":       doctorPass,
+				"mcp off":        doctorPass,
+				"mcp playwright": doctorPass,
+			},
+		},
+		{
+			name:     "brokenOpencode",
+			opencode: `{"mcp": {`,
+			sgaiJSON: `{"mcp": {"custom": {"type": "local"}}}`,
+			want: map[string]string{
+				"opencode.jsonc": doctorFail,
+				configFileName:   doctorPass,
+				"mcp custom":     doctorFail,
+			},
+		},
+		{
+			name:     "brokenSgaiJSON",
+			opencode: `{}`,
+			sgaiJSON: `{"mcp": [`,
+			want: map[string]string{
+				"opencode.jsonc": doctorPass,
+				configFileName:   doctorFail,
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ws := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(ws, ".sgai"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(ws, ".sgai", "opencode.jsonc"), []byte(tc.opencode), 0o644))
+			require.NoError(t, os.WriteFile(filepath.Join(ws, configFileName), []byte(tc.sgaiJSON), 0o644))
+
+			got := make(map[string]string)
+			for _, check := range checkMCPCommands(ws) {
+				got[check.Name] = check.Status
+			}
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestCheckWritableDir(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "nested", "sgai")
+
+	missing := checkWritableDir(dir)
+
+	assert.Equal(t, doctorPass, missing.Status)
+	assert.Equal(t, "missing, will be created under "+base, missing.Detail)
+	assert.NoDirExists(t, filepath.Join(base, "nested"), "the check does not create anything")
+
+	existing := checkWritableDir(base)
+	assert.Equal(t, doctorPass, existing.Status)
+	entries, errRead := os.ReadDir(base)
+	require.NoError(t, errRead)
+	assert.Empty(t, entries, "the check does not write a probe file")
+
+	blocker := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(blocker, nil, 0o644))
+	assert.Equal(t, doctorFail, checkWritableDir(filepath.Join(blocker, "sgai")).Status)
+}
+
+func TestCheckWebappEmbed(t *testing.T) {
+	cases := []struct {
+		name string
+		dist fstest.MapFS
+		want string
+	}{
+		{"built", fstest.MapFS{"webapp/dist/index.html": {Data: []byte(`<html><script type="module" src="/assets/app.js"></script></html>`)}}, doctorPass},
+		{"placeholder", fstest.MapFS{"webapp/dist/index.html": {Data: []byte(`<html></html>`)}}, doctorWarn},
+		{"missing", fstest.MapFS{}, doctorFail},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, checkWebappEmbed(tc.dist).Status)
+		})
+	}
+}
+
+func TestRunDoctorChecksEachConfigDirOnce(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	dir := t.TempDir()
+
+	report := runDoctor(doctorOptions{configDirs: []string{dir, dir + string(filepath.Separator), filepath.Join(dir, "sub")}})
+
+	var names []string
+	for _, check := range report.Checks {
+		if strings.HasPrefix(check.Name, "config dir ") {
+			names = append(names, check.Name)
+		}
+	}
+	assert.Equal(t, []string{"config dir " + dir, "config dir " + filepath.Join(dir, "sub")}, names)
+}
+
+func TestHandleAPIHealth(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	server, rootDir := setupTestServer(t)
+	server.externalConfigDir = t.TempDir()
+	setupTestWorkspace(t, rootDir, "ws")
+
+	resp := serveHTTP(server, http.MethodGet, "/api/v1/health?workspace=ws", "")
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	var report doctorReport
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &report))
+	assert.Equal(t, doctorFail, report.Status)
+	statuses := make(map[string]string)
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	assert.Equal(t, doctorFail, statuses["opencode"])
+	assert.Equal(t, doctorFail, statuses["jj"])
+	assert.Equal(t, doctorPass, statuses["config dir "+server.externalConfigDir])
+
+	notFound := serveHTTP(server, http.MethodGet, "/api/v1/health?workspace=missing", "")
+	assert.Equal(t, http.StatusNotFound, notFound.Code)
+}
+
+func TestHealthServiceCachesModelCatalog(t *testing.T) {
+	binDir := t.TempDir()
+	countFile := filepath.Join(t.TempDir(), "models-calls")
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "models.txt"), []byte(fakeModelsVerboseOutput), 0o644))
+	writeExecutable(t, filepath.Join(binDir, "opencode"), "#!/bin/sh\n"+
+		"if [ \"$1\" = \"models\" ]; then echo call >> "+countFile+"; cat \"$(dirname \"$0\")/models.txt\"; exit 0; fi\n"+
+		"echo 'opencode 1.0.0'\n")
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	server, _ := setupTestServer(t)
+	server.externalConfigDir = t.TempDir()
+
+	server.healthService("")
+	report := server.healthService("")
+
+	calls, errRead := os.ReadFile(countFile)
+	require.NoError(t, errRead)
+	assert.Equal(t, "call\n", string(calls))
+	statuses := make(map[string]string)
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	assert.Equal(t, doctorPass, statuses["model catalog"])
+}
+
+func writeExecutable(t *testing.T, path, script string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+}
@@ -25,19 +25,32 @@ func parseModelAndVariant(modelSpec string) (model, variant string) {
 // GoalMetadata represents the YAML frontmatter in GOAL.md files.
 // It configures available agents, model selection, and workflow options.
 type GoalMetadata struct {
-	Agents               []string `json:"agents,omitempty" yaml:"agents,omitempty"`
-	Model                string   `json:"model,omitempty" yaml:"model,omitempty"`
-	Interactive          string   `json:"interactive,omitempty" yaml:"interactive,omitempty"`
-	CompletionGateScript string   `json:"completionGateScript,omitempty" yaml:"completionGateScript,omitempty"`
-	ContinuousModePrompt string   `json:"continuousModePrompt,omitempty" yaml:"continuousModePrompt,omitempty"`
-	ContinuousModeAuto   string   `json:"continuousModeAuto,omitempty" yaml:"continuousModeAuto,omitempty"`
-	ContinuousModeCron   string   `json:"continuousModeCron,omitempty" yaml:"continuousModeCron,omitempty"`
-	Retrospective        string   `json:"retrospective,omitempty" yaml:"retrospective,omitempty"`
+	Agents                     []string            `json:"agents,omitempty" yaml:"agents,omitempty"`
+	Model                      string              `json:"model,omitempty" yaml:"model,omitempty"`
+	Models                     map[string]string   `json:"models,omitempty" yaml:"models,omitempty"`
+	FallbackModels             []string            `json:"fallbackModels,omitempty" yaml:"fallbackModels,omitempty"`
+	Interactive                string              `json:"interactive,omitempty" yaml:"interactive,omitempty"`
+	CompletionGateScript       string              `json:"completionGateScript,omitempty" yaml:"completionGateScript,omitempty"`
+	ContinuousModePrompt       string              `json:"continuousModePrompt,omitempty" yaml:"continuousModePrompt,omitempty"`
+	ContinuousModeAuto         string              `json:"continuousModeAuto,omitempty" yaml:"continuousModeAuto,omitempty"`
+	ContinuousModeCron         string              `json:"continuousModeCron,omitempty" yaml:"continuousModeCron,omitempty"`
+	ContinuousModeCronTimezone string              `json:"continuousModeCronTimezone,omitempty" yaml:"continuousModeCronTimezone,omitempty"`
+	ContinuousModeCronMissed   string              `json:"continuousModeCronMissed,omitempty" yaml:"continuousModeCronMissed,omitempty"`
+	ContinuousModeCronOverlap  string              `json:"continuousModeCronOverlap,omitempty" yaml:"continuousModeCronOverlap,omitempty"`
+	ContinuousModeCronJitter   string              `json:"continuousModeCronJitter,omitempty" yaml:"continuousModeCronJitter,omitempty"`
+	ContinuousModeTriggers     *continuousTriggers `json:"continuousModeTriggers,omitempty" yaml:"continuousModeTriggers,omitempty"`
+	Retrospective              string              `json:"retrospective,omitempty" yaml:"retrospective,omitempty"`
+	StuckLoop                  *stuckLoopConfig    `json:"stuckLoop,omitempty" yaml:"stuckLoop,omitempty"`
+	DependsOn                  []string            `json:"dependsOn,omitempty" yaml:"dependsOn,omitempty"`
+	PipelineArtifacts          string              `json:"pipelineArtifacts,omitempty" yaml:"pipelineArtifacts,omitempty"`
 }
 
 type agentMetadata struct {
 	Log      bool     `json:"log" yaml:"log"`
 	Snippets []string `json:"snippets" yaml:"snippets"`
+	// Permission is the opencode permission block: a decision string, or a
+	// map whose values are decisions or maps of command patterns.
+	Permission any `json:"permission" yaml:"permission"`
 }
 
 func parseYAMLFrontmatterFromFile(goalPath string) (GoalMetadata, error) {
@@ -25,6 +25,9 @@ func (rt *captureRoundTripper) RoundTrip(req *http.Request) (*http.Response, err
 func TestRequiresOpencodeSkipsInternalMCP(t *testing.T) {
 	assert.False(t, requiresOpencode("internal-mcp"))
 	assert.False(t, requiresOpencode("help"))
+	assert.False(t, requiresOpencode("ctl"))
+	assert.False(t, requiresOpencode("doctor"))
+	assert.False(t, requiresOpencode("tui"))
 	assert.True(t, requiresOpencode("serve"))
 }
 
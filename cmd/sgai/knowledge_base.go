@@ -0,0 +1,472 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	knowledgeBaseFileName      = "knowledge.db"
+	knowledgeDefaultLimit      = 20
+	knowledgeMaxLimit          = 200
+	knowledgeRetroTag          = "retrospective"
+	knowledgeRetroAgent        = "retrospective"
+	knowledgeRetroSourcePrefix = "retrospective:"
+	retroCompleteMarker        = "RETRO_COMPLETE"
+	retroQuestionMarker        = "RETRO_QUESTION"
+)
+
+var (
+	errKnowledgeNoteNotFound = errors.New("note not found")
+	errKnowledgeNoteEmpty    = errors.New("note content is required")
+)
+
+const knowledgeSchema = `
+CREATE TABLE IF NOT EXISTS notes (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	content    TEXT    NOT NULL,
+	workspace  TEXT    NOT NULL DEFAULT '',
+	agent      TEXT    NOT NULL DEFAULT '',
+	source     TEXT    NOT NULL DEFAULT '',
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS notes_source ON notes(source) WHERE source != '';
+CREATE TABLE IF NOT EXISTS note_tags (
+	note_id INTEGER NOT NULL,
+	tag     TEXT    NOT NULL,
+	PRIMARY KEY (note_id, tag)
+);
+CREATE INDEX IF NOT EXISTS note_tags_tag ON note_tags(tag);
+`
+
+// knowledgeNote is one entry of the cross-workspace knowledge base. Source
+// identifies notes seeded from elsewhere, such as a retrospective, so they
+// are only added once.
+type knowledgeNote struct {
+	ID        int64     `json:"id"`
+	Content   string    `json:"content"`
+	Tags      []string  `json:"tags"`
+	Workspace string    `json:"workspace,omitempty"`
+	Agent     string    `json:"agent,omitempty"`
+	Source    string    `json:"source,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// knowledgeQuery selects notes: every word of Text must appear in the
+// content or a tag, and every tag in Tags must be present.
+type knowledgeQuery struct {
+	Text      string
+	Tags      []string
+	Workspace string
+	Limit     int
+}
+
+// knowledgeBase is the SQLite store in the user config directory, shared by
+// every workspace and every sgai process on the machine.
+type knowledgeBase struct {
+	db *sql.DB
+}
+
+func openKnowledgeBase(configDir string) (*knowledgeBase, error) {
+	if errMkdir := os.MkdirAll(configDir, 0o700); errMkdir != nil {
+		return nil, fmt.Errorf("creating config directory: %w", errMkdir)
+	}
+	dbPath := filepath.Join(configDir, knowledgeBaseFileName)
+	db, errOpen := sql.Open("sqlite", "file:"+dbPath+"?_pragma=busy_timeout(5000)")
+	if errOpen != nil {
+		return nil, fmt.Errorf("opening knowledge base: %w", errOpen)
+	}
+	if _, errSchema := db.Exec(knowledgeSchema); errSchema != nil {
+		if errClose := db.Close(); errClose != nil {
+			log.Println("failed to close knowledge base:", errClose)
+		}
+		return nil, fmt.Errorf("creating knowledge base schema: %w", errSchema)
+	}
+	return &knowledgeBase{db: db}, nil
+}
+
+func withKnowledgeBase(configDir string, fn func(kb *knowledgeBase) error) error {
+	kb, errOpen := openKnowledgeBase(configDir)
+	if errOpen != nil {
+		return errOpen
+	}
+	defer func() {
+		if errClose := kb.db.Close(); errClose != nil {
+			log.Println("failed to close knowledge base:", errClose)
+		}
+	}()
+	return fn(kb)
+}
+
+// normalizeKnowledgeTags lowercases, trims, de-duplicates and sorts tags.
+func normalizeKnowledgeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" {
+			normalized = append(normalized, tag)
+		}
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
+
+// add stores note and returns it with its id and timestamps. A note whose
+// Source is already stored is not added again; the stored note is returned.
+func (kb *knowledgeBase) add(note knowledgeNote) (knowledgeNote, error) {
+	note.Content = strings.TrimSpace(note.Content)
+	if note.Content == "" {
+		return knowledgeNote{}, errKnowledgeNoteEmpty
+	}
+	if note.Source != "" {
+		var existing int64
+		errExisting := kb.db.QueryRow(`SELECT id FROM notes WHERE source = ?`, note.Source).Scan(&existing)
+		if errExisting == nil {
+			return kb.get(existing)
+		}
+		if !errors.Is(errExisting, sql.ErrNoRows) {
+			return knowledgeNote{}, fmt.Errorf("looking up note source: %w", errExisting)
+		}
+	}
+	note.Tags = normalizeKnowledgeTags(note.Tags)
+	now := time.Now().UTC()
+	note.CreatedAt, note.UpdatedAt = now, now
+
+	tx, errBegin := kb.db.Begin()
+	if errBegin != nil {
+		return knowledgeNote{}, fmt.Errorf("starting transaction: %w", errBegin)
+	}
+	defer func() { _ = tx.Rollback() }()
+	res, errInsert := tx.Exec(`INSERT INTO notes (content, workspace, agent, source, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		note.Content, note.Workspace, note.Agent, note.Source, now.UnixMilli(), now.UnixMilli())
+	if errInsert != nil {
+		return knowledgeNote{}, fmt.Errorf("inserting note: %w", errInsert)
+	}
+	id, errID := res.LastInsertId()
+	if errID != nil {
+		return knowledgeNote{}, fmt.Errorf("reading note id: %w", errID)
+	}
+	note.ID = id
+	if errTags := insertKnowledgeTags(tx, id, note.Tags); errTags != nil {
+		return knowledgeNote{}, errTags
+	}
+	if errCommit := tx.Commit(); errCommit != nil {
+		return knowledgeNote{}, fmt.Errorf("committing note: %w", errCommit)
+	}
+	return note, nil
+}
+
+func insertKnowledgeTags(tx *sql.Tx, id int64, tags []string) error {
+	for _, tag := range tags {
+		if _, errTag := tx.Exec(`INSERT INTO note_tags (note_id, tag) VALUES (?, ?)`, id, tag); errTag != nil {
+			return fmt.Errorf("inserting note tag: %w", errTag)
+		}
+	}
+	return nil
+}
+
+func (kb *knowledgeBase) get(id int64) (knowledgeNote, error) {
+	notes, errQuery := kb.query(`SELECT id, content, workspace, agent, source, created_at, updated_at FROM notes WHERE id = ?`, id)
+	if errQuery != nil {
+		return knowledgeNote{}, errQuery
+	}
+	if len(notes) == 0 {
+		return knowledgeNote{}, fmt.Errorf("%w: %d", errKnowledgeNoteNotFound, id)
+	}
+	return notes[0], nil
+}
+
+// update replaces the content and, when tags is not nil, the tags of a note.
+func (kb *knowledgeBase) update(id int64, content string, tags []string) (knowledgeNote, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return knowledgeNote{}, errKnowledgeNoteEmpty
+	}
+	tx, errBegin := kb.db.Begin()
+	if errBegin != nil {
+		return knowledgeNote{}, fmt.Errorf("starting transaction: %w", errBegin)
+	}
+	defer func() { _ = tx.Rollback() }()
+	res, errUpdate := tx.Exec(`UPDATE notes SET content = ?, updated_at = ? WHERE id = ?`, content, time.Now().UTC().UnixMilli(), id)
+	if errUpdate != nil {
+		return knowledgeNote{}, fmt.Errorf("updating note: %w", errUpdate)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return knowledgeNote{}, fmt.Errorf("%w: %d", errKnowledgeNoteNotFound, id)
+	}
+	if tags != nil {
+		if _, errDelete := tx.Exec(`DELETE FROM note_tags WHERE note_id = ?`, id); errDelete != nil {
+			return knowledgeNote{}, fmt.Errorf("replacing note tags: %w", errDelete)
+		}
+		if errTags := insertKnowledgeTags(tx, id, normalizeKnowledgeTags(tags)); errTags != nil {
+			return knowledgeNote{}, errTags
+		}
+	}
+	if errCommit := tx.Commit(); errCommit != nil {
+		return knowledgeNote{}, fmt.Errorf("committing note: %w", errCommit)
+	}
+	return kb.get(id)
+}
+
+func (kb *knowledgeBase) delete(id int64) error {
+	tx, errBegin := kb.db.Begin()
+	if errBegin != nil {
+		return fmt.Errorf("starting transaction: %w", errBegin)
+	}
+	defer func() { _ = tx.Rollback() }()
+	res, errDelete := tx.Exec(`DELETE FROM notes WHERE id = ?`, id)
+	if errDelete != nil {
+		return fmt.Errorf("deleting note: %w", errDelete)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return fmt.Errorf("%w: %d", errKnowledgeNoteNotFound, id)
+	}
+	if _, errTags := tx.Exec(`DELETE FROM note_tags WHERE note_id = ?`, id); errTags != nil {
+		return fmt.Errorf("deleting note tags: %w", errTags)
+	}
+	if errCommit := tx.Commit(); errCommit != nil {
+		return fmt.Errorf("committing note deletion: %w", errCommit)
+	}
+	return nil
+}
+
+// search returns the notes matching q, most recently updated first.
+func (kb *knowledgeBase) search(q knowledgeQuery) ([]knowledgeNote, error) {
+	var where []string
+	var args []any
+	for _, term := range strings.Fields(strings.ToLower(q.Text)) {
+		pattern := "%" + escapeLikePattern(term) + "%"
+		where = append(where, `(lower(n.content) LIKE ? ESCAPE '\' OR EXISTS (SELECT 1 FROM note_tags t WHERE t.note_id = n.id AND t.tag LIKE ? ESCAPE '\'))`)
+		args = append(args, pattern, pattern)
+	}
+	for _, tag := range normalizeKnowledgeTags(q.Tags) {
+		where = append(where, `EXISTS (SELECT 1 FROM note_tags t WHERE t.note_id = n.id AND t.tag = ?)`)
+		args = append(args, tag)
+	}
+	if workspace := strings.TrimSpace(q.Workspace); workspace != "" {
+		where = append(where, `n.workspace = ?`)
+		args = append(args, workspace)
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = knowledgeDefaultLimit
+	}
+	limit = min(limit, knowledgeMaxLimit)
+
+	query := `SELECT n.id, n.content, n.workspace, n.agent, n.source, n.created_at, n.updated_at FROM notes n`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	query += ` ORDER BY n.updated_at DESC, n.id DESC LIMIT ?`
+	return kb.query(query, append(args, limit)...)
+}
+
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// query runs a notes SELECT and attaches each note's tags.
+func (kb *knowledgeBase) query(query string, args ...any) ([]knowledgeNote, error) {
+	rows, errQuery := kb.db.Query(query, args...)
+	if errQuery != nil {
+		return nil, fmt.Errorf("querying notes: %w", errQuery)
+	}
+	defer func() {
+		if errClose := rows.Close(); errClose != nil {
+			log.Println("failed to close note rows:", errClose)
+		}
+	}()
+	notes := []knowledgeNote{}
+	byID := make(map[int64]int)
+	for rows.Next() {
+		var note knowledgeNote
+		var created, updated int64
+		if errScan := rows.Scan(&note.ID, &note.Content, &note.Workspace, &note.Agent, &note.Source, &created, &updated); errScan != nil {
+			return nil, fmt.Errorf("scanning note: %w", errScan)
+		}
+		note.CreatedAt = time.UnixMilli(created).UTC()
+		note.UpdatedAt = time.UnixMilli(updated).UTC()
+		note.Tags = []string{}
+		byID[note.ID] = len(notes)
+		notes = append(notes, note)
+	}
+	if errRows := rows.Err(); errRows != nil {
+		return nil, fmt.Errorf("reading notes: %w", errRows)
+	}
+	if len(notes) == 0 {
+		return notes, nil
+	}
+
+	ids := make([]any, 0, len(notes))
+	for _, note := range notes {
+		ids = append(ids, note.ID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	tagRows, errTags := kb.db.Query(`SELECT note_id, tag FROM note_tags WHERE note_id IN (`+placeholders+`) ORDER BY tag`, ids...)
+	if errTags != nil {
+		return nil, fmt.Errorf("querying note tags: %w", errTags)
+	}
+	defer func() {
+		if errClose := tagRows.Close(); errClose != nil {
+			log.Println("failed to close note tag rows:", errClose)
+		}
+	}()
+	for tagRows.Next() {
+		var id int64
+		var tag string
+		if errScan := tagRows.Scan(&id, &tag); errScan != nil {
+			return nil, fmt.Errorf("scanning note tag: %w", errScan)
+		}
+		if idx, ok := byID[id]; ok {
+			notes[idx].Tags = append(notes[idx].Tags, tag)
+		}
+	}
+	if errRows := tagRows.Err(); errRows != nil {
+		return nil, fmt.Errorf("reading note tags: %w", errRows)
+	}
+	return notes, nil
+}
+
+func formatKnowledgeNotes(notes []knowledgeNote) string {
+	if len(notes) == 0 {
+		return "No notes found."
+	}
+	var b strings.Builder
+	for i, note := range notes {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "## Note %d", note.ID)
+		if len(note.Tags) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(note.Tags, ", "))
+		}
+		b.WriteString("\n")
+		var origin []string
+		if note.Workspace != "" {
+			origin = append(origin, "workspace "+note.Workspace)
+		}
+		if note.Agent != "" {
+			origin = append(origin, "by "+note.Agent)
+		}
+		origin = append(origin, note.UpdatedAt.Format(time.DateOnly))
+		fmt.Fprintf(&b, "(%s)\n\n%s\n", strings.Join(origin, ", "), note.Content)
+	}
+	return b.String()
+}
+
+// knowledgeConfig is the knowledge section of the user-level config.json.
+type knowledgeConfig struct {
+	SeedFromRetrospectives bool `json:"seedFromRetrospectives,omitempty"`
+}
+
+// retroCompleteSummary returns the summary of the suggestions approved in
+// this session's retrospective, read from the ledger copy in retroDir. Entries
+// already present in an earlier session's copy are ignored, and so is the
+// RETRO_COMPLETE marker written when there was nothing to approve.
+func retroCompleteSummary(retroDir string) string {
+	entries, errLedger := readRetrospectiveLedger(retroDir)
+	if errLedger != nil {
+		log.Println("cannot read ledger for retrospective notes:", errLedger)
+		return ""
+	}
+	earlier := earlierRetrospectiveEntries(retroDir)
+	var asked bool
+	var summary string
+	for _, entry := range entries {
+		if earlier[retroLedgerKey(entry)] {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(entry.Title, retroQuestionMarker):
+			asked = true
+		case asked && strings.TrimSpace(entry.Title) == retroCompleteMarker:
+			summary = strings.TrimSpace(entry.Body)
+		}
+	}
+	return summary
+}
+
+func readRetrospectiveLedger(retroDir string) ([]ledgerEntry, error) {
+	content, errRead := os.ReadFile(filepath.Join(retroDir, "PROJECT_MANAGEMENT.md"))
+	if errRead != nil {
+		if os.IsNotExist(errRead) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading retrospective PROJECT_MANAGEMENT.md: %w", errRead)
+	}
+	return parseProjectManagementLedger(string(content)), nil
+}
+
+// earlierRetrospectiveEntries collects the ledger entries copied into the
+// retrospective directories of sessions before retroDir. The ledger is kept
+// across sessions, so those entries reappear in every later copy.
+func earlierRetrospectiveEntries(retroDir string) map[string]bool {
+	earlier := make(map[string]bool)
+	dirEntries, errRead := os.ReadDir(filepath.Dir(retroDir))
+	if errRead != nil {
+		log.Println("cannot list earlier retrospectives:", errRead)
+		return earlier
+	}
+	current := filepath.Base(retroDir)
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() || dirEntry.Name() >= current {
+			continue
+		}
+		entries, errLedger := readRetrospectiveLedger(filepath.Join(filepath.Dir(retroDir), dirEntry.Name()))
+		if errLedger != nil {
+			log.Println("cannot read earlier retrospective ledger:", errLedger)
+			continue
+		}
+		for _, entry := range entries {
+			earlier[retroLedgerKey(entry)] = true
+		}
+	}
+	return earlier
+}
+
+func retroLedgerKey(entry ledgerEntry) string {
+	return entry.Title + "\x00" + entry.Timestamp + "\x00" + strings.TrimSpace(entry.Body)
+}
+
+// seedKnowledgeFromRetrospective records the RETRO_COMPLETE summary of the
+// suggestions approved in a finished retrospective as a note when
+// seedFromRetrospectives is enabled in the user config. Each retrospective
+// directory is recorded at most once.
+func seedKnowledgeFromRetrospective(configDir, dir, retroDir string) {
+	config, errConfig := loadUserConfig(configDir)
+	if errConfig != nil {
+		log.Println("cannot load user config for retrospective notes:", errConfig)
+		return
+	}
+	if config == nil || config.Knowledge == nil || !config.Knowledge.SeedFromRetrospectives {
+		return
+	}
+	summary := retroCompleteSummary(retroDir)
+	if summary == "" {
+		return
+	}
+	workspace := filepath.Base(dir)
+	note := knowledgeNote{
+		Content:   summary,
+		Tags:      []string{knowledgeRetroTag},
+		Workspace: workspace,
+		Agent:     knowledgeRetroAgent,
+		Source:    knowledgeRetroSourcePrefix + workspace + "/" + filepath.Base(retroDir),
+	}
+	errSeed := withKnowledgeBase(configDir, func(kb *knowledgeBase) error {
+		_, errAdd := kb.add(note)
+		return errAdd
+	})
+	if errSeed != nil {
+		log.Println("failed to record retrospective note:", errSeed)
+	}
+}
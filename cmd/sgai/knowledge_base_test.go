@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func seedTestKnowledgeBase(t *testing.T, configDir string, notes ...knowledgeNote) []knowledgeNote {
+	t.Helper()
+	var added []knowledgeNote
+	require.NoError(t, withKnowledgeBase(configDir, func(kb *knowledgeBase) error {
+		for _, note := range notes {
+			stored, errAdd := kb.add(note)
+			if errAdd != nil {
+				return errAdd
+			}
+			added = append(added, stored)
+		}
+		return nil
+	}))
+	return added
+}
+
+func searchTestKnowledgeBase(t *testing.T, configDir string, q knowledgeQuery) []knowledgeNote {
+	t.Helper()
+	var notes []knowledgeNote
+	require.NoError(t, withKnowledgeBase(configDir, func(kb *knowledgeBase) error {
+		var errSearch error
+		notes, errSearch = kb.search(q)
+		return errSearch
+	}))
+	return notes
+}
+
+func noteIDs(notes []knowledgeNote) []int64 {
+	ids := []int64{}
+	for _, note := range notes {
+		ids = append(ids, note.ID)
+	}
+	return ids
+}
+
+func TestKnowledgeBaseSearch(t *testing.T) {
+	configDir := t.TempDir()
+	added := seedTestKnowledgeBase(t, configDir,
+		knowledgeNote{Content: "Run go test with -race in CI", Tags: []string{"Go", " testing ", "go"}, Workspace: "api", Agent: "go-developer"},
+		knowledgeNote{Content: "jj squash keeps the change id", Tags: []string{"jj"}, Workspace: "cli", Agent: "coordinator"},
+		knowledgeNote{Content: "100% coverage is not a goal_metric", Tags: []string{"testing"}, Workspace: "cli"},
+	)
+	require.Len(t, added, 3)
+	assert.Equal(t, []string{"go", "testing"}, added[0].Tags)
+
+	cases := []struct {
+		name  string
+		query knowledgeQuery
+		want  []int64
+	}{
+		{"newestFirst", knowledgeQuery{}, []int64{added[2].ID, added[1].ID, added[0].ID}},
+		{"allWordsRequired", knowledgeQuery{Text: "go race"}, []int64{added[0].ID}},
+		{"caseInsensitive", knowledgeQuery{Text: "JJ"}, []int64{added[1].ID}},
+		{"matchesTags", knowledgeQuery{Text: "testing"}, []int64{added[2].ID, added[0].ID}},
+		{"tagFilter", knowledgeQuery{Tags: []string{"TESTING", "go"}}, []int64{added[0].ID}},
+		{"workspaceFilter", knowledgeQuery{Workspace: "cli"}, []int64{added[2].ID, added[1].ID}},
+		{"likeWildcardsAreLiteral", knowledgeQuery{Text: "100%"}, []int64{added[2].ID}},
+		{"underscoreIsLiteral", knowledgeQuery{Text: "goal_m"}, []int64{added[2].ID}},
+		{"limit", knowledgeQuery{Limit: 1}, []int64{added[2].ID}},
+		{"noMatch", knowledgeQuery{Text: "rust"}, []int64{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, noteIDs(searchTestKnowledgeBase(t, configDir, tc.query)))
+		})
+	}
+}
+
+func TestKnowledgeBaseUpdateAndDelete(t *testing.T) {
+	configDir := t.TempDir()
+	added := seedTestKnowledgeBase(t, configDir, knowledgeNote{Content: "first", Tags: []string{"a", "b"}})
+	id := added[0].ID
+
+	require.NoError(t, withKnowledgeBase(configDir, func(kb *knowledgeBase) error {
+		kept, errKeep := kb.update(id, "second", nil)
+		require.NoError(t, errKeep)
+		assert.Equal(t, "second", kept.Content)
+		assert.Equal(t, []string{"a", "b"}, kept.Tags)
+
+		replaced, errReplace := kb.update(id, "third", []string{"c"})
+		require.NoError(t, errReplace)
+		assert.Equal(t, []string{"c"}, replaced.Tags)
+
+		_, errEmpty := kb.update(id, "  ", nil)
+		assert.ErrorIs(t, errEmpty, errKnowledgeNoteEmpty)
+		_, errMissing := kb.update(id+1, "x", nil)
+		assert.ErrorIs(t, errMissing, errKnowledgeNoteNotFound)
+
+		require.NoError(t, kb.delete(id))
+		assert.ErrorIs(t, kb.delete(id), errKnowledgeNoteNotFound)
+		_, errGet := kb.get(id)
+		assert.ErrorIs(t, errGet, errKnowledgeNoteNotFound)
+		return nil
+	}))
+	assert.Empty(t, searchTestKnowledgeBase(t, configDir, knowledgeQuery{Tags: []string{"c"}}))
+}
+
+func TestKnowledgeBaseAddRejectsEmptyAndDeduplicatesSource(t *testing.T) {
+	configDir := t.TempDir()
+	require.NoError(t, withKnowledgeBase(configDir, func(kb *knowledgeBase) error {
+		_, errEmpty := kb.add(knowledgeNote{Content: "\n"})
+		assert.ErrorIs(t, errEmpty, errKnowledgeNoteEmpty)
+
+		first, errFirst := kb.add(knowledgeNote{Content: "one", Source: "retrospective:ws/1"})
+		require.NoError(t, errFirst)
+		second, errSecond := kb.add(knowledgeNote{Content: "two", Source: "retrospective:ws/1"})
+		require.NoError(t, errSecond)
+		assert.Equal(t, first.ID, second.ID)
+		assert.Equal(t, "one", second.Content)
+		return nil
+	}))
+}
+
+func TestFormatKnowledgeNotes(t *testing.T) {
+	assert.Equal(t, "No notes found.", formatKnowledgeNotes(nil))
+
+	added := seedTestKnowledgeBase(t, t.TempDir(), knowledgeNote{Content: "use t.Setenv", Tags: []string{"go"}, Workspace: "api", Agent: "go-developer"})
+	formatted := formatKnowledgeNotes(added)
+
+	assert.Contains(t, formatted, fmt.Sprintf("## Note %d [go]\n(workspace api, by go-developer, ", added[0].ID))
+	assert.Contains(t, formatted, "\n\nuse t.Setenv\n")
+}
+
+func TestSeedKnowledgeFromRetrospective(t *testing.T) {
+	const enabled = `{"knowledge":{"seedFromRetrospectives":true}}`
+	const question = "## RETRO_QUESTION [MULTI-SELECT]: Skills Changes (1 proposal)\n\n- run go vet\n"
+	const approved = question + "\n## RETRO_COMPLETE (2026-10-18T10:00:00Z)\n<!-- sgai-ledger type=note agent=retrospective -->\n\nApplied: reviewers must run go vet.\n"
+	cases := []struct {
+		name          string
+		config        string
+		earlierLedger string
+		ledger        string
+		wantNotes     int
+	}{
+		{"enabled", enabled, "", "# Project Management\n\n" + approved, 1},
+		{"disabled", `{"knowledge":{"seedFromRetrospectives":false}}`, "", approved, 0},
+		{"noUserConfig", "", "", approved, 0},
+		{"noRetroComplete", enabled, "", "## Handoff\n\nnothing\n", 0},
+		{"nothingApproved", enabled, "", "## Retrospective finished\n\nRETRO_COMPLETE: no actionable suggestions.\n", 0},
+		{"completeWithoutQuestion", enabled, "", "## RETRO_COMPLETE\n\nApplied: reviewers must run go vet.\n", 0},
+		{"earlierSession", enabled, approved, approved + "\n## Handoff\n\nnothing\n", 0},
+		{"approvedAfterEarlierSession", enabled, "## Handoff\n\nnothing\n", "## Handoff\n\nnothing\n\n" + approved, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			configDir := t.TempDir()
+			if tc.config != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(configDir, userConfigFileName), []byte(tc.config), 0o600))
+			}
+			dir := filepath.Join(t.TempDir(), "api")
+			retrosDir := filepath.Join(dir, ".sgai", "retrospectives")
+			retroDir := filepath.Join(retrosDir, "2026-10-18-10-00.abcd")
+			require.NoError(t, os.MkdirAll(retroDir, 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(retroDir, "PROJECT_MANAGEMENT.md"), []byte(tc.ledger), 0o644))
+			if tc.earlierLedger != "" {
+				earlierDir := filepath.Join(retrosDir, "2026-10-17-09-00.wxyz")
+				require.NoError(t, os.MkdirAll(earlierDir, 0o755))
+				require.NoError(t, os.WriteFile(filepath.Join(earlierDir, "PROJECT_MANAGEMENT.md"), []byte(tc.earlierLedger), 0o644))
+			}
+
+			seedKnowledgeFromRetrospective(configDir, dir, retroDir)
+			seedKnowledgeFromRetrospective(configDir, dir, retroDir)
+
+			if tc.wantNotes == 0 {
+				assert.NoFileExists(t, filepath.Join(configDir, knowledgeBaseFileName))
+				return
+			}
+			notes := searchTestKnowledgeBase(t, configDir, knowledgeQuery{})
+			require.Len(t, notes, tc.wantNotes)
+			assert.Equal(t, "Applied: reviewers must run go vet.", notes[0].Content)
+			assert.Equal(t, []string{knowledgeRetroTag}, notes[0].Tags)
+			assert.Equal(t, "api", notes[0].Workspace)
+			assert.Equal(t, "retrospective:api/2026-10-18-10-00.abcd", notes[0].Source)
+		})
+	}
+}
+
+func TestNoteMCPTools(t *testing.T) {
+	workingDir := filepath.Join(t.TempDir(), "api")
+	mcpCtx := &mcpContext{workingDir: workingDir, agentName: "go-developer", knowledgeDir: t.TempDir()}
+	server := mcp.NewServer(&mcp.Implementation{Name: "sgai"}, nil)
+	registerTools(server, mcpCtx)
+
+	ct, st := mcp.NewInMemoryTransports()
+	_, errConnect := server.Connect(context.Background(), st, nil)
+	require.NoError(t, errConnect)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client"}, nil)
+	cs, errClient := client.Connect(context.Background(), ct, nil)
+	require.NoError(t, errClient)
+	t.Cleanup(func() { _ = cs.Close() })
+
+	callText := func(t *testing.T, name string, args map[string]any) string {
+		t.Helper()
+		result, errCall := cs.CallTool(context.Background(), &mcp.CallToolParams{Name: name, Arguments: args})
+		require.NoError(t, errCall)
+		require.False(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		return result.Content[0].(*mcp.TextContent).Text
+	}
+
+	assert.Equal(t, "Error: content is required.", callText(t, "record_note", map[string]any{"content": " "}))
+	assert.Equal(t, "Recorded note 1 with tags: ci, go", callText(t, "record_note", map[string]any{"content": "Cache the module download", "tags": []string{"go", "CI"}}))
+	assert.Equal(t, "No notes found.", callText(t, "search_notes", map[string]any{"query": "rust"}))
+
+	found := callText(t, "search_notes", map[string]any{"query": "module", "workspace": "api"})
+	assert.Contains(t, found, "## Note 1 [ci, go]")
+	assert.Contains(t, found, "workspace api, by go-developer")
+	assert.Contains(t, found, "Cache the module download")
+}
+
+func TestHandleAPINotes(t *testing.T) {
+	server, _ := setupTestServer(t)
+	server.externalConfigDir = t.TempDir()
+
+	created := serveHTTP(server, http.MethodPost, "/api/v1/notes", `{"content":"Prefer table tests","tags":["go"],"workspace":"api"}`)
+	require.Equal(t, http.StatusCreated, created.Code, created.Body.String())
+	var note knowledgeNote
+	require.NoError(t, json.Unmarshal(created.Body.Bytes(), &note))
+	assert.Equal(t, ledgerHumanAgent, note.Agent)
+	notePath := fmt.Sprintf("/api/v1/notes/%d", note.ID)
+
+	t.Run("list", func(t *testing.T) {
+		w := serveHTTP(server, http.MethodGet, "/api/v1/notes?q=table&tag=go&workspace=api", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var result listNotesResult
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, []int64{note.ID}, noteIDs(result.Notes))
+	})
+
+	t.Run("update", func(t *testing.T) {
+		w := serveHTTP(server, http.MethodPut, notePath, `{"content":"Prefer table-driven tests","tags":["go","testing"]}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var updated knowledgeNote
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+		assert.Equal(t, "Prefer table-driven tests", updated.Content)
+		assert.Equal(t, []string{"go", "testing"}, updated.Tags)
+	})
+
+	cases := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+	}{
+		{"emptyContent", http.MethodPost, "/api/v1/notes", `{"content":""}`, http.StatusBadRequest},
+		{"invalidBody", http.MethodPost, "/api/v1/notes", `{`, http.StatusBadRequest},
+		{"invalidLimit", http.MethodGet, "/api/v1/notes?limit=0", "", http.StatusBadRequest},
+		{"invalidID", http.MethodDelete, "/api/v1/notes/abc", "", http.StatusBadRequest},
+		{"updateMissing", http.MethodPut, "/api/v1/notes/999", `{"content":"x"}`, http.StatusNotFound},
+		{"delete", http.MethodDelete, notePath, "", http.StatusNoContent},
+		{"deleteAgain", http.MethodDelete, notePath, "", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serveHTTP(server, tc.method, tc.path, tc.body)
+			assert.Equal(t, tc.wantCode, w.Code, w.Body.String())
+		})
+	}
+}
@@ -36,6 +36,24 @@ func main() {
 	case "token-stats":
 		cmdTokenStats(os.Args[2:])
 		return
+	case "retro":
+		cmdRetro(os.Args[2:])
+		return
+	case "bundle":
+		cmdBundle(os.Args[2:])
+		return
+	case "ctl":
+		cmdCtl(os.Args[2:])
+		return
+	case "secrets":
+		cmdSecrets(os.Args[2:])
+		return
+	case "doctor":
+		cmdDoctor(os.Args[2:])
+		return
+	case "tui":
+		cmdTUI(os.Args[2:])
+		return
 	case "help", "-h", "--help":
 		printUsage()
 		return
@@ -50,7 +68,7 @@ func main() {
 
 func requiresOpencode(subcommand string) bool {
 	switch subcommand {
-	case "help", "-h", "--help", "internal-mcp", "token-stats":
+	case "help", "-h", "--help", "internal-mcp", "token-stats", "retro", "bundle", "ctl", "secrets", "doctor", "tui":
 		return false
 	default:
 		return true
@@ -62,7 +80,14 @@ func printUsage() {
 
 Usage:
   sgai [--listen-addr addr]    Start web server (default)
-  sgai token-stats <path>      Aggregate token usage for a workspace
+  sgai token-stats <path>      Aggregate token usage and cost for a workspace
+  sgai retro report [dir]      Summarize retrospectives across runs
+  sgai bundle export <path>    Package a workspace run as a portable tar.gz
+  sgai bundle import <file>    Unpack a bundle as a read-only archived workspace
+  sgai ctl <command>           Control a running sgai server from the terminal
+  sgai secrets <command>       Manage encrypted secrets injected into agents and gates
+  sgai doctor [dir]            Check tools, opencode database, models and MCP servers
+  sgai tui                     Terminal dashboard for a running sgai server
 
 Options:
   --listen-addr   HTTP server listen address (default: 127.0.0.1:8080)
@@ -73,5 +98,17 @@ Examples:
   sgai --listen-addr 0.0.0.0:8080
       Start web UI accessible externally
   sgai token-stats ./my-workspace
-      Print token usage broken down by agent and model`)
+      Print token usage and cost broken down by agent and model
+  sgai token-stats --all --format csv --since 720h .
+      Export the last 30 days of usage for every workspace under the root
+  sgai retro report --format html --output retro.html .
+      Write an HTML report covering every retrospective in the workspace
+  sgai ctl respond my-fork
+      Answer the pending question of a running workspace
+  echo "$TOKEN" | sgai secrets set GITHUB_TOKEN
+      Store a workspace secret for sgai.json to reference
+  sgai doctor --json .
+      Print a pass/warn/fail report of the environment as JSON
+  sgai tui --embed .
+      Run the server in-process and manage its workspaces from the terminal`)
 }
@@ -33,6 +33,27 @@ retrospective: true
 	assert.Equal(t, "true", metadata.Retrospective)
 }
 
+func TestParseYAMLFrontmatterAgentModels(t *testing.T) {
+	content := []byte(`---
+agents:
+  - go-developer
+  - go-reviewer
+models:
+  go-developer: "anthropic/claude-sonnet-4.5"
+  go-reviewer: "openai/gpt-5.5 (xhigh)"
+---
+# Goal
+`)
+
+	metadata, errParse := parseYAMLFrontmatter(content)
+
+	require.NoError(t, errParse)
+	assert.Equal(t, map[string]string{
+		"go-developer": "anthropic/claude-sonnet-4.5",
+		"go-reviewer":  "openai/gpt-5.5 (xhigh)",
+	}, metadata.Models)
+}
+
 func TestBuildAgentArgsCoordinatorModelVariant(t *testing.T) {
 	args := buildAgentArgs("coordinator", "openai/gpt-5.5 (xhigh)", "")
 
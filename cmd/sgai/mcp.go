@@ -222,6 +222,25 @@ type projectTodoWriteArgs struct {
 	Todos []state.TodoItem `json:"todos" jsonschema:"The updated todo list"`
 }
 
+type appendLedgerEntryArgs struct {
+	Type   string `json:"type" jsonschema:"Entry type. Valid values: handoff, blocker, question, completion-evidence, note"`
+	Title  string `json:"title" jsonschema:"Short section title (e.g. 'Auth endpoints ready for review')"`
+	Body   string `json:"body" jsonschema:"Markdown body with the concrete details: work done, evidence, blocker, or question"`
+	Target string `json:"target,omitempty" jsonschema:"For handoffs and questions: the agent expected to act next (e.g. 'coordinator')"`
+}
+
+type recordNoteArgs struct {
+	Content string   `json:"content" jsonschema:"The lesson or fact to remember, written so it makes sense outside this workspace"`
+	Tags    []string `json:"tags,omitempty" jsonschema:"Short lowercase tags to find the note by (e.g. 'go', 'testing', 'flaky-ci')"`
+}
+
+type searchNotesArgs struct {
+	Query     string   `json:"query,omitempty" jsonschema:"Words that must all appear in the note or its tags. Omit to list the newest notes."`
+	Tags      []string `json:"tags,omitempty" jsonschema:"Only return notes carrying all of these tags"`
+	Workspace string   `json:"workspace,omitempty" jsonschema:"Only return notes recorded in this workspace"`
+	Limit     int      `json:"limit,omitempty" jsonschema:"Maximum number of notes to return (default 20, at most 200)"`
+}
+
 type questionItem struct {
 	Question    string   `json:"question" jsonschema:"The question to ask"`
 	Choices     []string `json:"choices" jsonschema:"Multiple-choice options for this question"`
@@ -251,6 +270,9 @@ var (
 	schemaProjectTodoWrite = mustSchema[projectTodoWriteArgs]()
 	schemaAskUserQuestion  = mustSchema[askUserQuestionArgs]()
 	schemaAskUserWorkGate  = mustSchema[askUserWorkGateArgs]()
+	schemaAppendLedger     = mustSchema[appendLedgerEntryArgs]()
+	schemaRecordNote       = mustSchema[recordNoteArgs]()
+	schemaSearchNotes      = mustSchema[searchNotesArgs]()
 )
 
 func startMCPHTTPServer(workingDir string, coord *state.Coordinator) (string, func(), error) {
@@ -303,7 +325,8 @@ func buildMCPServer(workingDir string, r *http.Request, coord *state.Coordinator
 	agentName := parseAgentIdentityHeader(r)
 
 	server := mcp.NewServer(&mcp.Implementation{Name: "sgai"}, nil)
-	mcpCtx := &mcpContext{workingDir: workingDir, coord: coord, agentName: agentName}
+	server.AddReceivingMiddleware(toolCallerMiddleware(workingDir), toolCallRecordingMiddleware(workingDir, agentName), toolPermissionMiddleware(workingDir, agentName))
+	mcpCtx := &mcpContext{workingDir: workingDir, coord: coord, agentName: agentName, knowledgeDir: defaultUserConfigDir()}
 
 	registerTools(server, mcpCtx)
 
@@ -342,6 +365,24 @@ func registerTools(server *mcp.Server, mcpCtx *mcpContext) {
 		InputSchema: schemaEmpty,
 	}, mcpCtx.projectTodoReadHandler)
 
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "append_ledger_entry",
+		Description: "Append a well-formed entry to .sgai/PROJECT_MANAGEMENT.md, the shared ledger for handoffs, blockers, questions, and completion evidence. Prefer this over editing the markdown by hand.",
+		InputSchema: schemaAppendLedger,
+	}, mcpCtx.appendLedgerEntryHandler)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "record_note",
+		Description: "Record a lesson learned in the knowledge base shared by every workspace on this machine. Use it for knowledge that would help in other repositories; keep workspace-specific notes in the ledger.",
+		InputSchema: schemaRecordNote,
+	}, mcpCtx.recordNoteHandler)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "search_notes",
+		Description: "Search the knowledge base shared by every workspace on this machine for lessons recorded earlier, by words, tags, or source workspace.",
+		InputSchema: schemaSearchNotes,
+	}, mcpCtx.searchNotesHandler)
+
 	var wfState state.Workflow
 	if mcpCtx.coord != nil {
 		wfState = mcpCtx.coord.State()
@@ -363,9 +404,10 @@ func registerTools(server *mcp.Server, mcpCtx *mcpContext) {
 }
 
 type mcpContext struct {
-	workingDir string
-	coord      *state.Coordinator
-	agentName  string
+	workingDir   string
+	coord        *state.Coordinator
+	agentName    string
+	knowledgeDir string
 }
 
 type emptyResult struct{}
@@ -394,8 +436,8 @@ func (c *mcpContext) findSnippetsHandler(_ context.Context, _ *mcp.CallToolReque
 	}, emptyResult{}, nil
 }
 
-func (c *mcpContext) updateWorkflowStateHandler(_ context.Context, _ *mcp.CallToolRequest, args updateWorkflowStateArgs) (*mcp.CallToolResult, emptyResult, error) {
-	result, err := updateWorkflowState(c.coord, c.agentName, args)
+func (c *mcpContext) updateWorkflowStateHandler(ctx context.Context, _ *mcp.CallToolRequest, args updateWorkflowStateArgs) (*mcp.CallToolResult, emptyResult, error) {
+	result, err := updateWorkflowState(c.coord, toolCaller(ctx, c.agentName), args)
 	if err != nil {
 		return nil, emptyResult{}, err
 	}
@@ -424,6 +466,56 @@ func (c *mcpContext) projectTodoReadHandler(_ context.Context, _ *mcp.CallToolRe
 	}, emptyResult{}, nil
 }
 
+func (c *mcpContext) appendLedgerEntryHandler(ctx context.Context, _ *mcp.CallToolRequest, args appendLedgerEntryArgs) (*mcp.CallToolResult, emptyResult, error) {
+	result, err := appendLedgerEntry(c.workingDir, toolCaller(ctx, c.agentName), args)
+	if err != nil {
+		return nil, emptyResult{}, err
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: result}},
+	}, emptyResult{}, nil
+}
+
+func (c *mcpContext) recordNoteHandler(ctx context.Context, _ *mcp.CallToolRequest, args recordNoteArgs) (*mcp.CallToolResult, emptyResult, error) {
+	var note knowledgeNote
+	errRecord := withKnowledgeBase(c.knowledgeDir, func(kb *knowledgeBase) error {
+		var errAdd error
+		note, errAdd = kb.add(knowledgeNote{
+			Content:   args.Content,
+			Tags:      args.Tags,
+			Workspace: filepath.Base(c.workingDir),
+			Agent:     toolCaller(ctx, c.agentName),
+		})
+		return errAdd
+	})
+	if errors.Is(errRecord, errKnowledgeNoteEmpty) {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: content is required."}},
+		}, emptyResult{}, nil
+	}
+	if errRecord != nil {
+		return nil, emptyResult{}, fmt.Errorf("failed to record note: %w", errRecord)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Recorded note %d with tags: %s", note.ID, strings.Join(note.Tags, ", "))}},
+	}, emptyResult{}, nil
+}
+
+func (c *mcpContext) searchNotesHandler(_ context.Context, _ *mcp.CallToolRequest, args searchNotesArgs) (*mcp.CallToolResult, emptyResult, error) {
+	var notes []knowledgeNote
+	errSearch := withKnowledgeBase(c.knowledgeDir, func(kb *knowledgeBase) error {
+		var errQuery error
+		notes, errQuery = kb.search(knowledgeQuery{Text: args.Query, Tags: args.Tags, Workspace: args.Workspace, Limit: args.Limit})
+		return errQuery
+	})
+	if errSearch != nil {
+		return nil, emptyResult{}, fmt.Errorf("failed to search notes: %w", errSearch)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: formatKnowledgeNotes(notes)}},
+	}, emptyResult{}, nil
+}
+
 func (c *mcpContext) askUserQuestionHandler(ctx context.Context, _ *mcp.CallToolRequest, args askUserQuestionArgs) (*mcp.CallToolResult, textOutput, error) {
 	result, err := askUserQuestion(ctx, c.coord, args)
 	if err != nil {
@@ -435,7 +527,7 @@ func (c *mcpContext) askUserQuestionHandler(ctx context.Context, _ *mcp.CallTool
 }
 
 func (c *mcpContext) askUserWorkGateHandler(ctx context.Context, _ *mcp.CallToolRequest, args askUserWorkGateArgs) (*mcp.CallToolResult, textOutput, error) {
-	result, err := askUserWorkGate(ctx, c.coord, args.Summary)
+	result, err := askUserWorkGate(ctx, c.coord, c.workingDir, args.Summary)
 	if err != nil {
 		return nil, textOutput{}, err
 	}
@@ -493,7 +585,7 @@ func askUserQuestion(ctx context.Context, coord *state.Coordinator, args askUser
 	return questionSummary + "\nHuman response: " + answer, nil
 }
 
-func askUserWorkGate(ctx context.Context, coord *state.Coordinator, summary string) (string, error) {
+func askUserWorkGate(ctx context.Context, coord *state.Coordinator, workingDir, summary string) (string, error) {
 	if strings.TrimSpace(summary) == "" {
 		return "Error: A summary is required. You must compile a comprehensive summary (GOAL items, brainstorming decisions, task breakdown, validation criteria) before asking for work gate approval.", nil
 	}
@@ -508,31 +600,31 @@ func askUserWorkGate(ctx context.Context, coord *state.Coordinator, summary stri
 	}
 
 	questionText := summary + "\n\n---\n\nIs the definition complete? May I begin implementation?"
+	choices := []string{workGateApprovalText, workGateApproveWithCommentsText, workGateRequestChangesText}
 
 	question := &state.MultiChoiceQuestion{
 		Questions: []state.QuestionItem{
 			{
 				Question:    questionText,
-				Choices:     []string{workGateApprovalText, "Not ready yet, need more clarification"},
+				Choices:     choices,
 				MultiSelect: false,
 			},
 		},
 		IsWorkGate: true,
+		Sections:   splitWorkGateSummary(summary),
 	}
 
 	answer, err := coord.AskAndWait(ctx, question, questionText)
 	if err != nil {
 		return "", fmt.Errorf("waiting for human response: %w", err)
 	}
-	if strings.Contains(answer, workGateApprovalText) {
-		if errUpdate := coord.UpdateState(func(wf *state.Workflow) {
-			wf.InteractionMode = state.ModeSelfDrive
-		}); errUpdate != nil {
-			return "", fmt.Errorf("updating interaction mode: %w", errUpdate)
-		}
+	review := parseWorkGateAnswer(answer)
+	review.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	if errRecord := recordWorkGateReview(coord, workingDir, review); errRecord != nil {
+		return "", errRecord
 	}
 
-	return "Presented work gate question to user:\n\nQuestion: " + questionText + "\n  Choices: [DEFINITION IS COMPLETE, BUILD MAY BEGIN, Not ready yet, need more clarification]\n  MultiSelect: false\n\nHuman response: " + answer, nil
+	return fmt.Sprintf("Presented work gate question to user:\n\nQuestion: %s\n  Choices: %v\n  MultiSelect: false\n\n%s", questionText, choices, workGateReviewResult(review)), nil
 }
 
 func findSkills(workingDir, name string) (string, error) {
@@ -943,6 +1035,31 @@ func projectTodoWrite(coord *state.Coordinator, todos []state.TodoItem) (string,
 	return formatTodoList(todos), nil
 }
 
+func appendLedgerEntry(workingDir, callerAgent string, args appendLedgerEntryArgs) (string, error) {
+	entryType, errType := parseLedgerEntryType(args.Type)
+	if errType != nil || !slices.Contains(agentLedgerEntryTypes, entryType) {
+		return fmt.Sprintf("Error: invalid type %q. Valid values: handoff, blocker, question, completion-evidence, note", args.Type), nil
+	}
+	title := strings.TrimSpace(args.Title)
+	if title == "" || strings.ContainsAny(title, "\n\r") {
+		return "Error: title is required and must be a single line.", nil
+	}
+	if strings.TrimSpace(args.Body) == "" {
+		return "Error: body is required.", nil
+	}
+	entry := ledgerEntry{
+		Type:   entryType,
+		Title:  title,
+		Agent:  callerAgent,
+		Target: strings.TrimSpace(args.Target),
+		Body:   args.Body,
+	}
+	if errAppend := appendProjectManagementSection(workingDir, entry); errAppend != nil {
+		return "", fmt.Errorf("failed to append ledger entry: %w", errAppend)
+	}
+	return fmt.Sprintf("Appended %s entry %q to .sgai/PROJECT_MANAGEMENT.md", entryType, title), nil
+}
+
 func formatTodoList(todos []state.TodoItem) string {
 	nonCompletedCount := 0
 	for _, todo := range todos {
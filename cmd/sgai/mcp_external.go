@@ -1,6 +1,7 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -116,7 +117,7 @@ func registerStateTools(server *mcp.Server, ctx *externalMCPContext) {
 	}
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "get_agent_delegation_svg",
-		Description: "Get available agents for coordinator delegation as SVG for a workspace.",
+		Description: "Get the delegation graph of a workspace as SVG: the coordinator and subagents seen in its sessions, with Task delegation counts, durations and outcomes.",
 		InputSchema: mustSchema[getAgentDelegationSVGArgs](),
 	}, func(_ context.Context, _ *mcp.CallToolRequest, args getAgentDelegationSVGArgs) (*mcp.CallToolResult, emptyResult, error) {
 		workspacePath, err := ctx.resolveWorkspacePath(args.Workspace)
@@ -130,6 +131,58 @@ func registerStateTools(server *mcp.Server, ctx *externalMCPContext) {
 		return textResult(svg), emptyResult{}, nil
 	})
 
+	type getLedgerArgs struct {
+		Workspace string   `json:"workspace" jsonschema:"The workspace name"`
+		Types     []string `json:"types,omitempty" jsonschema:"Only return entries of these types: handoff, blocker, question, completion-evidence, gate-failure, retrospective-header, work-gate-review, note"`
+		Agent     string   `json:"agent,omitempty" jsonschema:"Only return entries written by this agent"`
+	}
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "get_ledger",
+		Description: "Get typed entries parsed from a workspace's PROJECT_MANAGEMENT.md ledger, optionally filtered by type and agent.",
+		InputSchema: mustSchema[getLedgerArgs](),
+	}, func(_ context.Context, _ *mcp.CallToolRequest, args getLedgerArgs) (*mcp.CallToolResult, emptyResult, error) {
+		workspacePath, err := ctx.resolveWorkspacePath(args.Workspace)
+		if err != nil {
+			return nil, emptyResult{}, err
+		}
+		ledgerResult, errLedger := ctx.srv.ledgerService(workspacePath, args.Types, args.Agent)
+		if errLedger != nil {
+			return textResult("error: " + errLedger.Error()), emptyResult{}, nil
+		}
+		result, err := jsonResult(apiLedgerResponse{Entries: ledgerResult.Entries})
+		return result, emptyResult{}, err
+	})
+
+	type getRetrospectiveReportArgs struct {
+		Workspace string `json:"workspace" jsonschema:"The workspace name"`
+		Format    string `json:"format,omitempty" jsonschema:"Report format: json (default), markdown or html"`
+		Since     string `json:"since,omitempty" jsonschema:"Only include runs started at or after this time (RFC3339, YYYY-MM-DD or a duration such as 168h)"`
+		Until     string `json:"until,omitempty" jsonschema:"Only include runs started before this time"`
+	}
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "get_retrospective_report",
+		Description: "Aggregate a workspace's retrospectives across runs: duration, iterations, gate failures, questions, tokens, agents and recurring blocker themes.",
+		InputSchema: mustSchema[getRetrospectiveReportArgs](),
+	}, func(_ context.Context, _ *mcp.CallToolRequest, args getRetrospectiveReportArgs) (*mcp.CallToolResult, emptyResult, error) {
+		workspacePath, err := ctx.resolveWorkspacePath(args.Workspace)
+		if err != nil {
+			return nil, emptyResult{}, err
+		}
+		window, errWindow := parseUsageTimeRange(args.Since, args.Until, time.Now())
+		if errWindow != nil {
+			return textResult("error: " + errWindow.Error()), emptyResult{}, nil
+		}
+		report, errReport := ctx.srv.retroReportService(workspacePath, window)
+		if errReport != nil {
+			return textResult("error: " + errReport.Error()), emptyResult{}, nil
+		}
+		var buf strings.Builder
+		if errWrite := writeRetroReport(&buf, report, cmp.Or(args.Format, retroFormatJSON)); errWrite != nil {
+			return textResult("error: " + errWrite.Error()), emptyResult{}, nil
+		}
+		return textResult(buf.String()), emptyResult{}, nil
+	})
+
 	type getWorkspaceDiffArgs struct {
 		Workspace string `json:"workspace" jsonschema:"The workspace name"`
 	}
@@ -146,6 +199,47 @@ func registerStateTools(server *mcp.Server, ctx *externalMCPContext) {
 		result, err := jsonResult(diffResult)
 		return result, emptyResult{}, err
 	})
+
+	type listCheckpointsArgs struct {
+		Workspace string `json:"workspace" jsonschema:"The workspace name"`
+	}
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "list_checkpoints",
+		Description: "List the per-iteration checkpoints of a workspace, oldest first. Each checkpoint records the iteration, the VCS snapshot and the workflow state.",
+		InputSchema: mustSchema[listCheckpointsArgs](),
+	}, func(_ context.Context, _ *mcp.CallToolRequest, args listCheckpointsArgs) (*mcp.CallToolResult, emptyResult, error) {
+		workspacePath, err := ctx.resolveWorkspacePath(args.Workspace)
+		if err != nil {
+			return nil, emptyResult{}, err
+		}
+		listResult, errList := ctx.srv.listCheckpointsService(workspacePath)
+		if errList != nil {
+			return textResult("error: " + errList.Error()), emptyResult{}, nil
+		}
+		result, err := jsonResult(listResult)
+		return result, emptyResult{}, err
+	})
+
+	type rewindCheckpointArgs struct {
+		Workspace  string `json:"workspace" jsonschema:"The workspace name"`
+		Checkpoint string `json:"checkpoint" jsonschema:"The checkpoint ID from list_checkpoints"`
+	}
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "rewind_to_checkpoint",
+		Description: "Restore a stopped workspace's files and workflow state to a checkpoint. A checkpoint of the current state is recorded first so the rewind can be undone.",
+		InputSchema: mustSchema[rewindCheckpointArgs](),
+	}, func(_ context.Context, _ *mcp.CallToolRequest, args rewindCheckpointArgs) (*mcp.CallToolResult, emptyResult, error) {
+		workspacePath, err := ctx.resolveWorkspacePath(args.Workspace)
+		if err != nil {
+			return nil, emptyResult{}, err
+		}
+		rewindResult, errRewind := ctx.srv.rewindCheckpointService(workspacePath, args.Checkpoint)
+		if errRewind != nil {
+			return textResult("error: " + errRewind.Error()), emptyResult{}, nil
+		}
+		result, err := jsonResult(rewindResult)
+		return result, emptyResult{}, err
+	})
 }
 
 func registerWorkspaceTools(server *mcp.Server, ctx *externalMCPContext) {
@@ -165,6 +259,33 @@ func registerWorkspaceTools(server *mcp.Server, ctx *externalMCPContext) {
 		return result, emptyResult{}, err
 	})
 
+	type listRootsArgs struct{}
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "list_roots",
+		Description: "List the root directories served by this dashboard and the workspaces in each.",
+		InputSchema: mustSchema[listRootsArgs](),
+	}, func(_ context.Context, _ *mcp.CallToolRequest, _ listRootsArgs) (*mcp.CallToolResult, emptyResult, error) {
+		result, err := jsonResult(ctx.srv.listRootsService())
+		return result, emptyResult{}, err
+	})
+
+	type addRootArgs struct {
+		Dir  string `json:"dir" jsonschema:"Absolute path of the parent directory to scan for workspaces"`
+		Name string `json:"name,omitempty" jsonschema:"Root name used to disambiguate workspace names (defaults to the directory base name)"`
+	}
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "add_root",
+		Description: "Add a root directory to the dashboard at run time. The root is remembered across restarts.",
+		InputSchema: mustSchema[addRootArgs](),
+	}, func(_ context.Context, _ *mcp.CallToolRequest, args addRootArgs) (*mcp.CallToolResult, emptyResult, error) {
+		entry, err := ctx.srv.addRootService(args.Name, args.Dir)
+		if err != nil {
+			return textResult("error: " + err.Error()), emptyResult{}, nil
+		}
+		result, err := jsonResult(entry)
+		return result, emptyResult{}, err
+	})
+
 	type forkWorkspaceArgs struct {
 		Workspace string `json:"workspace" jsonschema:"The parent workspace name to fork from"`
 		Name      string `json:"name" jsonschema:"The fork name"`
@@ -331,22 +452,66 @@ func registerSessionTools(server *mcp.Server, ctx *externalMCPContext) {
 		return result, emptyResult{}, err
 	})
 
+	type batchWorkspacesArgs struct {
+		Operation  string   `json:"operation" jsonschema:"One of start, stop, reset, update-goal or delete"`
+		Names      []string `json:"names,omitempty" jsonschema:"Workspace names to target"`
+		ForkGroup  string   `json:"forkGroup,omitempty" jsonschema:"Root workspace name; targets the root and all its forks"`
+		Status     string   `json:"status,omitempty" jsonschema:"Only targets with this workflow status (working, agent-done, complete, waiting-for-human) or session state (running, stopped)"`
+		Pinned     *bool    `json:"pinned,omitempty" jsonschema:"Only pinned (true) or unpinned (false) targets"`
+		NeedsInput *bool    `json:"needsInput,omitempty" jsonschema:"Only targets that are (true) or are not (false) waiting for human input"`
+		Mode       string   `json:"mode,omitempty" jsonschema:"For start: interactive (default) or self-drive"`
+		Content    string   `json:"content,omitempty" jsonschema:"For update-goal: the new GOAL.md content"`
+		Confirm    bool     `json:"confirm,omitempty" jsonschema:"Required for delete unless dryRun is set"`
+		DryRun     bool     `json:"dryRun,omitempty" jsonschema:"Report what would happen to each target without changing anything"`
+	}
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "batch_workspaces",
+		Description: "Run one operation (start, stop, reset, update-goal, delete) on several workspaces selected by names, fork group and/or filter. Returns a result per target.",
+		InputSchema: mustSchema[batchWorkspacesArgs](),
+	}, func(_ context.Context, _ *mcp.CallToolRequest, args batchWorkspacesArgs) (*mcp.CallToolResult, emptyResult, error) {
+		var filter *batchFilter
+		if args.Status != "" || args.Pinned != nil || args.NeedsInput != nil {
+			filter = &batchFilter{Status: args.Status, Pinned: args.Pinned, NeedsInput: args.NeedsInput}
+		}
+		batch, err := ctx.srv.batchService(batchRequest{
+			Operation: args.Operation,
+			Names:     args.Names,
+			ForkGroup: args.ForkGroup,
+			Filter:    filter,
+			Mode:      args.Mode,
+			Content:   args.Content,
+			Confirm:   args.Confirm,
+			DryRun:    args.DryRun,
+		})
+		if err != nil {
+			return textResult("error: " + err.Error()), emptyResult{}, nil
+		}
+		result, err := jsonResult(batch)
+		return result, emptyResult{}, err
+	})
+
 	type respondToQuestionArgs struct {
-		Workspace       string   `json:"workspace" jsonschema:"The workspace name"`
-		QuestionID      string   `json:"questionId" jsonschema:"The question ID from the pending question"`
-		Answer          string   `json:"answer,omitempty" jsonschema:"Free text answer"`
-		SelectedChoices []string `json:"selectedChoices,omitempty" jsonschema:"Selected choices for multi-choice questions"`
+		Workspace       string                    `json:"workspace" jsonschema:"The workspace name"`
+		QuestionID      string                    `json:"questionId" jsonschema:"The question ID from the pending question"`
+		Answer          string                    `json:"answer,omitempty" jsonschema:"Free text answer"`
+		SelectedChoices []string                  `json:"selectedChoices,omitempty" jsonschema:"Selected choices for multi-choice questions"`
+		WorkGate        *apiWorkGateReviewRequest `json:"workGate,omitempty" jsonschema:"Structured work-gate review: decision (approve, approve-with-comments, request-changes), an overall comment and per-section comments keyed by the section IDs of the pending question"`
 	}
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "respond_to_question",
-		Description: "Respond to a pending question in a workspace session.",
+		Description: "Respond to a pending question in a workspace session. Work-gate questions accept a structured review via workGate.",
 		InputSchema: mustSchema[respondToQuestionArgs](),
 	}, func(_ context.Context, _ *mcp.CallToolRequest, args respondToQuestionArgs) (*mcp.CallToolResult, emptyResult, error) {
 		workspacePath, err := ctx.resolveWorkspacePath(args.Workspace)
 		if err != nil {
 			return nil, emptyResult{}, err
 		}
-		respondResult, err := ctx.srv.respondService(workspacePath, args.QuestionID, args.Answer, args.SelectedChoices)
+		respondResult, err := ctx.srv.respondRequestService(workspacePath, apiRespondRequest{
+			QuestionID:      args.QuestionID,
+			Answer:          args.Answer,
+			SelectedChoices: args.SelectedChoices,
+			WorkGate:        args.WorkGate,
+		})
 		if err != nil {
 			return textResult("error: " + err.Error()), emptyResult{}, nil
 		}
@@ -512,17 +677,149 @@ func registerComposeTools(server *mcp.Server, ctx *externalMCPContext) {
 		return result, emptyResult{}, err
 	})
 
-	type getComposeTemplatesArgs struct{}
+	type getComposeTemplatesArgs struct {
+		Workspace string `json:"workspace,omitempty" jsonschema:"The workspace name (optional); adds its layer and .sgai/templates templates"`
+	}
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "get_compose_templates",
 		Description: "Get available workflow templates for the compose wizard.",
 		InputSchema: mustSchema[getComposeTemplatesArgs](),
-	}, func(_ context.Context, _ *mcp.CallToolRequest, _ getComposeTemplatesArgs) (*mcp.CallToolResult, emptyResult, error) {
-		templatesResult := ctx.srv.composeTemplatesService()
+	}, func(_ context.Context, _ *mcp.CallToolRequest, args getComposeTemplatesArgs) (*mcp.CallToolResult, emptyResult, error) {
+		var workspacePath string
+		if args.Workspace != "" {
+			var err error
+			workspacePath, err = ctx.resolveWorkspacePath(args.Workspace)
+			if err != nil {
+				return nil, emptyResult{}, err
+			}
+		}
+		templatesResult := ctx.srv.composeTemplatesService(workspacePath)
 		result, err := jsonResult(templatesResult)
 		return result, emptyResult{}, err
 	})
 
+	type checkHealthArgs struct {
+		Workspace string `json:"workspace,omitempty" jsonschema:"The workspace name (optional); adds checks for its MCP servers and sgai.json"`
+	}
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "check_health",
+		Description: "Run the sgai doctor checks: tools, opencode database, model catalog, MCP server commands, config directories and webapp. Each check is pass, warn or fail.",
+		InputSchema: mustSchema[checkHealthArgs](),
+	}, func(_ context.Context, _ *mcp.CallToolRequest, args checkHealthArgs) (*mcp.CallToolResult, emptyResult, error) {
+		var workspacePath string
+		if args.Workspace != "" {
+			var err error
+			workspacePath, err = ctx.resolveWorkspacePath(args.Workspace)
+			if err != nil {
+				return nil, emptyResult{}, err
+			}
+		}
+		result, err := jsonResult(ctx.srv.healthService(workspacePath))
+		return result, emptyResult{}, err
+	})
+
+	type searchNotesExternalArgs struct {
+		Query     string   `json:"query,omitempty" jsonschema:"Words that must all appear in the note or its tags (optional)"`
+		Tags      []string `json:"tags,omitempty" jsonschema:"Only return notes carrying all of these tags (optional)"`
+		Workspace string   `json:"workspace,omitempty" jsonschema:"Only return notes recorded in this workspace (optional)"`
+		Limit     int      `json:"limit,omitempty" jsonschema:"Maximum number of notes (default 20, at most 200)"`
+	}
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "search_notes",
+		Description: "Search the cross-workspace knowledge base that agents record lessons into. Newest notes first.",
+		InputSchema: mustSchema[searchNotesExternalArgs](),
+	}, func(_ context.Context, _ *mcp.CallToolRequest, args searchNotesExternalArgs) (*mcp.CallToolResult, emptyResult, error) {
+		notesResult, err := ctx.srv.listNotesService(knowledgeQuery{Text: args.Query, Tags: args.Tags, Workspace: args.Workspace, Limit: args.Limit})
+		if err != nil {
+			return nil, emptyResult{}, err
+		}
+		result, err := jsonResult(notesResult)
+		return result, emptyResult{}, err
+	})
+
+	type addNoteArgs struct {
+		Content   string   `json:"content" jsonschema:"The note text"`
+		Tags      []string `json:"tags,omitempty" jsonschema:"Tags to find the note by"`
+		Workspace string   `json:"workspace,omitempty" jsonschema:"The workspace the note comes from (optional)"`
+	}
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "add_note",
+		Description: "Add a note to the cross-workspace knowledge base.",
+		InputSchema: mustSchema[addNoteArgs](),
+	}, func(_ context.Context, _ *mcp.CallToolRequest, args addNoteArgs) (*mcp.CallToolResult, emptyResult, error) {
+		note, err := ctx.srv.addNoteService(args.Content, args.Tags, args.Workspace)
+		if err != nil {
+			return textResult("error: " + err.Error()), emptyResult{}, nil
+		}
+		result, err := jsonResult(note)
+		return result, emptyResult{}, err
+	})
+
+	type updateNoteArgs struct {
+		ID      int64    `json:"id" jsonschema:"The note id"`
+		Content string   `json:"content" jsonschema:"The new note text"`
+		Tags    []string `json:"tags,omitempty" jsonschema:"The new tags; omit to keep the current ones"`
+	}
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "update_note",
+		Description: "Edit the text and tags of a note in the cross-workspace knowledge base.",
+		InputSchema: mustSchema[updateNoteArgs](),
+	}, func(_ context.Context, _ *mcp.CallToolRequest, args updateNoteArgs) (*mcp.CallToolResult, emptyResult, error) {
+		note, err := ctx.srv.updateNoteService(args.ID, args.Content, args.Tags)
+		if err != nil {
+			return textResult("error: " + err.Error()), emptyResult{}, nil
+		}
+		result, err := jsonResult(note)
+		return result, emptyResult{}, err
+	})
+
+	type deleteNoteArgs struct {
+		ID int64 `json:"id" jsonschema:"The note id"`
+	}
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "delete_note",
+		Description: "Delete a note from the cross-workspace knowledge base.",
+		InputSchema: mustSchema[deleteNoteArgs](),
+	}, func(_ context.Context, _ *mcp.CallToolRequest, args deleteNoteArgs) (*mcp.CallToolResult, emptyResult, error) {
+		if err := ctx.srv.deleteNoteService(args.ID); err != nil {
+			return textResult("error: " + err.Error()), emptyResult{}, nil
+		}
+		return textResult(fmt.Sprintf("deleted note %d", args.ID)), emptyResult{}, nil
+	})
+
+	type saveComposeTemplateArgs struct {
+		Workspace   string `json:"workspace,omitempty" jsonschema:"The workspace name (optional, uses first workspace if omitted)"`
+		ID          string `json:"id" jsonschema:"Template id (lowercase letters, digits, '-' or '_'); also the file name"`
+		Name        string `json:"name,omitempty" jsonschema:"Display name"`
+		Description string `json:"description,omitempty" jsonschema:"Short description"`
+		Icon        string `json:"icon,omitempty" jsonschema:"Icon shown in the wizard"`
+		Scope       string `json:"scope,omitempty" jsonschema:"Where to save: workspace (.sgai/templates, default) or user (sgai config directory)"`
+		Overwrite   bool   `json:"overwrite,omitempty" jsonschema:"Replace an existing template with the same id"`
+	}
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "save_compose_template",
+		Description: "Save the current compose state of a workspace as a reusable workflow template.",
+		InputSchema: mustSchema[saveComposeTemplateArgs](),
+	}, func(_ context.Context, _ *mcp.CallToolRequest, args saveComposeTemplateArgs) (*mcp.CallToolResult, emptyResult, error) {
+		workspacePath, err := ctx.resolveAnyWorkspacePath(args.Workspace)
+		if err != nil {
+			return nil, emptyResult{}, err
+		}
+		entry, err := ctx.srv.saveComposeTemplateService(workspacePath, apiSaveComposeTemplateRequest{
+			ID:          args.ID,
+			Name:        args.Name,
+			Description: args.Description,
+			Icon:        args.Icon,
+			Scope:       args.Scope,
+			Overwrite:   args.Overwrite,
+		})
+		if err != nil {
+			return textResult("error: " + err.Error()), emptyResult{}, nil
+		}
+		result, err := jsonResult(entry)
+		return result, emptyResult{}, err
+	})
+
 	type getComposePreviewArgs struct {
 		Workspace string `json:"workspace,omitempty" jsonschema:"The workspace name (optional)"`
 	}
@@ -583,8 +880,9 @@ func registerAdhocTools(server *mcp.Server, ctx *externalMCPContext) {
 
 	type startAdhocArgs struct {
 		Workspace string `json:"workspace" jsonschema:"The workspace name"`
-		Prompt    string `json:"prompt" jsonschema:"The prompt text to run"`
-		Model     string `json:"model" jsonschema:"The model to use (e.g. 'openai/gpt-5.5')"`
+		Prompt    string `json:"prompt,omitempty" jsonschema:"The prompt text to run (ignored when action is set)"`
+		Model     string `json:"model,omitempty" jsonschema:"The model to use (e.g. 'openai/gpt-5.5'; ignored when action is set)"`
+		Action    string `json:"action,omitempty" jsonschema:"Name of an sgai.json action to run (optional); runs its prompt and model and injects the secrets configured for it"`
 	}
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "start_adhoc",
@@ -595,7 +893,7 @@ func registerAdhocTools(server *mcp.Server, ctx *externalMCPContext) {
 		if err != nil {
 			return nil, emptyResult{}, err
 		}
-		startResult := ctx.srv.adhocStartService(workspacePath, args.Prompt, args.Model)
+		startResult := ctx.srv.adhocStartService(workspacePath, args.Prompt, args.Model, args.Action)
 		if startResult.Error != nil {
 			return textResult("error: " + startResult.Error.Error()), emptyResult{}, nil
 		}
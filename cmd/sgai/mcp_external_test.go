@@ -1129,3 +1129,27 @@ func TestMCPToolUpdateGoalEmptyContentReturnsError(t *testing.T) {
 	require.NotNil(t, result)
 	assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "error:")
 }
+
+func TestMCPToolGetLedger(t *testing.T) {
+	srv, rootDir := setupTestServer(t)
+	wsDir := setupTestWorkspace(t, rootDir, "ledger-mcp")
+	require.NoError(t, os.WriteFile(filepath.Join(wsDir, ".sgai", "PROJECT_MANAGEMENT.md"), []byte(sampleLedger), 0o644))
+	cs := connectMCPClient(t, srv)
+
+	result, err := cs.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "get_ledger",
+		Arguments: map[string]any{"workspace": "ledger-mcp", "types": []string{"blocker"}},
+	})
+	require.NoError(t, err)
+	tc := result.Content[0].(*mcp.TextContent)
+	assert.Contains(t, tc.Text, "Pending TODO Items")
+	assert.NotContains(t, tc.Text, "Handoff to go-reviewer")
+
+	result, err = cs.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "get_ledger",
+		Arguments: map[string]any{"workspace": "ledger-mcp", "types": []string{"gossip"}},
+	})
+	require.NoError(t, err)
+	tc = result.Content[0].(*mcp.TextContent)
+	assert.Contains(t, tc.Text, "error:")
+}
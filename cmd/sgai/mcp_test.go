@@ -81,13 +81,13 @@ func TestAskUserQuestionEmptyChoices(t *testing.T) {
 }
 
 func TestAskUserWorkGateNoCoord(t *testing.T) {
-	result, err := askUserWorkGate(t.Context(), nil, "summary")
+	result, err := askUserWorkGate(t.Context(), nil, "", "summary")
 	require.NoError(t, err)
 	assert.Contains(t, result, "Error")
 }
 
 func TestAskUserWorkGateEmptySummary(t *testing.T) {
-	result, err := askUserWorkGate(t.Context(), nil, "")
+	result, err := askUserWorkGate(t.Context(), nil, "", "")
 	require.NoError(t, err)
 	assert.Contains(t, result, "Error")
 	assert.Contains(t, result, "summary is required")
@@ -119,7 +119,7 @@ func TestAskUserWorkGateToolsNotAllowed(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	result, errQ := askUserWorkGate(t.Context(), coord, "summary of work")
+	result, errQ := askUserWorkGate(t.Context(), coord, "", "summary of work")
 	require.NoError(t, errQ)
 	assert.Contains(t, result, "Error")
 	assert.Contains(t, result, "not allowed")
@@ -956,7 +956,7 @@ func TestAskUserWorkGateSelfDriveMode(t *testing.T) {
 	stateFile := filepath.Join(t.TempDir(), "state.json")
 	coord, errCoord := state.NewCoordinatorWith(stateFile, state.Workflow{InteractionMode: state.ModeSelfDrive})
 	require.NoError(t, errCoord)
-	result, err := askUserWorkGate(context.Background(), coord, "test summary")
+	result, err := askUserWorkGate(context.Background(), coord, "", "test summary")
 	require.NoError(t, err)
 	assert.Contains(t, result, "not allowed")
 }
@@ -993,13 +993,13 @@ func TestAskUserQuestionNoChoices(t *testing.T) {
 }
 
 func TestAskUserWorkGateBlankSummary(t *testing.T) {
-	result, err := askUserWorkGate(context.Background(), nil, "")
+	result, err := askUserWorkGate(context.Background(), nil, "", "")
 	require.NoError(t, err)
 	assert.Contains(t, result, "summary is required")
 }
 
 func TestAskUserWorkGateNilCoordinator(t *testing.T) {
-	result, err := askUserWorkGate(context.Background(), nil, "my summary")
+	result, err := askUserWorkGate(context.Background(), nil, "", "my summary")
 	require.NoError(t, err)
 	assert.Contains(t, result, "not allowed")
 }
@@ -1123,7 +1123,7 @@ func TestAskUserWorkGateWithValidCoordinator(t *testing.T) {
 	}()
 	t.Cleanup(cancel)
 
-	result, err := askUserWorkGate(ctx, coord, "here is my summary")
+	result, err := askUserWorkGate(ctx, coord, "", "here is my summary")
 	require.NoError(t, err)
 	assert.Contains(t, result, "here is my summary")
 	assert.Contains(t, result, "DEFINITION IS COMPLETE")
@@ -1325,3 +1325,62 @@ func TestBuildMCPHTTPHandlerWithHandler(t *testing.T) {
 	assert.NotNil(t, handler)
 	assert.Implements(t, (*http.Handler)(nil), handler)
 }
+
+func TestAppendLedgerEntry(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        appendLedgerEntryArgs
+		wantContain string
+		wantAgent   string
+		wantWritten bool
+	}{
+		{
+			name:        "handoffDefaultsToCaller",
+			args:        appendLedgerEntryArgs{Type: "handoff", Title: "Ready for review", Body: "done", Target: "go-reviewer"},
+			wantContain: "Appended handoff entry",
+			wantAgent:   "coordinator",
+			wantWritten: true,
+		},
+		{
+			name:        "completionEvidence",
+			args:        appendLedgerEntryArgs{Type: "completion-evidence", Title: "Tests pass", Body: "go test ./... ok"},
+			wantContain: "Appended completion-evidence entry",
+			wantAgent:   "coordinator",
+			wantWritten: true,
+		},
+		{
+			name:        "systemTypeRejected",
+			args:        appendLedgerEntryArgs{Type: "gate-failure", Title: "x", Body: "y"},
+			wantContain: "Error: invalid type",
+		},
+		{
+			name:        "multilineTitleRejected",
+			args:        appendLedgerEntryArgs{Type: "note", Title: "a\nb", Body: "y"},
+			wantContain: "Error: title is required",
+		},
+		{
+			name:        "emptyBodyRejected",
+			args:        appendLedgerEntryArgs{Type: "blocker", Title: "Blocked", Body: "  "},
+			wantContain: "Error: body is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			result, err := appendLedgerEntry(dir, "coordinator", tt.args)
+			require.NoError(t, err)
+			assert.Contains(t, result, tt.wantContain)
+
+			entries, errRead := readProjectManagementLedger(dir)
+			require.NoError(t, errRead)
+			if !tt.wantWritten {
+				assert.Empty(t, entries)
+				return
+			}
+			require.Len(t, entries, 1)
+			assert.Equal(t, tt.wantAgent, entries[0].Agent)
+			assert.Equal(t, ledgerEntryType(tt.args.Type), entries[0].Type)
+		})
+	}
+}
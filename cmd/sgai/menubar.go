@@ -1,8 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"net/url"
+	"os"
+	"path/filepath"
 	"sync"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
 )
 
 type menuBarItem struct {
@@ -12,6 +17,10 @@ type menuBarItem struct {
 	running     bool
 	stopped     bool
 	pinned      bool
+	complete    bool
+	// gateFailures counts the completion gate failures recorded in the
+	// ledger, so a new failure shows up as an increase.
+	gateFailures int
 }
 
 type menuBarState struct {
@@ -24,6 +33,21 @@ type menuBarAction struct {
 	actionURL string
 }
 
+// menuBarEntry is one row of the tray menu. An action without a URL quits.
+type menuBarEntry struct {
+	label     string
+	action    menuBarAction
+	separator bool
+}
+
+// desktopNotification is raised when a workspace changes in a way the user
+// should hear about while the dashboard is not in front of them.
+type desktopNotification struct {
+	summary   string
+	body      string
+	actionURL string
+}
+
 func countAttention(items []menuBarItem) int {
 	count := 0
 	for _, item := range items {
@@ -107,3 +131,114 @@ func allocTag(state *menuBarState, action menuBarAction) int {
 	state.tags[tag] = action
 	return tag
 }
+
+func menuBarTitle(runningCount, totalActive, attentionCount int) string {
+	switch {
+	case totalActive == 0:
+		return "\u25CF sgai"
+	case attentionCount > 0:
+		return fmt.Sprintf("\u26A0 %d/%d", runningCount, totalActive)
+	default:
+		return fmt.Sprintf("\u25CF %d/%d", runningCount, totalActive)
+	}
+}
+
+func buildMenuBarEntries(baseURL string, items []menuBarItem) []menuBarEntry {
+	entries := []menuBarEntry{
+		{label: "Open Dashboard", action: menuBarAction{actionURL: baseURL}},
+		{separator: true},
+	}
+	for _, item := range filterVisibleItems(items) {
+		itemURL := workspaceURL(baseURL, item.name, workspaceItemSubpath(item))
+		entries = append(entries, menuBarEntry{label: formatMenuItemLabel(item), action: menuBarAction{actionURL: itemURL}})
+	}
+	return append(entries, menuBarEntry{separator: true}, menuBarEntry{label: "Quit"})
+}
+
+// workspaceNotifications compares two snapshots and returns a notification
+// for each workspace that started needing input, failed its completion gate
+// or completed. A nil previous snapshot is the first scan and notifies
+// nothing.
+func workspaceNotifications(previous map[string]menuBarItem, items []menuBarItem, baseURL string) []desktopNotification {
+	if previous == nil {
+		return nil
+	}
+	var notifications []desktopNotification
+	for _, item := range items {
+		before := previous[item.name]
+		label := item.description
+		if label == "" {
+			label = item.name
+		}
+		switch {
+		case item.needsInput && !before.needsInput:
+			notifications = append(notifications, desktopNotification{summary: item.name + " needs input", body: label, actionURL: workspaceURL(baseURL, item.name, "respond")})
+		case item.gateFailures > before.gateFailures:
+			notifications = append(notifications, desktopNotification{summary: item.name + " failed its completion gate", body: label, actionURL: workspaceURL(baseURL, item.name, "progress")})
+		case item.complete && !before.complete:
+			notifications = append(notifications, desktopNotification{summary: item.name + " completed", body: label, actionURL: workspaceURL(baseURL, item.name, "progress")})
+		}
+	}
+	return notifications
+}
+
+func menuBarSnapshot(items []menuBarItem) map[string]menuBarItem {
+	snapshot := make(map[string]menuBarItem, len(items))
+	for _, item := range items {
+		snapshot[item.name] = item
+	}
+	return snapshot
+}
+
+func (s *Server) menuBarItems() ([]menuBarItem, error) {
+	groups, errScan := s.scanWorkspaceGroups()
+	if errScan != nil {
+		return nil, errScan
+	}
+
+	var items []menuBarItem
+	for _, grp := range groups {
+		items = append(items, s.toMenuBarItem(grp.Root))
+		for _, fork := range grp.Forks {
+			items = append(items, s.toMenuBarItem(fork))
+		}
+	}
+	return items, nil
+}
+
+func (s *Server) toMenuBarItem(w workspaceInfo) menuBarItem {
+	return menuBarItem{
+		name:         w.DirName,
+		description:  goalDescription(w.Directory, w.DirName),
+		needsInput:   w.NeedsInput,
+		running:      w.Running,
+		stopped:      !w.Running && w.InProgress,
+		pinned:       w.Pinned,
+		complete:     s.loadWorkspaceState(w.Directory).Status == state.StatusComplete,
+		gateFailures: countGateFailures(w.Directory),
+	}
+}
+
+func countGateFailures(dir string) int {
+	entries, errRead := readProjectManagementLedger(dir)
+	if errRead != nil {
+		return 0
+	}
+	return len(filterLedgerEntries(entries, []ledgerEntryType{ledgerGateFailure}, ""))
+}
+
+func goalDescription(directory, dirName string) string {
+	if directory == "" {
+		return dirName
+	}
+	goalPath := filepath.Join(directory, "GOAL.md")
+	data, errRead := os.ReadFile(goalPath)
+	if errRead != nil {
+		return dirName
+	}
+	desc := extractGoalDescription(string(data))
+	if desc == "" {
+		return dirName
+	}
+	return desc
+}
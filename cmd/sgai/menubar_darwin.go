@@ -21,9 +21,6 @@ import "C"
 
 import (
 	"context"
-	"fmt"
-	"os"
-	"path/filepath"
 	"unsafe"
 )
 
@@ -107,86 +104,32 @@ func menuBarUpdateLoop(ctx context.Context, srv *Server, state *darwinMenuBarSta
 }
 
 func rebuildMenuFromServer(srv *Server, state *darwinMenuBarState) {
-	groups, errScan := srv.scanWorkspaceGroups()
+	items, errScan := srv.menuBarItems()
 	if errScan != nil {
 		return
 	}
 
-	var items []menuBarItem
-	for _, grp := range groups {
-		items = append(items, toMenuBarItem(grp.Root))
-		for _, fork := range grp.Forks {
-			items = append(items, toMenuBarItem(fork))
-		}
-	}
-
 	state.mu.Lock()
 	state.nextTag = 0
 	state.tags = make(map[int]menuBarAction)
 	baseURL := state.baseURL
 	state.mu.Unlock()
 
-	runningCount := countRunning(items)
-	attentionCount := countAttention(items)
-	totalActive := countActive(items)
-	setMenuTitle(runningCount, totalActive, attentionCount)
+	setMenuTitle(menuBarTitle(countRunning(items), countActive(items), countAttention(items)))
 
 	C.MenuBarClear()
 
-	dashTag := allocTag(&state.menuBarState, menuBarAction{actionURL: baseURL})
-	addMenuEntry("Open Dashboard", dashTag, true)
-
-	C.MenuBarAddSeparator()
-
-	for _, item := range filterVisibleItems(items) {
-		label := formatMenuItemLabel(item)
-		itemURL := workspaceURL(baseURL, item.name, workspaceItemSubpath(item))
-		tag := allocTag(&state.menuBarState, menuBarAction{actionURL: itemURL})
-		addMenuEntry(label, tag, true)
-	}
-
-	C.MenuBarAddSeparator()
-	quitTag := allocTag(&state.menuBarState, menuBarAction{actionURL: ""})
-	addMenuEntry("Quit", quitTag, true)
-}
-
-func toMenuBarItem(w workspaceInfo) menuBarItem {
-	return menuBarItem{
-		name:        w.DirName,
-		description: goalDescription(w.Directory, w.DirName),
-		needsInput:  w.NeedsInput,
-		running:     w.Running,
-		stopped:     !w.Running && w.InProgress,
-		pinned:      w.Pinned,
-	}
-}
-
-func goalDescription(directory, dirName string) string {
-	if directory == "" {
-		return dirName
-	}
-	goalPath := filepath.Join(directory, "GOAL.md")
-	data, errRead := os.ReadFile(goalPath)
-	if errRead != nil {
-		return dirName
-	}
-	desc := extractGoalDescription(string(data))
-	if desc == "" {
-		return dirName
+	for _, entry := range buildMenuBarEntries(baseURL, items) {
+		if entry.separator {
+			C.MenuBarAddSeparator()
+			continue
+		}
+		tag := allocTag(&state.menuBarState, entry.action)
+		addMenuEntry(entry.label, tag, true)
 	}
-	return desc
 }
 
-func setMenuTitle(runningCount, totalActive, attentionCount int) {
-	var title string
-	switch {
-	case totalActive == 0:
-		title = "\u25CF sgai"
-	case attentionCount > 0:
-		title = fmt.Sprintf("\u26A0 %d/%d", runningCount, totalActive)
-	default:
-		title = fmt.Sprintf("\u25CF %d/%d", runningCount, totalActive)
-	}
+func setMenuTitle(title string) {
 	cTitle := C.CString(title)
 	defer C.free(unsafe.Pointer(cTitle))
 	C.MenuBarSetTitle(cTitle)
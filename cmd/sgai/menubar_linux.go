@@ -0,0 +1,410 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	notificationsService = "org.freedesktop.Notifications"
+	notificationsPath    = "/org/freedesktop/Notifications"
+
+	sniWatcherService = "org.kde.StatusNotifierWatcher"
+	sniWatcherPath    = "/StatusNotifierWatcher"
+	sniItemInterface  = "org.kde.StatusNotifierItem"
+	sniItemPath       = "/StatusNotifierItem"
+
+	dbusMenuInterface       = "com.canonical.dbusmenu"
+	dbusMenuPath            = "/MenuBar"
+	dbusPropertiesInterface = "org.freedesktop.DBus.Properties"
+
+	desktopCallTimeout = 5 * time.Second
+)
+
+// linuxMenuBarState publishes the menu model over D-Bus: desktop
+// notifications through org.freedesktop.Notifications and a tray icon through
+// StatusNotifierItem with a com.canonical.dbusmenu menu.
+type linuxMenuBarState struct {
+	conn       *dbusConn
+	baseURL    string
+	cancelFunc context.CancelFunc
+	openURL    func(string) error
+	itemName   string
+
+	mu            sync.Mutex
+	revision      uint32
+	entries       []menuBarEntry
+	title         string
+	attention     bool
+	previous      map[string]menuBarItem
+	notifications map[uint32]string
+}
+
+func startMenuBar(ctx context.Context, baseURL string, srv *Server, cancel context.CancelFunc) {
+	conn, errDial := dialSessionBus()
+	if errDial != nil {
+		log.Println("desktop notifications disabled:", errDial)
+		<-ctx.Done()
+		return
+	}
+	defer func() {
+		if errClose := conn.close(); errClose != nil {
+			log.Println("failed to close D-Bus connection:", errClose)
+		}
+	}()
+
+	state := newLinuxMenuBarState(conn, baseURL, cancel, openURLWithXDG)
+	if errPublish := state.publish(ctx); errPublish != nil {
+		log.Println("tray icon not registered:", errPublish)
+	}
+	linuxMenuBarUpdateLoop(ctx, srv, state)
+}
+
+func newLinuxMenuBarState(conn *dbusConn, baseURL string, cancel context.CancelFunc, openURL func(string) error) *linuxMenuBarState {
+	return &linuxMenuBarState{
+		conn:          conn,
+		baseURL:       baseURL,
+		cancelFunc:    cancel,
+		openURL:       openURL,
+		itemName:      fmt.Sprintf("org.kde.StatusNotifierItem-%d-1", os.Getpid()),
+		entries:       buildMenuBarEntries(baseURL, nil),
+		title:         menuBarTitle(0, 0, 0),
+		notifications: make(map[uint32]string),
+	}
+}
+
+func openURLWithXDG(target string) error {
+	return exec.Command("xdg-open", target).Run()
+}
+
+func linuxMenuBarUpdateLoop(ctx context.Context, srv *Server, state *linuxMenuBarState) {
+	sub := srv.signals.subscribe()
+	defer srv.signals.unsubscribe(sub)
+
+	rebuild := func() {
+		items, errScan := srv.menuBarItems()
+		if errScan != nil {
+			return
+		}
+		state.update(ctx, items)
+	}
+	rebuild()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.done:
+			return
+		case <-sub.ch:
+			rebuild()
+		}
+	}
+}
+
+// publish exports the tray objects, listens for notification clicks and
+// registers the tray with the StatusNotifierWatcher. A missing watcher is
+// not fatal: the item registers when one appears.
+func (s *linuxMenuBarState) publish(ctx context.Context) error {
+	s.exportItem()
+	s.exportMenu()
+	s.conn.setSignalHandler(s.handleSignal)
+
+	callCtx, cancel := context.WithTimeout(ctx, desktopCallTimeout)
+	defer cancel()
+	rules := []string{
+		"type='signal',interface='" + notificationsService + "'",
+		"type='signal',sender='" + dbusBusName + "',interface='" + dbusBusName + "',member='NameOwnerChanged',arg0='" + sniWatcherService + "'",
+	}
+	for _, rule := range rules {
+		if _, errMatch := s.conn.busCall(callCtx, "AddMatch", "s", rule); errMatch != nil {
+			return fmt.Errorf("subscribing to %s: %w", rule, errMatch)
+		}
+	}
+	if _, errName := s.conn.busCall(callCtx, "RequestName", "su", s.itemName, uint32(0)); errName != nil {
+		return fmt.Errorf("requesting %s: %w", s.itemName, errName)
+	}
+	return s.register(callCtx)
+}
+
+func (s *linuxMenuBarState) register(ctx context.Context) error {
+	if _, errRegister := s.conn.call(ctx, sniWatcherService, sniWatcherPath, sniWatcherService, "RegisterStatusNotifierItem", "s", s.itemName); errRegister != nil {
+		return fmt.Errorf("registering with %s: %w", sniWatcherService, errRegister)
+	}
+	return nil
+}
+
+// update refreshes the tray from a workspace scan and sends the
+// notifications for what changed since the previous scan.
+func (s *linuxMenuBarState) update(ctx context.Context, items []menuBarItem) {
+	entries := buildMenuBarEntries(s.baseURL, items)
+	title := menuBarTitle(countRunning(items), countActive(items), countAttention(items))
+	attention := countAttention(items) > 0
+
+	s.mu.Lock()
+	notifications := workspaceNotifications(s.previous, items, s.baseURL)
+	s.previous = menuBarSnapshot(items)
+	menuChanged := !slices.Equal(entries, s.entries)
+	if menuChanged {
+		s.entries = entries
+		s.revision++
+	}
+	revision := s.revision
+	titleChanged := title != s.title
+	s.title = title
+	statusChanged := attention != s.attention
+	s.attention = attention
+	status := s.status()
+	s.mu.Unlock()
+
+	if menuChanged {
+		s.emit(dbusMenuPath, dbusMenuInterface, "LayoutUpdated", "ui", revision, int32(0))
+	}
+	if titleChanged {
+		s.emit(sniItemPath, sniItemInterface, "NewTitle", "")
+		s.emit(sniItemPath, sniItemInterface, "NewToolTip", "")
+	}
+	if statusChanged {
+		s.emit(sniItemPath, sniItemInterface, "NewStatus", "s", status)
+	}
+	for _, n := range notifications {
+		s.notify(ctx, n)
+	}
+}
+
+func (s *linuxMenuBarState) emit(path, iface, member, sig string, args ...any) {
+	if errEmit := s.conn.emit(path, iface, member, sig, args...); errEmit != nil {
+		log.Println("tray signal", member+":", errEmit)
+	}
+}
+
+func (s *linuxMenuBarState) notify(ctx context.Context, n desktopNotification) {
+	callCtx, cancel := context.WithTimeout(ctx, desktopCallTimeout)
+	defer cancel()
+	reply, errNotify := s.conn.call(callCtx, notificationsService, notificationsPath, notificationsService, "Notify", "susssasa{sv}i",
+		"sgai", uint32(0), "", n.summary, n.body, []any{"default", "Open"}, []any{}, int32(-1))
+	if errNotify != nil {
+		log.Println("desktop notification failed:", errNotify)
+		return
+	}
+	if id, ok := dbusFirst[uint32](reply); ok {
+		s.mu.Lock()
+		s.notifications[id] = n.actionURL
+		s.mu.Unlock()
+	}
+}
+
+func (s *linuxMenuBarState) handleSignal(msg *dbusMessage) {
+	switch {
+	case msg.iface == notificationsService && msg.member == "ActionInvoked" && len(msg.body) == 2:
+		id, _ := msg.body[0].(uint32)
+		s.mu.Lock()
+		target, ok := s.notifications[id]
+		s.mu.Unlock()
+		if ok {
+			s.open(target)
+		}
+	case msg.iface == notificationsService && msg.member == "NotificationClosed" && len(msg.body) == 2:
+		id, _ := msg.body[0].(uint32)
+		s.mu.Lock()
+		delete(s.notifications, id)
+		s.mu.Unlock()
+	case msg.iface == dbusBusName && msg.member == "NameOwnerChanged" && len(msg.body) == 3:
+		if owner, _ := msg.body[2].(string); owner != "" {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), desktopCallTimeout)
+				defer cancel()
+				if errRegister := s.register(ctx); errRegister != nil {
+					log.Println("tray icon unavailable:", errRegister)
+				}
+			}()
+		}
+	}
+}
+
+func (s *linuxMenuBarState) open(target string) {
+	go func() {
+		if errOpen := s.openURL(target); errOpen != nil {
+			log.Println("failed to open", target+":", errOpen)
+		}
+	}()
+}
+
+// activate runs the action of a menu entry. Entry ids are their position in
+// the menu, starting at 1; the root is 0.
+func (s *linuxMenuBarState) activate(id int32) {
+	s.mu.Lock()
+	var entry menuBarEntry
+	found := id >= 1 && int(id) <= len(s.entries)
+	if found {
+		entry = s.entries[id-1]
+	}
+	s.mu.Unlock()
+	if !found || entry.separator {
+		return
+	}
+	if entry.action.actionURL == "" {
+		if s.cancelFunc != nil {
+			s.cancelFunc()
+		}
+		return
+	}
+	s.open(entry.action.actionURL)
+}
+
+// status must be called with s.mu held.
+func (s *linuxMenuBarState) status() string {
+	if s.attention {
+		return "NeedsAttention"
+	}
+	return "Active"
+}
+
+func (s *linuxMenuBarState) itemProperties() map[string]dbusVariant {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return map[string]dbusVariant{
+		"Category":          {sig: "s", value: "ApplicationStatus"},
+		"Id":                {sig: "s", value: "sgai"},
+		"Title":             {sig: "s", value: s.title},
+		"Status":            {sig: "s", value: s.status()},
+		"IconName":          {sig: "s", value: "utilities-terminal"},
+		"AttentionIconName": {sig: "s", value: "dialog-warning"},
+		"ToolTip":           {sig: "(sa(iiay)ss)", value: dbusStruct{"", []any{}, "sgai", s.title}},
+		"ItemIsMenu":        {sig: "b", value: true},
+		"Menu":              {sig: "o", value: dbusObjectPath(dbusMenuPath)},
+	}
+}
+
+func menuProperties() map[string]dbusVariant {
+	return map[string]dbusVariant{
+		"Version":       {sig: "u", value: uint32(3)},
+		"TextDirection": {sig: "s", value: "ltr"},
+		"Status":        {sig: "s", value: "normal"},
+		"IconThemePath": {sig: "as", value: []any{}},
+	}
+}
+
+func (s *linuxMenuBarState) exportItem() {
+	s.exportProperties(sniItemPath, sniItemInterface, s.itemProperties)
+	s.conn.export(sniItemPath, sniItemInterface, "Activate", "ii", func(*dbusMessage) (string, []any, error) {
+		s.open(s.baseURL)
+		return "", nil, nil
+	})
+	for member, sig := range map[string]string{"SecondaryActivate": "ii", "ContextMenu": "ii", "Scroll": "is"} {
+		s.conn.export(sniItemPath, sniItemInterface, member, sig, func(*dbusMessage) (string, []any, error) {
+			return "", nil, nil
+		})
+	}
+}
+
+func (s *linuxMenuBarState) exportProperties(path, iface string, props func() map[string]dbusVariant) {
+	s.conn.export(path, dbusPropertiesInterface, "Get", "ss", func(msg *dbusMessage) (string, []any, error) {
+		requested, name := msg.body[0].(string), msg.body[1].(string)
+		value, ok := props()[name]
+		if requested != iface || !ok {
+			return "", nil, &dbusError{name: "org.freedesktop.DBus.Error.UnknownProperty", message: requested + "." + name}
+		}
+		return "v", []any{value}, nil
+	})
+	s.conn.export(path, dbusPropertiesInterface, "GetAll", "s", func(msg *dbusMessage) (string, []any, error) {
+		if requested := msg.body[0].(string); requested != iface && requested != "" {
+			return "a{sv}", []any{[]any{}}, nil
+		}
+		return "a{sv}", []any{dbusProps(props())}, nil
+	})
+}
+
+func (s *linuxMenuBarState) exportMenu() {
+	s.exportProperties(dbusMenuPath, dbusMenuInterface, menuProperties)
+	s.conn.export(dbusMenuPath, dbusMenuInterface, "GetLayout", "iias", func(msg *dbusMessage) (string, []any, error) {
+		revision, layout := s.layout(msg.body[0].(int32))
+		return "u(ia{sv}av)", []any{revision, layout}, nil
+	})
+	s.conn.export(dbusMenuPath, dbusMenuInterface, "GetGroupProperties", "aias", func(msg *dbusMessage) (string, []any, error) {
+		groups := []any{}
+		for _, id := range msg.body[0].([]any) {
+			if props, ok := s.entryProperties(id.(int32)); ok {
+				groups = append(groups, dbusStruct{id, dbusProps(props)})
+			}
+		}
+		return "a(ia{sv})", []any{groups}, nil
+	})
+	s.conn.export(dbusMenuPath, dbusMenuInterface, "GetProperty", "is", func(msg *dbusMessage) (string, []any, error) {
+		id, name := msg.body[0].(int32), msg.body[1].(string)
+		props, _ := s.entryProperties(id)
+		value, ok := props[name]
+		if !ok {
+			return "", nil, &dbusError{name: "org.freedesktop.DBus.Error.InvalidArgs", message: fmt.Sprintf("no property %q on item %d", name, id)}
+		}
+		return "v", []any{value}, nil
+	})
+	s.conn.export(dbusMenuPath, dbusMenuInterface, "Event", "isvu", func(msg *dbusMessage) (string, []any, error) {
+		if msg.body[1] == "clicked" {
+			s.activate(msg.body[0].(int32))
+		}
+		return "", nil, nil
+	})
+	s.conn.export(dbusMenuPath, dbusMenuInterface, "EventGroup", "a(isvu)", func(msg *dbusMessage) (string, []any, error) {
+		for _, event := range msg.body[0].([]any) {
+			fields := event.(dbusStruct)
+			if fields[1] == "clicked" {
+				s.activate(fields[0].(int32))
+			}
+		}
+		return "ai", []any{[]any{}}, nil
+	})
+	s.conn.export(dbusMenuPath, dbusMenuInterface, "AboutToShow", "i", func(*dbusMessage) (string, []any, error) {
+		return "b", []any{false}, nil
+	})
+	s.conn.export(dbusMenuPath, dbusMenuInterface, "AboutToShowGroup", "ai", func(*dbusMessage) (string, []any, error) {
+		return "aiai", []any{[]any{}, []any{}}, nil
+	})
+}
+
+// layout returns the menu below parent as (id, properties, children). The
+// menu is flat, so only the root has children.
+func (s *linuxMenuBarState) layout(parent int32) (uint32, dbusStruct) {
+	s.mu.Lock()
+	revision := s.revision
+	count := len(s.entries)
+	s.mu.Unlock()
+
+	props, _ := s.entryProperties(parent)
+	children := []any{}
+	if parent == 0 {
+		for id := int32(1); int(id) <= count; id++ {
+			childProps, _ := s.entryProperties(id)
+			children = append(children, dbusVariant{sig: "(ia{sv}av)", value: dbusStruct{id, dbusProps(childProps), []any{}}})
+		}
+	}
+	return revision, dbusStruct{parent, dbusProps(props), children}
+}
+
+func (s *linuxMenuBarState) entryProperties(id int32) (map[string]dbusVariant, bool) {
+	if id == 0 {
+		return map[string]dbusVariant{"children-display": {sig: "s", value: "submenu"}}, true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id < 1 || int(id) > len(s.entries) {
+		return map[string]dbusVariant{}, false
+	}
+	entry := s.entries[id-1]
+	if entry.separator {
+		return map[string]dbusVariant{"type": {sig: "s", value: "separator"}}, true
+	}
+	return map[string]dbusVariant{
+		"label":   {sig: "s", value: strings.ReplaceAll(entry.label, "_", "__")},
+		"enabled": {sig: "b", value: true},
+	}, true
+}
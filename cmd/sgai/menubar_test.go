@@ -1,9 +1,12 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCountAttention(t *testing.T) {
@@ -422,3 +425,105 @@ func TestFilterVisibleItemsResult(t *testing.T) {
 	filtered := filterVisibleItems(items)
 	assert.Len(t, filtered, 3)
 }
+
+func TestMenuBarTitle(t *testing.T) {
+	assert.Equal(t, "\u25CF sgai", menuBarTitle(0, 0, 0))
+	assert.Equal(t, "\u25CF 1/2", menuBarTitle(1, 2, 0))
+	assert.Equal(t, "\u26A0 1/3", menuBarTitle(1, 3, 1))
+}
+
+func TestBuildMenuBarEntries(t *testing.T) {
+	items := []menuBarItem{
+		{name: "idle-ws"},
+		{name: "input-ws", needsInput: true},
+		{name: "pinned-ws", pinned: true, description: "Pinned goal"},
+	}
+
+	entries := buildMenuBarEntries("http://localhost:8080", items)
+
+	assert.Equal(t, []menuBarEntry{
+		{label: "Open Dashboard", action: menuBarAction{actionURL: "http://localhost:8080"}},
+		{separator: true},
+		{label: "\u26A0 input-ws (Needs Input)", action: menuBarAction{actionURL: "http://localhost:8080/workspaces/input-ws/respond"}},
+		{label: "\u25CB Pinned goal", action: menuBarAction{actionURL: "http://localhost:8080/workspaces/pinned-ws/progress"}},
+		{separator: true},
+		{label: "Quit"},
+	}, entries)
+}
+
+func TestWorkspaceNotifications(t *testing.T) {
+	const baseURL = "http://localhost:8080"
+	previous := map[string]menuBarItem{
+		"ws": {name: "ws", description: "Build the thing", running: true, gateFailures: 1},
+	}
+
+	tests := []struct {
+		name     string
+		previous map[string]menuBarItem
+		item     menuBarItem
+		expected []desktopNotification
+	}{
+		{
+			name:     "firstScan",
+			previous: nil,
+			item:     menuBarItem{name: "ws", needsInput: true},
+			expected: nil,
+		},
+		{
+			name:     "unchanged",
+			previous: previous,
+			item:     previous["ws"],
+			expected: nil,
+		},
+		{
+			name:     "needsInput",
+			previous: previous,
+			item:     menuBarItem{name: "ws", description: "Build the thing", needsInput: true, gateFailures: 1},
+			expected: []desktopNotification{{summary: "ws needs input", body: "Build the thing", actionURL: baseURL + "/workspaces/ws/respond"}},
+		},
+		{
+			name:     "gateFailed",
+			previous: previous,
+			item:     menuBarItem{name: "ws", description: "Build the thing", running: true, gateFailures: 2},
+			expected: []desktopNotification{{summary: "ws failed its completion gate", body: "Build the thing", actionURL: baseURL + "/workspaces/ws/progress"}},
+		},
+		{
+			name:     "completed",
+			previous: previous,
+			item:     menuBarItem{name: "ws", description: "Build the thing", complete: true, gateFailures: 1},
+			expected: []desktopNotification{{summary: "ws completed", body: "Build the thing", actionURL: baseURL + "/workspaces/ws/progress"}},
+		},
+		{
+			name:     "newWorkspaceNeedsInput",
+			previous: previous,
+			item:     menuBarItem{name: "other", needsInput: true},
+			expected: []desktopNotification{{summary: "other needs input", body: "other", actionURL: baseURL + "/workspaces/other/respond"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := workspaceNotifications(tt.previous, []menuBarItem{tt.item}, baseURL)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestServerMenuBarItems(t *testing.T) {
+	srv, rootDir := setupTestServer(t)
+	done := setupTestWorkspace(t, rootDir, "done")
+	require.NoError(t, os.WriteFile(filepath.Join(done, ".sgai", "state.json"), []byte(`{"status":"complete"}`), 0o644))
+	gated := setupTestWorkspace(t, rootDir, "gated")
+	for range 2 {
+		require.NoError(t, appendProjectManagementSection(gated, ledgerEntry{Type: ledgerGateFailure, Title: "Completion gate failure", Body: "FAIL"}))
+	}
+
+	items, errItems := srv.menuBarItems()
+
+	require.NoError(t, errItems)
+	byName := menuBarSnapshot(items)
+	assert.True(t, byName["done"].complete)
+	assert.Zero(t, byName["done"].gateFailures)
+	assert.False(t, byName["gated"].complete)
+	assert.Equal(t, 2, byName["gated"].gateFailures)
+}
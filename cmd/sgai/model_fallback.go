@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+)
+
+type agentFailureKind string
+
+const (
+	failureNone           agentFailureKind = ""
+	failureRateLimit      agentFailureKind = "rate-limit"
+	failureQuota          agentFailureKind = "quota-exhausted"
+	failureProviderOutage agentFailureKind = "provider-outage"
+	failureOther          agentFailureKind = "other"
+)
+
+const (
+	providerFailuresBeforeFallback = 3
+	providerBackoffBase            = 5 * time.Second
+	providerBackoffMax             = 5 * time.Minute
+)
+
+const providerErrorTailLines = 20
+
+// providerErrorLinePattern matches the error lines opencode prints to stderr
+// when a provider call fails: "Error: ...", "AI_APICallError: ..." or a JSON
+// error event. Anything else in the output is agent work and never classified.
+var providerErrorLinePattern = regexp.MustCompile(`(?i)^(?:[\w.]*error\b\s*:|\{\s*"(?:type"\s*:\s*"error|error)")`)
+
+var providerFailurePatterns = []struct {
+	kind    agentFailureKind
+	pattern *regexp.Regexp
+}{
+	{
+		kind:    failureQuota,
+		pattern: regexp.MustCompile(`(?i)\binsufficient_quota\b|\bquota exceeded\b|\bexceeded your current quota\b|\bcredit balance is too low\b|\bbilling_hard_limit_reached\b|\b402 payment required\b`),
+	},
+	{
+		kind:    failureRateLimit,
+		pattern: regexp.MustCompile(`(?i)\brate[ _]?limit|\btoo many requests\b|\bstatus(?: code)?:? 429\b|^[\w.]*error\b\s*:\s*429\b`),
+	},
+	{
+		kind:    failureProviderOutage,
+		pattern: regexp.MustCompile(`(?i)\boverloaded(?:_error)?\b|\bservice unavailable\b|\bbad gateway\b|\bgateway timeout\b|\bstatus(?: code)?:? 5(?:00|02|03|04|29)\b|\b(?:econnreset|econnrefused|etimedout)\b|\bsocket hang up\b`),
+	},
+}
+
+func (k agentFailureKind) isProviderFailure() bool {
+	return k == failureRateLimit || k == failureQuota || k == failureProviderOutage
+}
+
+// classifyAgentFailure inspects only the last lines of the agent's stderr,
+// and only those that are provider error lines.
+func classifyAgentFailure(exitCode int, stderrTail string) agentFailureKind {
+	if exitCode == 0 {
+		return failureNone
+	}
+	lines := strings.Split(strings.TrimRight(stderrTail, "\n"), "\n")
+	if len(lines) > providerErrorTailLines {
+		lines = lines[len(lines)-providerErrorTailLines:]
+	}
+	for _, line := range slices.Backward(lines) {
+		line = strings.TrimSpace(ansiEscapePattern.ReplaceAllString(line, ""))
+		if !providerErrorLinePattern.MatchString(line) {
+			continue
+		}
+		for _, group := range providerFailurePatterns {
+			if group.pattern.MatchString(line) {
+				return group.kind
+			}
+		}
+	}
+	return failureOther
+}
+
+func exitCodeFromError(err error) int {
+	if err == nil {
+		return 0
+	}
+	var errExit *exec.ExitError
+	if errors.As(err, &errExit) && errExit.ExitCode() > 0 {
+		return errExit.ExitCode()
+	}
+	return 1
+}
+
+func providerBackoff(consecutiveFailures int) time.Duration {
+	if consecutiveFailures <= 0 {
+		return 0
+	}
+	backoff := providerBackoffBase
+	for range consecutiveFailures - 1 {
+		backoff *= 2
+		if backoff >= providerBackoffMax {
+			return providerBackoffMax
+		}
+	}
+	return backoff
+}
+
+type modelFallback struct {
+	chain               []string
+	index               int
+	consecutiveFailures int
+}
+
+func newModelFallback(primary string, fallbacks []string) *modelFallback {
+	chain := []string{primary}
+	for _, fallback := range fallbacks {
+		if fallback != "" && !slices.Contains(chain, fallback) {
+			chain = append(chain, fallback)
+		}
+	}
+	return &modelFallback{chain: chain}
+}
+
+func (f *modelFallback) sameChain(primary string, fallbacks []string) bool {
+	return slices.Equal(f.chain, newModelFallback(primary, fallbacks).chain)
+}
+
+func (f *modelFallback) current() string {
+	return f.chain[f.index]
+}
+
+func (f *modelFallback) recordSuccess() {
+	f.consecutiveFailures = 0
+}
+
+func (f *modelFallback) recordFailure(kind agentFailureKind) (backoff time.Duration, switchedFrom string) {
+	if !kind.isProviderFailure() {
+		f.consecutiveFailures = 0
+		return 0, ""
+	}
+	f.consecutiveFailures++
+	if f.consecutiveFailures >= providerFailuresBeforeFallback && f.index+1 < len(f.chain) {
+		switchedFrom = f.current()
+		f.index++
+		f.consecutiveFailures = 0
+		return 0, switchedFrom
+	}
+	return providerBackoff(f.consecutiveFailures), ""
+}
+
+func modelDisplayName(modelSpec string) string {
+	if modelSpec == "" {
+		return "default model"
+	}
+	return modelSpec
+}
+
+func recordModelFallbackProgress(coord *state.Coordinator, agent, message string) {
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	if errUpdate := coord.UpdateState(func(wf *state.Workflow) {
+		wf.Progress = append(wf.Progress, state.ProgressEntry{
+			Timestamp:   timestamp,
+			Agent:       agent,
+			Description: message,
+		})
+	}); errUpdate != nil {
+		log.Println("failed to record model fallback progress:", errUpdate)
+	}
+}
+
+func (r *workflowRunner) handleAgentFailure(ctx context.Context, cfg agentRunConfig, kind agentFailureKind) {
+	failedModel := r.fallback.current()
+	backoff, switchedFrom := r.fallback.recordFailure(kind)
+	if switchedFrom != "" {
+		message := fmt.Sprintf("switching model from %s to %s after %d consecutive provider failures (%s)", modelDisplayName(switchedFrom), modelDisplayName(r.fallback.current()), providerFailuresBeforeFallback, kind)
+		fmt.Println("["+cfg.paddedsgai+"]", message)
+		recordModelFallbackProgress(cfg.coord, cfg.agent, message)
+		return
+	}
+	if backoff == 0 {
+		return
+	}
+	fmt.Println("["+cfg.paddedsgai+"]", "provider failure", "("+string(kind)+")", "on", modelDisplayName(failedModel)+";", "retrying in", backoff)
+	if r.wait != nil {
+		r.wait(ctx, backoff)
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(backoff):
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyAgentFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		exitCode int
+		output   string
+		expected agentFailureKind
+	}{
+		{
+			name:     "success",
+			exitCode: 0,
+			output:   "rate limit exceeded",
+			expected: failureNone,
+		},
+		{
+			name:     "rateLimit",
+			exitCode: 1,
+			output:   "AI_APICallError: Rate limit reached for requests",
+			expected: failureRateLimit,
+		},
+		{
+			name:     "tooManyRequests",
+			exitCode: 1,
+			output:   "error: 429 Too Many Requests",
+			expected: failureRateLimit,
+		},
+		{
+			name:     "quota",
+			exitCode: 1,
+			output:   `{"error":{"code":"insufficient_quota"}}`,
+			expected: failureQuota,
+		},
+		{
+			name:     "overloaded",
+			exitCode: 1,
+			output:   "overloaded_error: Overloaded",
+			expected: failureProviderOutage,
+		},
+		{
+			name:     "serviceUnavailable",
+			exitCode: 2,
+			output:   "Error: provider returned status code 503",
+			expected: failureProviderOutage,
+		},
+		{
+			name:     "ansiColoredErrorLine",
+			exitCode: 1,
+			output:   "\x1b[91m\x1b[1mError: \x1b[0mAPICallError: Too Many Requests",
+			expected: failureRateLimit,
+		},
+		{
+			name:     "jsonErrorEvent",
+			exitCode: 1,
+			output:   `{"type":"error","error":{"name":"APIError","data":{"message":"Overloaded"}}}`,
+			expected: failureProviderOutage,
+		},
+		{
+			name:     "agentWorkMentioningProviderTerms",
+			exitCode: 1,
+			output:   "editing internal/billing/invoice.go\n--- FAIL: TestHandler (0.00s)\n    got status 500 internal server error\n    fetch failed for 429 items\n",
+			expected: failureOther,
+		},
+		{
+			name:     "errorLineOutsideTail",
+			exitCode: 1,
+			output:   "Error: rate limit reached\n" + strings.Repeat("working\n", providerErrorTailLines),
+			expected: failureOther,
+		},
+		{
+			name:     "unrelatedFailure",
+			exitCode: 1,
+			output:   "panic: runtime error: index out of range",
+			expected: failureOther,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, classifyAgentFailure(tt.exitCode, tt.output))
+		})
+	}
+}
+
+func TestExitCodeFromError(t *testing.T) {
+	assert.Equal(t, 0, exitCodeFromError(nil))
+	assert.Equal(t, 1, exitCodeFromError(errors.New("signal: killed")))
+
+	errRun := exec.Command("sh", "-c", "exit 3").Run()
+	require.Error(t, errRun)
+	assert.Equal(t, 3, exitCodeFromError(errRun))
+}
+
+func TestProviderBackoff(t *testing.T) {
+	assert.Equal(t, time.Duration(0), providerBackoff(0))
+	assert.Equal(t, providerBackoffBase, providerBackoff(1))
+	assert.Equal(t, 2*providerBackoffBase, providerBackoff(2))
+	assert.Equal(t, 4*providerBackoffBase, providerBackoff(3))
+	assert.Equal(t, providerBackoffMax, providerBackoff(20))
+}
+
+func TestModelFallbackSwitchesAfterConsecutiveProviderFailures(t *testing.T) {
+	fallback := newModelFallback("openai/gpt-5.5 (xhigh)", []string{"anthropic/claude-sonnet-4.5", "openai/gpt-5.5 (xhigh)", ""})
+	assert.Equal(t, []string{"openai/gpt-5.5 (xhigh)", "anthropic/claude-sonnet-4.5"}, fallback.chain)
+
+	for i := 1; i < providerFailuresBeforeFallback; i++ {
+		backoff, switchedFrom := fallback.recordFailure(failureRateLimit)
+		assert.Equal(t, providerBackoff(i), backoff)
+		assert.Empty(t, switchedFrom)
+	}
+
+	backoff, switchedFrom := fallback.recordFailure(failureProviderOutage)
+	assert.Zero(t, backoff)
+	assert.Equal(t, "openai/gpt-5.5 (xhigh)", switchedFrom)
+	assert.Equal(t, "anthropic/claude-sonnet-4.5", fallback.current())
+
+	for range providerFailuresBeforeFallback {
+		_, switchedFrom = fallback.recordFailure(failureQuota)
+		assert.Empty(t, switchedFrom)
+	}
+	assert.Equal(t, "anthropic/claude-sonnet-4.5", fallback.current())
+}
+
+func TestModelFallbackResetsOnSuccessAndNonProviderFailure(t *testing.T) {
+	fallback := newModelFallback("primary", []string{"secondary"})
+
+	for range providerFailuresBeforeFallback - 1 {
+		fallback.recordFailure(failureRateLimit)
+	}
+	fallback.recordSuccess()
+	_, switchedFrom := fallback.recordFailure(failureRateLimit)
+	assert.Empty(t, switchedFrom)
+
+	fallback.recordFailure(failureRateLimit)
+	fallback.recordFailure(failureOther)
+	_, switchedFrom = fallback.recordFailure(failureRateLimit)
+	assert.Empty(t, switchedFrom)
+	assert.Equal(t, "primary", fallback.current())
+}
+
+func TestModelFallbackSameChain(t *testing.T) {
+	fallback := newModelFallback("primary", []string{"secondary"})
+	assert.True(t, fallback.sameChain("primary", []string{"secondary", "primary"}))
+	assert.False(t, fallback.sameChain("primary", []string{"tertiary"}))
+}
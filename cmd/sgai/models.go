@@ -124,6 +124,24 @@ func validateModelSpec(catalog modelCatalog, modelSpec string) error {
 	return nil
 }
 
+func validateAgentModels(catalog modelCatalog, agentModels map[string]string) error {
+	agents := make([]string, 0, len(agentModels))
+	for agent := range agentModels {
+		agents = append(agents, agent)
+	}
+	slices.Sort(agents)
+
+	for _, agent := range agents {
+		if agent == "coordinator" {
+			return fmt.Errorf("models.coordinator is not supported; use model to select the coordinator model")
+		}
+		if errValidate := validateModelSpec(catalog, agentModels[agent]); errValidate != nil {
+			return fmt.Errorf("invalid model for agent %s: %w", agent, errValidate)
+		}
+	}
+	return nil
+}
+
 func modelEntries(catalog modelCatalog) []apiModelEntry {
 	ids := make([]string, 0, len(catalog))
 	for id := range catalog {
@@ -87,6 +87,59 @@ func TestValidateProjectConfigReturnsOpenCodeFailure(t *testing.T) {
 	assert.Contains(t, err.Error(), "not logged in")
 }
 
+func TestValidateGoalModelsWithOpenCodeModels(t *testing.T) {
+	setupFakeOpenCode(t, fakeModelsVerboseOutput, 0)
+
+	tests := []struct {
+		name        string
+		models      map[string]string
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name: "noOverrides",
+		},
+		{
+			name: "validOverrides",
+			models: map[string]string{
+				"go-developer": "anthropic/claude-sonnet-4.5",
+				"go-reviewer":  "openai/gpt-5.5 (xhigh)",
+			},
+		},
+		{
+			name:        "missingModel",
+			models:      map[string]string{"go-developer": "openai/gpt-missing"},
+			wantErr:     true,
+			errContains: "invalid model for agent go-developer: model openai/gpt-missing is not available",
+		},
+		{
+			name:        "missingVariant",
+			models:      map[string]string{"go-reviewer": "openai/gpt-5.5 (extreme)"},
+			wantErr:     true,
+			errContains: "variant extreme is not available for model openai/gpt-5.5",
+		},
+		{
+			name:        "coordinatorRejected",
+			models:      map[string]string{"coordinator": "openai/gpt-5.5"},
+			wantErr:     true,
+			errContains: "models.coordinator is not supported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGoalModels(GoalMetadata{Models: tt.models})
+
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
 func TestParseOpenCodeModelsVerboseReturnsVariantsForModel(t *testing.T) {
 	catalog, err := parseOpenCodeModelsVerbose([]byte(fakeModelsVerboseOutput))
 
@@ -17,6 +17,8 @@ type openCodeConfigContent struct {
 }
 
 type openCodeAgentConfig struct {
+	Model      string                     `json:"model,omitempty"`
+	Variant    string                     `json:"variant,omitempty"`
 	Permission map[string]json.RawMessage `json:"permission,omitempty"`
 	extra      map[string]json.RawMessage
 }
@@ -77,6 +79,16 @@ func (c *openCodeAgentConfig) UnmarshalJSON(data []byte) error {
 		return errUnmarshal
 	}
 	c.extra = fields
+	for key, target := range map[string]*string{"model": &c.Model, "variant": &c.Variant} {
+		rawValue, ok := fields[key]
+		if !ok {
+			continue
+		}
+		if errUnmarshal := json.Unmarshal(rawValue, target); errUnmarshal != nil {
+			return errUnmarshal
+		}
+		delete(c.extra, key)
+	}
 	if rawPermission, ok := fields["permission"]; ok {
 		if errUnmarshal := json.Unmarshal(rawPermission, &c.Permission); errUnmarshal != nil {
 			return errUnmarshal
@@ -91,6 +103,16 @@ func (c openCodeAgentConfig) MarshalJSON() ([]byte, error) {
 	for key, value := range c.extra {
 		fields[key] = value
 	}
+	for key, value := range map[string]string{"model": c.Model, "variant": c.Variant} {
+		if value == "" {
+			continue
+		}
+		valueData, errMarshal := json.Marshal(value)
+		if errMarshal != nil {
+			return nil, errMarshal
+		}
+		fields[key] = valueData
+	}
 	if len(c.Permission) > 0 {
 		permissionData, errMarshal := json.Marshal(c.Permission)
 		if errMarshal != nil {
@@ -101,7 +123,7 @@ func (c openCodeAgentConfig) MarshalJSON() ([]byte, error) {
 	return json.Marshal(fields)
 }
 
-func buildOpenCodeConfigContent(baseContent, sgaiBinPath, mcpURL, agentIdentity string) (string, error) {
+func buildOpenCodeConfigContent(baseContent, sgaiBinPath, mcpURL, agentIdentity string, agentModels map[string]string) (string, error) {
 	config := openCodeConfigContent{}
 	if baseContent != "" {
 		if errUnmarshal := json.Unmarshal([]byte(baseContent), &config); errUnmarshal != nil {
@@ -124,6 +146,8 @@ func buildOpenCodeConfigContent(baseContent, sgaiBinPath, mcpURL, agentIdentity
 	}
 	config.MCP["sgai"] = sgaiData
 
+	applyAgentModelOverrides(&config, agentModels)
+
 	data, errMarshal := json.Marshal(config)
 	if errMarshal != nil {
 		return "", fmt.Errorf("encoding OPENCODE_CONFIG_CONTENT: %w", errMarshal)
@@ -131,6 +155,22 @@ func buildOpenCodeConfigContent(baseContent, sgaiBinPath, mcpURL, agentIdentity
 	return string(data), nil
 }
 
+func applyAgentModelOverrides(config *openCodeConfigContent, agentModels map[string]string) {
+	if len(agentModels) == 0 {
+		return
+	}
+	if config.Agent == nil {
+		config.Agent = map[string]openCodeAgentConfig{}
+	}
+	for agent, modelSpec := range agentModels {
+		model, variant := parseModelAndVariant(modelSpec)
+		agentConfig := config.Agent[agent]
+		agentConfig.Model = model
+		agentConfig.Variant = variant
+		config.Agent[agent] = agentConfig
+	}
+}
+
 func sgaiExecutablePath() string {
 	path, errExecutable := os.Executable()
 	if errExecutable == nil && path != "" {
@@ -154,8 +194,8 @@ func buildBaseOpenCodeEnv(dir string) []string {
 		"OPENCODE_CONFIG_DIR="+filepath.Join(dir, ".sgai"))
 }
 
-func buildManagedOpenCodeEnv(dir, mcpURL, agentIdentity, interactiveEnv string) []string {
-	configContent, errConfig := buildOpenCodeConfigContent(os.Getenv("OPENCODE_CONFIG_CONTENT"), sgaiExecutablePath(), mcpURL, agentIdentity)
+func buildManagedOpenCodeEnv(dir, mcpURL, agentIdentity, interactiveEnv string, agentModels map[string]string) []string {
+	configContent, errConfig := buildOpenCodeConfigContent(os.Getenv("OPENCODE_CONFIG_CONTENT"), sgaiExecutablePath(), mcpURL, agentIdentity, agentModels)
 	if errConfig != nil {
 		logFatalConfigContent(errConfig)
 	}
@@ -17,6 +17,8 @@ type testOpenCodeConfig struct {
 
 type testOpenCodeAgentConfig struct {
 	Mode       string                     `json:"mode"`
+	Model      string                     `json:"model"`
+	Variant    string                     `json:"variant"`
 	Permission map[string]json.RawMessage `json:"permission"`
 }
 
@@ -28,7 +30,7 @@ type testOpenCodeMCP struct {
 }
 
 func TestBuildOpenCodeConfigContentAddsLocalSGAIMCP(t *testing.T) {
-	content, err := buildOpenCodeConfigContent(`{"username":"dev","mcp":{"context7":{"type":"local","command":["npx"]}}}`, "/bin/sgai", "http://127.0.0.1:1234/mcp", "builder|model|variant")
+	content, err := buildOpenCodeConfigContent(`{"username":"dev","mcp":{"context7":{"type":"local","command":["npx"]}}}`, "/bin/sgai", "http://127.0.0.1:1234/mcp", "builder|model|variant", nil)
 	require.NoError(t, err)
 
 	var config testOpenCodeConfig
@@ -45,7 +47,7 @@ func TestBuildOpenCodeConfigContentAddsLocalSGAIMCP(t *testing.T) {
 
 func TestBuildManagedOpenCodeEnvIncludesConfigContent(t *testing.T) {
 	t.Setenv("OPENCODE_CONFIG_CONTENT", `{"username":"dev"}`)
-	env := buildManagedOpenCodeEnv("/tmp/workspace", "http://127.0.0.1:1234/mcp", "agent", "auto")
+	env := buildManagedOpenCodeEnv("/tmp/workspace", "http://127.0.0.1:1234/mcp", "agent", "auto", nil)
 	envMap := envToMap(env)
 
 	assert.Equal(t, filepath.Join("/tmp/workspace", ".sgai"), envMap["OPENCODE_CONFIG_DIR"])
@@ -60,6 +62,42 @@ func TestBuildManagedOpenCodeEnvIncludesConfigContent(t *testing.T) {
 	assert.Equal(t, "internal-mcp", sgai.Command[1])
 }
 
+func TestBuildOpenCodeConfigContentAppliesAgentModelOverrides(t *testing.T) {
+	base := `{"agent":{"go-reviewer":{"mode":"subagent","model":"openai/gpt-4","variant":"low","permission":{"edit":"deny"}}}}`
+	agentModels := map[string]string{
+		"go-developer": "anthropic/claude-sonnet-4.5",
+		"go-reviewer":  "openai/gpt-5.5 (xhigh)",
+	}
+
+	content, err := buildOpenCodeConfigContent(base, "/bin/sgai", "http://127.0.0.1:1234/mcp", "coordinator", agentModels)
+	require.NoError(t, err)
+
+	var config testOpenCodeConfig
+	require.NoError(t, json.Unmarshal([]byte(content), &config))
+
+	developer := config.Agent["go-developer"]
+	assert.Equal(t, "anthropic/claude-sonnet-4.5", developer.Model)
+	assert.Empty(t, developer.Variant)
+
+	reviewer := config.Agent["go-reviewer"]
+	assert.Equal(t, "openai/gpt-5.5", reviewer.Model)
+	assert.Equal(t, "xhigh", reviewer.Variant)
+	assert.Equal(t, "subagent", reviewer.Mode)
+	assert.JSONEq(t, `"deny"`, string(reviewer.Permission["edit"]))
+}
+
+func TestBuildOpenCodeConfigContentWithoutOverridesKeepsAgentSection(t *testing.T) {
+	base := `{"agent":{"go-reviewer":{"model":"openai/gpt-4","variant":"low"}}}`
+
+	content, err := buildOpenCodeConfigContent(base, "/bin/sgai", "http://127.0.0.1:1234/mcp", "coordinator", nil)
+	require.NoError(t, err)
+
+	var config testOpenCodeConfig
+	require.NoError(t, json.Unmarshal([]byte(content), &config))
+	assert.Equal(t, "openai/gpt-4", config.Agent["go-reviewer"].Model)
+	assert.Equal(t, "low", config.Agent["go-reviewer"].Variant)
+}
+
 func envToMap(env []string) map[string]string {
 	result := make(map[string]string)
 	for _, entry := range env {
@@ -13,24 +13,46 @@ func formatLogTimestamp(t time.Time) string {
 	return "[" + t.UTC().Format(time.RFC3339) + "]"
 }
 
+// prefixWriter timestamps and prefixes each line. Partial lines are held
+// until their newline arrives, or until Flush.
 type prefixWriter struct {
-	prefix string
-	w      io.Writer
+	prefix  string
+	w       io.Writer
+	pending []byte
 }
 
 func (p *prefixWriter) Write(data []byte) (int, error) {
-	lines := linesWithTrailingEmpty(string(data))
-	for i, line := range lines {
-		if i < len(lines)-1 || line != "" {
-			timestamp := formatLogTimestamp(time.Now())
-			if _, errWrite := p.w.Write([]byte(timestamp + p.prefix + line + "\n")); errWrite != nil {
-				return 0, errWrite
-			}
+	p.pending = append(p.pending, data...)
+	for {
+		idx := bytes.IndexByte(p.pending, '\n')
+		if idx == -1 {
+			break
+		}
+		line := string(p.pending[:idx])
+		p.pending = p.pending[idx+1:]
+		if errWrite := p.writeLine(line); errWrite != nil {
+			return 0, errWrite
 		}
 	}
 	return len(data), nil
 }
 
+// Flush writes a trailing partial line, if any.
+func (p *prefixWriter) Flush() error {
+	if len(p.pending) == 0 {
+		return nil
+	}
+	line := string(p.pending)
+	p.pending = nil
+	return p.writeLine(line)
+}
+
+func (p *prefixWriter) writeLine(line string) error {
+	timestamp := formatLogTimestamp(time.Now())
+	_, errWrite := p.w.Write([]byte(timestamp + p.prefix + maskSecrets(line) + "\n"))
+	return errWrite
+}
+
 type sessionIDCaptureWriter struct {
 	pending        []byte
 	sessionID      string
@@ -129,6 +129,12 @@ func (r *ringWriter) dump(w io.Writer) {
 	}
 }
 
+func (r *ringWriter) String() string {
+	var sb strings.Builder
+	r.dump(&sb)
+	return sb.String()
+}
+
 func (r *ringWriter) addLine(line string) {
 	r.ring.Value = line
 	r.ring = r.ring.Next()
@@ -203,19 +209,25 @@ func (w *sessionLogWriter) Write(data []byte) (int, error) {
 }
 
 func (w *sessionLogWriter) addLine(text string) {
-	w.sess.outputLog.add(logLine{text: text})
+	w.sess.outputLog.add(logLine{text: maskSecrets(text)})
 	w.srv.notifyStateChange()
 }
 
-func buildAgentOutputWriter(base io.Writer, extra ...io.Writer) io.Writer {
+// buildAgentOutputWriter fans agent output out to base and extra. With a
+// redactor, every sink receives the redacted text.
+func buildAgentOutputWriter(red *redactor, base io.Writer, extra ...io.Writer) io.Writer {
 	writers := []io.Writer{base}
 	for _, w := range extra {
 		if w != nil {
 			writers = append(writers, w)
 		}
 	}
-	if len(writers) == 1 {
-		return base
+	out := base
+	if len(writers) > 1 {
+		out = io.MultiWriter(writers...)
+	}
+	if red == nil {
+		return out
 	}
-	return io.MultiWriter(writers...)
+	return &redactingWriter{r: red, w: out}
 }
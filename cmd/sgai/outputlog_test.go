@@ -220,7 +220,7 @@ func TestBuildAgentOutputWriter(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := buildAgentOutputWriter(tt.base, tt.extra...)
+			result := buildAgentOutputWriter(nil, tt.base, tt.extra...)
 			if tt.expectMulti {
 				assert.NotNil(t, result)
 			} else {
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+)
+
+const (
+	// pipelineTriggerKind marks runs started because upstream workspaces completed.
+	pipelineTriggerKind = "pipeline"
+
+	maxPipelineEvidenceEntries = 3
+)
+
+// pipelineConfig is the pipeline section of sgai.json. The dependsOn list of
+// the GOAL.md frontmatter takes precedence over it.
+type pipelineConfig struct {
+	DependsOn []string `json:"dependsOn,omitempty"`
+	Artifacts bool     `json:"artifacts,omitempty"`
+}
+
+// pipelineDependencies are the upstream workspaces that must complete before
+// a workspace is started automatically, and whether their artifacts are
+// passed on to it.
+type pipelineDependencies struct {
+	upstreams []string
+	artifacts bool
+}
+
+func loadPipelineDependencies(dir string) pipelineDependencies {
+	self := filepath.Base(dir)
+	metadata, _ := parseYAMLFrontmatterFromFile(filepath.Join(dir, "GOAL.md"))
+	if len(metadata.DependsOn) > 0 {
+		return pipelineDependencies{
+			upstreams: normalizeUpstreams(self, metadata.DependsOn),
+			artifacts: isTruthy(metadata.PipelineArtifacts),
+		}
+	}
+	config, _ := loadProjectConfig(dir)
+	if config == nil || config.Pipeline == nil {
+		return pipelineDependencies{}
+	}
+	return pipelineDependencies{
+		upstreams: normalizeUpstreams(self, config.Pipeline.DependsOn),
+		artifacts: config.Pipeline.Artifacts,
+	}
+}
+
+func normalizeUpstreams(self string, names []string) []string {
+	var upstreams []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || name == self || slices.Contains(upstreams, name) {
+			continue
+		}
+		upstreams = append(upstreams, name)
+	}
+	return upstreams
+}
+
+type pipelineNode struct {
+	Name      string   `json:"name"`
+	Dir       string   `json:"dir,omitempty"`
+	Status    string   `json:"status"`
+	Running   bool     `json:"running"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+	Artifacts bool     `json:"artifacts,omitempty"`
+	// Missing is set for upstreams that name no known workspace.
+	Missing bool `json:"missing,omitempty"`
+	// InCycle is set for workspaces whose dependencies loop back to them;
+	// they are never started automatically.
+	InCycle bool `json:"inCycle,omitempty"`
+}
+
+type pipelineEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// pipelineGraph is the DAG of workspaces connected by dependsOn; edges point
+// from upstream to downstream.
+type pipelineGraph struct {
+	Nodes []pipelineNode `json:"nodes"`
+	Edges []pipelineEdge `json:"edges"`
+}
+
+func (g pipelineGraph) node(name string) (pipelineNode, bool) {
+	for _, node := range g.Nodes {
+		if node.Name == name {
+			return node, true
+		}
+	}
+	return pipelineNode{}, false
+}
+
+// nodeByDir finds the workspace node for a directory. Node names are the
+// dashboard names, which differ from the base name for duplicates across roots.
+func (g pipelineGraph) nodeByDir(dir string) (pipelineNode, bool) {
+	for _, node := range g.Nodes {
+		if node.Dir != "" && filepath.Clean(node.Dir) == filepath.Clean(dir) {
+			return node, true
+		}
+	}
+	return pipelineNode{}, false
+}
+
+func (g pipelineGraph) upstreamsComplete(node pipelineNode) bool {
+	for _, name := range node.DependsOn {
+		upstream, ok := g.node(name)
+		if !ok || upstream.Missing || upstream.Running || upstream.Status != state.StatusComplete {
+			return false
+		}
+	}
+	return true
+}
+
+// readyDownstreams returns the idle workspaces that depend on upstream and
+// whose upstreams have all completed.
+func (g pipelineGraph) readyDownstreams(upstream string) []pipelineNode {
+	var ready []pipelineNode
+	for _, node := range g.Nodes {
+		if !slices.Contains(node.DependsOn, upstream) || node.Running {
+			continue
+		}
+		if node.InCycle {
+			log.Println("pipeline: not starting", node.Name+": its dependencies form a cycle")
+			continue
+		}
+		if !g.upstreamsComplete(node) {
+			log.Println("pipeline:", node.Name, "is still waiting for", strings.Join(node.DependsOn, ", "))
+			continue
+		}
+		ready = append(ready, node)
+	}
+	return ready
+}
+
+func markPipelineCycles(nodes []pipelineNode) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	index := make(map[string]int, len(nodes))
+	for i, node := range nodes {
+		index[node.Name] = i
+	}
+	color := make([]int, len(nodes))
+	var stack []int
+	var visit func(i int)
+	visit = func(i int) {
+		color[i] = visiting
+		stack = append(stack, i)
+		for _, name := range nodes[i].DependsOn {
+			j, ok := index[name]
+			if !ok {
+				continue
+			}
+			switch color[j] {
+			case visiting:
+				for k := len(stack) - 1; k >= 0; k-- {
+					nodes[stack[k]].InCycle = true
+					if stack[k] == j {
+						break
+					}
+				}
+			case unvisited:
+				visit(j)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		color[i] = visited
+	}
+	for i := range nodes {
+		if color[i] == unvisited {
+			visit(i)
+		}
+	}
+}
+
+// pipelineTrigger returns trigger when it was recorded by the pipeline.
+func pipelineTrigger(trigger *state.Trigger) *state.Trigger {
+	if trigger == nil || trigger.Kind != pipelineTriggerKind {
+		return nil
+	}
+	return trigger
+}
+
+func buildPipelinePayload(graph pipelineGraph, node pipelineNode) string {
+	var sb strings.Builder
+	sb.WriteString("Upstream workspaces completed: ")
+	sb.WriteString(strings.Join(node.DependsOn, ", "))
+	sb.WriteString("\n")
+	if !node.Artifacts {
+		return sb.String()
+	}
+	for _, name := range node.DependsOn {
+		upstream, ok := graph.node(name)
+		if !ok || upstream.Dir == "" {
+			continue
+		}
+		sb.WriteString("\n### " + name + "\n")
+		if stat := collectJJDiffStat(upstream.Dir); stat != "" {
+			sb.WriteString("\nDiff summary:\n\n```\n")
+			sb.WriteString(strings.TrimRight(stat, "\n"))
+			sb.WriteString("\n```\n")
+		}
+		for _, entry := range upstreamCompletionEvidence(upstream.Dir) {
+			sb.WriteString("\nCompletion evidence: " + entry.Title + "\n")
+			if body := strings.TrimSpace(entry.Body); body != "" {
+				sb.WriteString("\n" + body + "\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+func collectJJDiffStat(dir string) string {
+	if !hasJJRepo(dir) {
+		return ""
+	}
+	statCmd := exec.Command("jj", "diff", "--from", "default@", "--stat")
+	statCmd.Dir = dir
+	output, errStat := statCmd.Output()
+	if errStat != nil {
+		return ""
+	}
+	return string(output)
+}
+
+func upstreamCompletionEvidence(dir string) []ledgerEntry {
+	entries, errLedger := readProjectManagementLedger(dir)
+	if errLedger != nil {
+		log.Println("pipeline: cannot read completion evidence of", filepath.Base(dir)+":", errLedger)
+		return nil
+	}
+	evidence := filterLedgerEntries(entries, []ledgerEntryType{ledgerCompletionEvidence}, "")
+	if len(evidence) > maxPipelineEvidenceEntries {
+		evidence = evidence[len(evidence)-maxPipelineEvidenceEntries:]
+	}
+	return evidence
+}
+
+func formatPipelineTrigger(trigger *state.Trigger) string {
+	var sb strings.Builder
+	sb.WriteString("\n## Pipeline\n\nThis run was started automatically because the workspaces it depends on completed.\n")
+	if payload := strings.TrimSpace(trigger.Payload); payload != "" {
+		sb.WriteString("\n")
+		sb.WriteString(payload)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// recordPipelineTrigger stores why the downstream is being started in its
+// state.json. A downstream that already completed starts a fresh run.
+func recordPipelineTrigger(coord *state.Coordinator, payload, interactionMode string) error {
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	errUpdate := coord.UpdateState(func(wf *state.Workflow) {
+		if wf.Status == state.StatusComplete {
+			wf.Status = ""
+		}
+		wf.InteractionMode = interactionMode
+		wf.Trigger = &state.Trigger{Kind: pipelineTriggerKind, Payload: truncateTriggerPayload(payload), Timestamp: timestamp}
+	})
+	if errUpdate != nil {
+		return fmt.Errorf("recording pipeline trigger: %w", errUpdate)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePipelineWorkspace(t *testing.T, rootDir, name, goal, status string) string {
+	t.Helper()
+	dir := setupTestWorkspace(t, rootDir, name)
+	if goal != "" {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "GOAL.md"), []byte(goal), 0644))
+	}
+	if status != "" {
+		_, errCoord := state.NewCoordinatorWith(statePath(dir), state.Workflow{Status: status})
+		require.NoError(t, errCoord)
+	}
+	return dir
+}
+
+func TestLoadPipelineDependencies(t *testing.T) {
+	cases := []struct {
+		name          string
+		goal          string
+		config        string
+		wantUpstreams []string
+		wantArtifacts bool
+	}{
+		{"none", "---\nmodel: m\n---\n", "", nil, false},
+		{"goalDependsOn", "---\ndependsOn: [backend, api]\n---\n", "", []string{"backend", "api"}, false},
+		{"goalArtifacts", "---\ndependsOn:\n  - backend\npipelineArtifacts: yes\n---\n", "", []string{"backend"}, true},
+		{"selfAndDuplicatesDropped", "---\ndependsOn: [frontend, backend, ' backend ', '']\n---\n", "", []string{"backend"}, false},
+		{"configFallback", "", `{"pipeline":{"dependsOn":["backend"],"artifacts":true}}`, []string{"backend"}, true},
+		{"goalTakesPrecedence", "---\ndependsOn: [api]\n---\n", `{"pipeline":{"dependsOn":["backend"],"artifacts":true}}`, []string{"api"}, false},
+		{"brokenGoalFallsBackToConfig", "---\ndependsOn: [\n---\n", `{"pipeline":{"dependsOn":["backend"]}}`, []string{"backend"}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "frontend")
+			require.NoError(t, os.MkdirAll(dir, 0755))
+			if tc.goal != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "GOAL.md"), []byte(tc.goal), 0644))
+			}
+			if tc.config != "" {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, configFileName), []byte(tc.config), 0644))
+			}
+
+			deps := loadPipelineDependencies(dir)
+
+			assert.Equal(t, tc.wantUpstreams, deps.upstreams)
+			assert.Equal(t, tc.wantArtifacts, deps.artifacts)
+		})
+	}
+}
+
+func TestMarkPipelineCycles(t *testing.T) {
+	nodes := []pipelineNode{
+		{Name: "a", DependsOn: []string{"c"}},
+		{Name: "b", DependsOn: []string{"a"}},
+		{Name: "c", DependsOn: []string{"b"}},
+		{Name: "d", DependsOn: []string{"c"}},
+		{Name: "e"},
+		{Name: "f", DependsOn: []string{"e", "missing"}},
+	}
+
+	markPipelineCycles(nodes)
+
+	var inCycle []string
+	for _, node := range nodes {
+		if node.InCycle {
+			inCycle = append(inCycle, node.Name)
+		}
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, inCycle)
+}
+
+func TestPipelineGraphService(t *testing.T) {
+	server, rootDir := setupTestServer(t)
+	writePipelineWorkspace(t, rootDir, "backend", "", state.StatusComplete)
+	writePipelineWorkspace(t, rootDir, "frontend", "---\ndependsOn: [backend]\npipelineArtifacts: true\n---\n", "")
+	writePipelineWorkspace(t, rootDir, "docs", "---\ndependsOn: [frontend, design]\n---\n", "")
+	writePipelineWorkspace(t, rootDir, "unrelated", "", state.StatusComplete)
+
+	w := serveHTTP(server, http.MethodGet, "/api/v1/pipeline", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var graph pipelineGraph
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
+
+	names := make(map[string]pipelineNode)
+	for _, node := range graph.Nodes {
+		names[node.Name] = node
+	}
+	require.Len(t, names, 4)
+	assert.NotContains(t, names, "unrelated")
+	assert.Equal(t, state.StatusComplete, names["backend"].Status)
+	assert.Equal(t, []string{"backend"}, names["frontend"].DependsOn)
+	assert.True(t, names["frontend"].Artifacts)
+	assert.True(t, names["design"].Missing)
+	assert.ElementsMatch(t, []pipelineEdge{
+		{From: "backend", To: "frontend"},
+		{From: "frontend", To: "docs"},
+		{From: "design", To: "docs"},
+	}, graph.Edges)
+}
+
+func TestPipelineGraphReadyDownstreams(t *testing.T) {
+	graph := pipelineGraph{Nodes: []pipelineNode{
+		{Name: "backend", Status: state.StatusComplete},
+		{Name: "api", Status: state.StatusWorking},
+		{Name: "schema", Status: state.StatusComplete, Running: true},
+		{Name: "frontend", DependsOn: []string{"backend"}},
+		{Name: "mobile", DependsOn: []string{"backend", "api"}},
+		{Name: "admin", DependsOn: []string{"backend", "schema"}},
+		{Name: "busy", DependsOn: []string{"backend"}, Running: true},
+		{Name: "loop", DependsOn: []string{"backend"}, InCycle: true},
+		{Name: "ghost", DependsOn: []string{"backend", "gone"}},
+		{Name: "gone", Missing: true},
+	}}
+
+	var names []string
+	for _, node := range graph.readyDownstreams("backend") {
+		names = append(names, node.Name)
+	}
+
+	assert.Equal(t, []string{"frontend"}, names)
+}
+
+func TestPipelineGraphNodeByDir(t *testing.T) {
+	graph := pipelineGraph{Nodes: []pipelineNode{
+		{Name: "api", Dir: "/srv/work/api"},
+		{Name: "api@clients", Dir: "/srv/clients/api"},
+		{Name: "design", Missing: true},
+	}}
+
+	node, ok := graph.nodeByDir("/srv/clients/api/")
+	require.True(t, ok)
+	assert.Equal(t, "api@clients", node.Name)
+
+	_, ok = graph.nodeByDir("/srv/clients/web")
+	assert.False(t, ok)
+}
+
+func TestBuildPipelinePayload(t *testing.T) {
+	rootDir := t.TempDir()
+	backend := setupTestWorkspace(t, rootDir, "backend")
+	ledger := "# Project Management\n\n" +
+		"## Handoff to reviewer (2026-03-01T10:00:00Z)\nplease review\n\n" +
+		"## Verification (2026-03-01T11:00:00Z)\nGOAL COMPLETE: /api/orders returns 200\n"
+	require.NoError(t, os.WriteFile(filepath.Join(backend, ".sgai", "PROJECT_MANAGEMENT.md"), []byte(ledger), 0644))
+	graph := pipelineGraph{Nodes: []pipelineNode{{Name: "backend", Dir: backend, Status: state.StatusComplete}}}
+
+	t.Run("withArtifacts", func(t *testing.T) {
+		payload := buildPipelinePayload(graph, pipelineNode{Name: "frontend", DependsOn: []string{"backend"}, Artifacts: true})
+
+		assert.Contains(t, payload, "Upstream workspaces completed: backend")
+		assert.Contains(t, payload, "### backend")
+		assert.Contains(t, payload, "Completion evidence: Verification")
+		assert.Contains(t, payload, "GOAL COMPLETE: /api/orders returns 200")
+		assert.NotContains(t, payload, "please review")
+	})
+
+	t.Run("withoutArtifacts", func(t *testing.T) {
+		payload := buildPipelinePayload(graph, pipelineNode{Name: "frontend", DependsOn: []string{"backend"}})
+
+		assert.Equal(t, "Upstream workspaces completed: backend\n", payload)
+	})
+}
+
+func TestRecordPipelineTrigger(t *testing.T) {
+	dir := setupTestWorkspace(t, t.TempDir(), "frontend")
+	coord, errCoord := state.NewCoordinatorWith(statePath(dir), state.Workflow{Status: state.StatusComplete, Task: "old run"})
+	require.NoError(t, errCoord)
+
+	require.NoError(t, recordPipelineTrigger(coord, "Upstream workspaces completed: backend\n", state.ModeSelfDrive))
+
+	wf := coord.State()
+	assert.Empty(t, wf.Status, "a completed downstream starts a fresh run")
+	assert.Equal(t, state.ModeSelfDrive, wf.InteractionMode)
+	require.NotNil(t, wf.Trigger)
+	assert.Equal(t, pipelineTriggerKind, wf.Trigger.Kind)
+
+	msg := buildAgentMessage(agentRunConfig{dir: dir, agent: "coordinator"}, wf, GoalMetadata{})
+	assert.Contains(t, msg, "## Pipeline")
+	assert.Contains(t, msg, "Upstream workspaces completed: backend")
+
+	wf.Trigger = &state.Trigger{Kind: "webhook", Payload: "push"}
+	msg = buildAgentMessage(agentRunConfig{dir: dir, agent: "coordinator"}, wf, GoalMetadata{})
+	assert.NotContains(t, msg, "## Pipeline")
+}
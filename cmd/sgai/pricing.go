@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"strings"
+)
+
+// modelPricing holds USD rates per million tokens for one model.
+// Reasoning tokens are billed at the output rate.
+type modelPricing struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheRead  float64 `json:"cacheRead"`
+	CacheWrite float64 `json:"cacheWrite"`
+}
+
+// pricingTable maps "provider/model" (or a bare model id) to its rates.
+type pricingTable map[string]modelPricing
+
+func loadPricingTable(userConfigDir, workspaceDir string) (pricingTable, error) {
+	table := pricingTable{}
+
+	user, errUser := loadUserConfig(userConfigDir)
+	if errUser != nil {
+		return nil, errUser
+	}
+	if user != nil {
+		maps.Copy(table, user.Pricing)
+	}
+
+	if workspaceDir != "" {
+		project, errProject := loadProjectConfig(workspaceDir)
+		if errProject != nil {
+			return nil, errProject
+		}
+		if project != nil {
+			maps.Copy(table, project.Pricing)
+		}
+	}
+
+	return table, nil
+}
+
+func (p pricingTable) lookup(rawModel string) (modelPricing, bool) {
+	if len(p) == 0 || rawModel == "" {
+		return modelPricing{}, false
+	}
+	provider, id := modelPricingKey(rawModel)
+	if provider != "" {
+		if pricing, ok := p[provider+"/"+id]; ok {
+			return pricing, true
+		}
+	}
+	pricing, ok := p[id]
+	return pricing, ok
+}
+
+func (p pricingTable) cost(row tokenUsageRow) (float64, bool) {
+	pricing, ok := p.lookup(row.Model)
+	if !ok {
+		return 0, row.Total == 0
+	}
+	const perMillion = 1_000_000
+	cost := float64(row.Input)*pricing.Input +
+		float64(row.Output+row.Reasoning)*pricing.Output +
+		float64(row.CacheRead)*pricing.CacheRead +
+		float64(row.CacheWrite)*pricing.CacheWrite
+	return cost / perMillion, true
+}
+
+func modelPricingKey(rawModel string) (provider, id string) {
+	var desc modelDescriptor
+	if errJSON := json.Unmarshal([]byte(rawModel), &desc); errJSON == nil && desc.ID != "" {
+		return desc.ProviderID, desc.ID
+	}
+	baseModel, _ := parseModelAndVariant(rawModel)
+	if before, after, found := strings.Cut(baseModel, "/"); found {
+		return before, after
+	}
+	return "", baseModel
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPricingTableMergesUserAndProjectConfig(t *testing.T) {
+	userDir := t.TempDir()
+	workspaceDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(userDir, userConfigFileName), []byte(`{
+		"pricing": {
+			"openai/gpt-5.5": {"input": 1, "output": 2},
+			"anthropic/claude-sonnet-4.5": {"input": 3, "output": 15, "cacheRead": 0.3, "cacheWrite": 3.75}
+		}
+	}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(workspaceDir, configFileName), []byte(`{
+		"pricing": {"openai/gpt-5.5": {"input": 1.25, "output": 10, "cacheRead": 0.125}}
+	}`), 0o644))
+
+	table, err := loadPricingTable(userDir, workspaceDir)
+
+	require.NoError(t, err)
+	assert.Equal(t, modelPricing{Input: 1.25, Output: 10, CacheRead: 0.125}, table["openai/gpt-5.5"])
+	assert.Equal(t, modelPricing{Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75}, table["anthropic/claude-sonnet-4.5"])
+}
+
+func TestLoadPricingTableMissingConfigs(t *testing.T) {
+	table, err := loadPricingTable(t.TempDir(), t.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, table)
+}
+
+func TestLoadPricingTableInvalidUserConfig(t *testing.T) {
+	userDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(userDir, userConfigFileName), []byte(`{"pricing": [}`), 0o644))
+
+	_, err := loadPricingTable(userDir, "")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "parsing user config")
+}
+
+func TestPricingTableLookup(t *testing.T) {
+	table := pricingTable{
+		"openai/gpt-5.5": {Input: 1},
+		"claude-opus-4":  {Input: 2},
+	}
+
+	tests := []struct {
+		name      string
+		rawModel  string
+		wantFound bool
+		wantInput float64
+	}{
+		{
+			name:      "descriptorWithProvider",
+			rawModel:  `{"id":"gpt-5.5","providerID":"openai","variant":"xhigh"}`,
+			wantFound: true,
+			wantInput: 1,
+		},
+		{
+			name:      "plainModelSpecWithVariant",
+			rawModel:  "openai/gpt-5.5 (high)",
+			wantFound: true,
+			wantInput: 1,
+		},
+		{
+			name:      "bareIDFallback",
+			rawModel:  `{"id":"claude-opus-4","providerID":"anthropic"}`,
+			wantFound: true,
+			wantInput: 2,
+		},
+		{
+			name:     "unknownModel",
+			rawModel: `{"id":"mystery","providerID":"acme"}`,
+		},
+		{
+			name: "emptyModel",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pricing, found := table.lookup(tt.rawModel)
+			assert.Equal(t, tt.wantFound, found)
+			assert.InDelta(t, tt.wantInput, pricing.Input, 1e-9)
+		})
+	}
+}
+
+func TestPricingTableCost(t *testing.T) {
+	table := pricingTable{"openai/gpt-5.5": {Input: 2, Output: 10, CacheRead: 0.5, CacheWrite: 4}}
+
+	cost, priced := table.cost(tokenUsageRow{
+		Model:      "openai/gpt-5.5",
+		Input:      1_000_000,
+		Output:     500_000,
+		Reasoning:  500_000,
+		CacheRead:  2_000_000,
+		CacheWrite: 250_000,
+		Total:      4_250_000,
+	})
+	assert.True(t, priced)
+	assert.InDelta(t, 2+10+1+1, cost, 1e-9)
+
+	cost, priced = table.cost(tokenUsageRow{Model: "acme/unknown", Input: 10, Total: 10})
+	assert.False(t, priced)
+	assert.Zero(t, cost)
+
+	_, priced = table.cost(tokenUsageRow{Model: "acme/unknown"})
+	assert.True(t, priced)
+}
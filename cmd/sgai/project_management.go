@@ -1,15 +1,81 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 )
 
-func appendProjectManagementSection(dir, title, body string) error {
+type ledgerEntryType string
+
+const (
+	ledgerHandoff             ledgerEntryType = "handoff"
+	ledgerBlocker             ledgerEntryType = "blocker"
+	ledgerQuestion            ledgerEntryType = "question"
+	ledgerCompletionEvidence  ledgerEntryType = "completion-evidence"
+	ledgerGateFailure         ledgerEntryType = "gate-failure"
+	ledgerRetrospectiveHeader ledgerEntryType = "retrospective-header"
+	ledgerWorkGateReview      ledgerEntryType = "work-gate-review"
+	ledgerNote                ledgerEntryType = "note"
+)
+
+var ledgerEntryTypes = []ledgerEntryType{
+	ledgerHandoff,
+	ledgerBlocker,
+	ledgerQuestion,
+	ledgerCompletionEvidence,
+	ledgerGateFailure,
+	ledgerRetrospectiveHeader,
+	ledgerWorkGateReview,
+	ledgerNote,
+}
+
+var agentLedgerEntryTypes = []ledgerEntryType{
+	ledgerHandoff,
+	ledgerBlocker,
+	ledgerQuestion,
+	ledgerCompletionEvidence,
+	ledgerNote,
+}
+
+const (
+	ledgerSystemAgent = "sgai"
+	ledgerHumanAgent  = "human"
+)
+
+var errUnknownLedgerType = errors.New("unknown ledger entry type")
+
+// ledgerEntry is one typed section of .sgai/PROJECT_MANAGEMENT.md.
+type ledgerEntry struct {
+	Type      ledgerEntryType `json:"type"`
+	Title     string          `json:"title"`
+	Timestamp string          `json:"timestamp,omitempty"`
+	Agent     string          `json:"agent,omitempty"`
+	Target    string          `json:"target,omitempty"`
+	Body      string          `json:"body"`
+}
+
+var (
+	ledgerMetaPattern      = regexp.MustCompile(`^<!--\s*sgai-ledger\s+(.*?)\s*-->$`)
+	ledgerHeadingPattern   = regexp.MustCompile(`^(.*?)\s*\((\d{4}-\d{2}-\d{2}T[^)]*)\)$`)
+	ledgerAgentLinePattern = regexp.MustCompile(`(?i)^[-*\s]*\**agent\**\s*:\**\s*([\w.-]+)`)
+)
+
+func parseLedgerEntryType(value string) (ledgerEntryType, error) {
+	entryType := ledgerEntryType(strings.TrimSpace(strings.ToLower(value)))
+	if !slices.Contains(ledgerEntryTypes, entryType) {
+		return "", fmt.Errorf("%w %q", errUnknownLedgerType, value)
+	}
+	return entryType, nil
+}
+
+func appendProjectManagementSection(dir string, entry ledgerEntry) error {
 	pmPath := filepath.Join(dir, ".sgai", "PROJECT_MANAGEMENT.md")
 	if errMkdir := os.MkdirAll(filepath.Dir(pmPath), 0755); errMkdir != nil {
 		return errMkdir
@@ -24,10 +90,191 @@ func appendProjectManagementSection(dir, title, body string) error {
 		}
 	}()
 	timestamp := time.Now().UTC().Format(time.RFC3339)
-	_, errWrite := fmt.Fprintf(file, "\n## %s (%s)\n%s\n", title, timestamp, strings.TrimSpace(body))
+	_, errWrite := fmt.Fprintf(file, "\n## %s (%s)\n%s\n%s\n", entry.Title, timestamp, formatLedgerMeta(entry), strings.TrimSpace(entry.Body))
 	return errWrite
 }
 
+func formatLedgerMeta(entry ledgerEntry) string {
+	fields := []string{"type=" + string(entry.Type)}
+	if entry.Agent != "" {
+		fields = append(fields, "agent="+ledgerMetaValue(entry.Agent))
+	}
+	if entry.Target != "" {
+		fields = append(fields, "to="+ledgerMetaValue(entry.Target))
+	}
+	return "<!-- sgai-ledger " + strings.Join(fields, " ") + " -->"
+}
+
+func ledgerMetaValue(value string) string {
+	return strings.Join(strings.Fields(value), "-")
+}
+
+func readProjectManagementLedger(dir string) ([]ledgerEntry, error) {
+	content, errRead := os.ReadFile(filepath.Join(dir, ".sgai", "PROJECT_MANAGEMENT.md"))
+	if errRead != nil {
+		if os.IsNotExist(errRead) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading PROJECT_MANAGEMENT.md: %w", errRead)
+	}
+	return parseProjectManagementLedger(string(content)), nil
+}
+
+func parseProjectManagementLedger(content string) []ledgerEntry {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	var entries []ledgerEntry
+
+	start := 0
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "---") {
+		for i := 1; i < len(lines); i++ {
+			if !strings.HasPrefix(lines[i], "---") {
+				continue
+			}
+			if header, ok := parseRetrospectiveHeader(lines[1:i]); ok {
+				entries = append(entries, header)
+			}
+			start = i + 1
+			break
+		}
+	}
+
+	var current *ledgerEntry
+	var bodyLines []string
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Body = strings.TrimSpace(strings.Join(bodyLines, "\n"))
+		classifyLedgerEntry(current)
+		entries = append(entries, *current)
+		current = nil
+		bodyLines = nil
+	}
+
+	// A heading directly followed by its sgai-ledger comment starts a typed
+	// entry. Plain headings only start entries in the legacy, hand-written part
+	// of the ledger before the first typed entry; after that they belong to the
+	// body of the entry they appear in.
+	body := lines[start:]
+	typed := false
+	for i := 0; i < len(body); i++ {
+		line := body[i]
+		if heading, ok := strings.CutPrefix(line, "## "); ok {
+			var meta []string
+			if i+1 < len(body) {
+				meta = ledgerMetaPattern.FindStringSubmatch(strings.TrimSpace(body[i+1]))
+			}
+			if meta != nil || !typed {
+				flush()
+				current = &ledgerEntry{}
+				current.Title, current.Timestamp = splitLedgerHeading(strings.TrimSpace(heading))
+				if meta != nil {
+					applyLedgerMeta(current, meta[1])
+					typed = true
+					i++
+				}
+				continue
+			}
+		}
+		if current == nil {
+			continue
+		}
+		bodyLines = append(bodyLines, line)
+	}
+	flush()
+
+	return entries
+}
+
+func parseRetrospectiveHeader(lines []string) (ledgerEntry, bool) {
+	for _, line := range lines {
+		if value, ok := strings.CutPrefix(line, "Retrospective Session: "); ok {
+			return ledgerEntry{
+				Type:  ledgerRetrospectiveHeader,
+				Title: "Retrospective Session",
+				Agent: ledgerSystemAgent,
+				Body:  strings.TrimSpace(value),
+			}, true
+		}
+	}
+	return ledgerEntry{}, false
+}
+
+func splitLedgerHeading(heading string) (title, timestamp string) {
+	matches := ledgerHeadingPattern.FindStringSubmatch(heading)
+	if matches == nil {
+		return heading, ""
+	}
+	if _, errParse := time.Parse(time.RFC3339, matches[2]); errParse != nil {
+		return heading, ""
+	}
+	return matches[1], matches[2]
+}
+
+func applyLedgerMeta(entry *ledgerEntry, meta string) {
+	for field := range strings.FieldsSeq(meta) {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "type":
+			if entryType, errType := parseLedgerEntryType(value); errType == nil {
+				entry.Type = entryType
+			}
+		case "agent":
+			entry.Agent = value
+		case "to":
+			entry.Target = value
+		}
+	}
+}
+
+func classifyLedgerEntry(entry *ledgerEntry) {
+	if entry.Agent == "" {
+		for line := range strings.SplitSeq(entry.Body, "\n") {
+			if matches := ledgerAgentLinePattern.FindStringSubmatch(strings.TrimSpace(line)); matches != nil {
+				entry.Agent = matches[1]
+				break
+			}
+		}
+	}
+	if entry.Type != "" {
+		return
+	}
+	title := strings.ToLower(entry.Title)
+	switch {
+	case strings.Contains(title, "gate failure") || strings.Contains(title, "gate failed"):
+		entry.Type = ledgerGateFailure
+	case strings.Contains(title, "work gate review"):
+		entry.Type = ledgerWorkGateReview
+	case strings.Contains(title, "handoff") || strings.Contains(title, "hand-off") || strings.Contains(title, "hand off"):
+		entry.Type = ledgerHandoff
+	case strings.Contains(title, "blocker") || strings.Contains(title, "blocked") || strings.Contains(title, "pending todo"):
+		entry.Type = ledgerBlocker
+	case strings.Contains(title, "question"):
+		entry.Type = ledgerQuestion
+	case strings.Contains(title, "complete") || strings.Contains(title, "evidence") || strings.HasPrefix(entry.Body, "GOAL COMPLETE:"):
+		entry.Type = ledgerCompletionEvidence
+	default:
+		entry.Type = ledgerNote
+	}
+}
+
+func filterLedgerEntries(entries []ledgerEntry, types []ledgerEntryType, agent string) []ledgerEntry {
+	filtered := make([]ledgerEntry, 0, len(entries))
+	for _, entry := range entries {
+		if len(types) > 0 && !slices.Contains(types, entry.Type) {
+			continue
+		}
+		if agent != "" && !strings.EqualFold(entry.Agent, agent) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
 func copyProjectManagementToRetrospective(dir, retrospectiveDir string) {
 	if retrospectiveDir == "" {
 		return
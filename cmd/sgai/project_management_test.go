@@ -0,0 +1,198 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleLedger = `---
+Retrospective Session: .sgai/retrospectives/2026-03-01-10-00.abcd
+---
+
+# Project Management
+
+## Pending TODO Items (2026-03-01T10:00:00Z)
+You have 2 pending TODO items. Please complete them before marking workflow complete.
+
+## Completion Gate Failure (2026-03-01T10:02:00Z)
+make test failed
+
+## Open question on token expiry
+Agent: go-developer
+Should refresh tokens expire after 7 or 30 days?
+
+## Handoff to go-reviewer (2026-03-01T10:05:00Z)
+<!-- sgai-ledger type=handoff agent=go-developer to=go-reviewer -->
+Auth endpoints implemented; please review.
+
+## Verification (2026-03-01T11:00:00Z)
+<!-- sgai-ledger type=completion-evidence agent=go-reviewer -->
+GOAL COMPLETE: all endpoints tested
+
+## Notes (2026-03-01T11:05:00Z)
+<!-- sgai-ledger type=note agent=coordinator -->
+### Details
+Something worth remembering.
+
+## Appendix
+Headings inside a typed entry stay in its body.
+`
+
+func TestParseProjectManagementLedger(t *testing.T) {
+	entries := parseProjectManagementLedger(sampleLedger)
+
+	require.Len(t, entries, 7)
+
+	assert.Equal(t, ledgerEntry{
+		Type:  ledgerRetrospectiveHeader,
+		Title: "Retrospective Session",
+		Agent: ledgerSystemAgent,
+		Body:  ".sgai/retrospectives/2026-03-01-10-00.abcd",
+	}, entries[0])
+
+	assert.Equal(t, ledgerBlocker, entries[1].Type)
+	assert.Equal(t, ledgerGateFailure, entries[2].Type)
+
+	assert.Equal(t, ledgerQuestion, entries[3].Type)
+	assert.Equal(t, "Open question on token expiry", entries[3].Title)
+	assert.Empty(t, entries[3].Timestamp)
+	assert.Equal(t, "go-developer", entries[3].Agent)
+
+	assert.Equal(t, ledgerEntry{
+		Type:      ledgerHandoff,
+		Title:     "Handoff to go-reviewer",
+		Timestamp: "2026-03-01T10:05:00Z",
+		Agent:     "go-developer",
+		Target:    "go-reviewer",
+		Body:      "Auth endpoints implemented; please review.",
+	}, entries[4])
+
+	assert.Equal(t, ledgerCompletionEvidence, entries[5].Type)
+	assert.Equal(t, ledgerNote, entries[6].Type)
+	assert.Equal(t, "### Details\nSomething worth remembering.\n\n## Appendix\nHeadings inside a typed entry stay in its body.", entries[6].Body)
+}
+
+func TestParseProjectManagementLedgerKeepsBodyHeadings(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, appendProjectManagementSection(dir, ledgerEntry{
+		Type:  ledgerCompletionEvidence,
+		Title: "Verification",
+		Agent: "go-reviewer",
+		Body:  "GOAL COMPLETE: tests pass\n\n## Commands\n```sh\n# run the suite\nmake test\n```",
+	}))
+	require.NoError(t, appendProjectManagementSection(dir, ledgerEntry{Type: ledgerNote, Title: "Follow-up", Agent: "coordinator", Body: "done"}))
+
+	entries, err := readProjectManagementLedger(dir)
+
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Contains(t, entries[0].Body, "## Commands")
+	assert.Contains(t, entries[0].Body, "make test")
+	assert.Equal(t, "Follow-up", entries[1].Title)
+}
+
+func TestAppendProjectManagementSectionRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, appendProjectManagementSection(dir, ledgerEntry{
+		Type:   ledgerQuestion,
+		Title:  "Database choice",
+		Agent:  "go developer",
+		Target: "coordinator",
+		Body:   "  PostgreSQL or SQLite?  ",
+	}))
+	require.NoError(t, appendProjectManagementSection(dir, ledgerEntry{
+		Type:  ledgerGateFailure,
+		Title: "Completion Gate Failure",
+		Agent: ledgerSystemAgent,
+		Body:  "exit status 1",
+	}))
+
+	entries, err := readProjectManagementLedger(dir)
+
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, ledgerQuestion, entries[0].Type)
+	assert.Equal(t, "Database choice", entries[0].Title)
+	assert.Equal(t, "go-developer", entries[0].Agent)
+	assert.Equal(t, "coordinator", entries[0].Target)
+	assert.Equal(t, "PostgreSQL or SQLite?", entries[0].Body)
+	assert.NotEmpty(t, entries[0].Timestamp)
+	assert.Equal(t, ledgerGateFailure, entries[1].Type)
+	assert.Equal(t, ledgerSystemAgent, entries[1].Agent)
+}
+
+func TestReadProjectManagementLedgerMissingFile(t *testing.T) {
+	entries, err := readProjectManagementLedger(t.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestFilterLedgerEntries(t *testing.T) {
+	entries := parseProjectManagementLedger(sampleLedger)
+
+	tests := []struct {
+		name      string
+		types     []ledgerEntryType
+		agent     string
+		wantCount int
+	}{
+		{
+			name:      "noFilter",
+			wantCount: 7,
+		},
+		{
+			name:      "byType",
+			types:     []ledgerEntryType{ledgerBlocker, ledgerGateFailure},
+			wantCount: 2,
+		},
+		{
+			name:      "byAgent",
+			agent:     "GO-DEVELOPER",
+			wantCount: 2,
+		},
+		{
+			name:      "byTypeAndAgent",
+			types:     []ledgerEntryType{ledgerQuestion},
+			agent:     "go-developer",
+			wantCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Len(t, filterLedgerEntries(entries, tt.types, tt.agent), tt.wantCount)
+		})
+	}
+}
+
+func TestParseLedgerTypeFilter(t *testing.T) {
+	types, err := parseLedgerTypeFilter([]string{"handoff,blocker", " question ", ""})
+	require.NoError(t, err)
+	assert.Equal(t, []ledgerEntryType{ledgerHandoff, ledgerBlocker, ledgerQuestion}, types)
+
+	_, err = parseLedgerTypeFilter([]string{"gossip"})
+	assert.ErrorIs(t, err, errUnknownLedgerType)
+}
+
+func TestHandleAPILedger(t *testing.T) {
+	server, rootDir := setupTestServer(t)
+	wsDir := setupTestWorkspace(t, rootDir, "ledger-ws")
+	require.NoError(t, os.WriteFile(filepath.Join(wsDir, ".sgai", "PROJECT_MANAGEMENT.md"), []byte(sampleLedger), 0o644))
+
+	w := serveHTTP(server, "GET", "/api/v1/workspaces/ledger-ws/ledger?type=handoff&type=question&agent=go-developer", "")
+	require.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"title":"Handoff to go-reviewer"`)
+	assert.Contains(t, w.Body.String(), `"type":"question"`)
+	assert.NotContains(t, w.Body.String(), `"gate-failure"`)
+
+	w = serveHTTP(server, "GET", "/api/v1/workspaces/ledger-ws/ledger?type=gossip", "")
+	assert.Equal(t, 400, w.Code)
+
+	w = serveHTTP(server, "GET", "/api/v1/workspaces/missing/ledger", "")
+	assert.Equal(t, 404, w.Code)
+}
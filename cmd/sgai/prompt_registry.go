@@ -10,7 +10,7 @@ RIGHT NOW, you must read @GOAL.md and @.sgai/PROJECT_MANAGEMENT.md, then work to
 
 const promptSectionHumanCommDirect = `if you want to tell me something, use ask_user_question to present structured questions;`
 
-const promptSectionMessaging = `.sgai/PROJECT_MANAGEMENT.md is the shared ledger for inter-agent state, handoffs, blockers, questions, and completion evidence. Read it before acting and append concise coordinator sections when handing work off or reporting completion. Coordinator state updates must be explicit: current phase, concrete work completed, next action, blocker if any, and the expected owner of the next step.`
+const promptSectionMessaging = `.sgai/PROJECT_MANAGEMENT.md is the shared ledger for inter-agent state, handoffs, blockers, questions, and completion evidence. Read it before acting and append concise sections with sgai_append_ledger_entry when handing work off or reporting completion instead of hand-editing the markdown. Coordinator state updates must be explicit: current phase, concrete work completed, next action, blocker if any, and the expected owner of the next step.`
 
 const promptSectionProjectManagementMonitor = `Use .sgai/PROJECT_MANAGEMENT.md to monitor inter-agent communication and workflow history.`
 
@@ -18,7 +18,7 @@ const promptSectionWorkFocus = `Critically, you must strictly do the work that y
 
 const promptSectionDelegation = `## Delegation
 SGAI runs this top-level session as the coordinator. Use the Task tool for subagent delegation instead of routing the SGAI runtime between agents:
-- Append handoffs, blockers, questions, or completion evidence to .sgai/PROJECT_MANAGEMENT.md
+- Append handoffs, blockers, questions, or completion evidence to .sgai/PROJECT_MANAGEMENT.md with sgai_append_ledger_entry
 - Make every coordinator state update explicit about current phase, completed work, next step, blocker status, and handoff target
 - Delegate by calling the Task tool with one of the available subagent types listed below
 - Do not use bash, shell commands, opencode, or opencode run to delegate work
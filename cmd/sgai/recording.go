@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/sandgardenhq/sgai/pkg/state"
+)
+
+// recordEnvVar enables recording when set to "1"/"true" (writes to
+// .sgai/recordings/<timestamp>.jsonl) or to an explicit file path.
+const recordEnvVar = "SGAI_RECORD"
+
+const recordingFormatVersion = 1
+
+var errEmptyRecording = errors.New("recording has no header")
+
+// agentExecutor runs one agent iteration and reports the resulting state.
+// executeAgentProcess is the live implementation; replays substitute recorded
+// outcomes.
+type agentExecutor func(ctx context.Context, cfg agentRunConfig, agentArgs []string, agentMsg, prefix string, outputCapture *ringWriter, wfState state.Workflow, modelSpec string) (state.Workflow, string, agentFailureKind, *state.Workflow)
+
+type recordingHeader struct {
+	Version      int               `json:"version"`
+	Workspace    string            `json:"workspace"`
+	RecordedAt   string            `json:"recordedAt"`
+	Goal         string            `json:"goal"`
+	Agents       map[string]string `json:"agents,omitempty"`
+	InitialState state.Workflow    `json:"initialState"`
+}
+
+type recordedToolCall struct {
+	Agent  string          `json:"agent"`
+	Tool   string          `json:"tool"`
+	Args   json.RawMessage `json:"args,omitempty"`
+	Result string          `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type iterationRecord struct {
+	Iteration   int                `json:"iteration"`
+	Agent       string             `json:"agent"`
+	Model       string             `json:"model,omitempty"`
+	Args        []string           `json:"args"`
+	Message     string             `json:"message"`
+	StateBefore state.Workflow     `json:"stateBefore"`
+	StateAfter  state.Workflow     `json:"stateAfter"`
+	SessionID   string             `json:"sessionID,omitempty"`
+	Failure     agentFailureKind   `json:"failure,omitempty"`
+	Failed      bool               `json:"failed,omitempty"`
+	ToolCalls   []recordedToolCall `json:"toolCalls"`
+}
+
+type recording struct {
+	Header     recordingHeader
+	Iterations []iterationRecord
+}
+
+// runRecorder appends one JSON line per iteration. Tool calls reported by the
+// MCP server between iterations are attached to the next iteration record.
+type runRecorder struct {
+	mu        sync.Mutex
+	w         io.WriteCloser
+	iteration int
+	toolCalls []recordedToolCall
+}
+
+var (
+	activeRecordersMu sync.Mutex
+	activeRecorders   = make(map[string]*runRecorder)
+)
+
+func recordingPath(dir, envValue string, now time.Time) string {
+	switch strings.ToLower(strings.TrimSpace(envValue)) {
+	case "":
+		return ""
+	case "1", "true", "yes":
+		return filepath.Join(dir, ".sgai", "recordings", now.Format("2006-01-02-15-04-05")+".jsonl")
+	default:
+		return envValue
+	}
+}
+
+func startRunRecorder(dir, path string, wfState state.Workflow, now time.Time) (*runRecorder, error) {
+	goal, errGoal := os.ReadFile(filepath.Join(dir, "GOAL.md"))
+	if errGoal != nil {
+		return nil, fmt.Errorf("reading GOAL.md: %w", errGoal)
+	}
+	if errMkdir := os.MkdirAll(filepath.Dir(path), 0755); errMkdir != nil {
+		return nil, fmt.Errorf("creating recording directory: %w", errMkdir)
+	}
+	file, errCreate := os.Create(path)
+	if errCreate != nil {
+		return nil, fmt.Errorf("creating recording: %w", errCreate)
+	}
+	rec := &runRecorder{w: file}
+	header := recordingHeader{
+		Version:      recordingFormatVersion,
+		Workspace:    filepath.Base(dir),
+		RecordedAt:   now.UTC().Format(time.RFC3339),
+		Goal:         string(goal),
+		Agents:       readAgentDefinitions(dir),
+		InitialState: wfState,
+	}
+	if errWrite := rec.writeLine(header); errWrite != nil {
+		_ = file.Close()
+		return nil, errWrite
+	}
+
+	activeRecordersMu.Lock()
+	activeRecorders[filepath.Clean(dir)] = rec
+	activeRecordersMu.Unlock()
+	return rec, nil
+}
+
+// readAgentDefinitions captures .sgai/agent/*.md because buildAgentMessage
+// reads agent descriptions from disk.
+func readAgentDefinitions(dir string) map[string]string {
+	matches, errGlob := filepath.Glob(filepath.Join(dir, ".sgai", "agent", "*.md"))
+	if errGlob != nil || len(matches) == 0 {
+		return nil
+	}
+	agents := make(map[string]string, len(matches))
+	for _, match := range matches {
+		content, errRead := os.ReadFile(match)
+		if errRead != nil {
+			log.Println("failed to read agent definition:", errRead)
+			continue
+		}
+		agents[strings.TrimSuffix(filepath.Base(match), ".md")] = string(content)
+	}
+	return agents
+}
+
+func recorderFor(dir string) *runRecorder {
+	activeRecordersMu.Lock()
+	defer activeRecordersMu.Unlock()
+	return activeRecorders[filepath.Clean(dir)]
+}
+
+func (r *runRecorder) close(dir string) {
+	activeRecordersMu.Lock()
+	if activeRecorders[filepath.Clean(dir)] == r {
+		delete(activeRecorders, filepath.Clean(dir))
+	}
+	activeRecordersMu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if errClose := r.w.Close(); errClose != nil {
+		log.Println("failed to close recording:", errClose)
+	}
+}
+
+func (r *runRecorder) writeLine(v any) error {
+	data, errJSON := json.Marshal(v)
+	if errJSON != nil {
+		return fmt.Errorf("encoding recording line: %w", errJSON)
+	}
+	if _, errWrite := r.w.Write(append(data, '\n')); errWrite != nil {
+		return fmt.Errorf("writing recording: %w", errWrite)
+	}
+	return nil
+}
+
+func (r *runRecorder) recordToolCall(call recordedToolCall) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.toolCalls = append(r.toolCalls, call)
+}
+
+func (r *runRecorder) recordIteration(rec iterationRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.iteration++
+	rec.Iteration = r.iteration
+	rec.ToolCalls = r.toolCalls
+	if rec.ToolCalls == nil {
+		rec.ToolCalls = []recordedToolCall{}
+	}
+	r.toolCalls = nil
+	if errWrite := r.writeLine(rec); errWrite != nil {
+		log.Println("failed to record iteration:", errWrite)
+	}
+}
+
+// wrap returns an executor that records every iteration run by next.
+func (r *runRecorder) wrap(next agentExecutor) agentExecutor {
+	return func(ctx context.Context, cfg agentRunConfig, agentArgs []string, agentMsg, prefix string, outputCapture *ringWriter, wfState state.Workflow, modelSpec string) (state.Workflow, string, agentFailureKind, *state.Workflow) {
+		newState, sessionID, failure, errState := next(ctx, cfg, agentArgs, agentMsg, prefix, outputCapture, wfState, modelSpec)
+		rec := iterationRecord{
+			Agent:       cfg.agent,
+			Model:       modelSpec,
+			Args:        agentArgs,
+			Message:     agentMsg,
+			StateBefore: wfState,
+			StateAfter:  newState,
+			SessionID:   sessionID,
+			Failure:     failure,
+		}
+		if errState != nil {
+			rec.Failed = true
+			rec.StateAfter = *errState
+		}
+		r.recordIteration(rec)
+		return newState, sessionID, failure, errState
+	}
+}
+
+func toolCallRecordingMiddleware(workingDir, agentName string) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			result, errCall := next(ctx, method, req)
+			rec := recorderFor(workingDir)
+			if rec == nil || method != "tools/call" {
+				return result, errCall
+			}
+			call := recordedToolCall{Agent: toolCaller(ctx, agentName)}
+			if callReq, ok := req.(*mcp.CallToolRequest); ok && callReq.Params != nil {
+				call.Tool = callReq.Params.Name
+				call.Args = callReq.Params.Arguments
+			}
+			if errCall != nil {
+				call.Error = errCall.Error()
+			}
+			if callResult, ok := result.(*mcp.CallToolResult); ok && callResult != nil {
+				var texts []string
+				for _, content := range callResult.Content {
+					if text, isText := content.(*mcp.TextContent); isText {
+						texts = append(texts, text.Text)
+					}
+				}
+				call.Result = strings.Join(texts, "\n")
+			}
+			rec.recordToolCall(call)
+			return result, errCall
+		}
+	}
+}
+
+func loadRecording(path string) (recording, error) {
+	file, errOpen := os.Open(path)
+	if errOpen != nil {
+		return recording{}, fmt.Errorf("opening recording: %w", errOpen)
+	}
+	defer func() {
+		if errClose := file.Close(); errClose != nil {
+			log.Println("failed to close recording:", errClose)
+		}
+	}()
+	return parseRecording(file)
+}
+
+func parseRecording(r io.Reader) (recording, error) {
+	var rec recording
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	headerSeen := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !headerSeen {
+			if errJSON := json.Unmarshal([]byte(line), &rec.Header); errJSON != nil {
+				return recording{}, fmt.Errorf("parsing recording header: %w", errJSON)
+			}
+			headerSeen = true
+			continue
+		}
+		var iteration iterationRecord
+		if errJSON := json.Unmarshal([]byte(line), &iteration); errJSON != nil {
+			return recording{}, fmt.Errorf("parsing recording iteration %d: %w", len(rec.Iterations)+1, errJSON)
+		}
+		rec.Iterations = append(rec.Iterations, iteration)
+	}
+	if errScan := scanner.Err(); errScan != nil {
+		return recording{}, fmt.Errorf("reading recording: %w", errScan)
+	}
+	if !headerSeen {
+		return recording{}, errEmptyRecording
+	}
+	return rec, nil
+}
+
+type replayResult struct {
+	Final       state.Workflow
+	Iterations  []iterationRecord
+	Divergences []string
+}
+
+// replayer feeds recorded iteration outcomes back to the runner in order and
+// notes every place where the runner asked for something different.
+type replayer struct {
+	records     []iterationRecord
+	next        int
+	cancel      context.CancelFunc
+	iterations  []iterationRecord
+	divergences []string
+}
+
+func (p *replayer) execute(_ context.Context, cfg agentRunConfig, agentArgs []string, agentMsg, _ string, _ *ringWriter, wfState state.Workflow, modelSpec string) (state.Workflow, string, agentFailureKind, *state.Workflow) {
+	if p.next >= len(p.records) {
+		p.divergences = append(p.divergences, fmt.Sprintf("iteration %d: runner asked for agent %s after the recording ended", p.next+1, cfg.agent))
+		p.cancel()
+		return state.Workflow{}, "", failureNone, &wfState
+	}
+	rec := p.records[p.next]
+	p.next++
+
+	p.compare(rec.Iteration, "agent", rec.Agent, cfg.agent)
+	p.compare(rec.Iteration, "model", rec.Model, modelSpec)
+	p.compare(rec.Iteration, "args", strings.Join(rec.Args, " "), strings.Join(agentArgs, " "))
+	p.compare(rec.Iteration, "message", rec.Message, agentMsg)
+	p.compareState(rec.Iteration, rec.StateBefore, wfState)
+
+	p.iterations = append(p.iterations, iterationRecord{
+		Iteration:   rec.Iteration,
+		Agent:       cfg.agent,
+		Model:       modelSpec,
+		Args:        agentArgs,
+		Message:     agentMsg,
+		StateBefore: wfState,
+		StateAfter:  rec.StateAfter,
+		SessionID:   rec.SessionID,
+		Failure:     rec.Failure,
+		Failed:      rec.Failed,
+		ToolCalls:   rec.ToolCalls,
+	})
+
+	after := rec.StateAfter
+	if errUpdate := cfg.coord.UpdateState(func(wf *state.Workflow) {
+		*wf = after
+	}); errUpdate != nil {
+		log.Println("failed to apply recorded state:", errUpdate)
+	}
+	if rec.Failed {
+		return state.Workflow{}, "", rec.Failure, &after
+	}
+	return after, rec.SessionID, failureNone, nil
+}
+
+func (p *replayer) compare(iteration int, field, recorded, actual string) {
+	if recorded == actual {
+		return
+	}
+	p.divergences = append(p.divergences, fmt.Sprintf("iteration %d: %s differs: %s", iteration, field, firstDifference(recorded, actual)))
+}
+
+func (p *replayer) compareState(iteration int, recorded, actual state.Workflow) {
+	recordedJSON, errRecorded := json.Marshal(recorded)
+	actualJSON, errActual := json.Marshal(actual)
+	if errRecorded != nil || errActual != nil {
+		return
+	}
+	p.compare(iteration, "state", string(recordedJSON), string(actualJSON))
+}
+
+func firstDifference(recorded, actual string) string {
+	recordedLines := strings.Split(recorded, "\n")
+	actualLines := strings.Split(actual, "\n")
+	for i := range max(len(recordedLines), len(actualLines)) {
+		var want, got string
+		if i < len(recordedLines) {
+			want = recordedLines[i]
+		}
+		if i < len(actualLines) {
+			got = actualLines[i]
+		}
+		if want != got {
+			return fmt.Sprintf("line %d: recorded %q, got %q", i+1, want, got)
+		}
+	}
+	return "content differs"
+}
+
+// replayRecording runs the workflow runner in dir against a recording without
+// starting opencode. dir receives the recorded GOAL.md and a fresh state.json.
+func replayRecording(ctx context.Context, dir string, rec recording) (replayResult, error) {
+	goalPath := filepath.Join(dir, "GOAL.md")
+	if errMkdir := os.MkdirAll(filepath.Join(dir, ".sgai"), 0755); errMkdir != nil {
+		return replayResult{}, fmt.Errorf("creating replay workspace: %w", errMkdir)
+	}
+	if errWrite := os.WriteFile(goalPath, []byte(rec.Header.Goal), 0644); errWrite != nil {
+		return replayResult{}, fmt.Errorf("writing GOAL.md: %w", errWrite)
+	}
+	agentDir := filepath.Join(dir, ".sgai", "agent")
+	for name, content := range rec.Header.Agents {
+		if errMkdir := os.MkdirAll(agentDir, 0755); errMkdir != nil {
+			return replayResult{}, fmt.Errorf("creating agent directory: %w", errMkdir)
+		}
+		if errWrite := os.WriteFile(filepath.Join(agentDir, filepath.Base(name)+".md"), []byte(content), 0644); errWrite != nil {
+			return replayResult{}, fmt.Errorf("writing agent definition: %w", errWrite)
+		}
+	}
+	metadata, errParse := parseYAMLFrontmatterFromFile(goalPath)
+	if errParse != nil {
+		return replayResult{}, fmt.Errorf("parsing recorded GOAL.md: %w", errParse)
+	}
+	config, errConfig := loadProjectConfig(dir)
+	if errConfig != nil {
+		return replayResult{}, errConfig
+	}
+	applyConfigDefaults(config, &metadata)
+
+	coord := state.NewCoordinatorEmpty(filepath.Join(dir, ".sgai", "state.json"))
+	replayCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	player := &replayer{records: slices.Clone(rec.Iterations), cancel: cancel}
+
+	runner := &workflowRunner{
+		dir:        dir,
+		goalPath:   goalPath,
+		coord:      coord,
+		metadata:   metadata,
+		config:     config,
+		fallback:   newModelFallback(metadata.Model, metadata.FallbackModels),
+		wfState:    rec.Header.InitialState,
+		paddedsgai: filepath.Base(dir) + "][sgai",
+		logWriter:  io.Discard,
+		execute:    player.execute,
+		wait:       func(context.Context, time.Duration) {},
+	}
+	runner.run(replayCtx)
+
+	if player.next < len(player.records) {
+		player.divergences = append(player.divergences, fmt.Sprintf("runner stopped after %d of %d recorded iterations", player.next, len(player.records)))
+	}
+	return replayResult{
+		Final:       coord.State(),
+		Iterations:  player.iterations,
+		Divergences: player.divergences,
+	}, nil
+}
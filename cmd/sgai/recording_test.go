@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/sandgardenhq/sgai/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const recordingTestGoal = "---\nagents:\n  - coordinator\n  - backend-go-developer\n---\n# Build the thing\n"
+
+func scriptedExecutor(steps []state.Workflow) agentExecutor {
+	next := 0
+	return func(_ context.Context, cfg agentRunConfig, _ []string, _, _ string, _ *ringWriter, wfState state.Workflow, _ string) (state.Workflow, string, agentFailureKind, *state.Workflow) {
+		if next >= len(steps) {
+			return state.Workflow{}, "", failureOther, &wfState
+		}
+		step := steps[next]
+		next++
+		if errUpdate := cfg.coord.UpdateState(func(wf *state.Workflow) {
+			*wf = step
+		}); errUpdate != nil {
+			panic(errUpdate)
+		}
+		return cfg.coord.State(), "ses_" + string(rune('a'+next)), failureNone, nil
+	}
+}
+
+func recordScriptedRun(t *testing.T, steps []state.Workflow) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "GOAL.md"), []byte(recordingTestGoal), 0644))
+	recordingFile := filepath.Join(t.TempDir(), "run.jsonl")
+	t.Setenv(recordEnvVar, recordingFile)
+
+	runner, cleanup, ok := buildWorkflowRunner(dir, "", nil, nil)
+	require.True(t, ok)
+	recorder := recorderFor(dir)
+	require.NotNil(t, recorder)
+	runner.retroDir = ""
+	runner.execute = recorder.wrap(scriptedExecutor(steps))
+	runner.run(context.Background())
+	cleanup()
+	assert.Nil(t, recorderFor(dir))
+	return recordingFile
+}
+
+func pendingTodosRun() []state.Workflow {
+	pending := []state.TodoItem{{ID: "1", Content: "write tests", Status: "pending", Priority: "high"}}
+	done := []state.TodoItem{{ID: "1", Content: "write tests", Status: "completed", Priority: "high"}}
+	return []state.Workflow{
+		{Status: state.StatusWorking, Task: "planning", Todos: pending},
+		{Status: state.StatusComplete, Task: "done early", Todos: pending},
+		{Status: state.StatusWorking, Task: "finishing", Todos: done},
+		{Status: state.StatusComplete, Task: "shipped", Todos: done},
+	}
+}
+
+func TestRecordingPath(t *testing.T) {
+	cases := []struct {
+		name     string
+		envValue string
+		want     string
+	}{
+		{"disabled", "", ""},
+		{"enabledFlag", "1", filepath.Join("ws", ".sgai", "recordings", "2026-01-02-03-04-05.jsonl")},
+		{"enabledTrue", "true", filepath.Join("ws", ".sgai", "recordings", "2026-01-02-03-04-05.jsonl")},
+		{"explicitPath", "/tmp/run.jsonl", "/tmp/run.jsonl"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+			assert.Equal(t, tc.want, recordingPath("ws", tc.envValue, now))
+		})
+	}
+}
+
+func TestRecordAndReplayCompletionBlockedByTodos(t *testing.T) {
+	recordingFile := recordScriptedRun(t, pendingTodosRun())
+
+	rec, errLoad := loadRecording(recordingFile)
+	require.NoError(t, errLoad)
+	assert.Equal(t, recordingFormatVersion, rec.Header.Version)
+	assert.Equal(t, recordingTestGoal, rec.Header.Goal)
+	require.Len(t, rec.Iterations, 4)
+	assert.Equal(t, "coordinator", rec.Iterations[0].Agent)
+	assert.Contains(t, rec.Iterations[0].Message, "backend-go-developer")
+	assert.Equal(t, state.StatusWorking, rec.Iterations[2].StateBefore.Status, "completion with pending todos is turned back into working")
+
+	result, errReplay := replayRecording(context.Background(), t.TempDir(), rec)
+	require.NoError(t, errReplay)
+	assert.Empty(t, result.Divergences)
+	require.Len(t, result.Iterations, 4)
+	assert.Equal(t, state.StatusComplete, result.Final.Status)
+	assert.Equal(t, "shipped", result.Final.Task)
+}
+
+func TestReplayReportsDivergences(t *testing.T) {
+	recordingFile := recordScriptedRun(t, pendingTodosRun())
+	rec, errLoad := loadRecording(recordingFile)
+	require.NoError(t, errLoad)
+
+	t.Run("changedGoal", func(t *testing.T) {
+		changed := rec
+		changed.Header.Goal = strings.Replace(rec.Header.Goal, "  - backend-go-developer\n", "  - react-developer\n", 1)
+		result, errReplay := replayRecording(context.Background(), t.TempDir(), changed)
+		require.NoError(t, errReplay)
+		require.NotEmpty(t, result.Divergences)
+		assert.Contains(t, result.Divergences[0], "iteration 1: message differs")
+	})
+
+	t.Run("truncatedRecording", func(t *testing.T) {
+		truncated := rec
+		truncated.Iterations = rec.Iterations[:2]
+		result, errReplay := replayRecording(context.Background(), t.TempDir(), truncated)
+		require.NoError(t, errReplay)
+		require.NotEmpty(t, result.Divergences)
+		assert.Contains(t, result.Divergences[len(result.Divergences)-1], "after the recording ended")
+	})
+}
+
+func TestParseRecordingErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"empty", "", errEmptyRecording.Error()},
+		{"badHeader", "not json\n", "parsing recording header"},
+		{"badIteration", `{"version":1}` + "\n{\n", "parsing recording iteration 1"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, errParse := parseRecording(strings.NewReader(tc.content))
+			require.Error(t, errParse)
+			assert.Contains(t, errParse.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestToolCallRecordingMiddleware(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "GOAL.md"), []byte(recordingTestGoal), 0644))
+	recordingFile := filepath.Join(t.TempDir(), "run.jsonl")
+	recorder, errStart := startRunRecorder(dir, recordingFile, state.Workflow{}, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	require.NoError(t, errStart)
+
+	coord, errCoord := state.NewCoordinatorWith(filepath.Join(dir, ".sgai", "state.json"), state.Workflow{})
+	require.NoError(t, errCoord)
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set(sgaiAgentIdentityHeader, "builder|")
+	server := buildMCPServer(dir, r, coord)
+
+	ct, st := mcp.NewInMemoryTransports()
+	_, errConnect := server.Connect(context.Background(), st, nil)
+	require.NoError(t, errConnect)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client"}, nil)
+	cs, errClient := client.Connect(context.Background(), ct, nil)
+	require.NoError(t, errClient)
+	t.Cleanup(func() { _ = cs.Close() })
+
+	_, errCall := cs.CallTool(context.Background(), &mcp.CallToolParams{Name: "find_skills", Arguments: map[string]any{"name": "nothing-matches"}})
+	require.NoError(t, errCall)
+
+	recorder.recordIteration(iterationRecord{Agent: "coordinator"})
+	recorder.close(dir)
+
+	rec, errLoad := loadRecording(recordingFile)
+	require.NoError(t, errLoad)
+	require.Len(t, rec.Iterations, 1)
+	require.Len(t, rec.Iterations[0].ToolCalls, 1)
+	call := rec.Iterations[0].ToolCalls[0]
+	assert.Equal(t, "builder", call.Agent)
+	assert.Equal(t, "find_skills", call.Tool)
+	assert.JSONEq(t, `{"name":"nothing-matches"}`, string(call.Args))
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"io"
+	"log"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+)
+
+const (
+	redactionEntropyName      = "high-entropy"
+	redactionPrivateKeyName   = "private-key"
+	redactionEntropyMinLength = 32
+	// redactionEntropyThreshold sits above the 4 bits per character that hex
+	// digests can reach, so commit and content hashes stay readable.
+	redactionEntropyThreshold = 4.3
+)
+
+// redactionConfig is the redaction section of sgai.json.
+type redactionConfig struct {
+	Patterns []redactionPatternConfig `json:"patterns,omitempty"`
+	Entropy  *bool                    `json:"entropy,omitempty"`
+}
+
+type redactionPatternConfig struct {
+	Name  string `json:"name"`
+	Regex string `json:"regex"`
+}
+
+// redactionDetector replaces every match of pattern. With keepContext, the
+// first capture group is kept before the marker and the second after it.
+type redactionDetector struct {
+	name        string
+	pattern     *regexp.Regexp
+	keepContext bool
+}
+
+func builtinRedactionDetectors() []redactionDetector {
+	return []redactionDetector{
+		{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), false},
+		{"aws-secret-key", regexp.MustCompile(`(?i)(aws_secret_access_key["']?\s*[:=]\s*["']?)[A-Za-z0-9/+=]{40}`), true},
+		{"gcp-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`), false},
+		{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`), false},
+		{"slack-token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`), false},
+		{"api-key", regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}\b`), false},
+		{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`), false},
+		{"bearer-token", regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9._~+/-]{16,}=*`), true},
+		{"basic-auth-url", regexp.MustCompile(`(\b[a-z][a-z0-9+.-]*://[^/\s:@]+:)[^/\s@]+(@)`), true},
+	}
+}
+
+var (
+	privateKeyBeginPattern = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+	privateKeyEndPattern   = regexp.MustCompile(`-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+	entropyCandidate       = regexp.MustCompile(`[A-Za-z0-9+/_=-]{32,}`)
+)
+
+// projectRedactionConfig returns the redaction section of the workspace
+// sgai.json, or nil when there is none.
+func projectRedactionConfig(workspacePath string) *redactionConfig {
+	config, errLoad := loadProjectConfig(workspacePath)
+	if errLoad != nil {
+		log.Println("cannot load sgai.json redaction settings:", errLoad)
+		return nil
+	}
+	if config == nil {
+		return nil
+	}
+	return config.Redaction
+}
+
+// redactor scrubs credentials from captured output and counts what it
+// removed per detector.
+type redactor struct {
+	detectors []redactionDetector
+	entropy   bool
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// newRedactor combines the built-in detectors with the custom patterns of
+// config. Invalid custom patterns are logged and skipped.
+func newRedactor(config *redactionConfig) *redactor {
+	r := &redactor{detectors: builtinRedactionDetectors(), entropy: true, counts: map[string]int{}}
+	if config == nil {
+		return r
+	}
+	if config.Entropy != nil {
+		r.entropy = *config.Entropy
+	}
+	for _, custom := range config.Patterns {
+		pattern, errCompile := regexp.Compile(custom.Regex)
+		if errCompile != nil {
+			log.Println("skipping invalid redaction pattern", custom.Name+":", errCompile)
+			continue
+		}
+		r.detectors = append(r.detectors, redactionDetector{name: cmp.Or(custom.Name, "custom"), pattern: pattern})
+	}
+	return r
+}
+
+// redactLine scrubs one line. inPrivateKey carries PEM block state across
+// lines of the same stream.
+func (r *redactor) redactLine(line string, inPrivateKey *bool) string {
+	if *inPrivateKey || privateKeyBeginPattern.MatchString(line) {
+		if !*inPrivateKey {
+			r.count(redactionPrivateKeyName, 1)
+		}
+		*inPrivateKey = !privateKeyEndPattern.MatchString(line)
+		return redactionMarker(redactionPrivateKeyName)
+	}
+
+	for _, detector := range r.detectors {
+		line = detector.pattern.ReplaceAllStringFunc(line, func(match string) string {
+			r.count(detector.name, 1)
+			if !detector.keepContext {
+				return redactionMarker(detector.name)
+			}
+			sub := detector.pattern.FindStringSubmatch(match)
+			var prefix, suffix string
+			if len(sub) > 1 {
+				prefix = sub[1]
+			}
+			if len(sub) > 2 {
+				suffix = sub[2]
+			}
+			return prefix + redactionMarker(detector.name) + suffix
+		})
+	}
+
+	if r.entropy {
+		line = entropyCandidate.ReplaceAllStringFunc(line, func(match string) string {
+			if !looksLikeSecret(match) {
+				return match
+			}
+			r.count(redactionEntropyName, 1)
+			return redactionMarker(redactionEntropyName)
+		})
+	}
+	return line
+}
+
+func redactionMarker(name string) string {
+	return "[redacted:" + name + "]"
+}
+
+// looksLikeSecret reports whether token mixes letters and digits and is
+// random enough to be a key rather than a word, path or hash.
+func looksLikeSecret(token string) bool {
+	if len(token) < redactionEntropyMinLength || strings.Contains(token, "[redacted:") {
+		return false
+	}
+	hasLetter := strings.ContainsFunc(token, func(c rune) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') })
+	hasDigit := strings.ContainsFunc(token, func(c rune) bool { return c >= '0' && c <= '9' })
+	return hasLetter && hasDigit && shannonEntropy(token) >= redactionEntropyThreshold
+}
+
+func shannonEntropy(s string) float64 {
+	freq := make(map[rune]int)
+	for _, c := range s {
+		freq[c]++
+	}
+	total := float64(len(s))
+	var entropy float64
+	for _, n := range freq {
+		p := float64(n) / total
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+func (r *redactor) count(name string, n int) {
+	r.mu.Lock()
+	r.counts[name] += n
+	r.mu.Unlock()
+}
+
+// drain returns the counts gathered since the previous call and resets them.
+func (r *redactor) drain() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := r.counts
+	r.counts = map[string]int{}
+	return counts
+}
+
+// uncounted returns a redactor with the same detectors whose matches are not
+// added to r's counts, for re-printing output that was already counted.
+func (r *redactor) uncounted() *redactor {
+	if r == nil {
+		return nil
+	}
+	return &redactor{detectors: r.detectors, entropy: r.entropy, counts: map[string]int{}}
+}
+
+// totals returns the counts gathered so far without resetting them.
+func (r *redactor) totals() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return maps.Clone(r.counts)
+}
+
+// redactingWriter redacts every line written to it before forwarding it.
+// Partial lines are held until their newline arrives, or until Flush, so a
+// credential split across two writes is still matched.
+type redactingWriter struct {
+	r            *redactor
+	w            io.Writer
+	inPrivateKey bool
+	pending      []byte
+}
+
+func (rw *redactingWriter) Write(data []byte) (int, error) {
+	rw.pending = append(rw.pending, data...)
+	idx := bytes.LastIndexByte(rw.pending, '\n')
+	if idx == -1 {
+		return len(data), nil
+	}
+	complete := string(rw.pending[:idx+1])
+	rw.pending = slices.Clone(rw.pending[idx+1:])
+	if _, errWrite := io.WriteString(rw.w, rw.r.redactText(complete, &rw.inPrivateKey)); errWrite != nil {
+		return 0, errWrite
+	}
+	return len(data), nil
+}
+
+// Flush writes a trailing partial line, if any.
+func (rw *redactingWriter) Flush() error {
+	if len(rw.pending) == 0 {
+		return nil
+	}
+	text := string(rw.pending)
+	rw.pending = nil
+	_, errWrite := io.WriteString(rw.w, rw.r.redactText(text, &rw.inPrivateKey))
+	return errWrite
+}
+
+// redactText redacts text line by line, keeping its line endings.
+func (r *redactor) redactText(text string, inPrivateKey *bool) string {
+	var buf strings.Builder
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		content, newline := strings.CutSuffix(line, "\n")
+		buf.WriteString(r.redactLine(content, inPrivateKey))
+		if newline {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.String()
+}
+
+// recordRedactions adds counts to the session totals in the workflow state.
+func recordRedactions(coord *state.Coordinator, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	if errUpdate := coord.UpdateState(func(wf *state.Workflow) {
+		if wf.Redactions == nil {
+			wf.Redactions = map[string]int{}
+		}
+		for name, n := range counts {
+			wf.Redactions[name] += n
+		}
+	}); errUpdate != nil {
+		log.Println("failed to record redaction counts:", errUpdate)
+	}
+}
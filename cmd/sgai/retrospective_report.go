@@ -0,0 +1,581 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+)
+
+var errUnknownRetroFormat = errors.New("unknown report format")
+
+const (
+	retroFormatMarkdown = "markdown"
+	retroFormatHTML     = "html"
+	retroFormatJSON     = "json"
+)
+
+const (
+	retroDirTimeLayout = "2006-01-02-15-04"
+	maxRetroThemes     = 10
+	maxThemeExamples   = 3
+)
+
+var sessionExportNamePattern = regexp.MustCompile(`^(\d{4})-(.+)-(\d{14})\.json$`)
+
+// retroRun summarizes one .sgai/retrospectives/<timestamp.suffix> directory.
+type retroRun struct {
+	Name            string        `json:"name"`
+	Started         string        `json:"started,omitempty"`
+	Ended           string        `json:"ended,omitempty"`
+	DurationSeconds int64         `json:"durationSeconds"`
+	Status          string        `json:"status,omitempty"`
+	Iterations      int           `json:"iterations"`
+	GateFailures    int           `json:"gateFailures"`
+	Questions       int           `json:"questions"`
+	Blockers        int           `json:"blockers"`
+	Agents          []string      `json:"agents"`
+	Tokens          tokenUsageRow `json:"tokens"`
+
+	started    time.Time
+	blockers   []ledgerEntry
+	agentUsage map[string]*retroAgentSummary
+}
+
+type retroAgentSummary struct {
+	Agent      string        `json:"agent"`
+	Runs       int           `json:"runs"`
+	Iterations int           `json:"iterations"`
+	Tokens     tokenUsageRow `json:"tokens"`
+}
+
+type retroTheme struct {
+	Keyword     string   `json:"keyword"`
+	Runs        int      `json:"runs"`
+	Occurrences int      `json:"occurrences"`
+	Examples    []string `json:"examples"`
+}
+
+type retroTotals struct {
+	Runs            int           `json:"runs"`
+	DurationSeconds int64         `json:"durationSeconds"`
+	Iterations      int           `json:"iterations"`
+	GateFailures    int           `json:"gateFailures"`
+	Questions       int           `json:"questions"`
+	Blockers        int           `json:"blockers"`
+	Tokens          tokenUsageRow `json:"tokens"`
+}
+
+type retroReport struct {
+	Workspace string              `json:"workspace"`
+	Generated string              `json:"generated"`
+	Runs      []retroRun          `json:"runs"`
+	Totals    retroTotals         `json:"totals"`
+	Agents    []retroAgentSummary `json:"agents"`
+	Themes    []retroTheme        `json:"themes"`
+}
+
+type sessionExport struct {
+	Messages []struct {
+		Info struct {
+			Role       string `json:"role"`
+			ModelID    string `json:"modelID"`
+			ProviderID string `json:"providerID"`
+			Tokens     struct {
+				Input     int64 `json:"input"`
+				Output    int64 `json:"output"`
+				Reasoning int64 `json:"reasoning"`
+				Cache     struct {
+					Read  int64 `json:"read"`
+					Write int64 `json:"write"`
+				} `json:"cache"`
+			} `json:"tokens"`
+		} `json:"info"`
+		Parts []struct {
+			Type  string `json:"type"`
+			Tool  string `json:"tool"`
+			State struct {
+				Input json.RawMessage `json:"input"`
+			} `json:"state"`
+		} `json:"parts"`
+	} `json:"messages"`
+}
+
+func resolveRetrospectivesDir(path string) string {
+	candidate := filepath.Join(path, ".sgai", "retrospectives")
+	if info, errStat := os.Stat(candidate); errStat == nil && info.IsDir() {
+		return candidate
+	}
+	return path
+}
+
+func buildRetroReport(workspacePath, userConfigDir string, window usageTimeRange, now time.Time) (retroReport, error) {
+	report := retroReport{
+		Workspace: filepath.Base(workspacePath),
+		Generated: now.UTC().Format(time.RFC3339),
+		Runs:      []retroRun{},
+		Agents:    []retroAgentSummary{},
+		Themes:    []retroTheme{},
+	}
+
+	pricing, errPricing := loadPricingTable(userConfigDir, workspacePath)
+	if errPricing != nil {
+		return retroReport{}, errPricing
+	}
+
+	retrosDir := resolveRetrospectivesDir(workspacePath)
+	dirEntries, errRead := os.ReadDir(retrosDir)
+	if errRead != nil {
+		if os.IsNotExist(errRead) {
+			return report, nil
+		}
+		return retroReport{}, fmt.Errorf("reading retrospectives: %w", errRead)
+	}
+
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		run, errRun := loadRetroRun(filepath.Join(retrosDir, dirEntry.Name()), pricing)
+		if errRun != nil {
+			log.Println("skipping retrospective", dirEntry.Name()+":", errRun)
+			continue
+		}
+		if !window.isZero() && (run.started.IsZero() || !window.contains(run.started)) {
+			continue
+		}
+		report.Runs = append(report.Runs, run)
+	}
+	slices.SortStableFunc(report.Runs, func(a, b retroRun) int {
+		return cmp.Or(a.started.Compare(b.started), cmp.Compare(a.Name, b.Name))
+	})
+
+	summarizeRetroRuns(&report)
+	return report, nil
+}
+
+func loadRetroRun(dir string, pricing pricingTable) (retroRun, error) {
+	run := retroRun{Name: filepath.Base(dir), Agents: []string{}, agentUsage: make(map[string]*retroAgentSummary)}
+
+	dirEntries, errRead := os.ReadDir(dir)
+	if errRead != nil {
+		return retroRun{}, errRead
+	}
+
+	var ended time.Time
+	iterations := make(map[int]bool)
+	agents := make(map[string]bool)
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			continue
+		}
+		if info, errInfo := dirEntry.Info(); errInfo == nil {
+			ended = latestTime(ended, info.ModTime())
+			if run.started.IsZero() || info.ModTime().Before(run.started) {
+				run.started = info.ModTime()
+			}
+		}
+		matches := sessionExportNamePattern.FindStringSubmatch(dirEntry.Name())
+		if matches == nil {
+			continue
+		}
+		iteration, _ := strconv.Atoi(matches[1])
+		iterations[iteration] = true
+		agent := matches[2]
+		agents[agent] = true
+		usage, exists := run.agentUsage[agent]
+		if !exists {
+			usage = &retroAgentSummary{Agent: agent}
+			run.agentUsage[agent] = usage
+		}
+		usage.Iterations++
+		rows, questions, errExport := readSessionExportUsage(filepath.Join(dir, dirEntry.Name()), agent)
+		if errExport != nil {
+			log.Println("skipping session export", dirEntry.Name()+":", errExport)
+			continue
+		}
+		run.Questions += questions
+		for _, row := range rows {
+			cost, priced := pricing.cost(row)
+			row.Cost = cost
+			row.Unpriced = !priced
+			run.Tokens.add(row)
+			usage.Tokens.add(row)
+		}
+	}
+	run.Iterations = len(iterations)
+
+	if started, errParse := time.ParseInLocation(retroDirTimeLayout, strings.SplitN(run.Name, ".", 2)[0], time.Local); errParse == nil {
+		run.started = started
+	}
+
+	if wf, errState := readRetroState(dir); errState == nil {
+		run.Status = wf.Status
+		for _, entry := range wf.Progress {
+			if timestamp, errParse := time.Parse(time.RFC3339, entry.Timestamp); errParse == nil {
+				ended = latestTime(ended, timestamp)
+			}
+			if entry.Agent != "" && entry.Agent != "continuous-mode" {
+				agents[entry.Agent] = true
+			}
+		}
+	}
+
+	entries, errLedger := readRetroLedger(dir)
+	if errLedger != nil {
+		return retroRun{}, errLedger
+	}
+	for _, entry := range entries {
+		switch entry.Type {
+		case ledgerGateFailure:
+			run.GateFailures++
+			run.blockers = append(run.blockers, entry)
+		case ledgerBlocker:
+			run.Blockers++
+			run.blockers = append(run.blockers, entry)
+		case ledgerQuestion:
+			run.Questions++
+		}
+	}
+
+	for agent := range agents {
+		run.Agents = append(run.Agents, agent)
+	}
+	slices.Sort(run.Agents)
+
+	if !run.started.IsZero() {
+		run.Started = run.started.UTC().Format(time.RFC3339)
+	}
+	if !ended.IsZero() {
+		run.Ended = ended.UTC().Format(time.RFC3339)
+	}
+	if !run.started.IsZero() && ended.After(run.started) {
+		run.DurationSeconds = int64(ended.Sub(run.started) / time.Second)
+	}
+	return run, nil
+}
+
+func latestTime(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+func readRetroState(dir string) (state.Workflow, error) {
+	data, errRead := os.ReadFile(filepath.Join(dir, "state.json"))
+	if errRead != nil {
+		return state.Workflow{}, errRead
+	}
+	var wf state.Workflow
+	if errJSON := json.Unmarshal(data, &wf); errJSON != nil {
+		return state.Workflow{}, fmt.Errorf("parsing state.json: %w", errJSON)
+	}
+	return wf, nil
+}
+
+func readRetroLedger(dir string) ([]ledgerEntry, error) {
+	content, errRead := os.ReadFile(filepath.Join(dir, "PROJECT_MANAGEMENT.md"))
+	if errRead != nil {
+		if os.IsNotExist(errRead) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading PROJECT_MANAGEMENT.md: %w", errRead)
+	}
+	return parseProjectManagementLedger(string(content)), nil
+}
+
+// readSessionExportUsage returns per-model token rows for one exported
+// opencode session and the number of questions put to the human partner.
+func readSessionExportUsage(path, agent string) ([]tokenUsageRow, int, error) {
+	data, errRead := os.ReadFile(path)
+	if errRead != nil {
+		return nil, 0, errRead
+	}
+	if start := bytes.IndexByte(data, '{'); start > 0 {
+		data = data[start:]
+	}
+	var export sessionExport
+	if errJSON := json.Unmarshal(data, &export); errJSON != nil {
+		return nil, 0, fmt.Errorf("parsing session export: %w", errJSON)
+	}
+
+	var rows []tokenUsageRow
+	index := make(map[string]int)
+	questions := 0
+	for _, message := range export.Messages {
+		for _, part := range message.Parts {
+			if part.Type == "tool" && isAskUserTool(part.Tool) {
+				questions += countAskedQuestions(part.State.Input)
+			}
+		}
+		if message.Info.Role != "assistant" {
+			continue
+		}
+		model := message.Info.ModelID
+		if message.Info.ProviderID != "" {
+			model = message.Info.ProviderID + "/" + model
+		}
+		i, exists := index[model]
+		if !exists {
+			i = len(rows)
+			index[model] = i
+			rows = append(rows, tokenUsageRow{Agent: agent, Model: model, SessionCount: 1})
+		}
+		tokens := message.Info.Tokens
+		row := &rows[i]
+		row.Input += tokens.Input
+		row.Output += tokens.Output
+		row.Reasoning += tokens.Reasoning
+		row.Other += tokens.Reasoning
+		row.CacheRead += tokens.Cache.Read
+		row.CacheWrite += tokens.Cache.Write
+		row.Total += tokens.Input + tokens.Output + tokens.Reasoning + tokens.Cache.Read + tokens.Cache.Write
+	}
+	return rows, questions, nil
+}
+
+func isAskUserTool(tool string) bool {
+	return strings.HasSuffix(tool, "ask_user_question") || strings.HasSuffix(tool, "ask_user_work_gate")
+}
+
+func countAskedQuestions(input json.RawMessage) int {
+	var args struct {
+		Questions []json.RawMessage `json:"questions"`
+	}
+	if errJSON := json.Unmarshal(input, &args); errJSON == nil && len(args.Questions) > 0 {
+		return len(args.Questions)
+	}
+	return 1
+}
+
+func summarizeRetroRuns(report *retroReport) {
+	agentIndex := make(map[string]int)
+	for _, run := range report.Runs {
+		report.Totals.Runs++
+		report.Totals.DurationSeconds += run.DurationSeconds
+		report.Totals.Iterations += run.Iterations
+		report.Totals.GateFailures += run.GateFailures
+		report.Totals.Questions += run.Questions
+		report.Totals.Blockers += run.Blockers
+		report.Totals.Tokens.add(run.Tokens)
+		for _, agent := range run.Agents {
+			i, exists := agentIndex[agent]
+			if !exists {
+				i = len(report.Agents)
+				agentIndex[agent] = i
+				report.Agents = append(report.Agents, retroAgentSummary{Agent: agent})
+			}
+			report.Agents[i].Runs++
+			if usage, ok := run.agentUsage[agent]; ok {
+				report.Agents[i].Iterations += usage.Iterations
+				report.Agents[i].Tokens.add(usage.Tokens)
+			}
+		}
+	}
+	slices.SortStableFunc(report.Agents, func(a, b retroAgentSummary) int {
+		return cmp.Or(cmp.Compare(b.Runs, a.Runs), cmp.Compare(a.Agent, b.Agent))
+	})
+	report.Themes = recurringBlockerThemes(report.Runs)
+}
+
+var themeStopwords = map[string]bool{
+	"about": true, "after": true, "again": true, "agent": true, "before": true,
+	"being": true, "blocked": true, "blocker": true, "cannot": true, "could": true,
+	"failed": true, "failure": true, "from": true, "have": true, "into": true,
+	"items": true, "marking": true, "more": true, "need": true, "needs": true,
+	"please": true, "should": true, "still": true, "that": true, "their": true,
+	"them": true, "then": true, "there": true, "these": true, "this": true,
+	"until": true, "when": true, "which": true, "while": true, "will": true,
+	"with": true, "without": true, "would": true, "your": true,
+}
+
+// recurringBlockerThemes returns keywords that show up in blocker and
+// gate-failure entries of more than one run, most widespread first.
+func recurringBlockerThemes(runs []retroRun) []retroTheme {
+	themes := make(map[string]*retroTheme)
+	for _, run := range runs {
+		seen := make(map[string]bool)
+		for _, entry := range run.blockers {
+			keywords := make(map[string]bool)
+			for _, word := range themeKeywords(entry.Title + "\n" + entry.Body) {
+				keywords[word] = true
+			}
+			for word := range keywords {
+				theme, exists := themes[word]
+				if !exists {
+					theme = &retroTheme{Keyword: word, Examples: []string{}}
+					themes[word] = theme
+				}
+				theme.Occurrences++
+				if !seen[word] {
+					seen[word] = true
+					theme.Runs++
+				}
+				if len(theme.Examples) < maxThemeExamples && !slices.Contains(theme.Examples, entry.Title) {
+					theme.Examples = append(theme.Examples, entry.Title)
+				}
+			}
+		}
+	}
+
+	result := []retroTheme{}
+	for _, theme := range themes {
+		if theme.Runs >= 2 {
+			result = append(result, *theme)
+		}
+	}
+	slices.SortFunc(result, func(a, b retroTheme) int {
+		return cmp.Or(cmp.Compare(b.Runs, a.Runs), cmp.Compare(b.Occurrences, a.Occurrences), cmp.Compare(a.Keyword, b.Keyword))
+	})
+	if len(result) > maxRetroThemes {
+		result = result[:maxRetroThemes]
+	}
+	return result
+}
+
+func themeKeywords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+	})
+	var keywords []string
+	for _, word := range words {
+		word = strings.Trim(word, "-_")
+		if len(word) < 4 || themeStopwords[word] || strings.IndexFunc(word, unicode.IsLetter) < 0 {
+			continue
+		}
+		keywords = append(keywords, word)
+	}
+	return keywords
+}
+
+func writeRetroReport(w io.Writer, report retroReport, format string) error {
+	switch format {
+	case retroFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case retroFormatMarkdown:
+		_, errWrite := io.WriteString(w, renderRetroMarkdown(report))
+		return errWrite
+	case retroFormatHTML:
+		return retroHTMLTemplate.Execute(w, report)
+	default:
+		return fmt.Errorf("%w %q", errUnknownRetroFormat, format)
+	}
+}
+
+func formatRetroDuration(seconds int64) string {
+	if seconds <= 0 {
+		return "-"
+	}
+	return (time.Duration(seconds) * time.Second).String()
+}
+
+func renderRetroMarkdown(report retroReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Retrospective report: %s\n\n", report.Workspace)
+	fmt.Fprintf(&b, "Generated %s from %d run(s).\n\n", report.Generated, report.Totals.Runs)
+
+	b.WriteString("## Totals\n\n")
+	b.WriteString("| Runs | Duration | Iterations | Gate failures | Questions | Blockers | Tokens | Cost |\n")
+	b.WriteString("|---:|---:|---:|---:|---:|---:|---:|---:|\n")
+	fmt.Fprintf(&b, "| %d | %s | %d | %d | %d | %d | %d | %s |\n\n",
+		report.Totals.Runs, formatRetroDuration(report.Totals.DurationSeconds), report.Totals.Iterations,
+		report.Totals.GateFailures, report.Totals.Questions, report.Totals.Blockers,
+		report.Totals.Tokens.Total, formatCost(report.Totals.Tokens))
+
+	b.WriteString("## Runs\n\n")
+	if len(report.Runs) == 0 {
+		b.WriteString("No retrospectives found.\n\n")
+	} else {
+		b.WriteString("| Run | Started | Duration | Status | Iterations | Gate failures | Questions | Blockers | Tokens | Cost | Agents |\n")
+		b.WriteString("|---|---|---:|---|---:|---:|---:|---:|---:|---:|---|\n")
+		for _, run := range report.Runs {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %d | %d | %d | %d | %d | %s | %s |\n",
+				run.Name, cmp.Or(run.Started, "-"), formatRetroDuration(run.DurationSeconds), cmp.Or(run.Status, "-"),
+				run.Iterations, run.GateFailures, run.Questions, run.Blockers,
+				run.Tokens.Total, formatCost(run.Tokens), strings.Join(run.Agents, ", "))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(report.Agents) > 0 {
+		b.WriteString("## Agents\n\n")
+		b.WriteString("| Agent | Runs | Iterations | Tokens | Cost |\n")
+		b.WriteString("|---|---:|---:|---:|---:|\n")
+		for _, agent := range report.Agents {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %s |\n", agent.Agent, agent.Runs, agent.Iterations, agent.Tokens.Total, formatCost(agent.Tokens))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("## Recurring blocker themes\n\n")
+	if len(report.Themes) == 0 {
+		b.WriteString("No blocker themes recur across runs.\n")
+	} else {
+		for _, theme := range report.Themes {
+			fmt.Fprintf(&b, "- **%s**: %d runs, %d entries (e.g. %s)\n", theme.Keyword, theme.Runs, theme.Occurrences, strings.Join(theme.Examples, "; "))
+		}
+	}
+	return b.String()
+}
+
+var retroHTMLTemplate = template.Must(template.New("retro").Funcs(template.FuncMap{
+	"duration": formatRetroDuration,
+	"cost":     formatCost,
+	"join":     strings.Join,
+	"orDash": func(value string) string {
+		return cmp.Or(value, "-")
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Retrospective report: {{.Workspace}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; color: #1f2937; }
+table { border-collapse: collapse; margin-bottom: 1.5rem; }
+th, td { border: 1px solid #d1d5db; padding: 0.3rem 0.6rem; text-align: left; }
+td.num { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Retrospective report: {{.Workspace}}</h1>
+<p>Generated {{.Generated}} from {{.Totals.Runs}} run(s).</p>
+<h2>Totals</h2>
+<table>
+<tr><th>Runs</th><th>Duration</th><th>Iterations</th><th>Gate failures</th><th>Questions</th><th>Blockers</th><th>Tokens</th><th>Cost</th></tr>
+<tr><td class="num">{{.Totals.Runs}}</td><td class="num">{{duration .Totals.DurationSeconds}}</td><td class="num">{{.Totals.Iterations}}</td><td class="num">{{.Totals.GateFailures}}</td><td class="num">{{.Totals.Questions}}</td><td class="num">{{.Totals.Blockers}}</td><td class="num">{{.Totals.Tokens.Total}}</td><td class="num">{{cost .Totals.Tokens}}</td></tr>
+</table>
+<h2>Runs</h2>
+{{if .Runs}}<table>
+<tr><th>Run</th><th>Started</th><th>Duration</th><th>Status</th><th>Iterations</th><th>Gate failures</th><th>Questions</th><th>Blockers</th><th>Tokens</th><th>Cost</th><th>Agents</th></tr>
+{{range .Runs}}<tr><td>{{.Name}}</td><td>{{orDash .Started}}</td><td class="num">{{duration .DurationSeconds}}</td><td>{{orDash .Status}}</td><td class="num">{{.Iterations}}</td><td class="num">{{.GateFailures}}</td><td class="num">{{.Questions}}</td><td class="num">{{.Blockers}}</td><td class="num">{{.Tokens.Total}}</td><td class="num">{{cost .Tokens}}</td><td>{{join .Agents ", "}}</td></tr>
+{{end}}</table>{{else}}<p>No retrospectives found.</p>{{end}}
+{{if .Agents}}<h2>Agents</h2>
+<table>
+<tr><th>Agent</th><th>Runs</th><th>Iterations</th><th>Tokens</th><th>Cost</th></tr>
+{{range .Agents}}<tr><td>{{.Agent}}</td><td class="num">{{.Runs}}</td><td class="num">{{.Iterations}}</td><td class="num">{{.Tokens.Total}}</td><td class="num">{{cost .Tokens}}</td></tr>
+{{end}}</table>{{end}}
+<h2>Recurring blocker themes</h2>
+{{if .Themes}}<ul>
+{{range .Themes}}<li><strong>{{.Keyword}}</strong>: {{.Runs}} runs, {{.Occurrences}} entries (e.g. {{join .Examples "; "}})</li>
+{{end}}</ul>{{else}}<p>No blocker themes recur across runs.</p>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleSessionExport = `{
+  "info": {"id": "ses_1"},
+  "messages": [
+    {"info": {"role": "user"}, "parts": [{"type": "text"}]},
+    {
+      "info": {"role": "assistant", "providerID": "openai", "modelID": "gpt-5.5",
+        "tokens": {"input": 100, "output": 20, "reasoning": 5, "cache": {"read": 50, "write": 10}}},
+      "parts": [
+        {"type": "tool", "tool": "sgai_ask_user_question", "state": {"input": {"questions": [{"question": "a"}, {"question": "b"}]}}},
+        {"type": "tool", "tool": "sgai_ask_user_work_gate", "state": {"input": {}}}
+      ]
+    },
+    {
+      "info": {"role": "assistant", "providerID": "openai", "modelID": "gpt-5.5",
+        "tokens": {"input": 10, "output": 2, "reasoning": 0, "cache": {"read": 0, "write": 0}}},
+      "parts": []
+    }
+  ]
+}`
+
+func writeRetroRun(t *testing.T, retrosDir, name string, files map[string]string) string {
+	t.Helper()
+	dir := filepath.Join(retrosDir, name)
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	for fileName, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fileName), []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestReadSessionExportUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "0001-coordinator-20260301100000.json")
+	require.NoError(t, os.WriteFile(path, []byte("Exporting session: ses_1\n"+sampleSessionExport), 0o644))
+
+	rows, questions, err := readSessionExportUsage(path, "coordinator")
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, questions)
+	require.Len(t, rows, 1)
+	assert.Equal(t, tokenUsageRow{
+		Agent:        "coordinator",
+		Model:        "openai/gpt-5.5",
+		Input:        110,
+		Output:       22,
+		Reasoning:    5,
+		Other:        5,
+		CacheRead:    50,
+		CacheWrite:   10,
+		Total:        197,
+		SessionCount: 1,
+	}, rows[0])
+}
+
+func TestBuildRetroReport(t *testing.T) {
+	workspace := t.TempDir()
+	retrosDir := filepath.Join(workspace, ".sgai", "retrospectives")
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "sgai.json"), []byte(`{"pricing":{"openai/gpt-5.5":{"input":1000000,"output":1000000}}}`), 0o644))
+
+	writeRetroRun(t, retrosDir, "2026-03-01-10-00.aaaa", map[string]string{
+		"0001-coordinator-20260301100100.json":  sampleSessionExport,
+		"0002-go-developer-20260301101000.json": sampleSessionExport,
+		"state.json": `{"status":"complete","progress":[
+			{"timestamp":"2026-03-01T10:00:00Z","agent":"coordinator","description":"start"},
+			{"timestamp":"2099-03-01T10:30:00Z","agent":"go-developer","description":"done"}]}`,
+		"PROJECT_MANAGEMENT.md": "# PM\n\n## Completion Gate Failure (2026-03-01T10:20:00Z)\nmake test failed: flaky database migration\n\n## Blocked on database credentials\nmissing credentials\n",
+	})
+	writeRetroRun(t, retrosDir, "2026-03-02-10-00.bbbb", map[string]string{
+		"0001-coordinator-20260302100100.json": sampleSessionExport,
+		"PROJECT_MANAGEMENT.md":                "## Pending TODO Items (2026-03-02T10:10:00Z)\nthe database migration is unfinished\n\n## Question about scope\nAgent: coordinator\nwhich endpoints?\n",
+	})
+	writeRetroRun(t, retrosDir, "2025-01-01-10-00.cccc", map[string]string{
+		"PROJECT_MANAGEMENT.md": "## Old blocker\ndatabase\n",
+	})
+	require.NoError(t, os.MkdirAll(filepath.Join(retrosDir, "undated"), 0o755))
+
+	window, errWindow := parseUsageTimeRange("2026-01-01", "", time.Now())
+	require.NoError(t, errWindow)
+
+	report, err := buildRetroReport(workspace, t.TempDir(), window, time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC))
+
+	require.NoError(t, err)
+	require.Len(t, report.Runs, 2)
+
+	first := report.Runs[0]
+	assert.Equal(t, "2026-03-01-10-00.aaaa", first.Name)
+	assert.Equal(t, "complete", first.Status)
+	assert.Equal(t, 2, first.Iterations)
+	assert.Equal(t, 1, first.GateFailures)
+	assert.Equal(t, 1, first.Blockers)
+	assert.Equal(t, 6, first.Questions)
+	assert.Equal(t, []string{"coordinator", "go-developer"}, first.Agents)
+	assert.Equal(t, int64(394), first.Tokens.Total)
+	assert.InDelta(t, 2*(110+27), first.Tokens.Cost, 0.0001)
+	assert.Positive(t, first.DurationSeconds)
+
+	second := report.Runs[1]
+	assert.Equal(t, 1, second.Iterations)
+	assert.Equal(t, 1, second.Blockers)
+	assert.Equal(t, 4, second.Questions)
+
+	assert.Equal(t, 2, report.Totals.Runs)
+	assert.Equal(t, 3, report.Totals.Iterations)
+	assert.Equal(t, 10, report.Totals.Questions)
+	assert.Equal(t, int64(591), report.Totals.Tokens.Total)
+
+	require.Len(t, report.Agents, 2)
+	assert.Equal(t, retroAgentSummary{Agent: "coordinator", Runs: 2, Iterations: 2, Tokens: report.Agents[0].Tokens}, report.Agents[0])
+	assert.Equal(t, int64(394), report.Agents[0].Tokens.Total)
+	assert.Equal(t, "go-developer", report.Agents[1].Agent)
+
+	require.NotEmpty(t, report.Themes)
+	keywords := make([]string, 0, len(report.Themes))
+	for _, theme := range report.Themes {
+		keywords = append(keywords, theme.Keyword)
+	}
+	assert.Contains(t, keywords, "database")
+	assert.Contains(t, keywords, "migration")
+	assert.NotContains(t, keywords, "credentials")
+}
+
+func TestBuildRetroReportNoRetrospectives(t *testing.T) {
+	report, err := buildRetroReport(t.TempDir(), t.TempDir(), usageTimeRange{}, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, report.Runs)
+	assert.Equal(t, 0, report.Totals.Runs)
+}
+
+func TestWriteRetroReport(t *testing.T) {
+	report := retroReport{
+		Workspace: "demo",
+		Generated: "2026-03-03T00:00:00Z",
+		Runs:      []retroRun{{Name: "2026-03-01-10-00.aaaa", DurationSeconds: 90, Iterations: 2, Agents: []string{"coordinator"}}},
+		Totals:    retroTotals{Runs: 1, DurationSeconds: 90, Iterations: 2},
+		Agents:    []retroAgentSummary{{Agent: "coordinator", Runs: 1, Iterations: 2}},
+		Themes:    []retroTheme{{Keyword: "<database>", Runs: 2, Occurrences: 3, Examples: []string{"Blocked"}}},
+	}
+
+	tests := []struct {
+		name        string
+		format      string
+		wantContain []string
+		wantErr     bool
+	}{
+		{
+			name:        "markdown",
+			format:      retroFormatMarkdown,
+			wantContain: []string{"# Retrospective report: demo", "| 2026-03-01-10-00.aaaa |", "1m30s", "- **<database>**: 2 runs"},
+		},
+		{
+			name:        "html",
+			format:      retroFormatHTML,
+			wantContain: []string{"<h1>Retrospective report: demo</h1>", "&lt;database&gt;", "1m30s"},
+		},
+		{
+			name:        "json",
+			format:      retroFormatJSON,
+			wantContain: []string{`"workspace": "demo"`, `"occurrences": 3`},
+		},
+		{
+			name:    "unknown",
+			format:  "pdf",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := writeRetroReport(&buf, report, tt.format)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errUnknownRetroFormat)
+				return
+			}
+			require.NoError(t, err)
+			for _, want := range tt.wantContain {
+				assert.Contains(t, buf.String(), want)
+			}
+		})
+	}
+}
+
+func TestHandleAPIRetroReport(t *testing.T) {
+	server, rootDir := setupTestServer(t)
+	wsDir := setupTestWorkspace(t, rootDir, "retro-ws")
+	writeRetroRun(t, filepath.Join(wsDir, ".sgai", "retrospectives"), "2026-03-01-10-00.aaaa", map[string]string{
+		"0001-coordinator-20260301100100.json": sampleSessionExport,
+	})
+
+	w := serveHTTP(server, "GET", "/api/v1/workspaces/retro-ws/retrospectives/report", "")
+	require.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"2026-03-01-10-00.aaaa"`)
+
+	w = serveHTTP(server, "GET", "/api/v1/workspaces/retro-ws/retrospectives/report?format=markdown", "")
+	require.Equal(t, 200, w.Code)
+	assert.Equal(t, "text/markdown; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "# Retrospective report: retro-ws")
+
+	w = serveHTTP(server, "GET", "/api/v1/workspaces/retro-ws/retrospectives/report?format=pdf", "")
+	assert.Equal(t, 400, w.Code)
+
+	w = serveHTTP(server, "GET", "/api/v1/workspaces/retro-ws/retrospectives/report?since=bogus", "")
+	assert.Equal(t, 400, w.Code)
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	secretsFileName      = "secrets.enc"
+	secretsKeyFileName   = "secrets.key"
+	secretsPassphraseEnv = "SGAI_SECRETS_PASSPHRASE"
+
+	secretScopeWorkspace = "workspace"
+	secretScopeUser      = "user"
+
+	secretKeySourcePassphrase = "passphrase"
+	secretKeySourceKeyFile    = "keyfile"
+
+	secretsKDFIterations = 600_000
+	secretsFileVersion   = 1
+
+	// secretMinMaskLength keeps very short values from masking unrelated output.
+	secretMinMaskLength = 4
+)
+
+var (
+	errSecretsPassphraseRequired = errors.New("secrets file is encrypted with a passphrase: set " + secretsPassphraseEnv)
+	errSecretsDecrypt            = errors.New("cannot decrypt secrets: wrong passphrase or key file")
+	errInvalidSecretName         = errors.New("secret name must be an environment variable name (letters, digits and '_', not starting with a digit)")
+
+	secretNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// secretsConfig selects which stored secrets are injected into which
+// processes. Each entry is a secret name, exported under the same name, or
+// "ENV_NAME=secretName" to export it under another name.
+//
+// Agents is a single set for the whole workspace: every agent of a workflow
+// runs inside the coordinator's opencode process, so there is no way to give
+// one agent a secret without handing it to all of them.
+type secretsConfig struct {
+	Agents         []string            `json:"agents,omitempty"`
+	Actions        map[string][]string `json:"actions,omitempty"`
+	CompletionGate []string            `json:"completionGate,omitempty"`
+}
+
+// secretsEnvelope is the on-disk form of an encrypted secrets file.
+type secretsEnvelope struct {
+	Version    int    `json:"version"`
+	KeySource  string `json:"keySource"`
+	Salt       string `json:"salt,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+func secretsFilePath(scope, userConfigDir, workspacePath string) (string, error) {
+	switch scope {
+	case secretScopeUser:
+		return filepath.Join(userConfigDir, secretsFileName), nil
+	case secretScopeWorkspace:
+		return filepath.Join(workspacePath, ".sgai", secretsFileName), nil
+	default:
+		return "", fmt.Errorf("unknown secrets scope %q: use %s or %s", scope, secretScopeWorkspace, secretScopeUser)
+	}
+}
+
+// loadSecretsFile decrypts a secrets file. A missing file is an empty store.
+func loadSecretsFile(path, userConfigDir string) (map[string]string, error) {
+	data, errRead := os.ReadFile(path)
+	if errRead != nil {
+		if os.IsNotExist(errRead) {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("reading secrets: %w", errRead)
+	}
+	var envelope secretsEnvelope
+	if errJSON := json.Unmarshal(data, &envelope); errJSON != nil {
+		return nil, fmt.Errorf("parsing secrets file %s: %w", path, errJSON)
+	}
+	if envelope.Version != secretsFileVersion {
+		return nil, fmt.Errorf("unsupported secrets file version %d in %s", envelope.Version, path)
+	}
+
+	key, errKey := secretsKeyFor(envelope, userConfigDir, false)
+	if errKey != nil {
+		return nil, errKey
+	}
+	nonce, errNonce := base64.StdEncoding.DecodeString(envelope.Nonce)
+	ciphertext, errCiphertext := base64.StdEncoding.DecodeString(envelope.Ciphertext)
+	if errNonce != nil || errCiphertext != nil {
+		return nil, fmt.Errorf("corrupt secrets file %s", path)
+	}
+	gcm, errGCM := newSecretsGCM(key)
+	if errGCM != nil {
+		return nil, errGCM
+	}
+	plaintext, errOpen := gcm.Open(nil, nonce, ciphertext, nil)
+	if errOpen != nil {
+		return nil, fmt.Errorf("%s: %w", path, errSecretsDecrypt)
+	}
+
+	secrets := map[string]string{}
+	if errJSON := json.Unmarshal(plaintext, &secrets); errJSON != nil {
+		return nil, fmt.Errorf("parsing decrypted secrets: %w", errJSON)
+	}
+	return secrets, nil
+}
+
+// saveSecretsFile encrypts secrets with the passphrase from
+// SGAI_SECRETS_PASSPHRASE or, when it is unset, with the user key file.
+func saveSecretsFile(path, userConfigDir string, secrets map[string]string) error {
+	envelope := secretsEnvelope{Version: secretsFileVersion, KeySource: secretKeySourceKeyFile}
+	if os.Getenv(secretsPassphraseEnv) != "" {
+		salt := make([]byte, 16)
+		if _, errRand := rand.Read(salt); errRand != nil {
+			return fmt.Errorf("generating salt: %w", errRand)
+		}
+		envelope.KeySource = secretKeySourcePassphrase
+		envelope.Salt = base64.StdEncoding.EncodeToString(salt)
+		envelope.Iterations = secretsKDFIterations
+	}
+
+	key, errKey := secretsKeyFor(envelope, userConfigDir, true)
+	if errKey != nil {
+		return errKey
+	}
+	gcm, errGCM := newSecretsGCM(key)
+	if errGCM != nil {
+		return errGCM
+	}
+	plaintext, errJSON := json.Marshal(secrets)
+	if errJSON != nil {
+		return fmt.Errorf("encoding secrets: %w", errJSON)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, errRand := rand.Read(nonce); errRand != nil {
+		return fmt.Errorf("generating nonce: %w", errRand)
+	}
+	envelope.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	envelope.Ciphertext = base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, nil))
+
+	data, errEncode := json.MarshalIndent(envelope, "", "  ")
+	if errEncode != nil {
+		return fmt.Errorf("encoding secrets file: %w", errEncode)
+	}
+	if errMkdir := os.MkdirAll(filepath.Dir(path), 0o700); errMkdir != nil {
+		return fmt.Errorf("creating secrets directory: %w", errMkdir)
+	}
+	if errWrite := os.WriteFile(path, data, 0o600); errWrite != nil {
+		return fmt.Errorf("writing secrets: %w", errWrite)
+	}
+	return nil
+}
+
+func secretsKeyFor(envelope secretsEnvelope, userConfigDir string, create bool) ([]byte, error) {
+	switch envelope.KeySource {
+	case secretKeySourcePassphrase:
+		passphrase := os.Getenv(secretsPassphraseEnv)
+		if passphrase == "" {
+			return nil, errSecretsPassphraseRequired
+		}
+		salt, errSalt := base64.StdEncoding.DecodeString(envelope.Salt)
+		if errSalt != nil {
+			return nil, fmt.Errorf("corrupt secrets salt: %w", errSalt)
+		}
+		return pbkdf2.Key(sha256.New, passphrase, salt, envelope.Iterations, 32)
+	case secretKeySourceKeyFile:
+		return loadSecretsKeyFile(filepath.Join(userConfigDir, secretsKeyFileName), create)
+	default:
+		return nil, fmt.Errorf("unknown secrets key source %q", envelope.KeySource)
+	}
+}
+
+// loadSecretsKeyFile reads the random 256-bit key kept in the user config
+// directory, generating it on first write.
+func loadSecretsKeyFile(path string, create bool) ([]byte, error) {
+	data, errRead := os.ReadFile(path)
+	if errRead == nil {
+		key, errDecode := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if errDecode != nil || len(key) != 32 {
+			return nil, fmt.Errorf("invalid secrets key file %s", path)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(errRead) || !create {
+		return nil, fmt.Errorf("reading secrets key file: %w", errRead)
+	}
+	key := make([]byte, 32)
+	if _, errRand := rand.Read(key); errRand != nil {
+		return nil, fmt.Errorf("generating secrets key: %w", errRand)
+	}
+	if errMkdir := os.MkdirAll(filepath.Dir(path), 0o700); errMkdir != nil {
+		return nil, fmt.Errorf("creating secrets key directory: %w", errMkdir)
+	}
+	if errWrite := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600); errWrite != nil {
+		return nil, fmt.Errorf("writing secrets key file: %w", errWrite)
+	}
+	return key, nil
+}
+
+func newSecretsGCM(key []byte) (cipher.AEAD, error) {
+	block, errCipher := aes.NewCipher(key)
+	if errCipher != nil {
+		return nil, fmt.Errorf("creating cipher: %w", errCipher)
+	}
+	return cipher.NewGCM(block)
+}
+
+// loadSecrets merges the user secrets with the workspace secrets; workspace
+// values win.
+func loadSecrets(userConfigDir, workspacePath string) (map[string]string, error) {
+	secrets := map[string]string{}
+	for _, scope := range []string{secretScopeUser, secretScopeWorkspace} {
+		path, errPath := secretsFilePath(scope, userConfigDir, workspacePath)
+		if errPath != nil {
+			return nil, errPath
+		}
+		scoped, errLoad := loadSecretsFile(path, userConfigDir)
+		if errLoad != nil {
+			return nil, errLoad
+		}
+		maps.Copy(secrets, scoped)
+	}
+	return secrets, nil
+}
+
+// secretEnv resolves sgai.json secret references into environment entries.
+// Missing secrets are logged and skipped; every injected value is registered
+// for masking.
+func secretEnv(userConfigDir, workspacePath string, refs []string) []string {
+	if len(refs) == 0 {
+		return nil
+	}
+	secrets, errLoad := loadSecrets(userConfigDir, workspacePath)
+	if errLoad != nil {
+		log.Println("cannot load secrets:", errLoad)
+		return nil
+	}
+	var env []string
+	injected := map[string]string{}
+	for _, ref := range refs {
+		envName, secretName, ok := strings.Cut(ref, "=")
+		if !ok {
+			secretName = envName
+		}
+		value, found := secrets[secretName]
+		if !found {
+			log.Println("secret referenced in sgai.json is not set:", secretName)
+			continue
+		}
+		env = append(env, envName+"="+value)
+		injected[secretName] = value
+	}
+	registerSecretValues(injected)
+	return env
+}
+
+func (c *secretsConfig) agentRefs() []string {
+	if c == nil {
+		return nil
+	}
+	return uniqueRefs(c.Agents)
+}
+
+func (c *secretsConfig) actionRefs(action string) []string {
+	if c == nil || action == "" {
+		return nil
+	}
+	return uniqueRefs(c.Actions[action])
+}
+
+func (c *secretsConfig) completionGateRefs() []string {
+	if c == nil {
+		return nil
+	}
+	return uniqueRefs(c.CompletionGate)
+}
+
+func uniqueRefs(refs []string) []string {
+	var unique []string
+	for _, ref := range refs {
+		if !slices.Contains(unique, ref) {
+			unique = append(unique, ref)
+		}
+	}
+	return unique
+}
+
+// projectSecretsConfig returns the secrets section of the workspace sgai.json,
+// or nil when there is none.
+func projectSecretsConfig(workspacePath string) *secretsConfig {
+	config, errLoad := loadProjectConfig(workspacePath)
+	if errLoad != nil {
+		log.Println("cannot load sgai.json secrets:", errLoad)
+		return nil
+	}
+	if config == nil {
+		return nil
+	}
+	return config.Secrets
+}
+
+// secretMask replaces injected secret values in captured output.
+type secretMask struct {
+	mu       sync.RWMutex
+	values   map[string]string
+	replacer *strings.Replacer
+}
+
+var activeSecretMask = &secretMask{values: map[string]string{}}
+
+func registerSecretValues(secrets map[string]string) {
+	activeSecretMask.register(secrets)
+}
+
+func maskSecrets(text string) string {
+	return activeSecretMask.mask(text)
+}
+
+func (m *secretMask) register(secrets map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	changed := false
+	for name, value := range secrets {
+		if len(value) < secretMinMaskLength || m.values[value] == name {
+			continue
+		}
+		m.values[value] = name
+		changed = true
+	}
+	if !changed {
+		return
+	}
+	values := slices.Collect(maps.Keys(m.values))
+	slices.SortFunc(values, func(a, b string) int { return len(b) - len(a) })
+	pairs := make([]string, 0, len(values)*2)
+	for _, value := range values {
+		pairs = append(pairs, value, "[secret:"+m.values[value]+"]")
+	}
+	m.replacer = strings.NewReplacer(pairs...)
+}
+
+func (m *secretMask) mask(text string) string {
+	m.mu.RLock()
+	replacer := m.replacer
+	m.mu.RUnlock()
+	if replacer == nil {
+		return text
+	}
+	return replacer.Replace(text)
+}
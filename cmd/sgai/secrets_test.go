@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretsFileRoundTrip(t *testing.T) {
+	cases := []struct {
+		name       string
+		passphrase string
+		keySource  string
+	}{
+		{"keyFile", "", secretKeySourceKeyFile},
+		{"passphrase", "correct horse battery staple", secretKeySourcePassphrase},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(secretsPassphraseEnv, tc.passphrase)
+			userDir := t.TempDir()
+			path := filepath.Join(t.TempDir(), secretsFileName)
+
+			require.NoError(t, saveSecretsFile(path, userDir, map[string]string{"GITHUB_TOKEN": "ghp_roundtrip"}))
+
+			raw, errRead := os.ReadFile(path)
+			require.NoError(t, errRead)
+			assert.NotContains(t, string(raw), "ghp_roundtrip")
+			assert.Contains(t, string(raw), `"keySource": "`+tc.keySource+`"`)
+			info, errStat := os.Stat(path)
+			require.NoError(t, errStat)
+			assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+			secrets, errLoad := loadSecretsFile(path, userDir)
+			require.NoError(t, errLoad)
+			assert.Equal(t, map[string]string{"GITHUB_TOKEN": "ghp_roundtrip"}, secrets)
+		})
+	}
+}
+
+func TestLoadSecretsFileErrors(t *testing.T) {
+	userDir := t.TempDir()
+	path := filepath.Join(t.TempDir(), secretsFileName)
+	t.Setenv(secretsPassphraseEnv, "first")
+	require.NoError(t, saveSecretsFile(path, userDir, map[string]string{"A": "value"}))
+
+	t.Setenv(secretsPassphraseEnv, "second")
+	_, errWrong := loadSecretsFile(path, userDir)
+	require.ErrorIs(t, errWrong, errSecretsDecrypt)
+
+	t.Setenv(secretsPassphraseEnv, "")
+	_, errMissing := loadSecretsFile(path, userDir)
+	require.ErrorIs(t, errMissing, errSecretsPassphraseRequired)
+
+	secrets, errAbsent := loadSecretsFile(filepath.Join(t.TempDir(), secretsFileName), userDir)
+	require.NoError(t, errAbsent)
+	assert.Empty(t, secrets)
+}
+
+func TestSecretEnvMergesScopesAndRegistersMask(t *testing.T) {
+	t.Setenv(secretsPassphraseEnv, "")
+	userDir := t.TempDir()
+	wsDir := t.TempDir()
+	require.NoError(t, saveSecretsFile(filepath.Join(userDir, secretsFileName), userDir, map[string]string{
+		"DEPLOY_TOKEN": "user-deploy-value",
+		"GITHUB_TOKEN": "user-github-value",
+	}))
+	require.NoError(t, saveSecretsFile(filepath.Join(wsDir, ".sgai", secretsFileName), userDir, map[string]string{
+		"GITHUB_TOKEN": "workspace-github-value",
+	}))
+
+	env := secretEnv(userDir, wsDir, []string{"GITHUB_TOKEN", "TOKEN=DEPLOY_TOKEN", "MISSING"})
+
+	assert.Equal(t, []string{"GITHUB_TOKEN=workspace-github-value", "TOKEN=user-deploy-value"}, env)
+	assert.Equal(t, "push with [secret:GITHUB_TOKEN]", maskSecrets("push with workspace-github-value"))
+	assert.Equal(t, "user-github-value", maskSecrets("user-github-value"), "secrets that were not injected stay unmasked")
+}
+
+func TestSecretsConfigRefs(t *testing.T) {
+	config := &secretsConfig{
+		Agents:         []string{"OPENAI_API_KEY", "GITHUB_TOKEN", "OPENAI_API_KEY"},
+		Actions:        map[string][]string{"Create PR": {"GH_TOKEN=GITHUB_TOKEN"}},
+		CompletionGate: []string{"DEPLOY_TOKEN"},
+	}
+
+	assert.Equal(t, []string{"OPENAI_API_KEY", "GITHUB_TOKEN"}, config.agentRefs())
+	assert.Equal(t, []string{"GH_TOKEN=GITHUB_TOKEN"}, config.actionRefs("Create PR"))
+	assert.Empty(t, config.actionRefs(""))
+	assert.Equal(t, []string{"DEPLOY_TOKEN"}, config.completionGateRefs())
+
+	var missing *secretsConfig
+	assert.Empty(t, missing.agentRefs())
+	assert.Empty(t, missing.completionGateRefs())
+}
+
+func TestSecretMask(t *testing.T) {
+	mask := &secretMask{values: map[string]string{}}
+	assert.Equal(t, "nothing registered", mask.mask("nothing registered"))
+
+	mask.register(map[string]string{"SHORT": "abc", "PREFIX": "token", "LONG": "token-extended"})
+
+	assert.Equal(t, "abc [secret:LONG] [secret:PREFIX]", mask.mask("abc token-extended token"))
+}
+
+func TestPrefixWriterMasksSecrets(t *testing.T) {
+	registerSecretValues(map[string]string{"PREFIX_WRITER_SECRET": "pw-secret-value"})
+	var buf bytes.Buffer
+	w := &prefixWriter{prefix: "[ws] ", w: &buf}
+
+	_, errWrite := w.Write([]byte("curl -H 'Authorization: pw-secret-value'\n"))
+
+	require.NoError(t, errWrite)
+	assert.Contains(t, buf.String(), "[ws] curl -H 'Authorization: [secret:PREFIX_WRITER_SECRET]'")
+	assert.NotContains(t, buf.String(), "pw-secret-value")
+}
+
+func TestRunCompletionGateScriptInjectsAndMasksSecrets(t *testing.T) {
+	registerSecretValues(map[string]string{"GATE_SECRET": "gate-secret-value"})
+
+	output, errScript := runCompletionGateScript(context.Background(), t.TempDir(), `echo "token=$GATE_SECRET"`, []string{"GATE_SECRET=gate-secret-value"})
+
+	require.NoError(t, errScript)
+	assert.Equal(t, "token=[secret:GATE_SECRET]\n", output)
+}
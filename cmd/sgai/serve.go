@@ -23,7 +23,6 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/adrg/xdg"
 	"github.com/sandgardenhq/sgai/pkg/state"
 	"github.com/yuin/goldmark"
 	emoji "github.com/yuin/goldmark-emoji"
@@ -225,6 +224,7 @@ type Server struct {
 	externalDirs      map[string]bool
 	externalConfigDir string
 	rootDir           string
+	roots             []serveRoot
 	editorAvailable   bool
 	isTerminalEditor  bool
 	editorName        string
@@ -250,6 +250,12 @@ type Server struct {
 	stateFlight       singleflight[string, apiFactoryState]
 	stateCache        *ttlCache[string, apiFactoryState]
 	stateGeneration   uint64
+
+	opencodeDBOnce    sync.Once
+	opencodeDBPath    string
+	tokenTotalsFlight singleflight[string, tokenUsageRow]
+	tokenTotalsCache  *ttlCache[string, tokenUsageRow]
+	healthModelCache  *ttlCache[string, doctorCheck]
 }
 
 // NewServer creates a new Server instance with the given root directory.
@@ -279,13 +285,14 @@ func NewServerWithConfig(rootDir, editorConfig string) *Server {
 		sessions:           make(map[string]*session),
 		everStartedDirs:    make(map[string]bool),
 		pinnedDirs:         make(map[string]bool),
-		pinnedConfigDir:    filepath.Join(xdg.ConfigHome, "sgai"),
+		pinnedConfigDir:    defaultUserConfigDir(),
 		externalDirs:       make(map[string]bool),
-		externalConfigDir:  filepath.Join(xdg.ConfigHome, "sgai"),
+		externalConfigDir:  defaultUserConfigDir(),
 		adhocStates:        make(map[string]*adhocPromptState),
 		signals:            newSignalBroker(),
 		composerSessions:   make(map[string]*composerSession),
 		rootDir:            absRootDir,
+		roots:              []serveRoot{{Name: filepath.Base(absRootDir), Dir: absRootDir, Source: rootSourcePrimary}},
 		editorAvailable:    editorAvail,
 		isTerminalEditor:   editor.isTerminal,
 		editorName:         editor.name,
@@ -294,6 +301,8 @@ func NewServerWithConfig(rootDir, editorConfig string) *Server {
 		classifyCache:      newTTLCache[string, workspaceKind](5 * time.Second),
 		bookmarkCache:      newTTLCache[string, string](30 * time.Second),
 		stateCache:         newTTLCache[string, apiFactoryState](30 * time.Second),
+		tokenTotalsCache:   newTTLCache[string, tokenUsageRow](time.Minute),
+		healthModelCache:   newTTLCache[string, doctorCheck](5 * time.Minute),
 	}
 }
 
@@ -305,17 +314,29 @@ func (s *Server) notifyStateChange() {
 	s.signals.notify()
 }
 
+// validateDirectory accepts dir when it lies inside any of the served roots.
 func (s *Server) validateDirectory(dir string) (string, error) {
 	if dir == "" {
 		return "", fmt.Errorf("directory is required")
 	}
+	var errValidate error
+	for _, root := range s.serveRoots() {
+		var validated string
+		validated, errValidate = validateDirectoryUnder(dir, root.Dir)
+		if errValidate == nil {
+			return validated, nil
+		}
+	}
+	return "", errValidate
+}
 
+func validateDirectoryUnder(dir, rootDir string) (string, error) {
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return "", fmt.Errorf("invalid directory path: %w", err)
 	}
 
-	absRoot, err := filepath.Abs(s.rootDir)
+	absRoot, err := filepath.Abs(rootDir)
 	if err != nil {
 		return "", fmt.Errorf("invalid root path: %w", err)
 	}
@@ -380,6 +401,10 @@ type startSessionResult struct {
 }
 
 func (s *Server) startSession(workspacePath string) startSessionResult {
+	if isArchivedWorkspace(workspacePath) {
+		return startSessionResult{startError: errArchivedWorkspace}
+	}
+
 	s.mu.Lock()
 	sess := s.sessions[workspacePath]
 	if sess != nil && sess.running {
@@ -433,6 +458,7 @@ func (s *Server) startSession(workspacePath string) startSessionResult {
 			sess.running = false
 			sess.mu.Unlock()
 			s.clearEverStartedOnCompletion(workspacePath)
+			s.startPipelineDownstreams(workspacePath)
 			s.notifyStateChange()
 		}()
 
@@ -506,6 +532,8 @@ func dashboardBaseURL(listenAddr string) string {
 func cmdServe(args []string) {
 	serveFlags := flag.NewFlagSet("serve", flag.ExitOnError)
 	listenAddr := serveFlags.String("listen-addr", "127.0.0.1:8080", "HTTP server listen address")
+	var rootFlags repeatedFlag
+	serveFlags.Var(&rootFlags, "root", "additional root directory as name=dir or dir (repeatable)")
 	serveFlags.Parse(args) //nolint:errcheck // ExitOnError FlagSet exits on error, never returns non-nil
 
 	var rootDir string
@@ -530,12 +558,16 @@ func cmdServe(args []string) {
 
 	srv := NewServer(rootDir)
 	srv.shutdownCtx = ctx
-	if err := srv.loadPinnedProjects(); err != nil {
-		log.Println("warning: failed to load pinned projects:", err)
-	}
-	if err := srv.loadExternalDirs(); err != nil {
-		log.Println("warning: failed to load external dirs:", err)
+	for _, value := range rootFlags {
+		root, errRoot := parseRootFlag(value)
+		if errRoot != nil {
+			log.Fatalln(errRoot)
+		}
+		if _, errAdd := srv.addRoot(root); errAdd != nil {
+			log.Fatalln("failed to add root:", errAdd)
+		}
 	}
+	srv.loadPersistedSettings()
 	srv.startStateWatcher()
 	go srv.warmStateCache()
 
@@ -561,6 +593,20 @@ func cmdServe(args []string) {
 	}
 }
 
+// loadPersistedSettings restores the roots, pins and attached external
+// directories saved by earlier runs. Failures are logged, not fatal.
+func (s *Server) loadPersistedSettings() {
+	if err := s.loadConfiguredRoots(); err != nil {
+		log.Println("warning: failed to load roots:", err)
+	}
+	if err := s.loadPinnedProjects(); err != nil {
+		log.Println("warning: failed to load pinned projects:", err)
+	}
+	if err := s.loadExternalDirs(); err != nil {
+		log.Println("warning: failed to load external dirs:", err)
+	}
+}
+
 func linesWithTrailingEmpty(content string) []string {
 	var lines []string
 	for line := range strings.Lines(content) {
@@ -671,6 +717,7 @@ type workspaceInfo struct {
 	Pinned       bool
 	HasWorkspace bool
 	External     bool
+	Root         string
 }
 
 type workspaceGroup struct {
@@ -1206,13 +1253,18 @@ func (s *Server) invalidateWorkspaceScanCache() {
 }
 
 func (s *Server) doScanWorkspaceGroups() ([]workspaceGroup, error) {
-	projects, err := scanForProjects(s.rootDir)
+	projects, err := s.scanRoots()
 	if err != nil {
 		return nil, err
 	}
 
 	rootMap := make(map[string]*workspaceGroup)
 	var standaloneGroups []workspaceGroup
+	info := func(proj rootedProject, isRoot bool) workspaceInfo {
+		ws := s.createWorkspaceInfo(proj.Directory, proj.DirName, isRoot, proj.HasWorkspace, false)
+		ws.Root = proj.Root
+		return ws
+	}
 
 	for _, proj := range projects {
 		resolvedDir := resolveSymlinks(proj.Directory)
@@ -1221,30 +1273,26 @@ func (s *Server) doScanWorkspaceGroups() ([]workspaceGroup, error) {
 		switch classification {
 		case workspaceRoot:
 			if _, exists := rootMap[resolvedDir]; !exists {
-				rootMap[resolvedDir] = &workspaceGroup{
-					Root: s.createWorkspaceInfo(proj.Directory, proj.DirName, true, proj.HasWorkspace, false),
-				}
+				rootMap[resolvedDir] = &workspaceGroup{Root: info(proj, true)}
 			}
 		case workspaceFork:
 			rootPath := resolveSymlinks(getRootWorkspacePath(proj.Directory))
 			if rootPath == "" {
-				standaloneGroups = append(standaloneGroups, workspaceGroup{
-					Root: s.createWorkspaceInfo(proj.Directory, proj.DirName, false, proj.HasWorkspace, false),
-				})
+				standaloneGroups = append(standaloneGroups, workspaceGroup{Root: info(proj, false)})
 				continue
 			}
 			if existing, exists := rootMap[rootPath]; exists {
-				existing.Forks = append(existing.Forks, s.createWorkspaceInfo(proj.Directory, proj.DirName, false, proj.HasWorkspace, false))
+				existing.Forks = append(existing.Forks, info(proj, false))
 			} else {
+				root := s.createWorkspaceInfo(rootPath, filepath.Base(rootPath), true, hassgaiDirectory(rootPath), false)
+				root.Root = s.rootNameForDir(rootPath)
 				rootMap[rootPath] = &workspaceGroup{
-					Root:  s.createWorkspaceInfo(rootPath, filepath.Base(rootPath), true, hassgaiDirectory(rootPath), false),
-					Forks: []workspaceInfo{s.createWorkspaceInfo(proj.Directory, proj.DirName, false, proj.HasWorkspace, false)},
+					Root:  root,
+					Forks: []workspaceInfo{info(proj, false)},
 				}
 			}
 		default:
-			standaloneGroups = append(standaloneGroups, workspaceGroup{
-				Root: s.createWorkspaceInfo(proj.Directory, proj.DirName, false, proj.HasWorkspace, false),
-			})
+			standaloneGroups = append(standaloneGroups, workspaceGroup{Root: info(proj, false)})
 		}
 	}
 
@@ -17,6 +17,7 @@ type adhocPromptState struct {
 	done          chan struct{}
 	selectedModel string
 	promptText    string
+	redactor      *redactor
 }
 
 func (s *Server) getAdhocState(workspacePath string) *adhocPromptState {
@@ -31,15 +32,20 @@ func (s *Server) getAdhocState(workspacePath string) *adhocPromptState {
 }
 
 type lockedWriter struct {
-	mu  *sync.Mutex
-	buf *bytes.Buffer
+	mu           *sync.Mutex
+	buf          *bytes.Buffer
+	redactor     *redactor
+	inPrivateKey bool
 }
 
 func (w *lockedWriter) Write(p []byte) (int, error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	stripped := ansiEscapePattern.ReplaceAll(p, nil)
-	w.buf.Write(stripped)
+	text := maskSecrets(string(ansiEscapePattern.ReplaceAll(p, nil)))
+	if w.redactor != nil {
+		text = w.redactor.redactText(text, &w.inPrivateKey)
+	}
+	w.buf.WriteString(text)
 	return len(p), nil
 }
 
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"maps"
@@ -15,6 +18,7 @@ import (
 	"path"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -69,6 +73,7 @@ func (b *signalBroker) notify() {
 
 func (s *Server) registerAPIRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/v1/state", s.handleAPIState)
+	mux.HandleFunc("GET /api/v1/health", s.handleAPIHealth)
 	mux.HandleFunc("GET /api/v1/signal", s.handleSignalStream)
 	mux.HandleFunc("GET /api/v1/agents", s.handleAPIAgents)
 	mux.HandleFunc("GET /api/v1/skills", s.handleAPISkills)
@@ -77,36 +82,57 @@ func (s *Server) registerAPIRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/v1/snippets/{lang}", s.handleAPISnippetsByLanguage)
 	mux.HandleFunc("GET /api/v1/snippets/{lang}/{fileName}", s.handleAPISnippetDetail)
 	mux.HandleFunc("POST /api/v1/workspaces", s.handleAPICreateWorkspace)
+	mux.HandleFunc("POST /api/v1/workspaces/batch", s.handleAPIBatch)
 
-	mux.HandleFunc("POST /api/v1/workspaces/{name}/respond", s.handleAPIRespond)
-	mux.HandleFunc("POST /api/v1/workspaces/{name}/start", s.handleAPIStartSession)
+	mux.HandleFunc("POST /api/v1/workspaces/{name}/respond", s.rejectArchived(s.handleAPIRespond))
+	mux.HandleFunc("POST /api/v1/workspaces/{name}/start", s.rejectArchived(s.handleAPIStartSession))
 	mux.HandleFunc("POST /api/v1/workspaces/{name}/stop", s.handleAPIStopSession)
-	mux.HandleFunc("POST /api/v1/workspaces/{name}/reset", s.handleAPIResetWorkspace)
-	mux.HandleFunc("POST /api/v1/workspaces/{name}/fork", s.handleAPIForkWorkspace)
+	mux.HandleFunc("POST /api/v1/workspaces/{name}/reset", s.rejectArchived(s.handleAPIResetWorkspace))
+	mux.HandleFunc("POST /api/v1/workspaces/{name}/fork", s.rejectArchived(s.handleAPIForkWorkspace))
 	mux.HandleFunc("POST /api/v1/workspaces/{name}/delete-fork", s.handleAPIDeleteFork)
 	mux.HandleFunc("POST /api/v1/workspaces/{name}/delete", s.handleAPIDeleteWorkspace)
 	mux.HandleFunc("GET /api/v1/workspaces/{name}/goal", s.handleAPIGetGoal)
 	mux.HandleFunc("GET /api/v1/workspaces/{name}/fork-template", s.handleAPIForkTemplate)
-	mux.HandleFunc("PUT /api/v1/workspaces/{name}/goal", s.handleAPIUpdateGoal)
+	mux.HandleFunc("GET /api/v1/workspaces/{name}/ledger", s.handleAPILedger)
+	mux.HandleFunc("GET /api/v1/workspaces/{name}/diff", s.handleAPIWorkspaceDiff)
+	mux.HandleFunc("GET /api/v1/workspaces/{name}/delegation-graph", s.handleAPIDelegationGraph)
+	mux.HandleFunc("PUT /api/v1/workspaces/{name}/goal", s.rejectArchived(s.handleAPIUpdateGoal))
 	mux.HandleFunc("GET /api/v1/workspaces/{name}/adhoc", s.handleAPIAdhocStatus)
-	mux.HandleFunc("POST /api/v1/workspaces/{name}/adhoc", s.handleAPIAdhoc)
+	mux.HandleFunc("POST /api/v1/workspaces/{name}/adhoc", s.rejectArchived(s.handleAPIAdhoc))
 	mux.HandleFunc("DELETE /api/v1/workspaces/{name}/adhoc", s.handleAPIAdhocStop)
+	mux.HandleFunc("POST /api/v1/workspaces/{name}/trigger", s.rejectArchived(s.handleAPITrigger))
+	mux.HandleFunc("GET /api/v1/workspaces/{name}/schedule", s.handleAPISchedule)
+	mux.HandleFunc("GET /api/v1/workspaces/{name}/checkpoints", s.handleAPIListCheckpoints)
+	mux.HandleFunc("POST /api/v1/workspaces/{name}/checkpoints/{id}/rewind", s.rejectArchived(s.handleAPIRewindCheckpoint))
 
 	mux.HandleFunc("POST /api/v1/workspaces/{name}/pin", s.handleAPITogglePin)
 	mux.HandleFunc("POST /api/v1/workspaces/{name}/open-editor", s.handleAPIOpenEditor)
-	mux.HandleFunc("POST /api/v1/workspaces/{name}/open-editor/goal", s.handleAPIOpenEditorGoal)
-	mux.HandleFunc("POST /api/v1/workspaces/{name}/open-editor/project-management", s.handleAPIOpenEditorProjectManagement)
+	mux.HandleFunc("POST /api/v1/workspaces/{name}/open-editor/goal", s.rejectArchived(s.handleAPIOpenEditorGoal))
+	mux.HandleFunc("POST /api/v1/workspaces/{name}/open-editor/project-management", s.rejectArchived(s.handleAPIOpenEditorProjectManagement))
 	mux.HandleFunc("GET /api/v1/workspaces/{name}/token-stats", s.handleAPITokenStats)
+	mux.HandleFunc("GET /api/v1/token-stats", s.handleAPIAggregateTokenStats)
+	mux.HandleFunc("GET /api/v1/workspaces/{name}/retrospectives/report", s.handleAPIRetroReport)
+	mux.HandleFunc("GET /api/v1/workspaces/{name}/bundle", s.handleAPIExportBundle)
+	mux.HandleFunc("POST /api/v1/bundles/import", s.handleAPIImportBundle)
 	mux.HandleFunc("GET /api/v1/models", s.handleAPIListModels)
 	mux.HandleFunc("GET /api/v1/compose", s.handleAPIComposeState)
 	mux.HandleFunc("POST /api/v1/compose", s.handleAPIComposeSave)
 	mux.HandleFunc("GET /api/v1/compose/templates", s.handleAPIComposeTemplates)
+	mux.HandleFunc("POST /api/v1/compose/templates", s.handleAPISaveComposeTemplate)
 	mux.HandleFunc("GET /api/v1/compose/preview", s.handleAPIComposePreview)
 	mux.HandleFunc("POST /api/v1/compose/draft", s.handleAPIComposeDraft)
 
 	mux.HandleFunc("GET /api/v1/browse-directories", s.handleAPIBrowseDirectories)
+	mux.HandleFunc("GET /api/v1/pipeline", s.handleAPIPipeline)
+	mux.HandleFunc("GET /api/v1/roots", s.handleAPIListRoots)
+	mux.HandleFunc("POST /api/v1/roots", s.handleAPIAddRoot)
 	mux.HandleFunc("POST /api/v1/workspaces/attach", s.handleAPIAttachWorkspace)
 	mux.HandleFunc("POST /api/v1/workspaces/detach", s.handleAPIDetachWorkspace)
+
+	mux.HandleFunc("GET /api/v1/notes", s.handleAPIListNotes)
+	mux.HandleFunc("POST /api/v1/notes", s.handleAPIAddNote)
+	mux.HandleFunc("PUT /api/v1/notes/{id}", s.handleAPIUpdateNote)
+	mux.HandleFunc("DELETE /api/v1/notes/{id}", s.handleAPIDeleteNote)
 }
 
 func (s *Server) handleSignalStream(w http.ResponseWriter, r *http.Request) {
@@ -143,11 +169,13 @@ func (s *Server) handleSignalStream(w http.ResponseWriter, r *http.Request) {
 
 type apiFactoryState struct {
 	Workspaces []apiWorkspaceFullState `json:"workspaces"`
+	Roots      []apiRootEntry          `json:"roots,omitempty"`
 }
 
 type apiWorkspaceFullState struct {
 	Name            string                      `json:"name"`
 	Dir             string                      `json:"dir"`
+	Root            string                      `json:"root,omitempty"`
 	Running         bool                        `json:"running"`
 	NeedsInput      bool                        `json:"needsInput"`
 	InProgress      bool                        `json:"inProgress"`
@@ -162,6 +190,7 @@ type apiWorkspaceFullState struct {
 	HasEditedGoal   bool                        `json:"hasEditedGoal"`
 	InteractiveAuto bool                        `json:"interactiveAuto"`
 	ContinuousMode  bool                        `json:"continuousMode"`
+	DependsOn       []string                    `json:"dependsOn,omitempty"`
 	Task            string                      `json:"task"`
 	GoalContent     string                      `json:"goalContent"`
 	Description     string                      `json:"description"`
@@ -179,6 +208,9 @@ type apiWorkspaceFullState struct {
 	Log             []apiLogEntry               `json:"log"`
 	PendingQuestion *apiPendingQuestionResponse `json:"pendingQuestion,omitempty"`
 	Actions         []apiActionEntry            `json:"actions,omitempty"`
+	TokenTotals     *tokenUsageRow              `json:"tokenTotals,omitempty"`
+	Archived        *bundleManifest             `json:"archived,omitempty"`
+	Redactions      map[string]int              `json:"redactions,omitempty"`
 }
 
 func (s *Server) handleAPIState(w http.ResponseWriter, _ *http.Request) {
@@ -251,7 +283,7 @@ func (s *Server) buildFullFactoryState() apiFactoryState {
 	}
 	wg.Wait()
 
-	return apiFactoryState{Workspaces: workspaces}
+	return apiFactoryState{Workspaces: workspaces, Roots: s.rootEntries(groups)}
 }
 
 const maxStateSizeBytes = 10 * 1024 * 1024
@@ -294,7 +326,9 @@ func (s *Server) buildWorkspaceFullState(ws workspaceInfo, groups []workspaceGro
 	var pendingQuestion *apiPendingQuestionResponse
 	if wfState.NeedsHumanInput() {
 		var questions []apiQuestionItem
+		var sections []state.WorkGateSection
 		if wfState.MultiChoiceQuestion != nil {
+			sections = wfState.MultiChoiceQuestion.Sections
 			questions = make([]apiQuestionItem, 0, len(wfState.MultiChoiceQuestion.Questions))
 			for _, q := range wfState.MultiChoiceQuestion.Questions {
 				questions = append(questions, apiQuestionItem{
@@ -309,6 +343,7 @@ func (s *Server) buildWorkspaceFullState(ws workspaceInfo, groups []workspaceGro
 			Type:       questionType(wfState),
 			Message:    wfState.HumanMessage,
 			Questions:  questions,
+			Sections:   sections,
 		}
 	}
 
@@ -320,6 +355,7 @@ func (s *Server) buildWorkspaceFullState(ws workspaceInfo, groups []workspaceGro
 	full := apiWorkspaceFullState{
 		Name:            ws.DirName,
 		Dir:             ws.Directory,
+		Root:            ws.Root,
 		Running:         ws.Running,
 		NeedsInput:      needsInput,
 		InProgress:      ws.InProgress,
@@ -334,6 +370,7 @@ func (s *Server) buildWorkspaceFullState(ws workspaceInfo, groups []workspaceGro
 		HasEditedGoal:   hasEditedGoal,
 		InteractiveAuto: interactiveAuto,
 		ContinuousMode:  readContinuousModePrompt(ws.Directory) != "",
+		DependsOn:       loadPipelineDependencies(ws.Directory).upstreams,
 		Task:            wfState.Task,
 		GoalContent:     goalContent,
 		Description:     description,
@@ -350,6 +387,9 @@ func (s *Server) buildWorkspaceFullState(ws workspaceInfo, groups []workspaceGro
 		Log:             logLines,
 		PendingQuestion: pendingQuestion,
 		Actions:         loadActionsForAPI(ws.Directory),
+		TokenTotals:     s.workspaceTokenTotals(ws.Directory),
+		Archived:        archivedManifestForAPI(ws.Directory),
+		Redactions:      wfState.Redactions,
 	}
 
 	if kind == workspaceRoot {
@@ -786,11 +826,7 @@ type apiActionEntry struct {
 }
 
 func loadActionsForAPI(workspacePath string) []apiActionEntry {
-	config, errLoad := loadProjectConfig(workspacePath)
-	if errLoad != nil || config == nil || config.Actions == nil {
-		return convertActionsForAPI(defaultActionConfigs())
-	}
-	return convertActionsForAPI(config.Actions)
+	return convertActionsForAPI(projectActions(workspacePath))
 }
 
 func convertActionsForAPI(configs []actionConfig) []apiActionEntry {
@@ -869,6 +905,17 @@ func writeJSON(w http.ResponseWriter, v any) {
 	}
 }
 
+// rejectArchived refuses requests that would modify an imported bundle.
+func (s *Server) rejectArchived(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if workspacePath := s.resolveWorkspaceNameToPath(r.PathValue("name")); workspacePath != "" && isArchivedWorkspace(workspacePath) {
+			http.Error(w, errArchivedWorkspace.Error(), http.StatusConflict)
+			return
+		}
+		next(w, r)
+	}
+}
+
 func (s *Server) resolveWorkspaceFromPath(w http.ResponseWriter, r *http.Request) (string, bool) {
 	workspaceName := r.PathValue("name")
 	if workspaceName == "" {
@@ -914,23 +961,110 @@ func (s *Server) handleAPITokenStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sessionIDs, errSessions := readSessionsJSONL(filepath.Join(workspacePath, ".sgai", "sessions.jsonl"))
-	if errSessions != nil {
-		writeJSON(w, tokenUsage{Rows: []tokenUsageRow{}})
+	window, errWindow := parseUsageTimeRange(r.URL.Query().Get("since"), r.URL.Query().Get("until"), time.Now())
+	if errWindow != nil {
+		http.Error(w, errWindow.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, s.tokenStatsService(workspacePath, window))
+}
+
+func (s *Server) handleAPIAggregateTokenStats(w http.ResponseWriter, r *http.Request) {
+	window, errWindow := parseUsageTimeRange(r.URL.Query().Get("since"), r.URL.Query().Get("until"), time.Now())
+	if errWindow != nil {
+		http.Error(w, errWindow.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, s.aggregateTokenStatsService(window))
+}
+
+func (s *Server) handleAPIRetroReport(w http.ResponseWriter, r *http.Request) {
+	workspacePath, ok := s.resolveWorkspaceFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	format := cmp.Or(r.URL.Query().Get("format"), retroFormatJSON)
+	contentTypes := map[string]string{
+		retroFormatJSON:     "application/json",
+		retroFormatMarkdown: "text/markdown; charset=utf-8",
+		retroFormatHTML:     "text/html; charset=utf-8",
+	}
+	contentType, known := contentTypes[format]
+	if !known {
+		http.Error(w, fmt.Sprintf("%s %q", errUnknownRetroFormat, format), http.StatusBadRequest)
+		return
+	}
+
+	window, errWindow := parseUsageTimeRange(r.URL.Query().Get("since"), r.URL.Query().Get("until"), time.Now())
+	if errWindow != nil {
+		http.Error(w, errWindow.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, errReport := s.retroReportService(workspacePath, window)
+	if errReport != nil {
+		http.Error(w, "failed to build retrospective report", http.StatusInternalServerError)
+		return
+	}
+
+	if format == retroFormatJSON {
+		writeJSON(w, report)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	if errWrite := writeRetroReport(w, report, format); errWrite != nil {
+		log.Println("failed to write retrospective report:", errWrite)
+	}
+}
+
+func (s *Server) handleAPIExportBundle(w http.ResponseWriter, r *http.Request) {
+	workspacePath, ok := s.resolveWorkspaceFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	manifest, errExport := s.exportBundleService(workspacePath, &buf)
+	if errExport != nil {
+		http.Error(w, "failed to export bundle", http.StatusInternalServerError)
 		return
 	}
 
-	dbPath := resolveOpencodeDBPath()
-	usage, errQuery := queryTokenUsage(dbPath, sessionIDs)
-	if errQuery != nil {
-		log.Println("failed to query token usage:", errQuery)
-		writeJSON(w, tokenUsage{Rows: []tokenUsageRow{}})
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", bundleFileName(manifest.Workspace, time.Now())))
+	if _, errWrite := buf.WriteTo(w); errWrite != nil {
+		log.Println("failed to write bundle:", errWrite)
+	}
+}
+
+type apiImportBundleResponse struct {
+	Name     string         `json:"name"`
+	Dir      string         `json:"dir"`
+	Manifest bundleManifest `json:"manifest"`
+}
+
+func (s *Server) handleAPIImportBundle(w http.ResponseWriter, r *http.Request) {
+	result, errImport := s.importBundleService(r.URL.Query().Get("name"), http.MaxBytesReader(w, r.Body, maxBundleFileSize))
+	if errImport != nil {
+		statusCode := http.StatusInternalServerError
+		switch {
+		case errors.Is(errImport, errDirectoryExists):
+			statusCode = http.StatusConflict
+		case errors.Is(errImport, errWorkspaceNameInvalid), errors.Is(errImport, errInvalidBundle), errors.Is(errImport, errUnsupportedBundle):
+			statusCode = http.StatusBadRequest
+		}
+		http.Error(w, errImport.Error(), statusCode)
 		return
 	}
-	if usage.Rows == nil {
-		usage.Rows = []tokenUsageRow{}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(apiImportBundleResponse(result)); err != nil {
+		log.Println("failed to encode json response:", err)
 	}
-	writeJSON(w, usage)
 }
 
 type apiEventEntry struct {
@@ -1017,16 +1151,18 @@ type apiQuestionItem struct {
 }
 
 type apiPendingQuestionResponse struct {
-	QuestionID string            `json:"questionId"`
-	Type       string            `json:"type"`
-	Message    string            `json:"message"`
-	Questions  []apiQuestionItem `json:"questions,omitempty"`
+	QuestionID string                  `json:"questionId"`
+	Type       string                  `json:"type"`
+	Message    string                  `json:"message"`
+	Questions  []apiQuestionItem       `json:"questions,omitempty"`
+	Sections   []state.WorkGateSection `json:"sections,omitempty"`
 }
 
 type apiRespondRequest struct {
-	QuestionID      string   `json:"questionId"`
-	Answer          string   `json:"answer"`
-	SelectedChoices []string `json:"selectedChoices"`
+	QuestionID      string                    `json:"questionId"`
+	Answer          string                    `json:"answer"`
+	SelectedChoices []string                  `json:"selectedChoices"`
+	WorkGate        *apiWorkGateReviewRequest `json:"workGate,omitempty"`
 }
 
 type apiRespondResponse struct {
@@ -1081,7 +1217,11 @@ func (s *Server) handleRespondViaCoordinator(w http.ResponseWriter, coord *state
 		return
 	}
 
-	responseText := buildAPIResponseText(req)
+	responseText, errResponse := buildRespondText(wfState, req)
+	if errResponse != nil {
+		http.Error(w, errResponse.Error(), http.StatusBadRequest)
+		return
+	}
 	if responseText == "" {
 		http.Error(w, "response cannot be empty", http.StatusBadRequest)
 		return
@@ -1097,6 +1237,15 @@ func (s *Server) handleRespondViaCoordinator(w http.ResponseWriter, coord *state
 	writeJSON(w, apiRespondResponse{Success: true, Message: "response submitted"})
 }
 
+// buildRespondText turns a respond request into the answer delivered to the
+// waiting coordinator; structured work-gate reviews are validated first.
+func buildRespondText(wfState state.Workflow, req apiRespondRequest) (string, error) {
+	if req.WorkGate != nil {
+		return buildWorkGateReviewAnswer(wfState.MultiChoiceQuestion, *req.WorkGate)
+	}
+	return buildAPIResponseText(req), nil
+}
+
 func buildAPIResponseText(req apiRespondRequest) string {
 	var parts []string
 	if len(req.SelectedChoices) > 0 {
@@ -1266,7 +1415,7 @@ func (s *Server) handleAPIComposeState(w http.ResponseWriter, r *http.Request) {
 	wizard := syncWizardState(cs.wizard, currentState)
 	cs.mu.Unlock()
 
-	techStack := buildAPITechStackItems(wizard.TechStack)
+	techStack := apiTechStackItemsFor(templateTechStackItems(loadWorkflowTemplates(s.externalConfigDir, workspacePath)), wizard.TechStack)
 
 	writeJSON(w, apiComposeStateResponse{
 		Workspace:      filepath.Base(workspacePath),
@@ -1277,14 +1426,17 @@ func (s *Server) handleAPIComposeState(w http.ResponseWriter, r *http.Request) {
 }
 
 func buildAPITechStackItems(selectedTech []string) []apiTechStackItem {
+	return apiTechStackItemsFor(defaultTechStackItems(), selectedTech)
+}
+
+func apiTechStackItemsFor(available []techStackItem, selectedTech []string) []apiTechStackItem {
 	selectedMap := make(map[string]bool)
 	for _, ts := range selectedTech {
 		selectedMap[ts] = true
 	}
 
-	defaults := defaultTechStackItems()
-	items := make([]apiTechStackItem, len(defaults))
-	for i, item := range defaults {
+	items := make([]apiTechStackItem, len(available))
+	for i, item := range available {
 		items[i] = apiTechStackItem{
 			ID:       item.ID,
 			Name:     item.Name,
@@ -1352,25 +1504,122 @@ func computeEtag(content []byte) string {
 }
 
 type apiComposeTemplateEntry struct {
-	ID          string              `json:"id"`
-	Name        string              `json:"name"`
-	Description string              `json:"description"`
-	Icon        string              `json:"icon"`
-	Agents      []composerAgentConf `json:"agents"`
+	ID             string              `json:"id"`
+	Name           string              `json:"name"`
+	Description    string              `json:"description"`
+	Icon           string              `json:"icon"`
+	Agents         []composerAgentConf `json:"agents"`
+	Model          string              `json:"model,omitempty"`
+	CompletionGate string              `json:"completionGate,omitempty"`
+	Interactive    string              `json:"interactive,omitempty"`
+	Tasks          string              `json:"tasks,omitempty"`
+	Body           string              `json:"body,omitempty"`
+	TechStack      []apiTechStackItem  `json:"techStack,omitempty"`
+	Source         string              `json:"source"`
+}
+
+func buildAPIComposeTemplateEntry(tmpl workflowTemplate) apiComposeTemplateEntry {
+	entry := apiComposeTemplateEntry{
+		ID:             tmpl.ID,
+		Name:           tmpl.Name,
+		Description:    tmpl.Description,
+		Icon:           tmpl.Icon,
+		Agents:         tmpl.Agents,
+		Model:          tmpl.Model,
+		CompletionGate: tmpl.CompletionGate,
+		Interactive:    tmpl.Interactive,
+		Tasks:          tmpl.Tasks,
+		Body:           tmpl.Body,
+		Source:         tmpl.Source,
+	}
+	if len(tmpl.TechStack) > 0 {
+		entry.TechStack = apiTechStackItemsFor(tmpl.TechStack, nil)
+	}
+	return entry
 }
 
 type apiComposeTemplatesResponse struct {
 	Templates []apiComposeTemplateEntry `json:"templates"`
 }
 
-func (s *Server) handleAPIComposeTemplates(w http.ResponseWriter, _ *http.Request) {
-	templates := workflowTemplates()
-	entries := make([]apiComposeTemplateEntry, len(templates))
-	for i, tmpl := range templates {
-		entries[i] = apiComposeTemplateEntry(tmpl)
+func (s *Server) handleAPIComposeTemplates(w http.ResponseWriter, r *http.Request) {
+	var workspacePath string
+	if name := r.URL.Query().Get("workspace"); name != "" {
+		workspacePath = s.resolveWorkspaceNameToPath(name)
+		if workspacePath == "" {
+			http.Error(w, "workspace not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	result := s.composeTemplatesService(workspacePath)
+	writeJSON(w, apiComposeTemplatesResponse{Templates: result.Templates})
+}
+
+// handleAPIHealth runs the sgai doctor checks. It answers 503 when any
+// check fails so load balancers and scripts can use it directly.
+func (s *Server) handleAPIHealth(w http.ResponseWriter, r *http.Request) {
+	var workspacePath string
+	if name := r.URL.Query().Get("workspace"); name != "" {
+		workspacePath = s.resolveWorkspaceNameToPath(name)
+		if workspacePath == "" {
+			http.Error(w, "workspace not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	report := s.healthService(workspacePath)
+	if report.Status != doctorFail {
+		writeJSON(w, report)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	if errEncode := json.NewEncoder(w).Encode(report); errEncode != nil {
+		log.Println("failed to encode health report:", errEncode)
+	}
+}
+
+type apiSaveComposeTemplateRequest struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	Scope       string `json:"scope"`
+	Overwrite   bool   `json:"overwrite"`
+}
+
+func (s *Server) handleAPISaveComposeTemplate(w http.ResponseWriter, r *http.Request) {
+	workspacePath := s.resolveAPIWorkspace(r)
+	if workspacePath == "" {
+		http.Error(w, "workspace not found", http.StatusNotFound)
+		return
+	}
+
+	var req apiSaveComposeTemplateRequest
+	if errDecode := json.NewDecoder(r.Body).Decode(&req); errDecode != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entry, errSave := s.saveComposeTemplateService(workspacePath, req)
+	if errSave != nil {
+		statusCode := http.StatusInternalServerError
+		switch {
+		case errors.Is(errSave, errInvalidTemplateID), errors.Is(errSave, errInvalidTemplateScope):
+			statusCode = http.StatusBadRequest
+		case errors.Is(errSave, errTemplateExists):
+			statusCode = http.StatusConflict
+		}
+		http.Error(w, errSave.Error(), statusCode)
+		return
 	}
 
-	writeJSON(w, apiComposeTemplatesResponse{Templates: entries})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(entry); err != nil {
+		log.Println("failed to encode json response:", err)
+	}
 }
 
 type apiComposePreviewResponse struct {
@@ -1617,49 +1866,70 @@ func (s *Server) handleAPIDeleteWorkspace(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	kind := s.classifyWorkspaceCached(workspacePath)
-	external := s.isExternalWorkspace(workspacePath)
-
-	if external {
-		switch kind {
-		case workspaceFork:
-			result, errDelete := s.deleteExternalForkService(workspacePath)
-			if errDelete != nil {
-				http.Error(w, errDelete.Error(), http.StatusInternalServerError)
-				return
-			}
-			writeJSON(w, apiDeleteWorkspaceResponse(result))
-		default:
-			s.stopSession(workspacePath)
-			result, errDetach := s.detachExternalWorkspaceService(workspacePath)
-			if errDetach != nil {
-				http.Error(w, errDetach.Error(), http.StatusInternalServerError)
-				return
-			}
-			writeJSON(w, apiDeleteWorkspaceResponse{Deleted: result.Detached, Message: result.Message})
+	result, errDelete := s.deleteWorkspaceByKindService(workspacePath)
+	if errDelete != nil {
+		if errors.Is(errDelete, errRootHasForks) {
+			http.Error(w, errDelete.Error(), http.StatusBadRequest)
+			return
 		}
+		http.Error(w, errDelete.Error(), http.StatusInternalServerError)
 		return
 	}
+	writeJSON(w, apiDeleteWorkspaceResponse(result))
+}
 
-	switch kind {
-	case workspaceRoot:
-		http.Error(w, "cannot delete a root workspace that has forks", http.StatusBadRequest)
+func (s *Server) handleAPIBatch(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if errDecode := json.NewDecoder(r.Body).Decode(&req); errDecode != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, errBatch := s.batchService(req)
+	switch {
+	case errors.Is(errBatch, errBatchForkGroupNotFound):
+		http.Error(w, errBatch.Error(), http.StatusNotFound)
+		return
+	case errors.Is(errBatch, errBatchScan):
+		http.Error(w, errBatch.Error(), http.StatusInternalServerError)
+		return
+	case errBatch != nil:
+		http.Error(w, errBatch.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, result)
+}
+
+type apiWorkspaceDiffResponse struct {
+	Diff string `json:"diff"`
+}
+
+func (s *Server) handleAPIWorkspaceDiff(w http.ResponseWriter, r *http.Request) {
+	workspacePath, ok := s.resolveWorkspaceFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, apiWorkspaceDiffResponse(s.workspaceDiffService(workspacePath)))
+}
+
+func (s *Server) handleAPIDelegationGraph(w http.ResponseWriter, r *http.Request) {
+	workspacePath, ok := s.resolveWorkspaceFromPath(w, r)
+	if !ok {
 		return
-	case workspaceFork:
-		result, errDelete := s.deleteForkByPathService(workspacePath)
-		if errDelete != nil {
-			http.Error(w, errDelete.Error(), http.StatusInternalServerError)
-			return
-		}
-		writeJSON(w, apiDeleteWorkspaceResponse(result))
-	default:
-		result, errDelete := s.deleteWorkspaceService(workspacePath)
-		if errDelete != nil {
-			http.Error(w, errDelete.Error(), http.StatusInternalServerError)
-			return
-		}
-		writeJSON(w, apiDeleteWorkspaceResponse(result))
 	}
+
+	graph, errGraph := s.delegationGraphService(workspacePath)
+	if errGraph != nil {
+		http.Error(w, "failed to load delegation graph", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, graph)
+}
+
+func (s *Server) handleAPIPipeline(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, s.pipelineGraphService())
 }
 
 type apiGoalResponse struct {
@@ -1681,6 +1951,29 @@ func (s *Server) handleAPIGetGoal(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, apiGoalResponse{Content: string(data)})
 }
 
+type apiLedgerResponse struct {
+	Entries []ledgerEntry `json:"entries"`
+}
+
+func (s *Server) handleAPILedger(w http.ResponseWriter, r *http.Request) {
+	workspacePath, ok := s.resolveWorkspaceFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	result, errLedger := s.ledgerService(workspacePath, r.URL.Query()["type"], r.URL.Query().Get("agent"))
+	if errors.Is(errLedger, errUnknownLedgerType) {
+		http.Error(w, errLedger.Error(), http.StatusBadRequest)
+		return
+	}
+	if errLedger != nil {
+		http.Error(w, "failed to read PROJECT_MANAGEMENT.md", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, apiLedgerResponse{Entries: result.Entries})
+}
+
 type apiForkTemplateResponse struct {
 	Content string `json:"content"`
 }
@@ -1752,6 +2045,178 @@ func readNewestForkGoal(forks []workspaceInfo) string {
 	return ""
 }
 
+type apiTriggerResponse struct {
+	Queued  bool   `json:"queued"`
+	Message string `json:"message"`
+}
+
+func (s *Server) handleAPITrigger(w http.ResponseWriter, r *http.Request) {
+	workspacePath, ok := s.resolveWorkspaceFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	triggers := readContinuousModeTriggers(workspacePath)
+	if triggers == nil || triggers.Webhook == nil {
+		http.Error(w, "webhook trigger is not enabled in GOAL.md", http.StatusNotFound)
+		return
+	}
+	secret := triggers.webhookSecret()
+	if secret == "" {
+		http.Error(w, "webhook secret is not configured", http.StatusForbidden)
+		return
+	}
+
+	body, errRead := io.ReadAll(io.LimitReader(r.Body, maxTriggerPayloadBytes+1))
+	if errRead != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxTriggerPayloadBytes {
+		http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !verifyWebhookRequest(secret, r.Header.Get("Authorization"), r.Header.Get("X-Hub-Signature-256"), body) {
+		http.Error(w, "invalid webhook credentials", http.StatusUnauthorized)
+		return
+	}
+
+	if !s.isContinuousSessionRunning(workspacePath) {
+		http.Error(w, "workspace is not running in continuous mode", http.StatusConflict)
+		return
+	}
+
+	response := apiTriggerResponse{Queued: true, Message: "trigger queued"}
+	if !deliverWebhookTrigger(workspacePath, string(body)) {
+		response = apiTriggerResponse{Queued: false, Message: "a webhook trigger is already pending"}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if errEncode := json.NewEncoder(w).Encode(response); errEncode != nil {
+		log.Println("failed to encode json response:", errEncode)
+	}
+}
+
+func (s *Server) isContinuousSessionRunning(workspacePath string) bool {
+	s.mu.Lock()
+	sess := s.sessions[workspacePath]
+	s.mu.Unlock()
+	if sess == nil {
+		return false
+	}
+	sess.mu.Lock()
+	running, coord := sess.running, sess.coord
+	sess.mu.Unlock()
+	return running && coord != nil && coord.State().InteractionMode == state.ModeContinuous
+}
+
+const (
+	defaultScheduleCount = 5
+	maxScheduleCount     = 100
+)
+
+type apiScheduleResponse struct {
+	Cron          string   `json:"cron"`
+	Timezone      string   `json:"timezone,omitempty"`
+	Missed        string   `json:"missed,omitempty"`
+	Overlap       string   `json:"overlap,omitempty"`
+	Jitter        string   `json:"jitter,omitempty"`
+	LastRun       string   `json:"lastRun,omitempty"`
+	LastScheduled string   `json:"lastScheduled,omitempty"`
+	Overdue       []string `json:"overdue"`
+	Upcoming      []string `json:"upcoming"`
+}
+
+func (s *Server) handleAPISchedule(w http.ResponseWriter, r *http.Request) {
+	workspacePath, ok := s.resolveWorkspaceFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	count := defaultScheduleCount
+	if raw := r.URL.Query().Get("count"); raw != "" {
+		parsed, errParse := strconv.Atoi(raw)
+		if errParse != nil || parsed < 1 || parsed > maxScheduleCount {
+			http.Error(w, fmt.Sprintf("count must be between 1 and %d", maxScheduleCount), http.StatusBadRequest)
+			return
+		}
+		count = parsed
+	}
+
+	schedule, errSchedule := readCronSchedule(workspacePath)
+	if errSchedule != nil {
+		http.Error(w, errSchedule.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if schedule.Expr == "" {
+		http.Error(w, "continuousModeCron is not set in GOAL.md", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	st := loadCronScheduleState(workspacePath)
+	response := apiScheduleResponse{
+		Cron:     schedule.Expr,
+		Timezone: schedule.Location.String(),
+		Missed:   schedule.Missed,
+		Overlap:  schedule.Overlap,
+		Overdue:  []string{},
+		Upcoming: formatScheduleTimes(schedule.upcoming(now, count)),
+	}
+	if schedule.Jitter > 0 {
+		response.Jitter = schedule.Jitter.String()
+	}
+	if !st.LastRun.IsZero() {
+		response.LastRun = st.LastRun.Format(time.RFC3339)
+	}
+	if !st.LastScheduled.IsZero() {
+		response.LastScheduled = st.LastScheduled.In(schedule.Location).Format(time.RFC3339)
+		response.Overdue = formatScheduleTimes(schedule.between(st.LastScheduled, now, maxCronCatchUp))
+	}
+	writeJSON(w, response)
+}
+
+func formatScheduleTimes(times []time.Time) []string {
+	formatted := make([]string, 0, len(times))
+	for _, t := range times {
+		formatted = append(formatted, t.Format(time.RFC3339))
+	}
+	return formatted
+}
+
+func (s *Server) handleAPIListCheckpoints(w http.ResponseWriter, r *http.Request) {
+	workspacePath, ok := s.resolveWorkspaceFromPath(w, r)
+	if !ok {
+		return
+	}
+	result, errList := s.listCheckpointsService(workspacePath)
+	if errList != nil {
+		http.Error(w, "failed to list checkpoints", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, result)
+}
+
+func (s *Server) handleAPIRewindCheckpoint(w http.ResponseWriter, r *http.Request) {
+	workspacePath, ok := s.resolveWorkspaceFromPath(w, r)
+	if !ok {
+		return
+	}
+	result, errRewind := s.rewindCheckpointService(workspacePath, r.PathValue("id"))
+	switch {
+	case errRewind == nil:
+		writeJSON(w, result)
+	case errors.Is(errRewind, errRewindWhileRunning):
+		http.Error(w, errRewind.Error(), http.StatusConflict)
+	case errors.Is(errRewind, errCheckpointNotFound):
+		http.Error(w, errRewind.Error(), http.StatusNotFound)
+	case errors.Is(errRewind, errInvalidCheckpoint):
+		http.Error(w, errRewind.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, errRewind.Error(), http.StatusInternalServerError)
+	}
+}
+
 type apiUpdateGoalRequest struct {
 	Content string `json:"content"`
 }
@@ -1795,12 +2260,14 @@ func (s *Server) handleAPIUpdateGoal(w http.ResponseWriter, r *http.Request) {
 type apiAdhocRequest struct {
 	Prompt string `json:"prompt"`
 	Model  string `json:"model"`
+	Action string `json:"action,omitempty"`
 }
 
 type apiAdhocResponse struct {
-	Running bool   `json:"running"`
-	Output  string `json:"output"`
-	Message string `json:"message"`
+	Running    bool           `json:"running"`
+	Output     string         `json:"output"`
+	Message    string         `json:"message"`
+	Redactions map[string]int `json:"redactions,omitempty"`
 }
 
 func (s *Server) handleAPIAdhocStatus(w http.ResponseWriter, r *http.Request) {
@@ -1826,7 +2293,7 @@ func (s *Server) handleAPIAdhoc(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := s.adhocStartService(workspacePath, req.Prompt, req.Model)
+	result := s.adhocStartService(workspacePath, req.Prompt, req.Model, req.Action)
 	if result.Error != nil {
 		status := http.StatusInternalServerError
 		if result.BadRequest {
@@ -2055,6 +2522,39 @@ func (s *Server) handleAPIAttachWorkspace(w http.ResponseWriter, r *http.Request
 	}
 }
 
+func (s *Server) handleAPIListRoots(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, s.listRootsService())
+}
+
+type apiAddRootRequest struct {
+	Name string `json:"name"`
+	Dir  string `json:"dir"`
+}
+
+func (s *Server) handleAPIAddRoot(w http.ResponseWriter, r *http.Request) {
+	var req apiAddRootRequest
+	if errDecode := json.NewDecoder(r.Body).Decode(&req); errDecode != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entry, errAdd := s.addRootService(req.Name, req.Dir)
+	if errAdd != nil {
+		statusCode := http.StatusBadRequest
+		if errors.Is(errAdd, errRootExists) {
+			statusCode = http.StatusConflict
+		}
+		http.Error(w, errAdd.Error(), statusCode)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(entry); err != nil {
+		log.Println("failed to encode json response:", err)
+	}
+}
+
 type apiDetachWorkspaceRequest struct {
 	Path string `json:"path"`
 }
@@ -2083,3 +2583,105 @@ func (s *Server) handleAPIDetachWorkspace(w http.ResponseWriter, r *http.Request
 
 	writeJSON(w, apiDetachWorkspaceResponse(result))
 }
+
+type apiNoteRequest struct {
+	Content   string   `json:"content"`
+	Tags      []string `json:"tags"`
+	Workspace string   `json:"workspace,omitempty"`
+}
+
+func (s *Server) handleAPIListNotes(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := knowledgeDefaultLimit
+	if raw := query.Get("limit"); raw != "" {
+		parsed, errParse := strconv.Atoi(raw)
+		if errParse != nil || parsed < 1 || parsed > knowledgeMaxLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", knowledgeMaxLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	result, errList := s.listNotesService(knowledgeQuery{
+		Text:      query.Get("q"),
+		Tags:      query["tag"],
+		Workspace: query.Get("workspace"),
+		Limit:     limit,
+	})
+	if errList != nil {
+		http.Error(w, errList.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, result)
+}
+
+func (s *Server) handleAPIAddNote(w http.ResponseWriter, r *http.Request) {
+	var req apiNoteRequest
+	if errDecode := json.NewDecoder(r.Body).Decode(&req); errDecode != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	note, errAdd := s.addNoteService(req.Content, req.Tags, req.Workspace)
+	if errAdd != nil {
+		writeNoteError(w, errAdd)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(note); err != nil {
+		log.Println("failed to encode json response:", err)
+	}
+}
+
+func (s *Server) handleAPIUpdateNote(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseNoteID(w, r)
+	if !ok {
+		return
+	}
+	var req apiNoteRequest
+	if errDecode := json.NewDecoder(r.Body).Decode(&req); errDecode != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	note, errUpdate := s.updateNoteService(id, req.Content, req.Tags)
+	if errUpdate != nil {
+		writeNoteError(w, errUpdate)
+		return
+	}
+	writeJSON(w, note)
+}
+
+func (s *Server) handleAPIDeleteNote(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseNoteID(w, r)
+	if !ok {
+		return
+	}
+	if errDelete := s.deleteNoteService(id); errDelete != nil {
+		writeNoteError(w, errDelete)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseNoteID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, errParse := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if errParse != nil || id < 1 {
+		http.Error(w, "invalid note id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func writeNoteError(w http.ResponseWriter, err error) {
+	statusCode := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, errKnowledgeNoteNotFound):
+		statusCode = http.StatusNotFound
+	case errors.Is(err, errKnowledgeNoteEmpty):
+		statusCode = http.StatusBadRequest
+	}
+	http.Error(w, err.Error(), statusCode)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	rootSourcePrimary = "primary"
+	rootSourceFlag    = "flag"
+	rootSourceConfig  = "config"
+	rootSourceAPI     = "api"
+)
+
+var (
+	errInvalidRootName = errors.New("root name must start with a letter or digit and contain only letters, digits, '.', '_' or '-'")
+	errRootExists      = errors.New("root already exists")
+
+	rootNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// serveRoot is one parent directory scanned for workspaces by sgai serve.
+type serveRoot struct {
+	Name   string `json:"name"`
+	Dir    string `json:"dir"`
+	Source string `json:"source,omitempty"`
+}
+
+// parseRootFlag parses a --root value, either "name=dir" or a bare directory
+// named after its base name.
+func parseRootFlag(value string) (serveRoot, error) {
+	name, dir, ok := strings.Cut(value, "=")
+	if !ok {
+		dir = value
+		name = ""
+	}
+	if strings.TrimSpace(dir) == "" {
+		return serveRoot{}, fmt.Errorf("invalid --root %q: directory is required", value)
+	}
+	absDir, errAbs := filepath.Abs(dir)
+	if errAbs != nil {
+		return serveRoot{}, fmt.Errorf("invalid --root %q: %w", value, errAbs)
+	}
+	if name == "" {
+		name = filepath.Base(absDir)
+	}
+	return serveRoot{Name: name, Dir: absDir, Source: rootSourceFlag}, nil
+}
+
+func (s *Server) serveRoots() []serveRoot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.roots)
+}
+
+// addRoot validates and registers a root. The name and the resolved
+// directory must both be unique across roots.
+func (s *Server) addRoot(root serveRoot) (serveRoot, error) {
+	if !rootNamePattern.MatchString(root.Name) {
+		return serveRoot{}, fmt.Errorf("%w: %q", errInvalidRootName, root.Name)
+	}
+	if !filepath.IsAbs(root.Dir) {
+		return serveRoot{}, errPathNotAbsolute
+	}
+	info, errStat := os.Stat(root.Dir)
+	if errStat != nil {
+		return serveRoot{}, fmt.Errorf("checking root directory: %w", errStat)
+	}
+	if !info.IsDir() {
+		return serveRoot{}, errNotADirectory
+	}
+	root.Dir = filepath.Clean(root.Dir)
+	resolved := resolveSymlinks(root.Dir)
+
+	s.mu.Lock()
+	for _, existing := range s.roots {
+		switch {
+		case strings.EqualFold(existing.Name, root.Name):
+			s.mu.Unlock()
+			return serveRoot{}, fmt.Errorf("%w: name %q is taken", errRootExists, root.Name)
+		case resolveSymlinks(existing.Dir) == resolved:
+			s.mu.Unlock()
+			return serveRoot{}, fmt.Errorf("%w: %s is already served as %q", errRootExists, root.Dir, existing.Name)
+		}
+	}
+	s.roots = append(s.roots, root)
+	s.mu.Unlock()
+
+	s.invalidateWorkspaceScanCache()
+	return root, nil
+}
+
+// rootNameForDir returns the name of the root whose direct child is dir, or
+// an empty string for external and nested directories.
+func (s *Server) rootNameForDir(dir string) string {
+	parent := resolveSymlinks(filepath.Dir(dir))
+	for _, root := range s.serveRoots() {
+		if resolveSymlinks(root.Dir) == parent {
+			return root.Name
+		}
+	}
+	return ""
+}
+
+// isUnderServeRoot reports whether dir is a root or lies inside one.
+func (s *Server) isUnderServeRoot(dir string) bool {
+	canonical := resolveSymlinks(dir)
+	for _, root := range s.serveRoots() {
+		rootResolved := resolveSymlinks(root.Dir)
+		if canonical == rootResolved || strings.HasPrefix(canonical+string(filepath.Separator), rootResolved+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) rootsFilePath() string {
+	return filepath.Join(s.externalConfigDir, "roots.json")
+}
+
+// loadConfiguredRoots adds the roots listed in the user config and the roots
+// added at run time through the API. Invalid entries are logged and skipped.
+func (s *Server) loadConfiguredRoots() error {
+	config, errConfig := loadUserConfig(s.externalConfigDir)
+	if errConfig != nil {
+		return errConfig
+	}
+	if config != nil {
+		for _, root := range config.Roots {
+			root.Source = rootSourceConfig
+			if _, errAdd := s.addRoot(root); errAdd != nil {
+				log.Println("skipping root from user config:", errAdd)
+			}
+		}
+	}
+
+	data, errRead := os.ReadFile(s.rootsFilePath())
+	if errRead != nil {
+		if os.IsNotExist(errRead) {
+			return nil
+		}
+		return fmt.Errorf("reading roots: %w", errRead)
+	}
+	var roots []serveRoot
+	if errJSON := json.Unmarshal(data, &roots); errJSON != nil {
+		return fmt.Errorf("parsing roots: %w", errJSON)
+	}
+	for _, root := range roots {
+		root.Source = rootSourceAPI
+		if _, errAdd := s.addRoot(root); errAdd != nil {
+			log.Println("skipping saved root:", errAdd)
+		}
+	}
+	return nil
+}
+
+func (s *Server) saveAPIRoots() error {
+	roots := []serveRoot{}
+	for _, root := range s.serveRoots() {
+		if root.Source == rootSourceAPI {
+			roots = append(roots, serveRoot{Name: root.Name, Dir: root.Dir})
+		}
+	}
+	if errDir := os.MkdirAll(s.externalConfigDir, 0o755); errDir != nil {
+		return fmt.Errorf("creating roots config directory: %w", errDir)
+	}
+	data, errJSON := json.Marshal(roots)
+	if errJSON != nil {
+		return fmt.Errorf("encoding roots: %w", errJSON)
+	}
+	if errWrite := os.WriteFile(s.rootsFilePath(), data, 0o644); errWrite != nil {
+		return fmt.Errorf("writing roots: %w", errWrite)
+	}
+	return nil
+}
+
+// rootedProject is a project found under a named root.
+type rootedProject struct {
+	project
+	Root string
+}
+
+func (s *Server) scanRoots() ([]rootedProject, error) {
+	var projects []rootedProject
+	for i, root := range s.serveRoots() {
+		found, errScan := scanForProjects(root.Dir)
+		if errScan != nil {
+			if i == 0 {
+				return nil, errScan
+			}
+			log.Println("failed to scan root", root.Name+":", errScan)
+			continue
+		}
+		for _, proj := range found {
+			projects = append(projects, rootedProject{project: proj, Root: root.Name})
+		}
+	}
+	return projectNames(projects), nil
+}
+
+// projectNames keeps the directory name of the first project that uses it, in
+// root order, and qualifies later duplicates as "<dir>@<root>" so workspace
+// names in URLs stay unique.
+func projectNames(projects []rootedProject) []rootedProject {
+	seen := make(map[string]bool, len(projects))
+	for i := range projects {
+		name := projects[i].DirName
+		if seen[name] {
+			projects[i].DirName = name + "@" + projects[i].Root
+		}
+		seen[name] = true
+	}
+	return projects
+}
+
+type apiRootEntry struct {
+	Name       string   `json:"name"`
+	Dir        string   `json:"dir"`
+	Source     string   `json:"source"`
+	Workspaces []string `json:"workspaces"`
+}
+
+type listRootsResult struct {
+	Roots []apiRootEntry `json:"roots"`
+}
+
+func (s *Server) listRootsService() listRootsResult {
+	entries := s.rootEntries(s.scanWorkspaceGroupsOrEmpty())
+	return listRootsResult{Roots: entries}
+}
+
+func (s *Server) scanWorkspaceGroupsOrEmpty() []workspaceGroup {
+	groups, errScan := s.scanWorkspaceGroups()
+	if errScan != nil {
+		return nil
+	}
+	return groups
+}
+
+// rootEntries groups workspace names by the root that contains them.
+func (s *Server) rootEntries(groups []workspaceGroup) []apiRootEntry {
+	roots := s.serveRoots()
+	entries := make([]apiRootEntry, len(roots))
+	index := make(map[string]int, len(roots))
+	for i, root := range roots {
+		entries[i] = apiRootEntry{Name: root.Name, Dir: root.Dir, Source: root.Source, Workspaces: []string{}}
+		index[root.Name] = i
+	}
+	for _, grp := range groups {
+		for _, ws := range append([]workspaceInfo{grp.Root}, grp.Forks...) {
+			if i, ok := index[ws.Root]; ok {
+				entries[i].Workspaces = append(entries[i].Workspaces, ws.DirName)
+			}
+		}
+	}
+	return entries
+}
+
+func (s *Server) addRootService(name, dir string) (apiRootEntry, error) {
+	if name == "" {
+		name = filepath.Base(filepath.Clean(dir))
+	}
+	root, errAdd := s.addRoot(serveRoot{Name: name, Dir: dir, Source: rootSourceAPI})
+	if errAdd != nil {
+		return apiRootEntry{}, errAdd
+	}
+	if errSave := s.saveAPIRoots(); errSave != nil {
+		log.Println("failed to persist roots:", errSave)
+	}
+	s.notifyStateChange()
+
+	for _, entry := range s.rootEntries(s.scanWorkspaceGroupsOrEmpty()) {
+		if entry.Name == root.Name {
+			return entry, nil
+		}
+	}
+	return apiRootEntry{Name: root.Name, Dir: root.Dir, Source: root.Source, Workspaces: []string{}}, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRootFlag(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    string
+		wantName string
+		wantDir  string
+		wantErr  bool
+	}{
+		{"named", "work=/srv/work", "work", "/srv/work", false},
+		{"bareDirectory", "/srv/personal", "personal", "/srv/personal", false},
+		{"missingDirectory", "work=", "", "", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			root, errParse := parseRootFlag(tc.value)
+			if tc.wantErr {
+				require.Error(t, errParse)
+				return
+			}
+			require.NoError(t, errParse)
+			assert.Equal(t, tc.wantName, root.Name)
+			assert.Equal(t, tc.wantDir, root.Dir)
+			assert.Equal(t, rootSourceFlag, root.Source)
+		})
+	}
+}
+
+func TestAddRootErrors(t *testing.T) {
+	server, rootDir := setupTestServer(t)
+	other := t.TempDir()
+	file := filepath.Join(other, "file.txt")
+	require.NoError(t, os.WriteFile(file, []byte("x"), 0o644))
+
+	cases := []struct {
+		name    string
+		root    serveRoot
+		wantErr error
+	}{
+		{"invalidName", serveRoot{Name: "../x", Dir: other}, errInvalidRootName},
+		{"relativeDir", serveRoot{Name: "rel", Dir: "relative"}, errPathNotAbsolute},
+		{"notDirectory", serveRoot{Name: "file", Dir: file}, errNotADirectory},
+		{"duplicateDir", serveRoot{Name: "again", Dir: rootDir}, errRootExists},
+		{"duplicateName", serveRoot{Name: filepath.Base(rootDir), Dir: other}, errRootExists},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, errAdd := server.addRoot(tc.root)
+			require.ErrorIs(t, errAdd, tc.wantErr)
+		})
+	}
+}
+
+func TestScanMultipleRootsDisambiguatesNames(t *testing.T) {
+	server, rootDir := setupTestServer(t)
+	primaryDup := setupTestWorkspace(t, rootDir, "shared")
+	setupTestWorkspace(t, rootDir, "alpha")
+	second := t.TempDir()
+	secondDup := setupTestWorkspace(t, second, "shared")
+	setupTestWorkspace(t, second, "beta")
+	_, errAdd := server.addRoot(serveRoot{Name: "clients", Dir: second, Source: rootSourceFlag})
+	require.NoError(t, errAdd)
+
+	assert.Equal(t, primaryDup, server.resolveWorkspaceNameToPath("shared"))
+	assert.Equal(t, secondDup, server.resolveWorkspaceNameToPath("shared@clients"))
+	assert.Equal(t, filepath.Join(second, "beta"), server.resolveWorkspaceNameToPath("beta"))
+
+	factory := server.buildFullFactoryState()
+	require.Len(t, factory.Roots, 2)
+	assert.Equal(t, filepath.Base(rootDir), factory.Roots[0].Name)
+	assert.ElementsMatch(t, []string{"alpha", "shared"}, factory.Roots[0].Workspaces)
+	assert.Equal(t, "clients", factory.Roots[1].Name)
+	assert.ElementsMatch(t, []string{"beta", "shared@clients"}, factory.Roots[1].Workspaces)
+	for _, ws := range factory.Workspaces {
+		if ws.Name == "shared@clients" {
+			assert.Equal(t, "clients", ws.Root)
+			assert.Equal(t, secondDup, ws.Dir)
+		}
+	}
+
+	validated, errValidate := server.validateDirectory(filepath.Join(second, "beta"))
+	require.NoError(t, errValidate)
+	assert.Equal(t, filepath.Join(second, "beta"), validated)
+}
+
+func TestHandleAPIRoots(t *testing.T) {
+	server, _ := setupTestServer(t)
+	server.externalConfigDir = t.TempDir()
+	extra := t.TempDir()
+	setupTestWorkspace(t, extra, "gamma")
+
+	w := serveHTTP(server, http.MethodPost, "/api/v1/roots", `{"name":"side","dir":"`+extra+`"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var entry apiRootEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entry))
+	assert.Equal(t, "side", entry.Name)
+	assert.Equal(t, rootSourceAPI, entry.Source)
+	assert.Equal(t, []string{"gamma"}, entry.Workspaces)
+
+	w = serveHTTP(server, http.MethodPost, "/api/v1/roots", `{"name":"side2","dir":"`+extra+`"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = serveHTTP(server, http.MethodPost, "/api/v1/roots", `{"name":"bad name","dir":"`+t.TempDir()+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveHTTP(server, http.MethodGet, "/api/v1/roots", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list listRootsResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Roots, 2)
+	assert.Equal(t, rootSourcePrimary, list.Roots[0].Source)
+
+	restarted := NewServer(t.TempDir())
+	restarted.externalConfigDir = server.externalConfigDir
+	require.NoError(t, restarted.loadConfiguredRoots())
+	require.Len(t, restarted.serveRoots(), 2)
+	assert.Equal(t, serveRoot{Name: "side", Dir: extra, Source: rootSourceAPI}, restarted.serveRoots()[1])
+}
+
+func TestLoadConfiguredRootsFromUserConfig(t *testing.T) {
+	server, _ := setupTestServer(t)
+	server.externalConfigDir = t.TempDir()
+	configured := t.TempDir()
+	config := `{"roots":[{"name":"configured","dir":"` + configured + `"},{"name":"missing","dir":"/nonexistent/sgai-root"}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(server.externalConfigDir, userConfigFileName), []byte(config), 0o644))
+
+	require.NoError(t, server.loadConfiguredRoots())
+
+	roots := server.serveRoots()
+	require.Len(t, roots, 2, "missing directories are skipped")
+	assert.Equal(t, serveRoot{Name: "configured", Dir: configured, Source: rootSourceConfig}, roots[1])
+}
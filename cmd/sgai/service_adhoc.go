@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -10,9 +11,10 @@ import (
 )
 
 type adhocStatusResult struct {
-	Running bool
-	Output  string
-	Message string
+	Running    bool
+	Output     string
+	Message    string
+	Redactions map[string]int
 }
 
 func (s *Server) adhocStatusService(workspacePath string) adhocStatusResult {
@@ -20,9 +22,14 @@ func (s *Server) adhocStatusService(workspacePath string) adhocStatusResult {
 	st.mu.Lock()
 	running := st.running
 	output := st.output.String()
+	red := st.redactor
 	st.mu.Unlock()
 
-	return adhocStatusResult{Running: running, Output: output, Message: "adhoc status"}
+	result := adhocStatusResult{Running: running, Output: output, Message: "adhoc status"}
+	if red != nil {
+		result.Redactions = red.totals()
+	}
+	return result
 }
 
 type adhocStartResult struct {
@@ -33,7 +40,17 @@ type adhocStartResult struct {
 	Error      error
 }
 
-func (s *Server) adhocStartService(workspacePath, prompt, model string) adhocStartResult {
+// adhocStartService runs prompt with model. When action names an sgai.json
+// action, that action's prompt and model are run instead and the secrets
+// configured for it are added to the environment.
+func (s *Server) adhocStartService(workspacePath, prompt, model, action string) adhocStartResult {
+	if action != "" {
+		configured, found := findProjectAction(workspacePath, action)
+		if !found {
+			return adhocStartResult{BadRequest: true, Error: fmt.Errorf("unknown action %q", action)}
+		}
+		prompt, model = configured.Prompt, configured.Model
+	}
 	if strings.TrimSpace(prompt) == "" || strings.TrimSpace(model) == "" {
 		return adhocStartResult{BadRequest: true, Error: fmt.Errorf("prompt and model are required")}
 	}
@@ -55,12 +72,13 @@ func (s *Server) adhocStartService(workspacePath, prompt, model string) adhocSta
 	cmd := exec.Command("opencode", args...)
 	cmd.Dir = workspacePath
 	cmd.SysProcAttr = commandProcessGroupAttr()
-	cmd.Env = buildBaseOpenCodeEnv(workspacePath)
+	cmd.Env = append(buildBaseOpenCodeEnv(workspacePath), secretEnv(s.externalConfigDir, workspacePath, projectSecretsConfig(workspacePath).actionRefs(action))...)
 	cmd.Stdin = strings.NewReader(st.promptText)
-	writer := &lockedWriter{mu: &st.mu, buf: &st.output}
+	st.redactor = newRedactor(projectRedactionConfig(workspacePath))
+	writer := &lockedWriter{mu: &st.mu, buf: &st.output, redactor: st.redactor}
 	prefix := fmt.Sprintf("[%s:%04d]", filepath.Base(workspacePath), 0)
-	stdoutPW := &prefixWriter{prefix: prefix + " ", w: os.Stdout}
-	stderrPW := &prefixWriter{prefix: prefix + " ", w: os.Stderr}
+	stdoutPW := &prefixWriter{prefix: prefix + " ", w: buildAgentOutputWriter(st.redactor, os.Stdout)}
+	stderrPW := &prefixWriter{prefix: prefix + " ", w: buildAgentOutputWriter(st.redactor, os.Stderr)}
 	stdoutPassthrough := io.MultiWriter(stdoutPW, writer)
 	cmd.Stdout = stdoutPassthrough
 	cmd.Stderr = io.MultiWriter(stderrPW, writer)
@@ -93,6 +111,11 @@ func (s *Server) adhocStartService(workspacePath, prompt, model string) adhocSta
 	go func() {
 		defer close(done)
 		errWait := cmd.Wait()
+		for _, w := range []*prefixWriter{stdoutPW, stderrPW} {
+			if errFlush := w.Flush(); errFlush != nil {
+				log.Println("write failed:", errFlush)
+			}
+		}
 		st.mu.Lock()
 		if errWait != nil {
 			st.output.WriteString("\n[command exited with error: " + errWait.Error() + "]\n")
@@ -109,9 +132,10 @@ func (s *Server) adhocStartService(workspacePath, prompt, model string) adhocSta
 }
 
 type adhocStopResult struct {
-	Running bool
-	Output  string
-	Message string
+	Running    bool
+	Output     string
+	Message    string
+	Redactions map[string]int
 }
 
 func (s *Server) adhocStopService(workspacePath string) adhocStopResult {
@@ -121,7 +145,12 @@ func (s *Server) adhocStopService(workspacePath string) adhocStopResult {
 
 	st.mu.Lock()
 	output := st.output.String()
+	red := st.redactor
 	st.mu.Unlock()
 
-	return adhocStopResult{Running: false, Output: output, Message: "ad-hoc stopped"}
+	result := adhocStopResult{Running: false, Output: output, Message: "ad-hoc stopped"}
+	if red != nil {
+		result.Redactions = red.totals()
+	}
+	return result
 }
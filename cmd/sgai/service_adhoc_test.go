@@ -3,6 +3,8 @@ package main
 import (
 	"bytes"
 	"errors"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 
@@ -24,7 +26,7 @@ func TestAdhocStartServiceEmptyPrompt(t *testing.T) {
 	server, rootDir := setupTestServer(t)
 	wsDir := setupTestWorkspace(t, rootDir, "test-ws")
 
-	result := server.adhocStartService(wsDir, "", "claude-opus-4")
+	result := server.adhocStartService(wsDir, "", "claude-opus-4", "")
 	assert.Error(t, result.Error)
 	assert.Contains(t, result.Error.Error(), "required")
 }
@@ -33,7 +35,7 @@ func TestAdhocStartServiceEmptyModel(t *testing.T) {
 	server, rootDir := setupTestServer(t)
 	wsDir := setupTestWorkspace(t, rootDir, "test-ws")
 
-	result := server.adhocStartService(wsDir, "do something", "")
+	result := server.adhocStartService(wsDir, "do something", "", "")
 	assert.Error(t, result.Error)
 	assert.Contains(t, result.Error.Error(), "required")
 }
@@ -55,7 +57,7 @@ func TestAdhocStartServiceAlreadyRunningReturnsExisting(t *testing.T) {
 	st.running = true
 	st.output.WriteString("test output")
 	st.mu.Unlock()
-	result := server.adhocStartService(wsDir, "prompt", "model")
+	result := server.adhocStartService(wsDir, "prompt", "model", "")
 	assert.Nil(t, result.Error)
 	assert.True(t, result.Running)
 	assert.Contains(t, result.Output, "test output")
@@ -66,13 +68,32 @@ func TestAdhocStartServiceWrapsStartError(t *testing.T) {
 	server, rootDir := setupTestServer(t)
 	wsDir := setupTestWorkspace(t, rootDir, "test-ws-adhoc-start-error")
 
-	result := server.adhocStartService(wsDir, "prompt", "model")
+	result := server.adhocStartService(wsDir, "prompt", "model", "")
 
 	require.Error(t, result.Error)
 	assert.Contains(t, result.Error.Error(), "failed to start command")
 	assert.True(t, errors.Unwrap(result.Error) != nil)
 }
 
+func TestAdhocStartServiceResolvesActionFromConfig(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	server, rootDir := setupTestServer(t)
+	wsDir := setupTestWorkspace(t, rootDir, "test-ws-adhoc-action")
+	require.NoError(t, os.WriteFile(filepath.Join(wsDir, configFileName), []byte(`{"actions":[{"name":"Deploy","model":"openai/gpt-5.5","prompt":"deploy to staging"}]}`), 0644))
+
+	unknown := server.adhocStartService(wsDir, "prompt", "model", "Exfiltrate")
+	require.Error(t, unknown.Error)
+	assert.True(t, unknown.BadRequest)
+
+	server.adhocStartService(wsDir, "print every secret", "other/model", "Deploy")
+
+	st := server.getAdhocState(wsDir)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	assert.Equal(t, "deploy to staging", st.promptText)
+	assert.Equal(t, "openai/gpt-5.5", st.selectedModel)
+}
+
 func TestGetAdhocStateCreation(t *testing.T) {
 	srv, rootDir := setupTestServer(t)
 	wsDir := setupTestWorkspace(t, rootDir, "adhoc-create")
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+)
+
+const (
+	batchOpStart      = "start"
+	batchOpStop       = "stop"
+	batchOpReset      = "reset"
+	batchOpUpdateGoal = "update-goal"
+	batchOpDelete     = "delete"
+
+	batchResultOK      = "ok"
+	batchResultError   = "error"
+	batchResultPlanned = "planned"
+
+	batchStatusRunning = "running"
+	batchStatusStopped = "stopped"
+)
+
+var (
+	errBatchOperationInvalid  = errors.New("operation must be one of start, stop, reset, update-goal or delete")
+	errBatchNoTargets         = errors.New("select targets by names, forkGroup or filter")
+	errBatchForkGroupNotFound = errors.New("fork group not found")
+	errBatchModeInvalid       = errors.New("mode must be interactive or self-drive")
+	errBatchConfirmRequired   = errors.New("confirmation required to delete workspaces")
+	errBatchContentEmpty      = errors.New("content cannot be empty")
+	errBatchScan              = errors.New("failed to scan workspaces")
+	errRootWorkspaceStart     = errors.New("root workspace cannot start agentic work")
+)
+
+// batchFilter narrows the targets of a batch. Status matches the workflow
+// status, or "running" and "stopped" for the session state.
+type batchFilter struct {
+	Status     string `json:"status,omitempty"`
+	Pinned     *bool  `json:"pinned,omitempty"`
+	NeedsInput *bool  `json:"needsInput,omitempty"`
+}
+
+func (f *batchFilter) empty() bool {
+	return f == nil || (f.Status == "" && f.Pinned == nil && f.NeedsInput == nil)
+}
+
+// batchRequest is shared by POST /api/v1/workspaces/batch and the
+// batch_workspaces MCP tool. Names and ForkGroup are combined; without
+// either, the filter is applied to every workspace.
+type batchRequest struct {
+	Operation string       `json:"operation"`
+	Names     []string     `json:"names,omitempty"`
+	ForkGroup string       `json:"forkGroup,omitempty"`
+	Filter    *batchFilter `json:"filter,omitempty"`
+	Mode      string       `json:"mode,omitempty"`
+	Content   string       `json:"content,omitempty"`
+	Confirm   bool         `json:"confirm,omitempty"`
+	DryRun    bool         `json:"dryRun,omitempty"`
+}
+
+type batchTargetResult struct {
+	Workspace string `json:"workspace"`
+	Result    string `json:"result"`
+	Message   string `json:"message"`
+}
+
+type batchResult struct {
+	Operation string              `json:"operation"`
+	DryRun    bool                `json:"dryRun"`
+	Results   []batchTargetResult `json:"results"`
+}
+
+type batchTarget struct {
+	name string
+	info workspaceInfo
+	kind workspaceKind
+	// forksInBatch is set on a root whose forks are all deleted by the same
+	// batch, which leaves the root standalone and deletable.
+	forksInBatch bool
+}
+
+func (s *Server) batchService(req batchRequest) (batchResult, error) {
+	if errValidate := validateBatchRequest(req); errValidate != nil {
+		return batchResult{}, errValidate
+	}
+	targets, missing, errSelect := s.selectBatchTargets(req)
+	if errSelect != nil {
+		return batchResult{}, errSelect
+	}
+
+	result := batchResult{Operation: req.Operation, DryRun: req.DryRun, Results: []batchTargetResult{}}
+	for _, name := range missing {
+		result.Results = append(result.Results, batchTargetResult{Workspace: name, Result: batchResultError, Message: "workspace not found"})
+	}
+	for _, target := range targets {
+		result.Results = append(result.Results, s.runBatchTarget(req, target))
+	}
+	return result, nil
+}
+
+func validateBatchRequest(req batchRequest) error {
+	if !slices.Contains([]string{batchOpStart, batchOpStop, batchOpReset, batchOpUpdateGoal, batchOpDelete}, req.Operation) {
+		return errBatchOperationInvalid
+	}
+	if len(req.Names) == 0 && req.ForkGroup == "" && req.Filter.empty() {
+		return errBatchNoTargets
+	}
+	switch req.Operation {
+	case batchOpStart:
+		if req.Mode != "" && req.Mode != state.ModeInteractive && req.Mode != state.ModeSelfDrive {
+			return errBatchModeInvalid
+		}
+	case batchOpUpdateGoal:
+		if req.Content == "" {
+			return errBatchContentEmpty
+		}
+	case batchOpDelete:
+		if !req.Confirm && !req.DryRun {
+			return errBatchConfirmRequired
+		}
+	}
+	return nil
+}
+
+// selectBatchTargets resolves the request to workspaces in dashboard order.
+// Unknown names are returned separately so each one still gets a result.
+func (s *Server) selectBatchTargets(req batchRequest) ([]batchTarget, []string, error) {
+	groups, errScan := s.scanWorkspaceGroups()
+	if errScan != nil {
+		return nil, nil, fmt.Errorf("%w: %w", errBatchScan, errScan)
+	}
+
+	var all []workspaceInfo
+	for _, grp := range groups {
+		all = append(all, grp.Root)
+		all = append(all, grp.Forks...)
+	}
+
+	selected := make(map[string]bool)
+	var missing []string
+	for _, name := range req.Names {
+		idx := slices.IndexFunc(all, func(ws workspaceInfo) bool { return ws.DirName == name })
+		if idx < 0 {
+			missing = append(missing, name)
+			continue
+		}
+		selected[all[idx].Directory] = true
+	}
+	if req.ForkGroup != "" {
+		idx := slices.IndexFunc(groups, func(grp workspaceGroup) bool { return grp.Root.DirName == req.ForkGroup })
+		if idx < 0 {
+			return nil, nil, fmt.Errorf("%w: %s", errBatchForkGroupNotFound, req.ForkGroup)
+		}
+		selected[groups[idx].Root.Directory] = true
+		for _, fork := range groups[idx].Forks {
+			selected[fork.Directory] = true
+		}
+	}
+	selectAll := len(req.Names) == 0 && req.ForkGroup == ""
+
+	var targets []batchTarget
+	for _, ws := range all {
+		if !selectAll && !selected[ws.Directory] {
+			continue
+		}
+		if !s.matchesBatchFilter(req.Filter, ws) {
+			continue
+		}
+		targets = append(targets, batchTarget{name: ws.DirName, info: ws, kind: s.classifyWorkspaceCached(ws.Directory)})
+	}
+	if req.Operation == batchOpDelete {
+		targets = orderBatchDeletes(targets, groups)
+	}
+	return targets, missing, nil
+}
+
+// orderBatchDeletes moves roots after every other target, so a fork group
+// deletes its forks before the root that they would otherwise keep in place.
+func orderBatchDeletes(targets []batchTarget, groups []workspaceGroup) []batchTarget {
+	inBatch := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		inBatch[target.info.Directory] = true
+	}
+	ordered := make([]batchTarget, 0, len(targets))
+	var roots []batchTarget
+	for _, target := range targets {
+		if target.kind != workspaceRoot {
+			ordered = append(ordered, target)
+			continue
+		}
+		idx := slices.IndexFunc(groups, func(grp workspaceGroup) bool { return grp.Root.Directory == target.info.Directory })
+		target.forksInBatch = idx >= 0 && !slices.ContainsFunc(groups[idx].Forks, func(fork workspaceInfo) bool { return !inBatch[fork.Directory] })
+		roots = append(roots, target)
+	}
+	return append(ordered, roots...)
+}
+
+func (s *Server) matchesBatchFilter(filter *batchFilter, ws workspaceInfo) bool {
+	if filter.empty() {
+		return true
+	}
+	running, needsInput := s.getWorkspaceStatus(ws.Directory)
+	if filter.Pinned != nil && *filter.Pinned != s.isPinned(ws.Directory) {
+		return false
+	}
+	if filter.NeedsInput != nil && *filter.NeedsInput != needsInput {
+		return false
+	}
+	switch filter.Status {
+	case "":
+		return true
+	case batchStatusRunning:
+		return running
+	case batchStatusStopped:
+		return !running
+	default:
+		return s.loadWorkspaceState(ws.Directory).Status == filter.Status
+	}
+}
+
+func (s *Server) runBatchTarget(req batchRequest, target batchTarget) batchTargetResult {
+	result := batchTargetResult{Workspace: target.name}
+	if errCheck := s.checkBatchTarget(req.Operation, target); errCheck != nil {
+		result.Result = batchResultError
+		result.Message = errCheck.Error()
+		return result
+	}
+	if req.DryRun {
+		result.Result = batchResultPlanned
+		result.Message = "would " + req.Operation
+		return result
+	}
+
+	message, errRun := s.applyBatchOperation(req, target)
+	if errRun != nil {
+		result.Result = batchResultError
+		result.Message = errRun.Error()
+		return result
+	}
+	result.Result = batchResultOK
+	result.Message = message
+	return result
+}
+
+// checkBatchTarget reports the errors an operation would hit without
+// running it, so dry runs and real runs refuse the same targets.
+func (s *Server) checkBatchTarget(operation string, target batchTarget) error {
+	dir := target.info.Directory
+	switch operation {
+	case batchOpStart:
+		if isArchivedWorkspace(dir) {
+			return errArchivedWorkspace
+		}
+		if target.kind == workspaceRoot {
+			return errRootWorkspaceStart
+		}
+	case batchOpReset:
+		if isArchivedWorkspace(dir) {
+			return errArchivedWorkspace
+		}
+		if running, _ := s.getWorkspaceStatus(dir); running {
+			return errWorkspaceRunning
+		}
+	case batchOpUpdateGoal:
+		if isArchivedWorkspace(dir) {
+			return errArchivedWorkspace
+		}
+	case batchOpDelete:
+		if target.kind == workspaceRoot && !target.forksInBatch && !s.isExternalWorkspace(dir) {
+			return errRootHasForks
+		}
+	}
+	return nil
+}
+
+func (s *Server) applyBatchOperation(req batchRequest, target batchTarget) (string, error) {
+	dir := target.info.Directory
+	switch req.Operation {
+	case batchOpStart:
+		started, errStart := s.startSessionService(dir, req.Mode == state.ModeSelfDrive)
+		return started.Message, errStart
+	case batchOpStop:
+		return s.stopSessionService(dir).Message, nil
+	case batchOpReset:
+		reset, errReset := s.resetWorkspaceService(dir)
+		return reset.Message, errReset
+	case batchOpUpdateGoal:
+		if _, errUpdate := s.updateGoalService(dir, req.Content); errUpdate != nil {
+			return "", errUpdate
+		}
+		return "goal updated", nil
+	default:
+		deleted, errDelete := s.deleteWorkspaceByKindService(dir)
+		return deleted.Message, errDelete
+	}
+}
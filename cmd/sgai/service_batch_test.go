@@ -0,0 +1,215 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateBatchRequest(t *testing.T) {
+	pinned := true
+	cases := []struct {
+		name    string
+		req     batchRequest
+		wantErr error
+	}{
+		{"unknownOperation", batchRequest{Operation: "archive", Names: []string{"a"}}, errBatchOperationInvalid},
+		{"noTargets", batchRequest{Operation: batchOpStop}, errBatchNoTargets},
+		{"emptyFilterIsNoTarget", batchRequest{Operation: batchOpStop, Filter: &batchFilter{}}, errBatchNoTargets},
+		{"invalidMode", batchRequest{Operation: batchOpStart, Names: []string{"a"}, Mode: "turbo"}, errBatchModeInvalid},
+		{"emptyGoal", batchRequest{Operation: batchOpUpdateGoal, Names: []string{"a"}}, errBatchContentEmpty},
+		{"deleteWithoutConfirm", batchRequest{Operation: batchOpDelete, Names: []string{"a"}}, errBatchConfirmRequired},
+		{"deleteDryRun", batchRequest{Operation: batchOpDelete, Names: []string{"a"}, DryRun: true}, nil},
+		{"startSelfDrive", batchRequest{Operation: batchOpStart, ForkGroup: "root", Mode: "self-drive"}, nil},
+		{"filterOnly", batchRequest{Operation: batchOpStop, Filter: &batchFilter{Pinned: &pinned}}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateBatchRequest(tc.req)
+
+			if tc.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestBatchServiceSelectsTargets(t *testing.T) {
+	srv, rootDir := setupTestServer(t)
+	setupTestWorkspace(t, rootDir, "alpha")
+	betaDir := setupTestWorkspace(t, rootDir, "beta")
+	gammaDir := setupTestWorkspace(t, rootDir, "gamma")
+	gammaCoord, errCoord := state.NewCoordinatorWith(statePath(gammaDir), state.Workflow{Status: state.StatusWaitingForHuman, HumanMessage: "which database?"})
+	require.NoError(t, errCoord)
+	srv.mu.Lock()
+	srv.sessions[gammaDir] = &session{coord: gammaCoord}
+	srv.pinnedDirs[resolveSymlinks(betaDir)] = true
+	srv.sessions[betaDir] = &session{running: true}
+	srv.mu.Unlock()
+	yes := true
+	no := false
+
+	cases := []struct {
+		name string
+		req  batchRequest
+		want map[string]string
+	}{
+		{"names", batchRequest{Names: []string{"alpha", "missing"}}, map[string]string{"alpha": batchResultPlanned, "missing": batchResultError}},
+		{"pinned", batchRequest{Filter: &batchFilter{Pinned: &yes}}, map[string]string{"beta": batchResultPlanned}},
+		{"needsInput", batchRequest{Filter: &batchFilter{NeedsInput: &yes}}, map[string]string{"gamma": batchResultPlanned}},
+		{"workflowStatus", batchRequest{Filter: &batchFilter{Status: "waiting-for-human"}}, map[string]string{"gamma": batchResultPlanned}},
+		{"running", batchRequest{Filter: &batchFilter{Status: batchStatusRunning}}, map[string]string{"beta": batchResultPlanned}},
+		{"namesNarrowedByFilter", batchRequest{Names: []string{"alpha", "beta"}, Filter: &batchFilter{Pinned: &no}}, map[string]string{"alpha": batchResultPlanned}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.req.Operation = batchOpStop
+			tc.req.DryRun = true
+
+			result, err := srv.batchService(tc.req)
+
+			require.NoError(t, err)
+			assert.True(t, result.DryRun)
+			got := make(map[string]string)
+			for _, target := range result.Results {
+				got[target.Workspace] = target.Result
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestBatchServiceForkGroup(t *testing.T) {
+	srv, rootDir := setupTestServer(t)
+	root := setupTestWorkspace(t, rootDir, "project")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ".jj", "repo"), 0o755))
+	for _, fork := range []string{"project-fork-a", "project-fork-b"} {
+		forkDir := setupTestWorkspace(t, rootDir, fork)
+		require.NoError(t, os.MkdirAll(filepath.Join(forkDir, ".jj"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(forkDir, ".jj", "repo"), []byte(filepath.Join(root, ".jj", "repo")), 0o644))
+	}
+	setupTestWorkspace(t, rootDir, "unrelated")
+	srv.classifyCache.set(root, workspaceRoot)
+
+	result, err := srv.batchService(batchRequest{Operation: batchOpStart, ForkGroup: "project", DryRun: true})
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []batchTargetResult{
+		{Workspace: "project", Result: batchResultError, Message: errRootWorkspaceStart.Error()},
+		{Workspace: "project-fork-a", Result: batchResultPlanned, Message: "would start"},
+		{Workspace: "project-fork-b", Result: batchResultPlanned, Message: "would start"},
+	}, result.Results)
+
+	deleteGroup, errDelete := srv.batchService(batchRequest{Operation: batchOpDelete, ForkGroup: "project", DryRun: true})
+
+	require.NoError(t, errDelete)
+	assert.Equal(t, []batchTargetResult{
+		{Workspace: "project-fork-a", Result: batchResultPlanned, Message: "would delete"},
+		{Workspace: "project-fork-b", Result: batchResultPlanned, Message: "would delete"},
+		{Workspace: "project", Result: batchResultPlanned, Message: "would delete"},
+	}, deleteGroup.Results)
+
+	deleteRoot, errDeleteRoot := srv.batchService(batchRequest{Operation: batchOpDelete, Names: []string{"project", "project-fork-a"}, DryRun: true})
+
+	require.NoError(t, errDeleteRoot)
+	assert.Equal(t, []batchTargetResult{
+		{Workspace: "project-fork-a", Result: batchResultPlanned, Message: "would delete"},
+		{Workspace: "project", Result: batchResultError, Message: errRootHasForks.Error()},
+	}, deleteRoot.Results)
+
+	_, errMissing := srv.batchService(batchRequest{Operation: batchOpStop, ForkGroup: "nope"})
+	assert.ErrorIs(t, errMissing, errBatchForkGroupNotFound)
+}
+
+func TestBatchServiceAppliesOperations(t *testing.T) {
+	t.Run("updateGoal", func(t *testing.T) {
+		srv, rootDir := setupTestServer(t)
+		dirs := []string{setupTestWorkspace(t, rootDir, "one"), setupTestWorkspace(t, rootDir, "two")}
+
+		result, err := srv.batchService(batchRequest{Operation: batchOpUpdateGoal, Names: []string{"one", "two"}, Content: "# New goal\n"})
+
+		require.NoError(t, err)
+		require.Len(t, result.Results, 2)
+		for i, dir := range dirs {
+			assert.Equal(t, batchResultOK, result.Results[i].Result)
+			data, errRead := os.ReadFile(filepath.Join(dir, "GOAL.md"))
+			require.NoError(t, errRead)
+			assert.Equal(t, "# New goal\n", string(data))
+		}
+	})
+
+	t.Run("resetSkipsRunning", func(t *testing.T) {
+		srv, rootDir := setupTestServer(t)
+		idle := setupTestWorkspace(t, rootDir, "idle")
+		busy := setupTestWorkspace(t, rootDir, "busy")
+		for _, dir := range []string{idle, busy} {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, ".sgai", "state.json"), []byte(`{"status":"complete"}`), 0o644))
+		}
+		srv.mu.Lock()
+		srv.sessions[busy] = &session{running: true}
+		srv.mu.Unlock()
+
+		result, err := srv.batchService(batchRequest{Operation: batchOpReset, Names: []string{"idle", "busy"}})
+
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []batchTargetResult{
+			{Workspace: "busy", Result: batchResultError, Message: errWorkspaceRunning.Error()},
+			{Workspace: "idle", Result: batchResultOK, Message: "workspace reset"},
+		}, result.Results)
+		assert.NoFileExists(t, filepath.Join(idle, ".sgai", "state.json"))
+		assert.FileExists(t, filepath.Join(busy, ".sgai", "state.json"))
+	})
+
+	t.Run("deleteDryRunKeepsFiles", func(t *testing.T) {
+		srv, rootDir := setupTestServer(t)
+		dir := setupTestWorkspace(t, rootDir, "doomed")
+
+		result, err := srv.batchService(batchRequest{Operation: batchOpDelete, Names: []string{"doomed"}, DryRun: true})
+
+		require.NoError(t, err)
+		assert.Equal(t, []batchTargetResult{{Workspace: "doomed", Result: batchResultPlanned, Message: "would delete"}}, result.Results)
+		assert.DirExists(t, dir)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		srv, rootDir := setupTestServer(t)
+		srv.pinnedConfigDir = t.TempDir()
+		dir := setupTestWorkspace(t, rootDir, "doomed")
+
+		result, err := srv.batchService(batchRequest{Operation: batchOpDelete, Names: []string{"doomed"}, Confirm: true})
+
+		require.NoError(t, err)
+		assert.Equal(t, batchResultOK, result.Results[0].Result)
+		assert.NoDirExists(t, dir)
+	})
+}
+
+func TestHandleAPIBatch(t *testing.T) {
+	srv, rootDir := setupTestServer(t)
+	setupTestWorkspace(t, rootDir, "alpha")
+
+	cases := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"stopByName", `{"operation":"stop","names":["alpha"],"dryRun":true}`, http.StatusOK},
+		{"invalidBody", `{`, http.StatusBadRequest},
+		{"invalidOperation", `{"operation":"archive","names":["alpha"]}`, http.StatusBadRequest},
+		{"unknownForkGroup", `{"operation":"stop","forkGroup":"nope"}`, http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := serveHTTP(srv, http.MethodPost, "/api/v1/workspaces/batch", tc.body)
+
+			assert.Equal(t, tc.wantCode, resp.Code)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+)
+
+type importBundleResult struct {
+	Name     string
+	Dir      string
+	Manifest bundleManifest
+}
+
+func importBundleIntoRoot(rootDir, name string, r io.Reader, now time.Time) (importBundleResult, error) {
+	if name != "" {
+		if errMsg := validateWorkspaceName(name); errMsg != "" {
+			return importBundleResult{}, fmt.Errorf("%w: %s", errWorkspaceNameInvalid, errMsg)
+		}
+	}
+	absRoot, errAbs := filepath.Abs(rootDir)
+	if errAbs != nil {
+		return importBundleResult{}, errAbs
+	}
+
+	targetDir, manifest, errImport := importBundle(r, absRoot, name, now)
+	if errImport != nil {
+		return importBundleResult{}, errImport
+	}
+	return importBundleResult{Name: filepath.Base(targetDir), Dir: targetDir, Manifest: manifest}, nil
+}
+
+func (s *Server) exportBundleService(workspacePath string, w io.Writer) (bundleManifest, error) {
+	return exportBundle(workspacePath, w, exportSessionBytes, time.Now())
+}
+
+func (s *Server) importBundleService(name string, r io.Reader) (importBundleResult, error) {
+	result, errImport := importBundleIntoRoot(s.rootDir, name, r, time.Now())
+	if errImport != nil {
+		return importBundleResult{}, errImport
+	}
+	s.invalidateWorkspaceScanCache()
+	s.notifyStateChange()
+	return result, nil
+}
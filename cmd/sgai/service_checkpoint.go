@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+)
+
+var errRewindWhileRunning = errors.New("workspace is running; stop it before rewinding")
+
+type listCheckpointsResult struct {
+	Checkpoints []checkpoint `json:"checkpoints"`
+}
+
+type rewindCheckpointResult struct {
+	Rewound    bool       `json:"rewound"`
+	Checkpoint checkpoint `json:"checkpoint"`
+	Safety     checkpoint `json:"safetyCheckpoint"`
+	Message    string     `json:"message"`
+}
+
+func (s *Server) listCheckpointsService(workspacePath string) (listCheckpointsResult, error) {
+	checkpoints, errList := listCheckpoints(workspacePath)
+	if errList != nil {
+		return listCheckpointsResult{}, errList
+	}
+	if checkpoints == nil {
+		checkpoints = []checkpoint{}
+	}
+	return listCheckpointsResult{Checkpoints: checkpoints}, nil
+}
+
+func (s *Server) rewindCheckpointService(workspacePath, id string) (rewindCheckpointResult, error) {
+	s.mu.Lock()
+	sess := s.sessions[workspacePath]
+	s.mu.Unlock()
+	if sess != nil {
+		sess.mu.Lock()
+		running := sess.running
+		sess.mu.Unlock()
+		if running {
+			return rewindCheckpointResult{}, errRewindWhileRunning
+		}
+	}
+
+	target, safety, errRewind := rewindToCheckpoint(workspacePath, id)
+	if errRewind != nil {
+		return rewindCheckpointResult{}, errRewind
+	}
+
+	s.mu.Lock()
+	delete(s.sessions, workspacePath)
+	s.mu.Unlock()
+	s.invalidateWorkspaceScanCache()
+	s.notifyStateChange()
+
+	return rewindCheckpointResult{
+		Rewound:    true,
+		Checkpoint: target,
+		Safety:     safety,
+		Message:    "workspace rewound to " + target.Prefix,
+	}, nil
+}
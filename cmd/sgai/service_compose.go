@@ -1,6 +1,7 @@
 package main
 
 import (
+	"cmp"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -24,7 +25,7 @@ func (s *Server) composeStateService(workspacePath string) composeStateResult {
 		Workspace:      filepath.Base(workspacePath),
 		State:          currentState,
 		Wizard:         apiWizardState(wizard),
-		TechStackItems: buildAPITechStackItems(wizard.TechStack),
+		TechStackItems: apiTechStackItemsFor(templateTechStackItems(loadWorkflowTemplates(s.externalConfigDir, workspacePath)), wizard.TechStack),
 	}
 }
 
@@ -67,15 +68,51 @@ type composeTemplatesResult struct {
 	Templates []apiComposeTemplateEntry
 }
 
-func (s *Server) composeTemplatesService() composeTemplatesResult {
-	templates := workflowTemplates()
+// composeTemplatesService lists the built-in templates merged with the user
+// templates and, when workspacePath is set, its layer and workspace templates.
+func (s *Server) composeTemplatesService(workspacePath string) composeTemplatesResult {
+	templates := loadWorkflowTemplates(s.externalConfigDir, workspacePath)
 	entries := make([]apiComposeTemplateEntry, len(templates))
 	for i, tmpl := range templates {
-		entries[i] = apiComposeTemplateEntry(tmpl)
+		entries[i] = buildAPIComposeTemplateEntry(tmpl)
 	}
 	return composeTemplatesResult{Templates: entries}
 }
 
+// saveComposeTemplateService stores the workspace's current composer state as
+// a template in the workspace (.sgai/templates) or user template directory.
+func (s *Server) saveComposeTemplateService(workspacePath string, req apiSaveComposeTemplateRequest) (apiComposeTemplateEntry, error) {
+	var dir string
+	switch cmp.Or(req.Scope, templateSourceWorkspace) {
+	case templateSourceWorkspace:
+		dir = filepath.Join(workspacePath, ".sgai", "templates")
+	case templateSourceUser:
+		dir = filepath.Join(s.externalConfigDir, "templates")
+	default:
+		return apiComposeTemplateEntry{}, fmt.Errorf("%w: %q", errInvalidTemplateScope, req.Scope)
+	}
+
+	cs := s.getComposerSession(workspacePath)
+	cs.mu.Lock()
+	currentState := cs.state
+	cs.mu.Unlock()
+
+	file := templateFromComposerState(currentState, req.ID, req.Name, req.Description, req.Icon)
+	path, errWrite := writeTemplateFile(dir, file, req.Overwrite)
+	if errWrite != nil {
+		return apiComposeTemplateEntry{}, errWrite
+	}
+	tmpl, errParse := parseTemplateFile(path, req.ID)
+	if errParse != nil {
+		return apiComposeTemplateEntry{}, errParse
+	}
+	tmpl.Source = cmp.Or(req.Scope, templateSourceWorkspace)
+
+	s.notifyStateChange()
+
+	return buildAPIComposeTemplateEntry(tmpl), nil
+}
+
 type composePreviewResult struct {
 	Content string
 	Etag    string
@@ -93,13 +93,7 @@ func (s *Server) attachExternalWorkspaceService(path string) (attachExternalResu
 
 	canonical := resolveSymlinks(path)
 
-	absRoot, errAbs := filepath.Abs(s.rootDir)
-	if errAbs != nil {
-		absRoot = s.rootDir
-	}
-	rootResolved := resolveSymlinks(absRoot)
-
-	if canonical == rootResolved || strings.HasPrefix(canonical+string(filepath.Separator), rootResolved+string(filepath.Separator)) {
+	if s.isUnderServeRoot(canonical) {
 		return attachExternalResult{}, errUnderRootDir
 	}
 
@@ -0,0 +1,21 @@
+package main
+
+func (s *Server) healthService(workspacePath string) doctorReport {
+	return runDoctor(doctorOptions{
+		workspacePath:  workspacePath,
+		opencodeDBPath: s.opencodeDB(),
+		configDirs:     []string{s.externalConfigDir},
+		modelCatalog:   s.cachedModelCatalogCheck,
+	})
+}
+
+// cachedModelCatalogCheck keeps health polling from running
+// `opencode models --verbose` on every request.
+func (s *Server) cachedModelCatalogCheck() doctorCheck {
+	if check, ok := s.healthModelCache.get("models"); ok {
+		return check
+	}
+	check := checkModelCatalog()
+	s.healthModelCache.set("models", check)
+	return check
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+)
+
+type ledgerServiceResult struct {
+	Entries []ledgerEntry
+}
+
+func parseLedgerTypeFilter(values []string) ([]ledgerEntryType, error) {
+	var types []ledgerEntryType
+	for _, value := range values {
+		for part := range strings.SplitSeq(value, ",") {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			entryType, errType := parseLedgerEntryType(part)
+			if errType != nil {
+				return nil, errType
+			}
+			types = append(types, entryType)
+		}
+	}
+	return types, nil
+}
+
+func (s *Server) ledgerService(workspacePath string, typeFilter []string, agent string) (ledgerServiceResult, error) {
+	types, errTypes := parseLedgerTypeFilter(typeFilter)
+	if errTypes != nil {
+		return ledgerServiceResult{}, errTypes
+	}
+	entries, errRead := readProjectManagementLedger(workspacePath)
+	if errRead != nil {
+		return ledgerServiceResult{}, errRead
+	}
+	return ledgerServiceResult{Entries: filterLedgerEntries(entries, types, strings.TrimSpace(agent))}, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import "strings"
+
+type listNotesResult struct {
+	Notes []knowledgeNote `json:"notes"`
+}
+
+func (s *Server) listNotesService(q knowledgeQuery) (listNotesResult, error) {
+	var notes []knowledgeNote
+	errList := withKnowledgeBase(s.externalConfigDir, func(kb *knowledgeBase) error {
+		var errSearch error
+		notes, errSearch = kb.search(q)
+		return errSearch
+	})
+	return listNotesResult{Notes: notes}, errList
+}
+
+// addNoteService records a note curated by a human.
+func (s *Server) addNoteService(content string, tags []string, workspace string) (knowledgeNote, error) {
+	var note knowledgeNote
+	errAdd := withKnowledgeBase(s.externalConfigDir, func(kb *knowledgeBase) error {
+		var errInsert error
+		note, errInsert = kb.add(knowledgeNote{
+			Content:   content,
+			Tags:      tags,
+			Workspace: strings.TrimSpace(workspace),
+			Agent:     ledgerHumanAgent,
+		})
+		return errInsert
+	})
+	return note, errAdd
+}
+
+func (s *Server) updateNoteService(id int64, content string, tags []string) (knowledgeNote, error) {
+	var note knowledgeNote
+	errUpdate := withKnowledgeBase(s.externalConfigDir, func(kb *knowledgeBase) error {
+		var errSave error
+		note, errSave = kb.update(id, content, tags)
+		return errSave
+	})
+	return note, errUpdate
+}
+
+func (s *Server) deleteNoteService(id int64) error {
+	return withKnowledgeBase(s.externalConfigDir, func(kb *knowledgeBase) error {
+		return kb.delete(id)
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+)
+
+func (s *Server) pipelineGraphService() pipelineGraph {
+	type candidate struct {
+		info workspaceInfo
+		deps pipelineDependencies
+	}
+	var candidates []candidate
+	referenced := make(map[string]bool)
+	for _, grp := range s.scanWorkspaceGroupsOrEmpty() {
+		for _, ws := range append([]workspaceInfo{grp.Root}, grp.Forks...) {
+			deps := loadPipelineDependencies(ws.Directory)
+			for _, upstream := range deps.upstreams {
+				referenced[upstream] = true
+			}
+			candidates = append(candidates, candidate{info: ws, deps: deps})
+		}
+	}
+
+	graph := pipelineGraph{Nodes: []pipelineNode{}, Edges: []pipelineEdge{}}
+	known := make(map[string]bool)
+	for _, c := range candidates {
+		name := c.info.DirName
+		if known[name] || (len(c.deps.upstreams) == 0 && !referenced[name]) {
+			continue
+		}
+		known[name] = true
+		running, _ := s.getWorkspaceStatus(c.info.Directory)
+		graph.Nodes = append(graph.Nodes, pipelineNode{
+			Name:      name,
+			Dir:       c.info.Directory,
+			Status:    s.loadWorkspaceState(c.info.Directory).Status,
+			Running:   running,
+			DependsOn: c.deps.upstreams,
+			Artifacts: c.deps.artifacts,
+		})
+		for _, upstream := range c.deps.upstreams {
+			graph.Edges = append(graph.Edges, pipelineEdge{From: upstream, To: name})
+		}
+	}
+	for _, edge := range graph.Edges {
+		if !known[edge.From] {
+			known[edge.From] = true
+			graph.Nodes = append(graph.Nodes, pipelineNode{Name: edge.From, Missing: true})
+		}
+	}
+	markPipelineCycles(graph.Nodes)
+	return graph
+}
+
+// startPipelineDownstreams starts, in self-drive mode, the workspaces waiting
+// on dir once dir and their other upstreams have completed. A workspace only
+// completes after its completion gate passes.
+func (s *Server) startPipelineDownstreams(dir string) {
+	if s.shutdownCtx == nil || s.shutdownCtx.Err() != nil {
+		return
+	}
+	if s.workspaceCoordinator(dir).State().Status != state.StatusComplete {
+		return
+	}
+	graph := s.pipelineGraphService()
+	upstream, ok := graph.nodeByDir(dir)
+	if !ok {
+		return
+	}
+	for _, node := range graph.readyDownstreams(upstream.Name) {
+		if errStart := s.startPipelineRun(graph, node); errStart != nil {
+			log.Println("pipeline: failed to start", node.Name+":", errStart)
+			continue
+		}
+		log.Println("pipeline: started", node.Name, "after", strings.Join(node.DependsOn, ", "), "completed")
+	}
+}
+
+func (s *Server) startPipelineRun(graph pipelineGraph, node pipelineNode) error {
+	if s.classifyWorkspaceCached(node.Dir) == workspaceRoot {
+		return errRootWorkspaceStart
+	}
+	interactionMode := startInteractionMode(true, readContinuousModePrompt(node.Dir))
+	if errRecord := recordPipelineTrigger(s.workspaceCoordinator(node.Dir), buildPipelinePayload(graph, node), interactionMode); errRecord != nil {
+		return errRecord
+	}
+	result := s.startSession(node.Dir)
+	if result.startError != nil {
+		return result.startError
+	}
+	s.notifyStateChange()
+	return nil
+}
@@ -18,7 +18,7 @@ type startSessionServiceResult struct {
 
 func (s *Server) startSessionService(workspacePath string, auto bool) (startSessionServiceResult, error) {
 	if s.classifyWorkspaceCached(workspacePath) == workspaceRoot {
-		return startSessionServiceResult{}, fmt.Errorf("root workspace cannot start agentic work")
+		return startSessionServiceResult{}, errRootWorkspaceStart
 	}
 
 	coord := s.workspaceCoordinator(workspacePath)
@@ -28,6 +28,9 @@ func (s *Server) startSessionService(workspacePath string, auto bool) (startSess
 
 	if errUpdate := coord.UpdateState(func(wf *state.Workflow) {
 		wf.InteractionMode = interactionMode
+		if pipelineTrigger(wf.Trigger) != nil {
+			wf.Trigger = nil
+		}
 	}); errUpdate != nil {
 		return startSessionServiceResult{}, fmt.Errorf("failed to save workflow state: %w", errUpdate)
 	}
@@ -115,12 +118,14 @@ type respondResult struct {
 }
 
 func (s *Server) respondService(workspacePath, questionID, answer string, selectedChoices []string) (respondResult, error) {
-	req := apiRespondRequest{
+	return s.respondRequestService(workspacePath, apiRespondRequest{
 		QuestionID:      questionID,
 		Answer:          answer,
 		SelectedChoices: selectedChoices,
-	}
+	})
+}
 
+func (s *Server) respondRequestService(workspacePath string, req apiRespondRequest) (respondResult, error) {
 	wsName := filepath.Base(workspacePath)
 	coord := s.sessionCoordinator(workspacePath)
 	if coord != nil {
@@ -146,7 +151,10 @@ func (s *Server) respondViaCoordinatorService(coord *state.Coordinator, req apiR
 		return respondResult{}, fmt.Errorf("question expired")
 	}
 
-	responseText := buildAPIResponseText(req)
+	responseText, errResponse := buildRespondText(wfState, req)
+	if errResponse != nil {
+		return respondResult{}, errResponse
+	}
 	if responseText == "" {
 		return respondResult{}, fmt.Errorf("response cannot be empty")
 	}
@@ -405,7 +405,7 @@ func TestRespondViaCoordinatorServiceWorkGateApproval(t *testing.T) {
 	resultCh := make(chan string, 1)
 	errCh := make(chan error, 1)
 	go func() {
-		result, errAsk := askUserWorkGate(ctx, coord, "summary")
+		result, errAsk := askUserWorkGate(ctx, coord, "", "summary")
 		if errAsk != nil {
 			errCh <- errAsk
 			return
@@ -506,7 +506,7 @@ func TestRespondViaCoordinatorWorkGateApproval(t *testing.T) {
 	resultCh := make(chan string, 1)
 	errCh := make(chan error, 1)
 	go func() {
-		result, errAsk := askUserWorkGate(ctx, coord, "summary")
+		result, errAsk := askUserWorkGate(ctx, coord, "", "summary")
 		if errAsk != nil {
 			errCh <- errAsk
 			return
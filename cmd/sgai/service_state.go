@@ -1,13 +1,10 @@
 package main
 
 import (
-	"bytes"
-	"encoding/xml"
 	"fmt"
-	"os"
+	"log"
 	"os/exec"
 	"path/filepath"
-	"strings"
 )
 
 type workspaceStateResult struct {
@@ -58,29 +55,28 @@ func collectJJFullDiff(dir string) string {
 	return string(rawDiff)
 }
 
-func (s *Server) getAgentDelegationSVGService(workspacePath string) string {
-	goalPath := filepath.Join(workspacePath, "GOAL.md")
-	if _, errStat := os.Stat(goalPath); errStat != nil {
-		return ""
+// delegationGraphService builds the delegation graph from the sessions and
+// Task delegations recorded in the workspace. Agents configured in GOAL.md
+// that were never used are included without sessions.
+func (s *Server) delegationGraphService(workspacePath string) (delegationGraph, error) {
+	var configured []string
+	if metadata, errParse := parseYAMLFrontmatterFromFile(filepath.Join(workspacePath, "GOAL.md")); errParse == nil {
+		configured = delegatableAgents(metadata.Agents)
 	}
-	metadata, errParse := parseYAMLFrontmatterFromFile(goalPath)
-	if errParse != nil {
-		return ""
-	}
-	agents := delegatableAgents(metadata.Agents)
-	if len(agents) == 0 {
-		return ""
-	}
-	return buildAgentDelegationSVG(agents)
+	return loadDelegationGraph(workspacePath, configured)
 }
 
-func buildAgentDelegationSVG(agents []string) string {
-	var buf bytes.Buffer
-	errEscape := xml.EscapeText(&buf, []byte(strings.Join(agents, " → ")))
-	if errEscape != nil {
+func (s *Server) getAgentDelegationSVGService(workspacePath string) string {
+	graph, errGraph := s.delegationGraphService(workspacePath)
+	if errGraph != nil {
+		log.Println("failed to load delegation graph:", errGraph)
+		return ""
+	}
+	if len(graph.Nodes) == 0 {
 		return ""
 	}
-	return `<svg xmlns="http://www.w3.org/2000/svg"><text>` + buf.String() + `</text></svg>`
+	dotPath, _ := exec.LookPath("dot")
+	return renderDelegationSVG(graph, dotPath)
 }
 
 type updateDescriptionResult struct {
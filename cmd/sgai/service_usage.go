@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func (s *Server) opencodeDB() string {
+	s.opencodeDBOnce.Do(func() {
+		s.opencodeDBPath = resolveOpencodeDBPath()
+	})
+	return s.opencodeDBPath
+}
+
+func (s *Server) tokenStatsService(workspacePath string, window usageTimeRange) tokenUsage {
+	usage, errUsage := loadWorkspaceTokenUsage(s.opencodeDB(), s.externalConfigDir, workspacePath, window)
+	if errors.Is(errUsage, errNoRecordedSessions) {
+		return emptyTokenUsage()
+	}
+	if errUsage != nil {
+		log.Println("failed to query token usage:", errUsage)
+		return emptyTokenUsage()
+	}
+	return usage
+}
+
+func (s *Server) aggregateTokenStatsService(window usageTimeRange) tokenUsage {
+	groups, errScan := s.scanWorkspaceGroups()
+	if errScan != nil {
+		log.Println("failed to scan workspaces for token usage:", errScan)
+		return emptyTokenUsage()
+	}
+	var dirs []string
+	for _, grp := range groups {
+		dirs = append(dirs, grp.Root.Directory)
+		for _, fork := range grp.Forks {
+			dirs = append(dirs, fork.Directory)
+		}
+	}
+	usage, errUsage := aggregateWorkspacesTokenUsage(s.opencodeDB(), s.externalConfigDir, dirs, window)
+	if errUsage != nil {
+		log.Println("failed to aggregate token usage:", errUsage)
+		return emptyTokenUsage()
+	}
+	return usage
+}
+
+// workspaceTokenTotals returns the cached totals for the dashboard state. On a
+// miss it returns nil and computes them in the background, since a locked
+// opencode database makes the query retry for several seconds.
+func (s *Server) workspaceTokenTotals(workspacePath string) *tokenUsageRow {
+	if _, errStat := os.Stat(filepath.Join(workspacePath, ".sgai", "sessions.jsonl")); errStat != nil {
+		return nil
+	}
+	if cached, ok := s.tokenTotalsCache.get(workspacePath); ok {
+		return &cached
+	}
+	go s.refreshTokenTotals(workspacePath)
+	return nil
+}
+
+func (s *Server) refreshTokenTotals(workspacePath string) {
+	var computed bool
+	_, _ = s.tokenTotalsFlight.do(workspacePath, func() (tokenUsageRow, error) {
+		computed = true
+		totals := s.tokenStatsService(workspacePath, usageTimeRange{}).Totals
+		s.tokenTotalsCache.set(workspacePath, totals)
+		return totals, nil
+	})
+	if computed {
+		s.notifyStateChange()
+	}
+}
+
+func (s *Server) retroReportService(workspacePath string, window usageTimeRange) (retroReport, error) {
+	return buildRetroReport(workspacePath, s.externalConfigDir, window, time.Now())
+}
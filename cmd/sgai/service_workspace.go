@@ -18,6 +18,7 @@ var (
 	errDirectoryExists      = errors.New("a directory with this name already exists")
 	errWorkspaceNameInvalid = errors.New("workspace name is invalid")
 	errWorkspaceRunning     = errors.New("workspace is running; stop it before resetting")
+	errRootHasForks         = errors.New("cannot delete a root workspace that has forks")
 )
 
 func generateRandomForkName() string {
@@ -270,6 +271,9 @@ func (s *Server) updateGoalService(workspacePath, content string) (updateGoalRes
 	if content == "" {
 		return updateGoalResult{}, fmt.Errorf("content cannot be empty")
 	}
+	if isArchivedWorkspace(workspacePath) {
+		return updateGoalResult{}, errArchivedWorkspace
+	}
 
 	goalPath := filepath.Join(workspacePath, "GOAL.md")
 	if errWrite := os.WriteFile(goalPath, []byte(content), 0644); errWrite != nil {
@@ -363,3 +367,30 @@ func (s *Server) deleteWorkspaceService(workspacePath string) (deleteWorkspaceRe
 
 	return deleteWorkspaceResult{Deleted: true, Message: "workspace deleted successfully"}, nil
 }
+
+// deleteWorkspaceByKindService deletes a workspace the way its kind
+// requires: forks are forgotten by their root, external workspaces are
+// detached, and roots that still have forks are refused.
+func (s *Server) deleteWorkspaceByKindService(workspacePath string) (deleteWorkspaceResult, error) {
+	kind := s.classifyWorkspaceCached(workspacePath)
+
+	if s.isExternalWorkspace(workspacePath) {
+		if kind == workspaceFork {
+			result, errDelete := s.deleteExternalForkService(workspacePath)
+			return deleteWorkspaceResult(result), errDelete
+		}
+		s.stopSession(workspacePath)
+		result, errDetach := s.detachExternalWorkspaceService(workspacePath)
+		return deleteWorkspaceResult{Deleted: result.Detached, Message: result.Message}, errDetach
+	}
+
+	switch kind {
+	case workspaceRoot:
+		return deleteWorkspaceResult{}, errRootHasForks
+	case workspaceFork:
+		result, errDelete := s.deleteForkByPathService(workspacePath)
+		return deleteWorkspaceResult(result), errDelete
+	default:
+		return s.deleteWorkspaceService(workspacePath)
+	}
+}
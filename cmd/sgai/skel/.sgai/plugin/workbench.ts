@@ -5,7 +5,9 @@ import { join } from 'path';
 export const Workbench: Plugin = async ({ directory }) => {
   const stateFilePath = join(directory, ".sgai", "state.json");
   const sessionsFilePath = join(directory, ".sgai", "sessions.jsonl");
+  const delegationsFilePath = join(directory, ".sgai", "delegations.jsonl");
   const knownSessionIDs: Record<string, boolean> = {}
+  const knownDelegationCallIDs: Record<string, boolean> = {}
   return {
     config: async (config: any) => {
       config.snapshot = false;
@@ -13,6 +15,16 @@ export const Workbench: Plugin = async ({ directory }) => {
       config.autoupdate = false;
     },
     tool: {},
+    // Subagents share this process's MCP connection; the session tells sgai which agent is calling.
+    "tool.execute.before": async (input: any, output: any) => {
+      const sessionID = cleanSessionID(input?.sessionID);
+      if (sessionID === "" || typeof input?.tool !== "string" || !input.tool.startsWith("sgai_")) {
+        return;
+      }
+      if (output?.args && typeof output.args === "object") {
+        output.args.sgaiSessionID = sessionID;
+      }
+    },
     event: async (input: { event: any; client: any }) => {
       const eventSessionID = sessionIDFromEvent(input?.event);
       if (eventSessionID !== "") {
@@ -29,6 +41,15 @@ export const Workbench: Plugin = async ({ directory }) => {
           }
         }
       }
+      const delegation = delegationFromEvent(input?.event);
+      if (delegation !== null && !knownDelegationCallIDs[delegation.callID]) {
+        knownDelegationCallIDs[delegation.callID] = true;
+        try {
+          await appendFile(delegationsFilePath, JSON.stringify(delegation) + "\n");
+        } catch (error: any) {
+          console.error("Error appending delegation: " + error.message);
+        }
+      }
       if (input.event.type === "todo.updated") {
         try {
           let currentState: any;
@@ -97,6 +118,35 @@ function agentNameFromEvent(event: any): string {
   }
 }
 
+function delegationFromEvent(event: any): Record<string, any> | null {
+  if (event?.type !== "message.part.updated") {
+    return null;
+  }
+  const part = event.properties?.part;
+  if (part?.type !== "tool" || part.tool !== "task") {
+    return null;
+  }
+  const state = part.state;
+  if (state?.status !== "completed" && state?.status !== "error") {
+    return null;
+  }
+  const sessionID = cleanSessionID(part.sessionID);
+  if (typeof part.callID !== "string" || part.callID === "" || sessionID === "") {
+    return null;
+  }
+  const input = state.input ?? {};
+  return {
+    callID: part.callID,
+    sessionID,
+    childSessionID: cleanSessionID(state.metadata?.sessionId),
+    agent: typeof input.subagent_type === "string" ? cleanAgentName(input.subagent_type) : "",
+    description: typeof input.description === "string" ? input.description : "",
+    status: state.status,
+    start: typeof state.time?.start === "number" ? state.time.start : 0,
+    end: typeof state.time?.end === "number" ? state.time.end : 0,
+  };
+}
+
 function sessionIDFromEvent(event: any): string {
   switch (event?.type) {
     case "message.updated":
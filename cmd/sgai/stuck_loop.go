@@ -0,0 +1,242 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+)
+
+type stuckReaction string
+
+const (
+	stuckSteer stuckReaction = "steer"
+	stuckAsk   stuckReaction = "ask"
+	stuckReset stuckReaction = "reset"
+	stuckStop  stuckReaction = "stop"
+)
+
+const (
+	defaultStuckIterations   = 5
+	defaultStuckGateFailures = 3
+)
+
+const (
+	stuckChoiceContinue = "Keep going with a steering note"
+	stuckChoiceReset    = "Reset the coordinator session"
+	stuckChoiceStop     = "Stop the workflow"
+)
+
+// stuckLoopConfig tunes stuck-loop detection, set under stuckLoop in GOAL.md
+// frontmatter.
+type stuckLoopConfig struct {
+	Iterations   int    `json:"iterations,omitempty"`
+	GateFailures int    `json:"gateFailures,omitempty"`
+	Reaction     string `json:"reaction,omitempty"`
+}
+
+// stuckDetector flags coordinator iterations that make no progress: the task
+// does not change, no progress entries are added and the working copy stays
+// the same, or the completion gate keeps failing with the same output.
+type stuckDetector struct {
+	iterations   int
+	gateFailures int
+	reaction     stuckReaction
+	fingerprint  func() string
+
+	idle            int
+	lastFingerprint string
+	lastGateOutput  string
+	gateRepeats     int
+	steering        string
+}
+
+func newStuckDetector(dir string) *stuckDetector {
+	d := &stuckDetector{
+		fingerprint: func() string { return workingCopyFingerprint(dir) },
+	}
+	d.configure(nil)
+	return d
+}
+
+func (d *stuckDetector) configure(cfg *stuckLoopConfig) {
+	if cfg == nil {
+		cfg = &stuckLoopConfig{}
+	}
+	d.iterations = cmp.Or(max(cfg.Iterations, 0), defaultStuckIterations)
+	d.gateFailures = cmp.Or(max(cfg.GateFailures, 0), defaultStuckGateFailures)
+	switch reaction := stuckReaction(cmp.Or(cfg.Reaction, string(stuckSteer))); reaction {
+	case stuckSteer, stuckAsk, stuckReset, stuckStop:
+		d.reaction = reaction
+	default:
+		log.Println("unknown stuckLoop.reaction", reaction, "- using steer")
+		d.reaction = stuckSteer
+	}
+}
+
+// workingCopyFingerprint hashes the jj diff of the working copy; it returns
+// an empty string when jj is not available.
+func workingCopyFingerprint(dir string) string {
+	cmd := exec.Command("jj", "diff", "--git")
+	cmd.Dir = dir
+	output, errDiff := cmd.Output()
+	if errDiff != nil {
+		return ""
+	}
+	sum := sha256.Sum256(output)
+	return hex.EncodeToString(sum[:])
+}
+
+func (d *stuckDetector) recordGateFailure(output string) {
+	if d == nil {
+		return
+	}
+	if output == d.lastGateOutput {
+		d.gateRepeats++
+		return
+	}
+	d.lastGateOutput = output
+	d.gateRepeats = 1
+}
+
+// observe compares the state before and after a coordinator iteration and
+// returns why the workflow looks stuck, or an empty string.
+func (d *stuckDetector) observe(before, after state.Workflow) string {
+	if d == nil {
+		return ""
+	}
+	fingerprint := d.fingerprint()
+	sameFiles := fingerprint == d.lastFingerprint
+	d.lastFingerprint = fingerprint
+
+	if after.Task == before.Task && len(after.Progress) <= len(before.Progress) && sameFiles {
+		d.idle++
+	} else {
+		d.idle = 0
+	}
+
+	switch {
+	case d.gateRepeats >= d.gateFailures:
+		reason := fmt.Sprintf("the completion gate failed %d times in a row with the same output", d.gateRepeats)
+		d.gateRepeats = 0
+		d.idle = 0
+		return reason
+	case d.idle >= d.iterations:
+		reason := fmt.Sprintf("no progress in %d coordinator iterations: the task stayed %q, no progress entries were added and the working copy did not change", d.idle, after.Task)
+		d.idle = 0
+		return reason
+	default:
+		return ""
+	}
+}
+
+func (d *stuckDetector) takeSteering() string {
+	if d == nil {
+		return ""
+	}
+	note := d.steering
+	d.steering = ""
+	return note
+}
+
+func formatSteeringNote(note string) string {
+	return fmt.Sprintf(`
+## Steering Note
+sgai detected that the workflow is not making progress: %s.
+Stop repeating the last step. Re-read GOAL.md and .sgai/PROJECT_MANAGEMENT.md, identify what is blocking you, and change approach: delegate differently, split the task, or ask the human partner if you need a decision.
+`, note)
+}
+
+// reactToStuckLoop records the reason in the progress log and the project
+// management ledger, then applies the configured reaction. It reports whether
+// the workflow should stop; a stopped workflow is left in agent-done so it no
+// longer looks like it is running.
+func (r *workflowRunner) reactToStuckLoop(ctx context.Context, cfg agentRunConfig, wfState *state.Workflow, reason string, capturedSessionID *string) bool {
+	reaction := r.stuck.reaction
+	if reaction == stuckAsk && !wfState.ToolsAllowed() {
+		reaction = stuckSteer
+	}
+
+	message := fmt.Sprintf("stuck loop detected (%s): %s", reaction, reason)
+	fmt.Println("["+cfg.paddedsgai+"]", message)
+	wfState.Progress = append(wfState.Progress, state.ProgressEntry{
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Agent:       ledgerSystemAgent,
+		Description: message,
+	})
+	saveState(cfg.coord, *wfState)
+	if errAppend := appendProjectManagementSection(cfg.dir, ledgerEntry{
+		Type:  ledgerBlocker,
+		Title: "Stuck Loop Detected",
+		Agent: ledgerSystemAgent,
+		Body:  fmt.Sprintf("Reason: %s\n\nReaction: %s", reason, reaction),
+	}); errAppend != nil {
+		log.Println("failed to append stuck loop blocker to PROJECT_MANAGEMENT.md:", errAppend)
+	}
+
+	if reaction == stuckAsk {
+		var guidance string
+		reaction, guidance = askAboutStuckLoop(ctx, cfg.coord, reason)
+		if guidance != "" {
+			reason += "; the human partner said: " + guidance
+		}
+	}
+
+	switch reaction {
+	case stuckStop:
+		wfState.Status = state.StatusAgentDone
+		wfState.Task = "stopped by stuck loop detection: " + reason
+		saveState(cfg.coord, *wfState)
+		return true
+	case stuckReset:
+		*capturedSessionID = ""
+	}
+	r.stuck.steering = reason
+	return false
+}
+
+func askAboutStuckLoop(ctx context.Context, coord *state.Coordinator, reason string) (stuckReaction, string) {
+	questionText := "The workflow looks stuck: " + reason + ".\n\nHow should sgai proceed?"
+	question := &state.MultiChoiceQuestion{
+		Questions: []state.QuestionItem{
+			{
+				Question: questionText,
+				Choices:  []string{stuckChoiceContinue, stuckChoiceReset, stuckChoiceStop},
+			},
+		},
+	}
+	answer, errAsk := coord.AskAndWait(ctx, question, questionText)
+	if errAsk != nil {
+		return stuckStop, ""
+	}
+	return parseStuckLoopAnswer(answer)
+}
+
+// parseStuckLoopAnswer reads the choices from the leading "Selected:" line
+// and returns the rest as guidance, so choice labels quoted in the guidance
+// do not change the reaction.
+func parseStuckLoopAnswer(answer string) (stuckReaction, string) {
+	first, rest, _ := strings.Cut(answer, "\n")
+	selected, ok := strings.CutPrefix(first, "Selected: ")
+	if !ok {
+		return stuckSteer, strings.TrimSpace(answer)
+	}
+	choices := strings.Split(selected, ", ")
+	guidance := strings.TrimSpace(rest)
+	switch {
+	case slices.Contains(choices, stuckChoiceStop):
+		return stuckStop, guidance
+	case slices.Contains(choices, stuckChoiceReset):
+		return stuckReset, guidance
+	default:
+		return stuckSteer, guidance
+	}
+}
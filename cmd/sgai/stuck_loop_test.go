@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStuckDetector(cfg *stuckLoopConfig, fingerprints ...string) *stuckDetector {
+	d := &stuckDetector{}
+	d.configure(cfg)
+	next := 0
+	d.fingerprint = func() string {
+		if len(fingerprints) == 0 {
+			return ""
+		}
+		fp := fingerprints[min(next, len(fingerprints)-1)]
+		next++
+		return fp
+	}
+	return d
+}
+
+func TestStuckDetectorConfigure(t *testing.T) {
+	cases := []struct {
+		name             string
+		cfg              *stuckLoopConfig
+		wantIterations   int
+		wantGateFailures int
+		wantReaction     stuckReaction
+	}{
+		{"defaults", nil, defaultStuckIterations, defaultStuckGateFailures, stuckSteer},
+		{"custom", &stuckLoopConfig{Iterations: 2, GateFailures: 4, Reaction: "reset"}, 2, 4, stuckReset},
+		{"negativeFallsBack", &stuckLoopConfig{Iterations: -1}, defaultStuckIterations, defaultStuckGateFailures, stuckSteer},
+		{"unknownReaction", &stuckLoopConfig{Reaction: "panic"}, defaultStuckIterations, defaultStuckGateFailures, stuckSteer},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newTestStuckDetector(tc.cfg)
+			assert.Equal(t, tc.wantIterations, d.iterations)
+			assert.Equal(t, tc.wantGateFailures, d.gateFailures)
+			assert.Equal(t, tc.wantReaction, d.reaction)
+		})
+	}
+}
+
+func TestStuckDetectorObserve(t *testing.T) {
+	idle := state.Workflow{Task: "looping", Progress: []state.ProgressEntry{{Description: "one"}}}
+	withProgress := state.Workflow{Task: "looping", Progress: []state.ProgressEntry{{Description: "one"}, {Description: "two"}}}
+	newTask := state.Workflow{Task: "something else", Progress: idle.Progress}
+
+	cases := []struct {
+		name         string
+		fingerprints []string
+		afters       []state.Workflow
+		wantStuckAt  int
+	}{
+		{"idleIterations", []string{"a"}, []state.Workflow{idle, idle, idle, idle}, 2},
+		{"progressResets", []string{"a"}, []state.Workflow{idle, idle, withProgress, idle}, -1},
+		{"taskChangeResets", []string{"a"}, []state.Workflow{idle, idle, newTask, idle}, -1},
+		{"fileChangesReset", []string{"a", "a", "b", "b"}, []state.Workflow{idle, idle, idle, idle}, -1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newTestStuckDetector(&stuckLoopConfig{Iterations: 3}, tc.fingerprints...)
+			d.lastFingerprint = "a"
+			stuckAt := -1
+			for i, after := range tc.afters {
+				if reason := d.observe(idle, after); reason != "" {
+					stuckAt = i
+					assert.Contains(t, reason, "no progress in 3 coordinator iterations")
+					break
+				}
+			}
+			assert.Equal(t, tc.wantStuckAt, stuckAt)
+		})
+	}
+}
+
+func TestStuckDetectorRepeatedGateFailure(t *testing.T) {
+	d := newTestStuckDetector(&stuckLoopConfig{GateFailures: 2}, "a", "b", "c", "d")
+	before := state.Workflow{Task: "verify"}
+	after := state.Workflow{Task: "fixing tests"}
+
+	d.recordGateFailure("FAIL: TestA")
+	assert.Empty(t, d.observe(before, after))
+	d.recordGateFailure("FAIL: TestB")
+	assert.Empty(t, d.observe(before, after), "different output restarts the count")
+	d.recordGateFailure("FAIL: TestB")
+	assert.Contains(t, d.observe(before, after), "completion gate failed 2 times")
+}
+
+func TestBuildAgentMessageIncludesSteeringNote(t *testing.T) {
+	dir := t.TempDir()
+	cfg := agentRunConfig{dir: dir, agent: "coordinator", steeringNote: "the task stayed \"x\""}
+
+	msg := buildAgentMessage(cfg, state.Workflow{}, GoalMetadata{})
+
+	assert.Contains(t, msg, "## Steering Note")
+	assert.Contains(t, msg, "the task stayed \"x\"")
+	assert.NotContains(t, buildAgentMessage(agentRunConfig{dir: dir, agent: "coordinator"}, state.Workflow{}, GoalMetadata{}), "## Steering Note")
+}
+
+func TestAskAboutStuckLoop(t *testing.T) {
+	cases := []struct {
+		name         string
+		answer       string
+		wantReaction stuckReaction
+		wantGuidance string
+	}{
+		{"continue", "Selected: " + stuckChoiceContinue, stuckSteer, ""},
+		{"resetWithGuidance", "Selected: " + stuckChoiceReset + "\ntry the other API", stuckReset, "try the other API"},
+		{"stop", "Selected: " + stuckChoiceStop, stuckStop, ""},
+		{"guidanceQuotesChoices", "Selected: " + stuckChoiceContinue + "\ndon't " + stuckChoiceStop + " or " + stuckChoiceReset + ", try X", stuckSteer, "don't " + stuckChoiceStop + " or " + stuckChoiceReset + ", try X"},
+		{"guidanceOnly", stuckChoiceStop + " is not needed, try X", stuckSteer, stuckChoiceStop + " is not needed, try X"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			coord := state.NewCoordinatorEmpty(filepath.Join(t.TempDir(), "state.json"))
+			go func() {
+				for !coord.State().NeedsHumanInput() {
+					time.Sleep(time.Millisecond)
+				}
+				coord.Respond(tc.answer)
+			}()
+
+			reaction, guidance := askAboutStuckLoop(context.Background(), coord, "no progress")
+
+			assert.Equal(t, tc.wantReaction, reaction)
+			assert.Equal(t, tc.wantGuidance, guidance)
+		})
+	}
+}
+
+func TestExecuteCoordinatorStopsStuckLoop(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "GOAL.md"), []byte("---\nstuckLoop:\n  iterations: 2\n  reaction: stop\n---\n# Goal\n"), 0644))
+	runner, cleanup, ok := buildWorkflowRunner(dir, "", nil, nil)
+	require.True(t, ok)
+	t.Cleanup(cleanup)
+	runner.retroDir = ""
+	runner.stuck = newTestStuckDetector(nil, "same")
+	looping := state.Workflow{Status: state.StatusWorking, Task: "looping"}
+	runner.execute = scriptedExecutor([]state.Workflow{looping, looping, looping, looping, looping})
+
+	runner.run(context.Background())
+
+	require.NotEmpty(t, runner.stopReason)
+	assert.Contains(t, runner.stopReason, "no progress in 2 coordinator iterations")
+	final := runner.coord.State()
+	assert.Equal(t, state.StatusAgentDone, final.Status)
+	assert.Contains(t, final.Task, "stopped by stuck loop detection")
+	require.NotEmpty(t, final.Progress)
+	assert.Contains(t, final.Progress[len(final.Progress)-1].Description, "stuck loop detected (stop)")
+	ledger, errRead := os.ReadFile(filepath.Join(dir, ".sgai", "PROJECT_MANAGEMENT.md"))
+	require.NoError(t, errRead)
+	assert.Contains(t, string(ledger), "Stuck Loop Detected")
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	toolPermissionAllow = "allow"
+	toolPermissionDeny  = "deny"
+	toolPermissionAsk   = "ask"
+
+	// agentToolPermissionPrefix is how opencode names the tools of the sgai
+	// MCP server inside an agent's permission block.
+	agentToolPermissionPrefix = "sgai_"
+
+	// toolCallSessionArg is the argument the workbench plugin adds to every
+	// sgai tool call, carrying the opencode session that made it.
+	toolCallSessionArg = "sgaiSessionID"
+
+	// toolCallSessionWait is how long a call from an unrecorded session
+	// waits for sessions.jsonl to catch up before it is denied.
+	toolCallSessionWait = 500 * time.Millisecond
+)
+
+type toolCallerKey struct{}
+
+// toolPermissionsConfig is the toolPermissions section of sgai.json. It maps
+// an agent name, or "*" for every agent, to tool name patterns and their
+// "allow" or "deny" decision.
+type toolPermissionsConfig map[string]map[string]string
+
+// toolRuleSet is the rules of one policy source; patterns use path.Match.
+type toolRuleSet struct {
+	source string
+	rules  map[string]string
+}
+
+// decide returns the decision of the most specific matching rule: an exact
+// tool name wins over patterns, longer patterns win over shorter ones, and
+// deny wins a tie.
+func (rs toolRuleSet) decide(tool string) (decision string, matched bool) {
+	if decision, ok := rs.rules[tool]; ok {
+		return decision, true
+	}
+	best := -1
+	for pattern, candidate := range rs.rules {
+		if ok, _ := path.Match(pattern, tool); !ok {
+			continue
+		}
+		if len(pattern) > best || (len(pattern) == best && candidate == toolPermissionDeny) {
+			best, decision = len(pattern), candidate
+		}
+	}
+	return decision, best >= 0
+}
+
+// toolPolicy is the server-side tool allowlist of one agent. Rule sets are
+// ordered from highest to lowest precedence; the first set with a matching
+// rule decides, and tools matched by no rule are allowed.
+type toolPolicy struct {
+	ruleSets []toolRuleSet
+}
+
+func (p toolPolicy) allows(tool string) (allowed bool, source string) {
+	for _, rs := range p.ruleSets {
+		if decision, matched := rs.decide(tool); matched {
+			return decision != toolPermissionDeny, rs.source
+		}
+	}
+	return true, ""
+}
+
+// loadToolPolicy combines the toolPermissions of sgai.json with the sgai_*
+// entries of the permission block in the agent's frontmatter. sgai.json
+// takes precedence, and its per-agent rules over its "*" rules.
+func loadToolPolicy(workingDir, agentName string) toolPolicy {
+	var policy toolPolicy
+	config, errLoad := loadProjectConfig(workingDir)
+	if errLoad != nil {
+		log.Println("cannot load sgai.json tool permissions:", errLoad)
+	}
+	if config != nil {
+		for _, key := range []string{agentName, "*"} {
+			rules := validToolRules(config.ToolPermissions[key], configFileName)
+			if len(rules) > 0 {
+				policy.ruleSets = append(policy.ruleSets, toolRuleSet{source: configFileName, rules: rules})
+			}
+		}
+	}
+	if metadata, ok := parseAgentFileMetadata(workingDir, agentName); ok {
+		source := filepath.Join(".sgai", "agent", agentName+".md")
+		rules := validToolRules(agentToolRules(metadata.Permission), source)
+		if len(rules) > 0 {
+			policy.ruleSets = append(policy.ruleSets, toolRuleSet{source: source, rules: rules})
+		}
+	}
+	return policy
+}
+
+// agentToolRules extracts the sgai MCP tool entries of an opencode permission
+// block. "ask" leaves the decision to opencode, so the server allows it.
+func agentToolRules(permission any) map[string]string {
+	rules := make(map[string]string)
+	entries, _ := permission.(map[string]any)
+	for key, value := range entries {
+		tool, ok := strings.CutPrefix(key, agentToolPermissionPrefix)
+		decision, isString := value.(string)
+		if !ok || !isString {
+			continue
+		}
+		if decision == toolPermissionAsk {
+			decision = toolPermissionAllow
+		}
+		rules[tool] = decision
+	}
+	return rules
+}
+
+func validToolRules(rules map[string]string, source string) map[string]string {
+	valid := make(map[string]string, len(rules))
+	for pattern, decision := range rules {
+		if _, errPattern := path.Match(pattern, ""); errPattern != nil {
+			log.Println("ignoring invalid tool permission pattern in", source+":", pattern)
+			continue
+		}
+		if decision != toolPermissionAllow && decision != toolPermissionDeny {
+			log.Println("ignoring tool permission in", source, "for", pattern+": expected allow or deny, got", decision)
+			continue
+		}
+		valid[pattern] = decision
+	}
+	return valid
+}
+
+// toolCallerMiddleware identifies the agent behind each tool call.
+// Subagents run inside the coordinator's opencode process and share its MCP
+// connection, so the process identity names the coordinator for all of them.
+// Instead, the session ID that the workbench plugin adds to the arguments is
+// removed and looked up in sessions.jsonl. Calls without a session keep the
+// process identity; calls from a session that is not recorded are denied.
+func toolCallerMiddleware(workingDir string) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			callReq, ok := req.(*mcp.CallToolRequest)
+			if method != "tools/call" || !ok || callReq.Params == nil {
+				return next(ctx, method, req)
+			}
+			sessionID, args := takeToolCallSession(callReq.Params.Arguments)
+			callReq.Params.Arguments = args
+			if sessionID == "" {
+				return next(ctx, method, req)
+			}
+			agent := awaitSessionAgent(ctx, workingDir, sessionID)
+			if agent == "" {
+				log.Println("denied MCP tool call:", callReq.Params.Name, "from session missing in sessions.jsonl:", sessionID)
+				return &mcp.CallToolResult{
+					IsError: true,
+					Content: []mcp.Content{&mcp.TextContent{
+						Text: fmt.Sprintf("permission denied: session %q is not recorded in sessions.jsonl, so its agent is unknown", sessionID),
+					}},
+				}, nil
+			}
+			return next(context.WithValue(ctx, toolCallerKey{}, agent), method, req)
+		}
+	}
+}
+
+// awaitSessionAgent looks sessionID up, reading sessions.jsonl once more
+// after a short wait: the plugin records new sessions asynchronously, so a
+// subagent's first tool call can arrive before its entry is written.
+func awaitSessionAgent(ctx context.Context, workingDir, sessionID string) string {
+	if agent := sessionAgent(workingDir, sessionID); agent != "" {
+		return agent
+	}
+	timer := time.NewTimer(toolCallSessionWait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ""
+	case <-timer.C:
+	}
+	return sessionAgent(workingDir, sessionID)
+}
+
+// toolCaller returns the agent toolCallerMiddleware resolved for the call,
+// or processAgent when it resolved none.
+func toolCaller(ctx context.Context, processAgent string) string {
+	if agent, ok := ctx.Value(toolCallerKey{}).(string); ok {
+		return agent
+	}
+	return processAgent
+}
+
+func takeToolCallSession(raw json.RawMessage) (string, json.RawMessage) {
+	var args map[string]json.RawMessage
+	if errJSON := json.Unmarshal(raw, &args); errJSON != nil {
+		return "", raw
+	}
+	value, found := args[toolCallSessionArg]
+	if !found {
+		return "", raw
+	}
+	delete(args, toolCallSessionArg)
+	stripped, errEncode := json.Marshal(args)
+	if errEncode != nil {
+		return "", raw
+	}
+	var sessionID string
+	if errJSON := json.Unmarshal(value, &sessionID); errJSON != nil {
+		return "", stripped
+	}
+	return sessionID, stripped
+}
+
+// sessionAgent returns the agent that sessions.jsonl records for sessionID.
+func sessionAgent(workingDir, sessionID string) string {
+	if sessionID == "" {
+		return ""
+	}
+	entries, errRead := readSessionEntries(filepath.Join(workingDir, ".sgai", "sessions.jsonl"))
+	if errRead != nil {
+		log.Println("cannot read sessions.jsonl:", errRead)
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.SessionID == sessionID {
+			return entry.Agent
+		}
+	}
+	return ""
+}
+
+// toolPermissionMiddleware enforces the caller's tool policy: denied tools
+// are left out of tools/list, and calling them anyway returns a tool error.
+// tools/list carries no session, so it is filtered for the process identity.
+func toolPermissionMiddleware(workingDir, agentName string) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			switch method {
+			case "tools/call":
+				callReq, ok := req.(*mcp.CallToolRequest)
+				if !ok || callReq.Params == nil {
+					break
+				}
+				tool := callReq.Params.Name
+				caller := toolCaller(ctx, agentName)
+				if allowed, source := loadToolPolicy(workingDir, caller).allows(tool); !allowed {
+					log.Println("denied MCP tool call:", caller, "called", tool, "which is denied by", source)
+					return &mcp.CallToolResult{
+						IsError: true,
+						Content: []mcp.Content{&mcp.TextContent{
+							Text: fmt.Sprintf("permission denied: agent %q may not call %s (denied by %s)", caller, tool, source),
+						}},
+					}, nil
+				}
+			case "tools/list":
+				result, errList := next(ctx, method, req)
+				listResult, ok := result.(*mcp.ListToolsResult)
+				if errList != nil || !ok || listResult == nil {
+					return result, errList
+				}
+				policy := loadToolPolicy(workingDir, agentName)
+				allowedTools := listResult.Tools[:0:0]
+				for _, tool := range listResult.Tools {
+					if allowed, _ := policy.allows(tool.Name); allowed {
+						allowedTools = append(allowedTools, tool)
+					}
+				}
+				listResult.Tools = allowedTools
+				return listResult, nil
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/sandgardenhq/sgai/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const toolPermissionsTestAgent = `---
+description: Reviews code.
+mode: subagent
+permission:
+  sgai_update_workflow_state: deny
+  sgai_ask_user_question: deny
+  sgai_project_todoread: ask
+  bash:
+    "*": deny
+  edit: deny
+---
+
+# Reviewer
+`
+
+func writeToolPermissionsWorkspace(t *testing.T, config string) string {
+	t.Helper()
+	dir := t.TempDir()
+	agentDir := filepath.Join(dir, ".sgai", "agent")
+	require.NoError(t, os.MkdirAll(agentDir, 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".sgai", "skills"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(agentDir, "reviewer.md"), []byte(toolPermissionsTestAgent), 0644))
+	if config != "" {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, configFileName), []byte(config), 0644))
+	}
+	return dir
+}
+
+func TestLoadToolPolicy(t *testing.T) {
+	cases := []struct {
+		name        string
+		config      string
+		agent       string
+		tool        string
+		wantAllowed bool
+		wantSource  string
+	}{
+		{"agentFileDeny", "", "reviewer", "update_workflow_state", false, filepath.Join(".sgai", "agent", "reviewer.md")},
+		{"agentFileAskAllows", "", "reviewer", "project_todoread", true, filepath.Join(".sgai", "agent", "reviewer.md")},
+		{"unlistedToolAllowed", "", "reviewer", "find_skills", true, ""},
+		{"agentWithoutFile", "", "coordinator", "update_workflow_state", true, ""},
+		{"configOverridesAgentFile", `{"toolPermissions":{"reviewer":{"update_workflow_state":"allow"}}}`, "reviewer", "update_workflow_state", true, configFileName},
+		{"configWildcardAgent", `{"toolPermissions":{"*":{"ask_user_*":"deny"}}}`, "coordinator", "ask_user_work_gate", false, configFileName},
+		{"configAllowlist", `{"toolPermissions":{"builder":{"*":"deny","find_*":"allow"}}}`, "builder", "find_snippets", true, configFileName},
+		{"configAllowlistDenies", `{"toolPermissions":{"builder":{"*":"deny","find_*":"allow"}}}`, "builder", "project_todowrite", false, configFileName},
+		{"perAgentBeatsWildcardAgent", `{"toolPermissions":{"*":{"*":"deny"},"builder":{"project_todowrite":"allow"}}}`, "builder", "project_todowrite", true, configFileName},
+		{"wildcardAgentAppliesToRest", `{"toolPermissions":{"*":{"*":"deny"},"builder":{"project_todowrite":"allow"}}}`, "builder", "find_skills", false, configFileName},
+		{"invalidDecisionIgnored", `{"toolPermissions":{"builder":{"find_skills":"maybe"}}}`, "builder", "find_skills", true, ""},
+		{"invalidPatternIgnored", `{"toolPermissions":{"builder":{"[":"deny"}}}`, "builder", "find_skills", true, ""},
+		{"brokenConfigFallsBackToAgentFile", `{`, "reviewer", "ask_user_question", false, filepath.Join(".sgai", "agent", "reviewer.md")},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeToolPermissionsWorkspace(t, tc.config)
+
+			allowed, source := loadToolPolicy(dir, tc.agent).allows(tc.tool)
+
+			assert.Equal(t, tc.wantAllowed, allowed)
+			assert.Equal(t, tc.wantSource, source)
+		})
+	}
+}
+
+func TestToolRuleSetDecide(t *testing.T) {
+	rs := toolRuleSet{rules: map[string]string{
+		"*":                "deny",
+		"project_*":        "allow",
+		"project_todo*":    "deny",
+		"project_todor?ad": "allow",
+		"find_skills":      "deny",
+		"find_*":           "allow",
+		"ask_*":            "allow",
+		"*_question":       "deny",
+	}}
+	cases := []struct {
+		name         string
+		tool         string
+		wantDecision string
+	}{
+		{"exactBeatsPattern", "find_skills", "deny"},
+		{"longerPatternWins", "project_todowrite", "deny"},
+		{"longestPatternWins", "project_todoread", "allow"},
+		{"shorterPatternStillMatches", "project_other", "allow"},
+		{"denyWinsTie", "ask_user_question", "deny"},
+		{"catchAll", "update_workflow_state", "deny"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			decision, matched := rs.decide(tc.tool)
+
+			assert.True(t, matched)
+			assert.Equal(t, tc.wantDecision, decision)
+		})
+	}
+}
+
+func TestToolPermissionMiddleware(t *testing.T) {
+	dir := writeToolPermissionsWorkspace(t, "")
+	coord, errCoord := state.NewCoordinatorWith(filepath.Join(dir, ".sgai", "state.json"), state.Workflow{})
+	require.NoError(t, errCoord)
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set(sgaiAgentIdentityHeader, "reviewer|model|variant")
+	server := buildMCPServer(dir, r, coord)
+
+	ct, st := mcp.NewInMemoryTransports()
+	_, errConnect := server.Connect(context.Background(), st, nil)
+	require.NoError(t, errConnect)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client"}, nil)
+	cs, errClient := client.Connect(context.Background(), ct, nil)
+	require.NoError(t, errClient)
+	t.Cleanup(func() { _ = cs.Close() })
+
+	t.Run("listHidesDeniedTools", func(t *testing.T) {
+		tools, errList := cs.ListTools(context.Background(), nil)
+		require.NoError(t, errList)
+		var names []string
+		for _, tool := range tools.Tools {
+			names = append(names, tool.Name)
+		}
+		assert.Contains(t, names, "find_skills")
+		assert.Contains(t, names, "project_todoread")
+		assert.NotContains(t, names, "update_workflow_state")
+	})
+
+	t.Run("deniedCallReturnsToolError", func(t *testing.T) {
+		result, errCall := cs.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "update_workflow_state",
+			Arguments: map[string]any{"status": "complete", "task": "", "addProgress": "done"},
+		})
+		require.NoError(t, errCall)
+		require.True(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		text := result.Content[0].(*mcp.TextContent).Text
+		assert.Contains(t, text, `agent "reviewer" may not call update_workflow_state`)
+		assert.Contains(t, text, filepath.Join(".sgai", "agent", "reviewer.md"))
+		assert.NotEqual(t, state.StatusComplete, coord.State().Status, "a denied call must not reach the handler")
+	})
+
+	t.Run("allowedCallPassesThrough", func(t *testing.T) {
+		result, errCall := cs.CallTool(context.Background(), &mcp.CallToolParams{Name: "find_skills", Arguments: map[string]any{"name": "nothing-matches"}})
+		require.NoError(t, errCall)
+		assert.False(t, result.IsError)
+	})
+}
+
+func TestToolPermissionMiddlewareIdentifiesSubagentSessions(t *testing.T) {
+	dir := writeToolPermissionsWorkspace(t, "")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".sgai", "sessions.jsonl"), []byte(`{"sessionID":"ses_c","agent":"coordinator"}
+{"sessionID":"ses_r","agent":"reviewer"}
+`), 0644))
+	coord, errCoord := state.NewCoordinatorWith(filepath.Join(dir, ".sgai", "state.json"), state.Workflow{})
+	require.NoError(t, errCoord)
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set(sgaiAgentIdentityHeader, "coordinator|model|variant")
+	server := buildMCPServer(dir, r, coord)
+
+	ct, st := mcp.NewInMemoryTransports()
+	_, errConnect := server.Connect(context.Background(), st, nil)
+	require.NoError(t, errConnect)
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client"}, nil)
+	cs, errClient := client.Connect(context.Background(), ct, nil)
+	require.NoError(t, errClient)
+	t.Cleanup(func() { _ = cs.Close() })
+
+	t.Run("subagentCallDenied", func(t *testing.T) {
+		result, errCall := cs.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "update_workflow_state",
+			Arguments: map[string]any{"status": "complete", "task": "", "addProgress": "done", toolCallSessionArg: "ses_r"},
+		})
+		require.NoError(t, errCall)
+		require.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, `agent "reviewer" may not call update_workflow_state`)
+		assert.NotEqual(t, state.StatusComplete, coord.State().Status)
+	})
+
+	t.Run("subagentCallAttributed", func(t *testing.T) {
+		result, errCall := cs.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "append_ledger_entry",
+			Arguments: map[string]any{"type": "handoff", "title": "Review done", "body": "looks good", toolCallSessionArg: "ses_r"},
+		})
+		require.NoError(t, errCall)
+		require.False(t, result.IsError)
+		entries, errLedger := readProjectManagementLedger(dir)
+		require.NoError(t, errLedger)
+		require.NotEmpty(t, entries)
+		assert.Equal(t, "reviewer", entries[len(entries)-1].Agent)
+	})
+
+	t.Run("sessionArgumentStripped", func(t *testing.T) {
+		result, errCall := cs.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "find_skills",
+			Arguments: map[string]any{"name": "nothing-matches", toolCallSessionArg: "ses_c"},
+		})
+		require.NoError(t, errCall)
+		assert.False(t, result.IsError)
+	})
+
+	t.Run("unknownSessionDenied", func(t *testing.T) {
+		result, errCall := cs.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "update_workflow_state",
+			Arguments: map[string]any{"status": "complete", "task": "", "addProgress": "done", toolCallSessionArg: "ses_unknown"},
+		})
+		require.NoError(t, errCall)
+		require.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, `session "ses_unknown" is not recorded`)
+		assert.NotEqual(t, state.StatusComplete, coord.State().Status)
+	})
+
+	t.Run("sessionRecordedLate", func(t *testing.T) {
+		go func() {
+			time.Sleep(toolCallSessionWait / 5)
+			file, errOpen := os.OpenFile(filepath.Join(dir, ".sgai", "sessions.jsonl"), os.O_APPEND|os.O_WRONLY, 0644)
+			if errOpen != nil {
+				return
+			}
+			_, _ = file.WriteString(`{"sessionID":"ses_late","agent":"coordinator"}` + "\n")
+			_ = file.Close()
+		}()
+		result, errCall := cs.CallTool(context.Background(), &mcp.CallToolParams{
+			Name:      "find_skills",
+			Arguments: map[string]any{"name": "nothing-matches", toolCallSessionArg: "ses_late"},
+		})
+		require.NoError(t, errCall)
+		assert.False(t, result.IsError)
+	})
+}
+
+func TestTakeToolCallSession(t *testing.T) {
+	cases := []struct {
+		name        string
+		raw         string
+		wantSession string
+		wantArgs    string
+	}{
+		{"tagged", `{"name":"x","sgaiSessionID":"ses_1"}`, "ses_1", `{"name":"x"}`},
+		{"untagged", `{"name":"x"}`, "", `{"name":"x"}`},
+		{"nonStringSession", `{"sgaiSessionID":7}`, "", `{}`},
+		{"notAnObject", `[1]`, "", `[1]`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sessionID, args := takeToolCallSession(json.RawMessage(tc.raw))
+
+			assert.Equal(t, tc.wantSession, sessionID)
+			assert.JSONEq(t, tc.wantArgs, string(args))
+		})
+	}
+}
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+type tuiKeyKind int
+
+const (
+	tuiKeyRune tuiKeyKind = iota
+	tuiKeyUp
+	tuiKeyDown
+	tuiKeyPageUp
+	tuiKeyPageDown
+	tuiKeyEnter
+	tuiKeyTab
+	tuiKeyBackspace
+	tuiKeyEscape
+	tuiKeyCtrlC
+)
+
+type tuiKey struct {
+	kind tuiKeyKind
+	r    rune
+}
+
+// parseTUIKeys decodes raw terminal input into keys. Unknown escape
+// sequences are dropped.
+func parseTUIKeys(data []byte) []tuiKey {
+	var keys []tuiKey
+	for len(data) > 0 {
+		switch {
+		case data[0] == 0x1b && len(data) >= 4 && data[1] == '[' && data[3] == '~':
+			switch data[2] {
+			case '5':
+				keys = append(keys, tuiKey{kind: tuiKeyPageUp})
+			case '6':
+				keys = append(keys, tuiKey{kind: tuiKeyPageDown})
+			}
+			data = data[4:]
+		case data[0] == 0x1b && len(data) >= 3 && (data[1] == '[' || data[1] == 'O'):
+			switch data[2] {
+			case 'A':
+				keys = append(keys, tuiKey{kind: tuiKeyUp})
+			case 'B':
+				keys = append(keys, tuiKey{kind: tuiKeyDown})
+			}
+			data = data[3:]
+		case data[0] == 0x1b:
+			keys = append(keys, tuiKey{kind: tuiKeyEscape})
+			data = data[1:]
+		case data[0] == '\r' || data[0] == '\n':
+			keys = append(keys, tuiKey{kind: tuiKeyEnter})
+			data = data[1:]
+		case data[0] == '\t':
+			keys = append(keys, tuiKey{kind: tuiKeyTab})
+			data = data[1:]
+		case data[0] == 0x7f || data[0] == 0x08:
+			keys = append(keys, tuiKey{kind: tuiKeyBackspace})
+			data = data[1:]
+		case data[0] == 0x03:
+			keys = append(keys, tuiKey{kind: tuiKeyCtrlC})
+			data = data[1:]
+		case data[0] < 0x20:
+			data = data[1:]
+		default:
+			r, size := utf8.DecodeRune(data)
+			keys = append(keys, tuiKey{kind: tuiKeyRune, r: r})
+			data = data[size:]
+		}
+	}
+	return keys
+}
+
+type tuiView int
+
+const (
+	tuiViewLog tuiView = iota
+	tuiViewDiff
+)
+
+type tuiCommandKind int
+
+const (
+	tuiCommandNone tuiCommandKind = iota
+	tuiCommandQuit
+	tuiCommandStart
+	tuiCommandStop
+	tuiCommandRespond
+	tuiCommandDiff
+	tuiCommandRefresh
+)
+
+// tuiCommand is a side effect requested by a key press. The dashboard loop
+// runs it against the server and reports back through the model.
+type tuiCommand struct {
+	kind      tuiCommandKind
+	workspace string
+	auto      bool
+	respond   apiRespondRequest
+}
+
+type tuiAnswerOption struct {
+	question int
+	choice   string
+	multi    bool
+	selected bool
+}
+
+// tuiAnswer is the response being composed for a pending question.
+type tuiAnswer struct {
+	questionID string
+	options    []tuiAnswerOption
+	cursor     int
+	focusText  bool
+	text       []rune
+}
+
+// tuiModel is the dashboard state. It is only touched by the dashboard loop.
+type tuiModel struct {
+	server     string
+	workspaces []apiWorkspaceFullState
+	selected   string
+	view       tuiView
+	diff       string
+	diffFor    string
+	diffScroll int
+	answer     *tuiAnswer
+	status     string
+}
+
+func (m *tuiModel) setState(factory apiFactoryState) {
+	m.workspaces = factory.Workspaces
+	if m.current() == nil && len(m.workspaces) > 0 {
+		m.selected = m.workspaces[0].Name
+	}
+	if m.answer != nil {
+		ws := m.current()
+		if ws == nil || ws.PendingQuestion == nil || ws.PendingQuestion.QuestionID != m.answer.questionID {
+			m.answer = nil
+			m.status = "question was answered elsewhere"
+		}
+	}
+}
+
+func (m *tuiModel) current() *apiWorkspaceFullState {
+	idx := slices.IndexFunc(m.workspaces, func(ws apiWorkspaceFullState) bool { return ws.Name == m.selected })
+	if idx < 0 {
+		return nil
+	}
+	return &m.workspaces[idx]
+}
+
+func (m *tuiModel) move(delta int) {
+	if len(m.workspaces) == 0 {
+		return
+	}
+	idx := slices.IndexFunc(m.workspaces, func(ws apiWorkspaceFullState) bool { return ws.Name == m.selected })
+	idx = min(max(idx+delta, 0), len(m.workspaces)-1)
+	m.selected = m.workspaces[idx].Name
+	m.view = tuiViewLog
+}
+
+func (m *tuiModel) handleKey(key tuiKey) tuiCommand {
+	if key.kind == tuiKeyCtrlC {
+		return tuiCommand{kind: tuiCommandQuit}
+	}
+	if m.answer != nil {
+		return m.handleAnswerKey(key)
+	}
+
+	ws := m.current()
+	switch {
+	case key.kind == tuiKeyUp || key.r == 'k':
+		m.move(-1)
+	case key.kind == tuiKeyDown || key.r == 'j':
+		m.move(1)
+	case key.kind == tuiKeyPageDown || key.r == 'J':
+		m.diffScroll += 10
+	case key.kind == tuiKeyPageUp || key.r == 'K':
+		m.diffScroll = max(m.diffScroll-10, 0)
+	case key.kind == tuiKeyEscape || key.r == 'l':
+		m.view = tuiViewLog
+	case key.kind != tuiKeyRune:
+	case key.r == 'q':
+		return tuiCommand{kind: tuiCommandQuit}
+	case key.r == 'r':
+		return tuiCommand{kind: tuiCommandRefresh}
+	case ws == nil:
+	case key.r == 's' || key.r == 'S':
+		return tuiCommand{kind: tuiCommandStart, workspace: ws.Name, auto: key.r == 'S'}
+	case key.r == 'x':
+		return tuiCommand{kind: tuiCommandStop, workspace: ws.Name}
+	case key.r == 'd':
+		m.view = tuiViewDiff
+		m.diffScroll = 0
+		return tuiCommand{kind: tuiCommandDiff, workspace: ws.Name}
+	case key.r == 'a':
+		if ws.PendingQuestion == nil {
+			m.status = ws.Name + " has no pending question"
+			return tuiCommand{}
+		}
+		m.answer = newTUIAnswer(ws.PendingQuestion)
+	}
+	return tuiCommand{}
+}
+
+func newTUIAnswer(q *apiPendingQuestionResponse) *tuiAnswer {
+	answer := &tuiAnswer{questionID: q.QuestionID}
+	for i, item := range q.Questions {
+		for _, choice := range item.Choices {
+			answer.options = append(answer.options, tuiAnswerOption{question: i, choice: choice, multi: item.MultiSelect})
+		}
+	}
+	answer.focusText = len(answer.options) == 0
+	return answer
+}
+
+func (m *tuiModel) handleAnswerKey(key tuiKey) tuiCommand {
+	a := m.answer
+	switch {
+	case key.kind == tuiKeyEscape:
+		m.answer = nil
+	case key.kind == tuiKeyTab:
+		a.focusText = !a.focusText || len(a.options) == 0
+	case key.kind == tuiKeyEnter:
+		req := a.request()
+		if req.Answer == "" && len(req.SelectedChoices) == 0 {
+			m.status = "select a choice or type an answer"
+			return tuiCommand{}
+		}
+		m.answer = nil
+		return tuiCommand{kind: tuiCommandRespond, workspace: m.selected, respond: req}
+	case a.focusText && key.kind == tuiKeyBackspace:
+		if len(a.text) > 0 {
+			a.text = a.text[:len(a.text)-1]
+		}
+	case a.focusText && key.kind == tuiKeyRune:
+		a.text = append(a.text, key.r)
+	case key.kind == tuiKeyUp:
+		a.cursor = max(a.cursor-1, 0)
+	case key.kind == tuiKeyDown:
+		a.cursor = min(a.cursor+1, len(a.options)-1)
+	case key.r == ' ':
+		a.toggle(a.cursor)
+	}
+	return tuiCommand{}
+}
+
+// toggle flips an option. Single-select questions keep at most one
+// selected choice.
+func (a *tuiAnswer) toggle(idx int) {
+	if idx < 0 || idx >= len(a.options) {
+		return
+	}
+	opt := &a.options[idx]
+	if !opt.multi && !opt.selected {
+		for i := range a.options {
+			if a.options[i].question == opt.question {
+				a.options[i].selected = false
+			}
+		}
+	}
+	opt.selected = !opt.selected
+}
+
+func (a *tuiAnswer) request() apiRespondRequest {
+	req := apiRespondRequest{QuestionID: a.questionID, Answer: strings.TrimSpace(string(a.text))}
+	for _, opt := range a.options {
+		if opt.selected {
+			req.SelectedChoices = append(req.SelectedChoices, opt.choice)
+		}
+	}
+	return req
+}
+
+// render draws the whole screen as height lines of exactly width columns.
+func (m *tuiModel) render(width, height int) []string {
+	width = max(width, 40)
+	height = max(height, 8)
+	leftWidth := min(max(width/3, 20), 40)
+	rightWidth := width - leftWidth - 3
+	bodyHeight := height - 2
+
+	left := m.renderList(bodyHeight)
+	right := m.renderDetail(rightWidth, bodyHeight)
+
+	lines := make([]string, 0, height)
+	lines = append(lines, tuiFit(m.header(), width))
+	for i := range bodyHeight {
+		var l, r string
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		lines = append(lines, tuiFit(l, leftWidth)+" │ "+tuiFit(r, rightWidth))
+	}
+	lines = append(lines, tuiFit(m.footer(), width))
+	return lines
+}
+
+func (m *tuiModel) header() string {
+	var running, needsInput int
+	for _, ws := range m.workspaces {
+		if ws.Running {
+			running++
+		}
+		if ws.NeedsInput {
+			needsInput++
+		}
+	}
+	return fmt.Sprintf("sgai %s — %d workspaces, %d running, %d need input", m.server, len(m.workspaces), running, needsInput)
+}
+
+func (m *tuiModel) footer() string {
+	help := "j/k move  s start  S self-drive  x stop  a answer  d diff  l log  r refresh  q quit"
+	if m.answer != nil {
+		help = "↑/↓ move  space select  tab choices/text  enter send  esc cancel"
+	}
+	if m.status != "" {
+		return m.status + "  │  " + help
+	}
+	return help
+}
+
+func (m *tuiModel) renderList(height int) []string {
+	if len(m.workspaces) == 0 {
+		return []string{"no workspaces"}
+	}
+	lines := make([]string, 0, len(m.workspaces))
+	for _, ws := range m.workspaces {
+		marker := "  "
+		if ws.Name == m.selected {
+			marker = "> "
+		}
+		indent := ""
+		if ws.IsFork {
+			indent = "  "
+		}
+		lines = append(lines, marker+indent+tuiBadgeSymbol(ws.BadgeClass)+" "+ws.Name)
+	}
+	idx := slices.IndexFunc(m.workspaces, func(ws apiWorkspaceFullState) bool { return ws.Name == m.selected })
+	return tuiWindow(lines, max(idx, 0), height)
+}
+
+// tuiBadgeSymbol maps the badge classes computed by badgeStatus to a
+// terminal symbol.
+func tuiBadgeSymbol(class string) string {
+	switch class {
+	case "badge-needs-input":
+		return "⚠"
+	case "badge-running":
+		return "▶"
+	case "badge-complete":
+		return "✓"
+	default:
+		return "■"
+	}
+}
+
+func (m *tuiModel) renderDetail(width, height int) []string {
+	ws := m.current()
+	if ws == nil {
+		return nil
+	}
+	lines := []string{
+		ws.Name + "  [" + cmp.Or(ws.BadgeText, "-") + "]",
+		"status: " + cmp.Or(ws.Status, "-"),
+	}
+	if ws.Task != "" {
+		lines = append(lines, "task: "+ws.Task)
+	}
+	if ws.LatestProgress != "" && ws.LatestProgress != "-" {
+		lines = append(lines, "progress: "+ws.LatestProgress)
+	}
+	lines = append(lines, strings.Repeat("─", width))
+
+	remaining := height - len(lines)
+	switch {
+	case m.answer != nil && ws.PendingQuestion != nil:
+		lines = append(lines, tuiWrap(m.renderAnswer(ws.PendingQuestion), width, remaining)...)
+	case m.view == tuiViewDiff:
+		lines = append(lines, m.renderDiff(ws.Name, remaining)...)
+	case ws.PendingQuestion != nil:
+		question := append(renderTUIQuestion(ws.PendingQuestion), "", "press a to answer")
+		lines = append(lines, tuiWrap(question, width, remaining)...)
+	default:
+		lines = append(lines, renderTUILog(ws.Log, remaining)...)
+	}
+	return lines
+}
+
+func renderTUIQuestion(q *apiPendingQuestionResponse) []string {
+	var lines []string
+	if q.Message != "" {
+		lines = append(lines, strings.Split(q.Message, "\n")...)
+	}
+	for _, section := range q.Sections {
+		lines = append(lines, "["+section.ID+"] "+section.Title)
+	}
+	for i, item := range q.Questions {
+		lines = append(lines, "", fmt.Sprintf("%d. %s", i+1, item.Question))
+		for j, choice := range item.Choices {
+			lines = append(lines, fmt.Sprintf("   %d) %s", j+1, choice))
+		}
+	}
+	return lines
+}
+
+func (m *tuiModel) renderAnswer(q *apiPendingQuestionResponse) []string {
+	a := m.answer
+	var lines []string
+	if q.Message != "" {
+		lines = append(lines, strings.Split(q.Message, "\n")...)
+	}
+	question := -1
+	for i, opt := range a.options {
+		if opt.question != question {
+			question = opt.question
+			lines = append(lines, "", q.Questions[question].Question)
+		}
+		cursor := "  "
+		if i == a.cursor && !a.focusText {
+			cursor = "> "
+		}
+		check := "[ ]"
+		if opt.selected {
+			check = "[x]"
+		}
+		lines = append(lines, cursor+check+" "+opt.choice)
+	}
+	label := "answer: "
+	if len(a.options) > 0 {
+		label = "comments: "
+	}
+	cursor := ""
+	if a.focusText {
+		cursor = "█"
+	}
+	return append(lines, "", label+string(a.text)+cursor)
+}
+
+func (m *tuiModel) renderDiff(name string, height int) []string {
+	if m.diffFor != name {
+		return []string{"loading diff…"}
+	}
+	if m.diff == "" {
+		return []string{"no changes"}
+	}
+	lines := strings.Split(strings.TrimRight(m.diff, "\n"), "\n")
+	m.diffScroll = min(m.diffScroll, max(len(lines)-height, 0))
+	end := min(m.diffScroll+height, len(lines))
+	return lines[m.diffScroll:end]
+}
+
+// renderTUILog returns the newest log lines that fit in height.
+func renderTUILog(entries []apiLogEntry, height int) []string {
+	if len(entries) == 0 {
+		return []string{"no output yet"}
+	}
+	start := max(len(entries)-height, 0)
+	lines := make([]string, 0, len(entries)-start)
+	for _, entry := range entries[start:] {
+		lines = append(lines, entry.Prefix+entry.Text)
+	}
+	return lines
+}
+
+// tuiWindow returns at most height lines of lines that include index.
+func tuiWindow(lines []string, index, height int) []string {
+	if len(lines) <= height {
+		return lines
+	}
+	start := min(max(index-height/2, 0), len(lines)-height)
+	return lines[start : start+height]
+}
+
+// tuiWrap wraps lines to width and keeps the first height lines.
+func tuiWrap(lines []string, width, height int) []string {
+	var wrapped []string
+	for _, line := range lines {
+		runes := []rune(line)
+		for len(runes) > width {
+			wrapped = append(wrapped, string(runes[:width]))
+			runes = runes[width:]
+		}
+		wrapped = append(wrapped, string(runes))
+	}
+	if len(wrapped) > height {
+		wrapped = wrapped[:max(height, 0)]
+	}
+	return wrapped
+}
+
+// tuiFit strips escape sequences and control characters from s and pads or
+// truncates it to exactly width columns.
+func tuiFit(s string, width int) string {
+	s = ansiEscapePattern.ReplaceAllString(s, "")
+	s = strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return ' '
+		}
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+	runes := []rune(s)
+	if len(runes) > width {
+		if width <= 1 {
+			return string(runes[:width])
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return s + strings.Repeat(" ", width-len(runes))
+}
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+
+package main
+
+import "fmt"
+
+type tuiTerminal struct{}
+
+func enterRawMode(_ int) (*tuiTerminal, error) {
+	return nil, fmt.Errorf("%w: the terminal dashboard needs Linux or macOS", errTUINotTerminal)
+}
+
+func (t *tuiTerminal) restore() error {
+	return nil
+}
+
+func (t *tuiTerminal) size() (int, int) {
+	return 80, 24
+}
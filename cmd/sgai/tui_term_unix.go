@@ -0,0 +1,52 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// tuiTerminal holds the terminal settings to restore when the dashboard
+// exits.
+type tuiTerminal struct {
+	fd       int
+	original unix.Termios
+}
+
+// enterRawMode switches fd to raw input so single keys reach the dashboard
+// without echo or line buffering.
+func enterRawMode(fd int) (*tuiTerminal, error) {
+	termios, errGet := unix.IoctlGetTermios(fd, ioctlReadTermios)
+	if errGet != nil {
+		return nil, fmt.Errorf("%w: %w", errTUINotTerminal, errGet)
+	}
+	term := &tuiTerminal{fd: fd, original: *termios}
+
+	raw := *termios
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Oflag &^= unix.OPOST
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if errSet := unix.IoctlSetTermios(fd, ioctlWriteTermios, &raw); errSet != nil {
+		return nil, fmt.Errorf("entering raw mode: %w", errSet)
+	}
+	return term, nil
+}
+
+func (t *tuiTerminal) restore() error {
+	return unix.IoctlSetTermios(t.fd, ioctlWriteTermios, &t.original)
+}
+
+// size returns the terminal width and height, falling back to 80x24.
+func (t *tuiTerminal) size() (int, int) {
+	ws, errSize := unix.IoctlGetWinsize(t.fd, unix.TIOCGWINSZ)
+	if errSize != nil || ws.Col == 0 || ws.Row == 0 {
+		return 80, 24
+	}
+	return int(ws.Col), int(ws.Row)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTUIKeys(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  []tuiKey
+	}{
+		{"runes", "jk", []tuiKey{{kind: tuiKeyRune, r: 'j'}, {kind: tuiKeyRune, r: 'k'}}},
+		{"unicode", "é", []tuiKey{{kind: tuiKeyRune, r: 'é'}}},
+		{"arrows", "\x1b[A\x1b[B\x1bOA", []tuiKey{{kind: tuiKeyUp}, {kind: tuiKeyDown}, {kind: tuiKeyUp}}},
+		{"pages", "\x1b[5~\x1b[6~", []tuiKey{{kind: tuiKeyPageUp}, {kind: tuiKeyPageDown}}},
+		{"bareEscape", "\x1b", []tuiKey{{kind: tuiKeyEscape}}},
+		{"controls", "\r\t\x7f\x03", []tuiKey{{kind: tuiKeyEnter}, {kind: tuiKeyTab}, {kind: tuiKeyBackspace}, {kind: tuiKeyCtrlC}}},
+		{"unknownSequenceDropped", "\x1b[Cq", []tuiKey{{kind: tuiKeyRune, r: 'q'}}},
+		{"otherControlDropped", "\x01", nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, parseTUIKeys([]byte(tc.input)))
+		})
+	}
+}
+
+func newTestTUIModel() *tuiModel {
+	m := &tuiModel{server: "http://127.0.0.1:8080"}
+	m.setState(apiFactoryState{Workspaces: []apiWorkspaceFullState{
+		{Name: "alpha", BadgeClass: "badge-running", BadgeText: "Running", Running: true},
+		{Name: "beta", BadgeClass: "badge-needs-input", BadgeText: "Needs Input", NeedsInput: true, PendingQuestion: &apiPendingQuestionResponse{
+			QuestionID: "q1",
+			Message:    "Pick a database",
+			Questions: []apiQuestionItem{
+				{Question: "Which database?", Choices: []string{"postgres", "sqlite"}},
+				{Question: "Which extras?", Choices: []string{"cache", "queue"}, MultiSelect: true},
+			},
+		}},
+	}})
+	return m
+}
+
+func runeKey(r rune) tuiKey {
+	return tuiKey{kind: tuiKeyRune, r: r}
+}
+
+func TestTUIModelHandleKey(t *testing.T) {
+	cases := []struct {
+		name         string
+		keys         []tuiKey
+		want         tuiCommand
+		wantSelected string
+		wantView     tuiView
+	}{
+		{"quit", []tuiKey{runeKey('q')}, tuiCommand{kind: tuiCommandQuit}, "alpha", tuiViewLog},
+		{"ctrlC", []tuiKey{{kind: tuiKeyCtrlC}}, tuiCommand{kind: tuiCommandQuit}, "alpha", tuiViewLog},
+		{"moveDown", []tuiKey{runeKey('j')}, tuiCommand{}, "beta", tuiViewLog},
+		{"moveClamped", []tuiKey{{kind: tuiKeyDown}, {kind: tuiKeyDown}, {kind: tuiKeyDown}}, tuiCommand{}, "beta", tuiViewLog},
+		{"moveUpClamped", []tuiKey{runeKey('k')}, tuiCommand{}, "alpha", tuiViewLog},
+		{"start", []tuiKey{runeKey('s')}, tuiCommand{kind: tuiCommandStart, workspace: "alpha"}, "alpha", tuiViewLog},
+		{"selfDrive", []tuiKey{runeKey('j'), runeKey('S')}, tuiCommand{kind: tuiCommandStart, workspace: "beta", auto: true}, "beta", tuiViewLog},
+		{"stop", []tuiKey{runeKey('x')}, tuiCommand{kind: tuiCommandStop, workspace: "alpha"}, "alpha", tuiViewLog},
+		{"diff", []tuiKey{runeKey('d')}, tuiCommand{kind: tuiCommandDiff, workspace: "alpha"}, "alpha", tuiViewDiff},
+		{"backToLog", []tuiKey{runeKey('d'), {kind: tuiKeyEscape}}, tuiCommand{}, "alpha", tuiViewLog},
+		{"refresh", []tuiKey{runeKey('r')}, tuiCommand{kind: tuiCommandRefresh}, "alpha", tuiViewLog},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newTestTUIModel()
+
+			var got tuiCommand
+			for _, key := range tc.keys {
+				got = m.handleKey(key)
+			}
+
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantSelected, m.selected)
+			assert.Equal(t, tc.wantView, m.view)
+		})
+	}
+}
+
+func TestTUIModelAnswer(t *testing.T) {
+	t.Run("noPendingQuestion", func(t *testing.T) {
+		m := newTestTUIModel()
+
+		m.handleKey(runeKey('a'))
+
+		assert.Nil(t, m.answer)
+		assert.Contains(t, m.status, "no pending question")
+	})
+
+	t.Run("choicesAndComment", func(t *testing.T) {
+		m := newTestTUIModel()
+		keys := []tuiKey{
+			runeKey('j'), runeKey('a'),
+			runeKey(' '), {kind: tuiKeyDown}, runeKey(' '),
+			{kind: tuiKeyDown}, runeKey(' '), {kind: tuiKeyDown}, runeKey(' '),
+			{kind: tuiKeyTab}, runeKey('o'), runeKey('k'), runeKey('!'), {kind: tuiKeyBackspace},
+		}
+		for _, key := range keys {
+			require.Equal(t, tuiCommand{}, m.handleKey(key))
+		}
+
+		got := m.handleKey(tuiKey{kind: tuiKeyEnter})
+
+		assert.Equal(t, tuiCommand{kind: tuiCommandRespond, workspace: "beta", respond: apiRespondRequest{
+			QuestionID:      "q1",
+			Answer:          "ok",
+			SelectedChoices: []string{"sqlite", "cache", "queue"},
+		}}, got)
+		assert.Nil(t, m.answer)
+	})
+
+	t.Run("emptyAnswerRejected", func(t *testing.T) {
+		m := newTestTUIModel()
+		m.handleKey(runeKey('j'))
+		m.handleKey(runeKey('a'))
+
+		got := m.handleKey(tuiKey{kind: tuiKeyEnter})
+
+		assert.Equal(t, tuiCommand{}, got)
+		assert.NotNil(t, m.answer)
+		assert.Contains(t, m.status, "select a choice")
+	})
+
+	t.Run("answeredElsewhere", func(t *testing.T) {
+		m := newTestTUIModel()
+		m.handleKey(runeKey('j'))
+		m.handleKey(runeKey('a'))
+
+		m.setState(apiFactoryState{Workspaces: []apiWorkspaceFullState{{Name: "alpha"}, {Name: "beta"}}})
+
+		assert.Nil(t, m.answer)
+		assert.Equal(t, "question was answered elsewhere", m.status)
+	})
+}
+
+func TestTUIModelRender(t *testing.T) {
+	cases := []struct {
+		name     string
+		prepare  func(m *tuiModel)
+		contains []string
+	}{
+		{"log", func(*tuiModel) {}, []string{"▶ alpha", "⚠ beta", "[coordinator] planning", "1 running, 1 need input"}},
+		{"question", func(m *tuiModel) { m.handleKey(runeKey('j')) }, []string{"Pick a database", "1) postgres", "press a to answer"}},
+		{"answer", func(m *tuiModel) { m.handleKey(runeKey('j')); m.handleKey(runeKey('a')) }, []string{"> [ ] postgres", "comments: ", "space select"}},
+		{"diffLoading", func(m *tuiModel) { m.handleKey(runeKey('d')) }, []string{"loading diff…"}},
+		{"diff", func(m *tuiModel) { m.handleKey(runeKey('d')); m.diff, m.diffFor = "+added line\n", "alpha" }, []string{"+added line"}},
+		{"status", func(m *tuiModel) { m.status = "alpha: session started" }, []string{"alpha: session started"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newTestTUIModel()
+			m.workspaces[0].Log = []apiLogEntry{{Prefix: "[coordinator] ", Text: "\x1b[31mplanning\x1b[0m"}}
+			tc.prepare(m)
+
+			lines := m.render(100, 20)
+
+			require.Len(t, lines, 20)
+			for _, line := range lines {
+				assert.Equal(t, 100, utf8.RuneCountInString(line))
+			}
+			screen := strings.Join(lines, "\n")
+			for _, want := range tc.contains {
+				assert.Contains(t, screen, want)
+			}
+			assert.NotContains(t, screen, "\x1b")
+		})
+	}
+}
+
+func TestTUIFit(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		width int
+		want  string
+	}{
+		{"pads", "ab", 4, "ab  "},
+		{"truncates", "abcdef", 4, "abc…"},
+		{"stripsANSI", "\x1b[1mab\x1b[0m", 3, "ab "},
+		{"stripsControls", "a\rb\tc", 5, "ab c "},
+		{"unicode", "✓ ok", 4, "✓ ok"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tuiFit(tc.input, tc.width))
+		})
+	}
+}
+
+func TestRenderTUILog(t *testing.T) {
+	entries := []apiLogEntry{{Prefix: "a ", Text: "1"}, {Prefix: "a ", Text: "2"}, {Prefix: "b ", Text: "3"}}
+
+	assert.Equal(t, []string{"a 2", "b 3"}, renderTUILog(entries, 2))
+	assert.Equal(t, []string{"no output yet"}, renderTUILog(nil, 2))
+}
+
+func TestCtlClientDiff(t *testing.T) {
+	serverURL, rootDir := startCtlTestServer(t)
+	setupTestWorkspace(t, rootDir, "alpha")
+	client := newCtlClient(serverURL)
+
+	diff, errDiff := client.diff(context.Background(), "alpha")
+
+	require.NoError(t, errDiff)
+	assert.Empty(t, diff, "workspaces without a jj repository have no diff")
+
+	_, errMissing := client.diff(context.Background(), "missing")
+	assert.Error(t, errMissing)
+}
+
+func TestRunTUIRequiresTerminal(t *testing.T) {
+	in, errOpen := os.Open(os.DevNull)
+	require.NoError(t, errOpen)
+	t.Cleanup(func() { _ = in.Close() })
+
+	errRun := runTUI(context.Background(), newCtlClient(defaultCtlServer), in, &strings.Builder{})
+
+	assert.ErrorIs(t, errRun, errTUINotTerminal)
+}
@@ -0,0 +1,188 @@
+import { useMemo } from "react";
+import { useNavigate } from "react-router";
+import { cn } from "@/lib/utils";
+import type { ApiWorkspaceEntry } from "@/types";
+
+const NODE_WIDTH = 160;
+const NODE_HEIGHT = 36;
+const COLUMN_GAP = 64;
+const ROW_GAP = 16;
+
+export interface PipelineNodeLayout {
+  name: string;
+  status: string;
+  running: boolean;
+  missing: boolean;
+  x: number;
+  y: number;
+}
+
+export interface PipelineEdgeLayout {
+  from: string;
+  to: string;
+}
+
+export interface PipelineLayout {
+  nodes: PipelineNodeLayout[];
+  edges: PipelineEdgeLayout[];
+  width: number;
+  height: number;
+}
+
+export function layoutPipeline(workspaces: ApiWorkspaceEntry[]): PipelineLayout {
+  const byName = new Map<string, ApiWorkspaceEntry>();
+  for (const w of workspaces) {
+    if (!byName.has(w.name)) {
+      byName.set(w.name, w);
+    }
+  }
+
+  const edges: PipelineEdgeLayout[] = [];
+  const names = new Set<string>();
+  for (const w of byName.values()) {
+    for (const upstream of w.dependsOn ?? []) {
+      edges.push({ from: upstream, to: w.name });
+      names.add(upstream);
+      names.add(w.name);
+    }
+  }
+
+  const depths = new Map<string, number>();
+  const visiting = new Set<string>();
+  const depthOf = (name: string): number => {
+    const known = depths.get(name);
+    if (known !== undefined) return known;
+    if (visiting.has(name)) return 0;
+    visiting.add(name);
+    let depth = 0;
+    for (const upstream of byName.get(name)?.dependsOn ?? []) {
+      depth = Math.max(depth, depthOf(upstream) + 1);
+    }
+    visiting.delete(name);
+    depths.set(name, depth);
+    return depth;
+  };
+
+  const rowsPerColumn = new Map<number, number>();
+  const nodes = Array.from(names).sort().map((name) => {
+    const column = depthOf(name);
+    const row = rowsPerColumn.get(column) ?? 0;
+    rowsPerColumn.set(column, row + 1);
+    const workspace = byName.get(name);
+    return {
+      name,
+      status: workspace?.status ?? "",
+      running: workspace?.running ?? false,
+      missing: !workspace,
+      x: column * (NODE_WIDTH + COLUMN_GAP),
+      y: row * (NODE_HEIGHT + ROW_GAP),
+    };
+  });
+
+  const width = nodes.reduce((max, n) => Math.max(max, n.x + NODE_WIDTH), 0);
+  const height = nodes.reduce((max, n) => Math.max(max, n.y + NODE_HEIGHT), 0);
+  return { nodes, edges, width, height };
+}
+
+function nodeDescription(node: PipelineNodeLayout): string {
+  if (node.missing) return `${node.name}: workspace not found`;
+  if (node.running) return `${node.name}: running`;
+  return `${node.name}: ${node.status || "not started"}`;
+}
+
+interface PipelineGraphProps {
+  workspaces: ApiWorkspaceEntry[];
+}
+
+export function PipelineGraph({ workspaces }: PipelineGraphProps) {
+  const navigate = useNavigate();
+  const layout = useMemo(() => layoutPipeline(workspaces), [workspaces]);
+  const positions = useMemo(
+    () => new Map(layout.nodes.map((node) => [node.name, node])),
+    [layout],
+  );
+
+  if (layout.nodes.length === 0) return null;
+
+  return (
+    <section aria-label="Pipeline" className="w-full">
+      <h2 className="text-sm font-semibold mb-2">Pipeline</h2>
+      <div className="overflow-auto">
+        <svg
+          width={layout.width + 2}
+          height={layout.height + 2}
+          role="img"
+          aria-label="Workspace pipeline graph"
+          className="text-xs"
+        >
+          <defs>
+            <marker
+              id="pipeline-arrow"
+              viewBox="0 0 10 10"
+              refX="10"
+              refY="5"
+              markerWidth="6"
+              markerHeight="6"
+              orient="auto-start-reverse"
+            >
+              <path d="M 0 0 L 10 5 L 0 10 z" className="fill-muted-foreground" />
+            </marker>
+          </defs>
+          {layout.edges.map((edge) => {
+            const from = positions.get(edge.from);
+            const to = positions.get(edge.to);
+            if (!from || !to) return null;
+            const x1 = from.x + NODE_WIDTH + 1;
+            const y1 = from.y + NODE_HEIGHT / 2 + 1;
+            const x2 = to.x + 1;
+            const y2 = to.y + NODE_HEIGHT / 2 + 1;
+            const mid = (x1 + x2) / 2;
+            return (
+              <path
+                key={`${edge.from}->${edge.to}`}
+                d={`M ${x1} ${y1} C ${mid} ${y1}, ${mid} ${y2}, ${x2} ${y2}`}
+                fill="none"
+                className="stroke-muted-foreground"
+                markerEnd="url(#pipeline-arrow)"
+              />
+            );
+          })}
+          {layout.nodes.map((node) => (
+            <g
+              key={node.name}
+              transform={`translate(${node.x + 1} ${node.y + 1})`}
+              role={node.missing ? undefined : "link"}
+              aria-label={nodeDescription(node)}
+              onClick={node.missing ? undefined : () => navigate(`/workspaces/${encodeURIComponent(node.name)}`)}
+              className={cn(!node.missing && "cursor-pointer")}
+            >
+              <title>{nodeDescription(node)}</title>
+              <rect
+                width={NODE_WIDTH}
+                height={NODE_HEIGHT}
+                rx={6}
+                strokeDasharray={node.missing ? "4 3" : undefined}
+                className={cn(
+                  "stroke-border",
+                  node.missing && "fill-transparent",
+                  !node.missing && node.running && "fill-primary/20",
+                  !node.missing && !node.running && node.status === "complete" && "fill-green-500/20",
+                  !node.missing && !node.running && node.status !== "complete" && "fill-muted",
+                )}
+              />
+              <text
+                x={NODE_WIDTH / 2}
+                y={NODE_HEIGHT / 2}
+                textAnchor="middle"
+                dominantBaseline="central"
+                className={cn(node.missing ? "fill-muted-foreground" : "fill-foreground")}
+              >
+                {node.name}
+              </text>
+            </g>
+          ))}
+        </svg>
+      </div>
+    </section>
+  );
+}
@@ -0,0 +1,102 @@
+import { Label } from "@/components/ui/label";
+import { Textarea } from "@/components/ui/textarea";
+import { MarkdownContent } from "@/components/MarkdownContent";
+import type { ApiWorkGateReviewRequest, WorkGateDecision, WorkGateSection } from "@/types";
+
+const DECISIONS: { value: WorkGateDecision; label: string }[] = [
+  { value: "approve", label: "Approve, build may begin" },
+  { value: "approve-with-comments", label: "Approve with comments" },
+  { value: "request-changes", label: "Request changes" },
+];
+
+export function buildWorkGateRequest(
+  decision: WorkGateDecision,
+  comment: string,
+  sectionComments: Record<string, string>,
+): ApiWorkGateReviewRequest {
+  const comments = Object.entries(sectionComments)
+    .map(([section, text]) => ({ section, comment: text.trim() }))
+    .filter((c) => c.comment !== "");
+  const request: ApiWorkGateReviewRequest = { decision };
+  if (comment.trim()) request.comment = comment.trim();
+  if (comments.length > 0) request.comments = comments;
+  return request;
+}
+
+export function workGateReviewError(
+  decision: WorkGateDecision | "",
+  comment: string,
+  sectionComments: Record<string, string>,
+): string | null {
+  if (!decision) return "Choose a decision.";
+  if (decision === "approve") return null;
+  const hasComments = comment.trim() !== "" || Object.values(sectionComments).some((c) => c.trim() !== "");
+  return hasComments ? null : "Add at least one comment for this decision.";
+}
+
+interface WorkGateReviewProps {
+  sections: WorkGateSection[];
+  decision: WorkGateDecision | "";
+  sectionComments: Record<string, string>;
+  onDecisionChange: (decision: WorkGateDecision) => void;
+  onSectionCommentChange: (sectionId: string, comment: string) => void;
+  idPrefix?: string;
+}
+
+export function WorkGateReview({
+  sections,
+  decision,
+  sectionComments,
+  onDecisionChange,
+  onSectionCommentChange,
+  idPrefix = "",
+}: WorkGateReviewProps) {
+  return (
+    <div className="space-y-4">
+      {sections.map((section) => (
+        <section
+          key={section.id}
+          aria-labelledby={`${idPrefix}work-gate-${section.id}-title`}
+          className="pb-4 border-b last:border-b-0"
+        >
+          <h3 id={`${idPrefix}work-gate-${section.id}-title`} className="text-sm font-semibold mb-2">
+            {section.title}
+          </h3>
+          {section.body && <MarkdownContent content={section.body} className="text-sm mb-2" />}
+          <Label htmlFor={`${idPrefix}work-gate-${section.id}-comment`} className="text-xs text-muted-foreground">
+            Comment on {section.title}
+          </Label>
+          <Textarea
+            id={`${idPrefix}work-gate-${section.id}-comment`}
+            value={sectionComments[section.id] ?? ""}
+            onChange={(e) => onSectionCommentChange(section.id, e.target.value)}
+            rows={2}
+            className="mt-1"
+          />
+        </section>
+      ))}
+
+      <fieldset>
+        <legend className="text-sm font-medium mb-2">Decision:</legend>
+        <div className="space-y-2">
+          {DECISIONS.map((option) => (
+            <div key={option.value} className="flex items-start gap-2">
+              <input
+                type="radio"
+                id={`${idPrefix}work-gate-decision-${option.value}`}
+                name={`${idPrefix}work-gate-decision`}
+                value={option.value}
+                checked={decision === option.value}
+                onChange={() => onDecisionChange(option.value)}
+                className="mt-1 shrink-0"
+              />
+              <label htmlFor={`${idPrefix}work-gate-decision-${option.value}`} className="text-sm cursor-pointer">
+                {option.label}
+              </label>
+            </div>
+          ))}
+        </div>
+      </fieldset>
+    </div>
+  );
+}
@@ -0,0 +1,108 @@
+import { describe, it, expect, afterEach } from "bun:test";
+import { render, screen, cleanup } from "@testing-library/react";
+import { MemoryRouter } from "react-router";
+import "../../../happydom";
+import { PipelineGraph, layoutPipeline } from "../PipelineGraph";
+import type { ApiWorkspaceEntry } from "@/types";
+
+afterEach(() => {
+  cleanup();
+});
+
+const createWorkspace = (overrides: Partial<ApiWorkspaceEntry> = {}): ApiWorkspaceEntry => ({
+  name: "workspace",
+  dir: "/path/to/workspace",
+  running: false,
+  needsInput: false,
+  inProgress: false,
+  pinned: false,
+  isRoot: false,
+  isFork: false,
+  description: "",
+  status: "",
+  badgeClass: "",
+  badgeText: "",
+  hasSgai: true,
+  hasEditedGoal: false,
+  interactiveAuto: false,
+  continuousMode: false,
+  task: "",
+  goalContent: "",
+  rawGoalContent: "",
+  pmContent: "",
+  hasProjectMgmt: false,
+  totalExecTime: "",
+  latestProgress: "",
+  humanMessage: "",
+  events: [],
+  projectTodos: [],
+  agentTodos: [],
+  log: [],
+  ...overrides,
+});
+
+const pipelineWorkspaces = [
+  createWorkspace({ name: "backend", status: "complete" }),
+  createWorkspace({ name: "frontend", running: true, dependsOn: ["backend"] }),
+  createWorkspace({ name: "docs", dependsOn: ["frontend", "design"] }),
+  createWorkspace({ name: "unrelated" }),
+];
+
+describe("layoutPipeline", () => {
+  it("places workspaces in columns after their upstreams", () => {
+    const layout = layoutPipeline(pipelineWorkspaces);
+    const columns = Object.fromEntries(layout.nodes.map((node) => [node.name, node.x]));
+
+    expect(Object.keys(columns).sort()).toEqual(["backend", "design", "docs", "frontend"]);
+    expect(columns.backend).toBe(0);
+    expect(columns.design).toBe(0);
+    expect(columns.frontend).toBeGreaterThan(columns.backend);
+    expect(columns.docs).toBeGreaterThan(columns.frontend);
+    expect(layout.edges).toHaveLength(3);
+  });
+
+  it("marks upstreams without a workspace as missing", () => {
+    const layout = layoutPipeline(pipelineWorkspaces);
+
+    expect(layout.nodes.find((node) => node.name === "design")?.missing).toBe(true);
+    expect(layout.nodes.find((node) => node.name === "backend")?.missing).toBe(false);
+  });
+
+  it("terminates on dependency cycles", () => {
+    const layout = layoutPipeline([
+      createWorkspace({ name: "a", dependsOn: ["b"] }),
+      createWorkspace({ name: "b", dependsOn: ["a"] }),
+    ]);
+
+    expect(layout.nodes).toHaveLength(2);
+  });
+
+  it("is empty without dependencies", () => {
+    expect(layoutPipeline([createWorkspace()]).nodes).toHaveLength(0);
+  });
+});
+
+describe("PipelineGraph", () => {
+  it("renders a node per pipeline workspace", () => {
+    render(
+      <MemoryRouter>
+        <PipelineGraph workspaces={pipelineWorkspaces} />
+      </MemoryRouter>,
+    );
+
+    expect(screen.getByRole("img", { name: "Workspace pipeline graph" })).toBeTruthy();
+    expect(screen.getByLabelText("frontend: running")).toBeTruthy();
+    expect(screen.getByLabelText("backend: complete")).toBeTruthy();
+    expect(screen.getByLabelText("design: workspace not found")).toBeTruthy();
+  });
+
+  it("renders nothing without dependencies", () => {
+    const { container } = render(
+      <MemoryRouter>
+        <PipelineGraph workspaces={[createWorkspace()]} />
+      </MemoryRouter>,
+    );
+
+    expect(container.innerHTML).toBe("");
+  });
+});
@@ -0,0 +1,65 @@
+import { describe, it, expect, afterEach, mock } from "bun:test";
+import { render, screen, cleanup, fireEvent } from "@testing-library/react";
+import "../../../happydom";
+import { WorkGateReview, buildWorkGateRequest, workGateReviewError } from "../WorkGateReview";
+
+afterEach(() => {
+  cleanup();
+});
+
+const sections = [
+  { id: "s1", title: "What Will Be Built", body: "- api" },
+  { id: "s2", title: "Key Decisions", body: "- sqlite" },
+];
+
+describe("buildWorkGateRequest", () => {
+  it("keeps only non-empty trimmed comments", () => {
+    expect(buildWorkGateRequest("request-changes", "  overall  ", { s1: " split it ", s2: "  " })).toEqual({
+      decision: "request-changes",
+      comment: "overall",
+      comments: [{ section: "s1", comment: "split it" }],
+    });
+  });
+
+  it("sends a bare decision without comments", () => {
+    expect(buildWorkGateRequest("approve", "", {})).toEqual({ decision: "approve" });
+  });
+});
+
+describe("workGateReviewError", () => {
+  it("requires a decision", () => {
+    expect(workGateReviewError("", "", {})).toBe("Choose a decision.");
+  });
+
+  it("requires a comment unless approving", () => {
+    expect(workGateReviewError("approve", "", {})).toBeNull();
+    expect(workGateReviewError("request-changes", "", { s1: " " })).not.toBeNull();
+    expect(workGateReviewError("request-changes", "", { s1: "split it" })).toBeNull();
+    expect(workGateReviewError("approve-with-comments", "looks good", {})).toBeNull();
+  });
+});
+
+describe("WorkGateReview", () => {
+  it("renders a comment box per section and reports changes", () => {
+    const onDecisionChange = mock(() => {});
+    const onSectionCommentChange = mock(() => {});
+    render(
+      <WorkGateReview
+        sections={sections}
+        decision=""
+        sectionComments={{ s2: "use postgres" }}
+        onDecisionChange={onDecisionChange}
+        onSectionCommentChange={onSectionCommentChange}
+      />,
+    );
+
+    expect(screen.getByRole("heading", { name: "What Will Be Built" })).toBeTruthy();
+    expect((screen.getByLabelText("Comment on Key Decisions") as HTMLTextAreaElement).value).toBe("use postgres");
+
+    fireEvent.change(screen.getByLabelText("Comment on What Will Be Built"), { target: { value: "split it" } });
+    expect(onSectionCommentChange).toHaveBeenCalledWith("s1", "split it");
+
+    fireEvent.click(screen.getByLabelText("Request changes"));
+    expect(onDecisionChange).toHaveBeenCalledWith("request-changes");
+  });
+});
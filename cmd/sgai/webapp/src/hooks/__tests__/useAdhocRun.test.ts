@@ -235,7 +235,7 @@ describe("useAdhocRun", () => {
         result.current.startRun();
       });
 
-      expect(mockAdhoc).toHaveBeenCalledWith("test-ws", "test prompt", "model-1");
+      expect(mockAdhoc).toHaveBeenCalledWith("test-ws", "test prompt", "model-1", undefined);
     });
 
     it("uses overrides when provided", async () => {
@@ -252,7 +252,7 @@ describe("useAdhocRun", () => {
         result.current.startRun("override prompt", "override-model");
       });
 
-      expect(mockAdhoc).toHaveBeenCalledWith("test-ws", "override prompt", "override-model");
+      expect(mockAdhoc).toHaveBeenCalledWith("test-ws", "override prompt", "override-model", undefined);
     });
 
     it("sets output after successful run", async () => {
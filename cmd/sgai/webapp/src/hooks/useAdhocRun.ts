@@ -45,7 +45,7 @@ export interface UseAdhocRunResult {
   output: string;
   isRunning: boolean;
   runError: string | null;
-  startRun: (promptOverride?: string, modelOverride?: string) => void;
+  startRun: (promptOverride?: string, modelOverride?: string, action?: string) => void;
   stopRun: () => void;
   handleSubmit: (event: React.FormEvent) => void;
   handleKeyDown: (event: React.KeyboardEvent) => void;
@@ -221,7 +221,7 @@ export function useAdhocRun({
   }, [workspaceName, startStatusPolling]);
 
   const startRun = useCallback(
-    async (promptOverride?: string, modelOverride?: string) => {
+    async (promptOverride?: string, modelOverride?: string, action?: string) => {
       const trimmedPrompt = (promptOverride ?? prompt).trim();
       const trimmedModel = (modelOverride ?? selectedModel).trim();
       if (!workspaceName || isRunning || !trimmedPrompt || !trimmedModel) return;
@@ -231,7 +231,7 @@ export function useAdhocRun({
       addToHistory(trimmedPrompt);
 
       try {
-        const result = await api.workspaces.adhoc(workspaceName, trimmedPrompt, trimmedModel);
+        const result = await api.workspaces.adhoc(workspaceName, trimmedPrompt, trimmedModel, action);
         if (result.output) updateRunState({ output: result.output });
         if (!result.running) { updateRunState({ isRunning: false }); return; }
         startStatusPolling();
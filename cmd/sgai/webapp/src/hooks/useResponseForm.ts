@@ -1,12 +1,28 @@
 import { useReducer, useEffect, useCallback, useRef } from "react";
 import { api, ApiError } from "@/lib/api";
 import { useFactoryState, triggerFactoryRefresh } from "@/lib/factory-state";
-import type { ApiPendingQuestionResponse, ApiWorkspaceEntry } from "@/types";
+import { buildWorkGateRequest, workGateReviewError } from "@/components/WorkGateReview";
+import type { ApiPendingQuestionResponse, ApiWorkspaceEntry, WorkGateDecision } from "@/types";
 
 interface StoredResponseState {
   selections: Record<string, string[]>;
   otherText: string;
   questionId: string;
+  decision?: WorkGateDecision | "";
+  sectionComments?: Record<string, string>;
+}
+
+interface FormState {
+  submitting: boolean;
+  submitError: string | null;
+  selections: Record<string, string[]>;
+  otherText: string;
+  decision: WorkGateDecision | "";
+  sectionComments: Record<string, string>;
+}
+
+export function isWorkGateReview(question: ApiPendingQuestionResponse | null): boolean {
+  return question?.type === "work-gate" && (question.sections?.length ?? 0) > 0;
 }
 
 function getStorageKey(prefix: string, workspaceName: string): string {
@@ -58,6 +74,10 @@ interface UseResponseFormReturn {
   selections: Record<string, string[]>;
   otherText: string;
   setOtherText: (text: string) => void;
+  decision: WorkGateDecision | "";
+  setDecision: (decision: WorkGateDecision) => void;
+  sectionComments: Record<string, string>;
+  handleSectionCommentChange: (sectionId: string, comment: string) => void;
   handleChoiceToggle: (questionIndex: number, choice: string, multiSelect: boolean) => void;
   handleSubmit: (e: React.FormEvent) => void;
 }
@@ -68,12 +88,9 @@ export function useResponseForm({
   active,
   onSubmitSuccess,
 }: UseResponseFormOptions): UseResponseFormReturn {
-  const [{ submitting, submitError, selections, otherText }, updateFormState] = useReducer(
-    (
-      state: { submitting: boolean; submitError: string | null; selections: Record<string, string[]>; otherText: string },
-      update: Partial<{ submitting: boolean; submitError: string | null; selections: Record<string, string[]>; otherText: string }>,
-    ) => ({ ...state, ...update }),
-    { submitting: false, submitError: null, selections: {}, otherText: "" },
+  const [{ submitting, submitError, selections, otherText, decision, sectionComments }, updateFormState] = useReducer(
+    (state: FormState, update: Partial<FormState>) => ({ ...state, ...update }),
+    { submitting: false, submitError: null, selections: {}, otherText: "", decision: "", sectionComments: {} },
   );
   const hasUnsavedChangesRef = useRef(false);
   const previousQuestionIdRef = useRef<string | null>(null);
@@ -94,9 +111,14 @@ export function useResponseForm({
       previousQuestionIdRef.current = question.questionId;
       const stored = loadStoredState(storagePrefix, workspaceName);
       if (stored && stored.questionId === question.questionId) {
-        updateFormState({ selections: stored.selections, otherText: stored.otherText });
+        updateFormState({
+          selections: stored.selections,
+          otherText: stored.otherText,
+          decision: stored.decision ?? "",
+          sectionComments: stored.sectionComments ?? {},
+        });
       } else {
-        updateFormState({ selections: {}, otherText: "" });
+        updateFormState({ selections: {}, otherText: "", decision: "", sectionComments: {} });
       }
     }
   }, [active, workspaceName, storagePrefix, question]);
@@ -106,14 +128,17 @@ export function useResponseForm({
 
     const hasSelections = Object.values(selections).some((s) => s.length > 0);
     const hasText = otherText.trim().length > 0;
-    hasUnsavedChangesRef.current = hasSelections || hasText;
+    const hasReview = decision !== "" || Object.values(sectionComments).some((c) => c.trim().length > 0);
+    hasUnsavedChangesRef.current = hasSelections || hasText || hasReview;
 
     saveStoredState(storagePrefix, workspaceName, {
       selections,
       otherText,
       questionId: question.questionId,
+      decision,
+      sectionComments,
     });
-  }, [selections, otherText, question, workspaceName, storagePrefix]);
+  }, [selections, otherText, decision, sectionComments, question, workspaceName, storagePrefix]);
 
   useEffect(() => {
     function handleBeforeUnload(e: BeforeUnloadEvent) {
@@ -132,6 +157,17 @@ export function useResponseForm({
     updateFormState({ otherText: text });
   }, []);
 
+  const setDecision = useCallback((value: WorkGateDecision) => {
+    updateFormState({ decision: value });
+  }, []);
+
+  const handleSectionCommentChange = useCallback(
+    (sectionId: string, comment: string) => {
+      updateFormState({ sectionComments: { ...sectionComments, [sectionId]: comment } });
+    },
+    [sectionComments],
+  );
+
   const handleChoiceToggle = useCallback(
     (questionIndex: number, choice: string, multiSelect: boolean) => {
       const key = String(questionIndex);
@@ -156,6 +192,15 @@ export function useResponseForm({
 
       if (!question || submitting) return;
 
+      const workGate = isWorkGateReview(question);
+      if (workGate) {
+        const reviewError = workGateReviewError(decision, otherText, sectionComments);
+        if (reviewError) {
+          updateFormState({ submitError: reviewError });
+          return;
+        }
+      }
+
       updateFormState({ submitting: true, submitError: null });
 
       const allSelectedChoices: string[] = [];
@@ -164,11 +209,20 @@ export function useResponseForm({
       }
 
       try {
-        await api.workspaces.respond(workspaceName, {
-          questionId: question.questionId,
-          answer: otherText.trim(),
-          selectedChoices: allSelectedChoices,
-        });
+        if (workGate && decision) {
+          await api.workspaces.respond(workspaceName, {
+            questionId: question.questionId,
+            answer: "",
+            selectedChoices: [],
+            workGate: buildWorkGateRequest(decision, otherText, sectionComments),
+          });
+        } else {
+          await api.workspaces.respond(workspaceName, {
+            questionId: question.questionId,
+            answer: otherText.trim(),
+            selectedChoices: allSelectedChoices,
+          });
+        }
         triggerFactoryRefresh();
 
         clearStoredState(storagePrefix, workspaceName);
@@ -184,7 +238,7 @@ export function useResponseForm({
         updateFormState({ submitting: false });
       }
     },
-    [question, submitting, selections, otherText, workspaceName, storagePrefix, onSubmitSuccess],
+    [question, submitting, selections, otherText, decision, sectionComments, workspaceName, storagePrefix, onSubmitSuccess],
   );
 
   return {
@@ -197,6 +251,10 @@ export function useResponseForm({
     selections,
     otherText,
     setOtherText,
+    decision,
+    setDecision,
+    sectionComments,
+    handleSectionCommentChange,
     handleChoiceToggle,
     handleSubmit,
   };
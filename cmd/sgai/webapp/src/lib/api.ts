@@ -10,11 +10,13 @@ import type {
   ApiGoalResponse,
   ApiCreateWorkspaceResponse,
   ApiComposeStateResponse,
+  ApiComposeTemplateEntry,
   ApiComposeTemplatesResponse,
   ApiComposePreviewResponse,
   ApiComposeDraftRequest,
   ApiComposeDraftResponse,
   ApiComposeSaveResponse,
+  ApiSaveComposeTemplateRequest,
   ApiForkResponse,
   ApiForkTemplateResponse,
   ApiUpdateGoalResponse,
@@ -106,10 +108,10 @@ export const api = {
         `/api/v1/workspaces/${encodeURIComponent(name)}/goal`,
         { method: "PUT", body: JSON.stringify({ content }) },
       ),
-    adhoc: (name: string, prompt: string, model: string) =>
+    adhoc: (name: string, prompt: string, model: string, action?: string) =>
       fetchJSON<ApiAdhocResponse>(
         `/api/v1/workspaces/${encodeURIComponent(name)}/adhoc`,
-        { method: "POST", body: JSON.stringify({ prompt, model }) },
+        { method: "POST", body: JSON.stringify({ prompt, model, action }) },
       ),
     adhocStatus: (name: string) =>
       fetchJSON<ApiAdhocResponse>(
@@ -237,8 +239,20 @@ export const api = {
         },
       );
     },
-    templates: () =>
-      fetchJSON<ApiComposeTemplatesResponse>("/api/v1/compose/templates"),
+    templates: (workspace?: string) =>
+      fetchJSON<ApiComposeTemplatesResponse>(
+        workspace
+          ? `/api/v1/compose/templates?workspace=${encodeURIComponent(workspace)}`
+          : "/api/v1/compose/templates",
+      ),
+    saveTemplate: (workspace: string, request: ApiSaveComposeTemplateRequest) =>
+      fetchJSON<ApiComposeTemplateEntry>(
+        `/api/v1/compose/templates?workspace=${encodeURIComponent(workspace)}`,
+        {
+          method: "POST",
+          body: JSON.stringify(request),
+        },
+      ),
     preview: (workspace: string) =>
       fetchJSON<ApiComposePreviewResponse>(
         `/api/v1/compose/preview?workspace=${encodeURIComponent(workspace)}`,
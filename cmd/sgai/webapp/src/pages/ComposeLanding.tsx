@@ -22,7 +22,7 @@ export function ComposeLanding() {
     async function loadTemplates() {
       let nextTemplates: ApiComposeTemplateEntry[] = [];
       try {
-        const resp = await api.compose.templates();
+        const resp = await api.compose.templates(workspace || undefined);
         nextTemplates = resp.templates;
       } catch {
         // Silently handle error
@@ -34,7 +34,7 @@ export function ComposeLanding() {
 
     loadTemplates();
     return () => { cancelled = true; };
-  }, []);
+  }, [workspace]);
 
   return (
     <div className="max-w-4xl mx-auto">
@@ -22,7 +22,7 @@ export function ComposeTemplateRedirect() {
       setError(null);
 
       try {
-        const resp = await api.compose.templates();
+        const resp = await api.compose.templates(workspace);
         const template = resp.templates.find((entry) => entry.id === id);
         if (!template) {
           throw new Error("Template not found");
@@ -81,20 +81,21 @@ export function ComposeTemplateRedirect() {
 function buildDraftRequest(template: ApiComposeTemplateEntry) {
   return {
     state: {
-      description: "",
-      completionGate: "",
+      description: template.body ?? "",
+      completionGate: template.completionGate ?? "",
       retrospective: false,
       agents: template.agents,
-      model: "openai/gpt-5.5 (xhigh)",
-      tasks: "",
+      model: template.model || "openai/gpt-5.5 (xhigh)",
+      interactive: template.interactive,
+      tasks: template.tasks ?? "",
     },
     wizard: {
       currentStep: 1,
       fromTemplate: template.id,
-      description: "",
-      techStack: [],
+      description: template.body ?? "",
+      techStack: template.techStack?.map((item) => item.id) ?? [],
       safetyAnalysis: false,
-      completionGate: "",
+      completionGate: template.completionGate ?? "",
       retrospective: false,
     },
   };
@@ -1,9 +1,15 @@
+import { PipelineGraph } from "@/components/PipelineGraph";
+import { useFactoryState } from "@/lib/factory-state";
+
 export function EmptyState() {
+  const { workspaces } = useFactoryState();
+
   return (
-    <div className="flex items-center justify-center h-full min-h-[300px]">
+    <div className="flex flex-col items-center justify-center gap-6 h-full min-h-[300px]">
       <p className="text-sm text-muted-foreground italic">
         Select a workspace to view its details
       </p>
+      <PipelineGraph workspaces={workspaces} />
     </div>
   );
 }
@@ -15,7 +15,8 @@ import { ScrollArea } from "@/components/ui/scroll-area";
 import { Skeleton } from "@/components/ui/skeleton";
 import { ResponseContext } from "@/components/ResponseContext";
 import { QuestionBlock } from "@/components/QuestionBlock";
-import { useResponseForm } from "@/hooks/useResponseForm";
+import { WorkGateReview } from "@/components/WorkGateReview";
+import { isWorkGateReview, useResponseForm } from "@/hooks/useResponseForm";
 
 const STORAGE_PREFIX = "sgai-response-modal-";
 
@@ -47,6 +48,10 @@ export function ResponseModal({
     selections,
     otherText,
     setOtherText,
+    decision,
+    setDecision,
+    sectionComments,
+    handleSectionCommentChange,
     handleChoiceToggle,
     handleSubmit,
   } = useResponseForm({
@@ -89,7 +94,16 @@ export function ResponseModal({
         {!loading && !error && question && (
           <form onSubmit={handleSubmit} className="flex flex-col flex-1 min-h-0">
             <ScrollArea className="flex-1 pr-2">
-              {question.questions && question.questions.length > 0 ? (
+              {isWorkGateReview(question) ? (
+                <WorkGateReview
+                  sections={question.sections ?? []}
+                  decision={decision}
+                  sectionComments={sectionComments}
+                  onDecisionChange={setDecision}
+                  onSectionCommentChange={handleSectionCommentChange}
+                  idPrefix="modal-"
+                />
+              ) : question.questions && question.questions.length > 0 ? (
                 <div className="space-y-4">
                   {question.questions.map((q, qIndex) => {
                     const questionKey = `${qIndex}-${question.questionId}-${q.question}-${q.choices.join("|")}-${q.multiSelect ? "multi" : "single"}`;
@@ -120,7 +134,7 @@ export function ResponseModal({
 
               <div className="mt-4 pt-3 border-t">
                 <Label htmlFor="modal-other" className="font-semibold text-sm">
-                  Other (additional comments or alternative answer):
+                  {isWorkGateReview(question) ? "Overall comment:" : "Other (additional comments or alternative answer):"}
                 </Label>
                 <div className="mt-2">
                   <MarkdownEditor
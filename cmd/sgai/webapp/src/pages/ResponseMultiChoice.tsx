@@ -10,7 +10,8 @@ import { Skeleton } from "@/components/ui/skeleton";
 import { Alert, AlertDescription, AlertTitle } from "@/components/ui/alert";
 import { ResponseContext } from "@/components/ResponseContext";
 import { QuestionBlock } from "@/components/QuestionBlock";
-import { useResponseForm } from "@/hooks/useResponseForm";
+import { WorkGateReview } from "@/components/WorkGateReview";
+import { isWorkGateReview, useResponseForm } from "@/hooks/useResponseForm";
 
 const STORAGE_PREFIX = "sgai-response-";
 
@@ -56,6 +57,10 @@ export function ResponseMultiChoice() {
     selections,
     otherText,
     setOtherText,
+    decision,
+    setDecision,
+    sectionComments,
+    handleSectionCommentChange,
     handleChoiceToggle,
     handleSubmit,
   } = useResponseForm({
@@ -131,7 +136,18 @@ export function ResponseMultiChoice() {
         </CardHeader>
         <CardContent>
           <form onSubmit={handleSubmit}>
-            {question.questions && question.questions.length > 0 ? (
+            {isWorkGateReview(question) ? (
+              <>
+                {question.message && <MarkdownContent content={question.message} className="mb-4" />}
+                <WorkGateReview
+                  sections={question.sections ?? []}
+                  decision={decision}
+                  sectionComments={sectionComments}
+                  onDecisionChange={setDecision}
+                  onSectionCommentChange={handleSectionCommentChange}
+                />
+              </>
+            ) : question.questions && question.questions.length > 0 ? (
               <div className="space-y-6">
               {question.questions.map((q, qIndex) => {
                 const questionKey = `${question.questionId}-${q.question}-${q.choices.join("|")}-${
@@ -162,7 +178,7 @@ export function ResponseMultiChoice() {
 
             <div className="mt-6 pt-4 border-t">
               <Label htmlFor="other" className="font-semibold">
-                Other (additional comments or alternative answer):
+                {isWorkGateReview(question) ? "Overall comment:" : "Other (additional comments or alternative answer):"}
               </Label>
               <Textarea
                 id="other"
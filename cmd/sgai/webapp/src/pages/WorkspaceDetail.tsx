@@ -198,7 +198,7 @@ export function WorkspaceDetail(): ReactNode {
 
   const handleActionClick = useCallback((action: ApiActionEntry, _forkName?: string) => {
     setActionOutputOpen(true);
-    startActionRun(action.prompt, action.model);
+    startActionRun(action.prompt, action.model, action.name);
   }, [startActionRun]);
 
   useEffect(() => {
@@ -536,6 +536,14 @@ function WorkspaceDetailActions({
     );
   }
 
+  if (detail.archived) {
+    return (
+      <Badge variant="secondary" title={`Imported ${detail.archived.importedAt ?? ""} from ${detail.archived.workspace}`}>
+        Archived
+      </Badge>
+    );
+  }
+
   return (
     <>
       {detail.needsInput && (
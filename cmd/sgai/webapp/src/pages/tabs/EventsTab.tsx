@@ -96,6 +96,11 @@ function formatTokens(n: number): string {
   return String(n);
 }
 
+function formatCost(row: ApiTokenUsageRow): string {
+  const cost = `$${(row.cost ?? 0).toFixed(2)}`;
+  return row.unpriced ? `${cost}*` : cost;
+}
+
 function modelDisplay(raw: string): string {
   if (!raw) return "";
   try {
@@ -216,7 +221,8 @@ function TokenUsageBox({ workspaceName }: { workspaceName: string }) {
                 <th className="py-1 pr-3 text-right">Cached In</th>
                 <th className="py-1 pr-3 text-right">Reasoning</th>
                 <th className="py-1 pr-3 text-right">Total</th>
-                <th className="py-1 text-right">Sessions</th>
+                <th className="py-1 pr-3 text-right">Sessions</th>
+                <th className="py-1 text-right">Cost</th>
               </tr>
             </thead>
             <tbody>
@@ -229,7 +235,8 @@ function TokenUsageBox({ workspaceName }: { workspaceName: string }) {
                   <td className="py-1 pr-3 text-right tabular-nums">{formatTokens(row.cacheRead)}</td>
                   <td className="py-1 pr-3 text-right tabular-nums">{formatTokens(row.reasoning)}</td>
                   <td className="py-1 pr-3 text-right tabular-nums font-semibold">{formatTokens(row.total)}</td>
-                  <td className="py-1 text-right tabular-nums">{row.sessionCount}</td>
+                  <td className="py-1 pr-3 text-right tabular-nums">{row.sessionCount}</td>
+                  <td className="py-1 text-right tabular-nums" title={row.unpriced ? "No pricing configured for this model" : undefined}>{formatCost(row)}</td>
                 </tr>
               ))}
             </tbody>
@@ -241,7 +248,8 @@ function TokenUsageBox({ workspaceName }: { workspaceName: string }) {
                 <td className="py-1 pr-3 text-right tabular-nums">{formatTokens(t.cacheRead)}</td>
                 <td className="py-1 pr-3 text-right tabular-nums">{formatTokens(t.reasoning)}</td>
                 <td className="py-1 pr-3 text-right tabular-nums">{formatTokens(t.total)}</td>
-                <td className="py-1 text-right tabular-nums">{t.sessionCount}</td>
+                <td className="py-1 pr-3 text-right tabular-nums">{t.sessionCount}</td>
+                <td className="py-1 text-right tabular-nums">{formatCost(t)}</td>
               </tr>
             </tfoot>
           </table>
@@ -289,7 +297,7 @@ export function EventsTab({ workspaceName, goalContent, actions }: EventsTabProp
 
   const handleActionClick = (action: ApiActionEntry) => {
     setActionOutputOpen(true);
-    startActionRun(action.prompt, action.model);
+    startActionRun(action.prompt, action.model, action.name);
   };
 
   if (fetchStatus === "fetching" && !workspace) return <EventsTabSkeleton />;
@@ -15,6 +15,7 @@ export interface ApiWorkspaceEntry {
   hasEditedGoal: boolean;
   interactiveAuto: boolean;
   continuousMode: boolean;
+  dependsOn?: string[];
   task: string;
   goalContent: string;
   rawGoalContent: string;
@@ -31,8 +32,29 @@ export interface ApiWorkspaceEntry {
   log: ApiLogEntry[];
   pendingQuestion?: ApiPendingQuestionResponse;
   actions?: ApiActionEntry[];
+  tokenTotals?: ApiTokenUsageRow;
   currentModel?: string;
   external?: boolean;
+  archived?: ApiBundleManifest;
+  redactions?: Record<string, number>;
+}
+
+export interface ApiBundleSession {
+  id: string;
+  agent?: string;
+  file?: string;
+  error?: string;
+}
+
+export interface ApiBundleManifest {
+  version: number;
+  workspace: string;
+  createdAt: string;
+  status?: string;
+  retrospective?: string;
+  files: string[];
+  sessions: ApiBundleSession[];
+  importedAt?: string;
 }
 
 export interface ApiActionEntry {
@@ -115,17 +137,38 @@ interface MultiChoiceQuestion {
   multiSelect: boolean;
 }
 
+export interface WorkGateSection {
+  id: string;
+  title: string;
+  body: string;
+}
+
+export type WorkGateDecision = "approve" | "approve-with-comments" | "request-changes";
+
+export interface WorkGateComment {
+  section: string;
+  comment: string;
+}
+
+export interface ApiWorkGateReviewRequest {
+  decision: WorkGateDecision;
+  comment?: string;
+  comments?: WorkGateComment[];
+}
+
 export interface ApiPendingQuestionResponse {
   questionId: string;
   type: "multi-choice" | "work-gate" | "free-text" | "";
   message: string;
   questions?: MultiChoiceQuestion[];
+  sections?: WorkGateSection[];
 }
 
 export interface ApiRespondRequest {
   questionId: string;
   answer: string;
   selectedChoices: string[];
+  workGate?: ApiWorkGateReviewRequest;
 }
 
 export interface ApiRespondResponse {
@@ -161,10 +204,18 @@ export interface ApiTokenUsageRow {
   other: number;
   total: number;
   sessionCount: number;
+  cost?: number;
+  unpriced?: boolean;
+  workspace?: string;
+  workspaceDir?: string;
+  sessionID?: string;
+  started?: string;
 }
 
 export interface ApiTokenUsageResponse {
   rows: ApiTokenUsageRow[];
+  sessions?: ApiTokenUsageRow[];
+  workspaces?: ApiTokenUsageRow[];
   totals: ApiTokenUsageRow;
 }
 
@@ -220,6 +271,7 @@ export interface ApiComposerState {
   retrospective: boolean;
   agents: ApiComposerAgentConf[];
   model: string;
+  interactive?: string;
   tasks: string;
 }
 
@@ -252,12 +304,28 @@ export interface ApiComposeTemplateEntry {
   description: string;
   icon: string;
   agents: ApiComposerAgentConf[];
+  model?: string;
+  completionGate?: string;
+  interactive?: string;
+  tasks?: string;
+  body?: string;
+  techStack?: ApiTechStackItem[];
+  source: string;
 }
 
 export interface ApiComposeTemplatesResponse {
   templates: ApiComposeTemplateEntry[];
 }
 
+export interface ApiSaveComposeTemplateRequest {
+  id: string;
+  name?: string;
+  description?: string;
+  icon?: string;
+  scope?: "workspace" | "user";
+  overwrite?: boolean;
+}
+
 export interface ApiComposePreviewResponse {
   content: string;
   etag: string;
@@ -318,6 +386,7 @@ export interface ApiAdhocResponse {
   running: boolean;
   output: string;
   message: string;
+  redactions?: Record<string, number>;
 }
 
 export interface ApiForkTemplateResponse {
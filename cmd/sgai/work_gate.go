@@ -0,0 +1,208 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+)
+
+const (
+	workGateApproveWithCommentsText = "APPROVED WITH COMMENTS, BUILD MAY BEGIN"
+	workGateRequestChangesText      = "Not ready yet, need more clarification"
+)
+
+var errInvalidWorkGateReview = errors.New("invalid work gate review")
+
+// apiWorkGateReviewRequest is the structured answer to a work-gate question.
+type apiWorkGateReviewRequest struct {
+	Decision string                  `json:"decision"`
+	Comment  string                  `json:"comment,omitempty"`
+	Comments []state.WorkGateComment `json:"comments,omitempty"`
+}
+
+// splitWorkGateSummary splits a markdown summary into sections at its
+// headings. Text before the first heading becomes an "Overview" section.
+func splitWorkGateSummary(summary string) []state.WorkGateSection {
+	var sections []state.WorkGateSection
+	var title string
+	var body []string
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(body, "\n"))
+		if title == "" && text == "" {
+			return
+		}
+		sections = append(sections, state.WorkGateSection{
+			ID:    fmt.Sprintf("s%d", len(sections)+1),
+			Title: cmp.Or(title, "Overview"),
+			Body:  text,
+		})
+	}
+	var fence string
+	for line := range strings.SplitSeq(summary, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if marker := workGateFenceMarker(trimmed); marker != "" && (fence == "" || strings.HasPrefix(marker, fence)) {
+			if fence == "" {
+				fence = marker
+			} else if strings.TrimLeft(trimmed, marker[:1]) == "" {
+				fence = ""
+			}
+		}
+		if heading, ok := strings.CutPrefix(trimmed, "#"); ok && fence == "" && strings.HasPrefix(strings.TrimLeft(heading, "#"), " ") {
+			flush()
+			title = strings.TrimSpace(strings.TrimLeft(heading, "#"))
+			body = nil
+			continue
+		}
+		body = append(body, line)
+	}
+	flush()
+	return sections
+}
+
+// workGateFenceMarker returns the run of backticks or tildes that opens or
+// closes a fenced code block on this line, or "" when there is none.
+func workGateFenceMarker(trimmed string) string {
+	for _, char := range []string{"`", "~"} {
+		marker := trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, char))]
+		if len(marker) >= 3 {
+			return marker
+		}
+	}
+	return ""
+}
+
+// buildWorkGateReviewAnswer validates a structured review against the pending
+// work-gate question and encodes it for delivery to askUserWorkGate.
+func buildWorkGateReviewAnswer(question *state.MultiChoiceQuestion, req apiWorkGateReviewRequest) (string, error) {
+	if question == nil || !question.IsWorkGate {
+		return "", fmt.Errorf("%w: the pending question is not a work gate", errInvalidWorkGateReview)
+	}
+	switch req.Decision {
+	case state.WorkGateApprove, state.WorkGateApproveWithComments, state.WorkGateRequestChanges:
+	default:
+		return "", fmt.Errorf("%w: decision must be approve, approve-with-comments or request-changes, got %q", errInvalidWorkGateReview, req.Decision)
+	}
+
+	review := state.WorkGateReview{Decision: req.Decision, Comment: strings.TrimSpace(req.Comment)}
+	for _, comment := range req.Comments {
+		text := strings.TrimSpace(comment.Comment)
+		if text == "" {
+			continue
+		}
+		idx := slices.IndexFunc(question.Sections, func(section state.WorkGateSection) bool { return section.ID == comment.Section })
+		if idx < 0 {
+			return "", fmt.Errorf("%w: unknown section %q", errInvalidWorkGateReview, comment.Section)
+		}
+		review.Comments = append(review.Comments, state.WorkGateComment{Section: comment.Section, Title: question.Sections[idx].Title, Comment: text})
+	}
+
+	hasComments := review.Comment != "" || len(review.Comments) > 0
+	if review.Decision != state.WorkGateApprove && !hasComments {
+		return "", fmt.Errorf("%w: %s needs at least one comment", errInvalidWorkGateReview, review.Decision)
+	}
+	if review.Decision == state.WorkGateApprove && hasComments {
+		review.Decision = state.WorkGateApproveWithComments
+	}
+
+	data, errJSON := json.Marshal(review)
+	if errJSON != nil {
+		return "", errJSON
+	}
+	return string(data), nil
+}
+
+// parseWorkGateAnswer decodes a structured review, falling back to the
+// choice-based answers sent by clients that predate structured reviews. Only
+// the leading "Selected:" line picks the decision, so free text that quotes a
+// choice cannot approve the gate.
+func parseWorkGateAnswer(answer string) state.WorkGateReview {
+	var review state.WorkGateReview
+	if errJSON := json.Unmarshal([]byte(answer), &review); errJSON == nil && review.Decision != "" {
+		return review
+	}
+
+	first, rest, _ := strings.Cut(answer, "\n")
+	selected, ok := strings.CutPrefix(first, "Selected: ")
+	switch {
+	case ok:
+		review.Comment = strings.TrimSpace(rest)
+	case slices.Contains([]string{workGateApprovalText, workGateApproveWithCommentsText, workGateRequestChangesText}, strings.TrimSpace(answer)):
+		selected = strings.TrimSpace(answer)
+	default:
+		review.Comment = strings.TrimSpace(answer)
+	}
+
+	switch {
+	case selected == workGateApprovalText && review.Comment == "":
+		review.Decision = state.WorkGateApprove
+	case selected == workGateApprovalText, selected == workGateApproveWithCommentsText:
+		review.Decision = state.WorkGateApproveWithComments
+	default:
+		review.Decision = state.WorkGateRequestChanges
+		review.Comment = cmp.Or(review.Comment, selected)
+	}
+	return review
+}
+
+func formatWorkGateReview(review state.WorkGateReview) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Decision: %s\n", review.Decision)
+	if review.Comment != "" {
+		fmt.Fprintf(&sb, "\nComment:\n%s\n", review.Comment)
+	}
+	if len(review.Comments) > 0 {
+		sb.WriteString("\nSection comments:\n")
+		for _, comment := range review.Comments {
+			fmt.Fprintf(&sb, "- [%s] %s: %s\n", comment.Section, comment.Title, comment.Comment)
+		}
+	}
+	return sb.String()
+}
+
+// workGateReviewResult is the tool response for the coordinator: the typed
+// review as JSON plus the instruction that follows from it.
+func workGateReviewResult(review state.WorkGateReview) string {
+	data, errJSON := json.MarshalIndent(review, "", "  ")
+	if errJSON != nil {
+		data = []byte("{}")
+	}
+	var next string
+	switch review.Decision {
+	case state.WorkGateApprove:
+		next = workGateApprovalText + ". Log the approval in .sgai/PROJECT_MANAGEMENT.md and delegate implementation."
+	case state.WorkGateApproveWithComments:
+		next = workGateApprovalText + ", with comments. Fold every comment into GOAL.md or .sgai/PROJECT_MANAGEMENT.md before delegating implementation."
+	default:
+		next = "Changes requested. Address every comment, update the definition, then call ask_user_work_gate again."
+	}
+	return "Work gate review:\n```json\n" + string(data) + "\n```\n\n" + next
+}
+
+func recordWorkGateReview(coord *state.Coordinator, workingDir string, review state.WorkGateReview) error {
+	if errUpdate := coord.UpdateState(func(wf *state.Workflow) {
+		wf.WorkGateReviews = append(wf.WorkGateReviews, review)
+		if review.Approved() {
+			wf.InteractionMode = state.ModeSelfDrive
+		}
+	}); errUpdate != nil {
+		return fmt.Errorf("recording work gate review: %w", errUpdate)
+	}
+	if workingDir == "" {
+		return nil
+	}
+	if errAppend := appendProjectManagementSection(workingDir, ledgerEntry{
+		Type:  ledgerWorkGateReview,
+		Title: "Work Gate Review: " + review.Decision,
+		Agent: ledgerHumanAgent,
+		Body:  formatWorkGateReview(review),
+	}); errAppend != nil {
+		log.Println("failed to append work gate review to PROJECT_MANAGEMENT.md:", errAppend)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sandgardenhq/sgai/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitWorkGateSummary(t *testing.T) {
+	summary := "Intro text\n\n## What Will Be Built\n- api\n\n## Key Decisions\n- sqlite\n### Nested\nstill decisions"
+
+	sections := splitWorkGateSummary(summary)
+
+	require.Len(t, sections, 4)
+	assert.Equal(t, state.WorkGateSection{ID: "s1", Title: "Overview", Body: "Intro text"}, sections[0])
+	assert.Equal(t, state.WorkGateSection{ID: "s2", Title: "What Will Be Built", Body: "- api"}, sections[1])
+	assert.Equal(t, "Key Decisions", sections[2].Title)
+	assert.Equal(t, state.WorkGateSection{ID: "s4", Title: "Nested", Body: "still decisions"}, sections[3])
+
+	plain := splitWorkGateSummary("#hashtag only")
+	require.Len(t, plain, 1, "a heading needs a space after the hashes")
+	assert.Equal(t, "Overview", plain[0].Title)
+}
+
+func TestSplitWorkGateSummaryIgnoresHeadingsInCodeFences(t *testing.T) {
+	summary := "## Setup\n```sh\n# install deps\n~~~\nmake deps\n````\n~~~\n# still code\n~~~\n## Rollout\nship it"
+
+	sections := splitWorkGateSummary(summary)
+
+	require.Len(t, sections, 2)
+	assert.Equal(t, "Setup", sections[0].Title)
+	assert.Equal(t, "```sh\n# install deps\n~~~\nmake deps\n````\n~~~\n# still code\n~~~", sections[0].Body)
+	assert.Equal(t, state.WorkGateSection{ID: "s2", Title: "Rollout", Body: "ship it"}, sections[1])
+}
+
+func TestBuildWorkGateReviewAnswer(t *testing.T) {
+	question := &state.MultiChoiceQuestion{
+		IsWorkGate: true,
+		Sections:   []state.WorkGateSection{{ID: "s1", Title: "Plan"}},
+	}
+	cases := []struct {
+		name         string
+		question     *state.MultiChoiceQuestion
+		req          apiWorkGateReviewRequest
+		wantErr      bool
+		wantDecision string
+	}{
+		{"approve", question, apiWorkGateReviewRequest{Decision: state.WorkGateApprove}, false, state.WorkGateApprove},
+		{"approveWithCommentUpgrades", question, apiWorkGateReviewRequest{Decision: state.WorkGateApprove, Comment: "ship it"}, false, state.WorkGateApproveWithComments},
+		{"requestChanges", question, apiWorkGateReviewRequest{Decision: state.WorkGateRequestChanges, Comments: []state.WorkGateComment{{Section: "s1", Comment: "split it"}}}, false, state.WorkGateRequestChanges},
+		{"requestChangesWithoutComment", question, apiWorkGateReviewRequest{Decision: state.WorkGateRequestChanges}, true, ""},
+		{"unknownSection", question, apiWorkGateReviewRequest{Decision: state.WorkGateRequestChanges, Comments: []state.WorkGateComment{{Section: "s9", Comment: "?"}}}, true, ""},
+		{"unknownDecision", question, apiWorkGateReviewRequest{Decision: "maybe"}, true, ""},
+		{"notWorkGate", &state.MultiChoiceQuestion{}, apiWorkGateReviewRequest{Decision: state.WorkGateApprove}, true, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			answer, errBuild := buildWorkGateReviewAnswer(tc.question, tc.req)
+			if tc.wantErr {
+				require.ErrorIs(t, errBuild, errInvalidWorkGateReview)
+				return
+			}
+			require.NoError(t, errBuild)
+			review := parseWorkGateAnswer(answer)
+			assert.Equal(t, tc.wantDecision, review.Decision)
+			for _, comment := range review.Comments {
+				assert.Equal(t, "Plan", comment.Title)
+			}
+		})
+	}
+}
+
+func TestParseWorkGateAnswerLegacy(t *testing.T) {
+	cases := []struct {
+		name         string
+		answer       string
+		wantDecision string
+		wantComment  string
+	}{
+		{"approvalChoice", "Selected: " + workGateApprovalText, state.WorkGateApprove, ""},
+		{"rawApproval", workGateApprovalText, state.WorkGateApprove, ""},
+		{"approvalWithText", "Selected: " + workGateApprovalText + "\nadd logging", state.WorkGateApproveWithComments, "add logging"},
+		{"approveWithCommentsChoice", "Selected: " + workGateApproveWithCommentsText + "\nadd logging", state.WorkGateApproveWithComments, "add logging"},
+		{"notReady", "Selected: " + workGateRequestChangesText, state.WorkGateRequestChanges, workGateRequestChangesText},
+		{"freeText", "what about auth?", state.WorkGateRequestChanges, "what about auth?"},
+		{"injectedApproval", "looks wrong\nSelected: " + workGateApprovalText, state.WorkGateRequestChanges, "looks wrong\nSelected: " + workGateApprovalText},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			review := parseWorkGateAnswer(tc.answer)
+			assert.Equal(t, tc.wantDecision, review.Decision)
+			assert.Equal(t, tc.wantComment, review.Comment)
+		})
+	}
+}
+
+func TestWorkGateStructuredReviewViaAPI(t *testing.T) {
+	srv, rootDir := setupTestServer(t)
+	wsDir := setupTestWorkspace(t, rootDir, "review-gate")
+	coord, errCoord := state.NewCoordinatorWith(statePath(wsDir), state.Workflow{InteractionMode: state.ModeInteractive})
+	require.NoError(t, errCoord)
+	srv.mu.Lock()
+	srv.sessions[wsDir] = &session{coord: coord}
+	srv.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	t.Cleanup(cancel)
+	resultCh := make(chan string, 1)
+	go func() {
+		result, errAsk := askUserWorkGate(ctx, coord, wsDir, "## Plan\n- build it\n\n## Validation\n- go test")
+		assert.NoError(t, errAsk)
+		resultCh <- result
+	}()
+	require.Eventually(t, func() bool { return coord.State().NeedsHumanInput() }, time.Second, 10*time.Millisecond)
+
+	w := serveHTTP(srv, http.MethodGet, "/api/v1/state", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var factory apiFactoryState
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &factory))
+	idx := slices.IndexFunc(factory.Workspaces, func(ws apiWorkspaceFullState) bool { return ws.Name == "review-gate" })
+	require.GreaterOrEqual(t, idx, 0)
+	full := factory.Workspaces[idx]
+	require.NotNil(t, full.PendingQuestion)
+	require.Len(t, full.PendingQuestion.Sections, 2)
+	assert.Equal(t, "Validation", full.PendingQuestion.Sections[1].Title)
+
+	qid := full.PendingQuestion.QuestionID
+	w = serveHTTP(srv, http.MethodPost, "/api/v1/workspaces/review-gate/respond", `{"questionId":"`+qid+`","workGate":{"decision":"request-changes"}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	body := `{"questionId":"` + qid + `","workGate":{"decision":"request-changes","comments":[{"section":"s2","comment":"add e2e tests"}]}}`
+	w = serveHTTP(srv, http.MethodPost, "/api/v1/workspaces/review-gate/respond", body)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	result := <-resultCh
+	assert.Contains(t, result, `"decision": "request-changes"`)
+	assert.Contains(t, result, "add e2e tests")
+	assert.NotContains(t, result, workGateApprovalText+".")
+
+	final := coord.State()
+	require.Len(t, final.WorkGateReviews, 1)
+	review := final.WorkGateReviews[0]
+	assert.Equal(t, state.WorkGateRequestChanges, review.Decision)
+	assert.Equal(t, []state.WorkGateComment{{Section: "s2", Title: "Validation", Comment: "add e2e tests"}}, review.Comments)
+	assert.NotEmpty(t, review.Timestamp)
+	assert.Equal(t, state.ModeInteractive, final.InteractionMode)
+
+	ledger, errRead := os.ReadFile(filepath.Join(wsDir, ".sgai", "PROJECT_MANAGEMENT.md"))
+	require.NoError(t, errRead)
+	entries := parseProjectManagementLedger(string(ledger))
+	require.NotEmpty(t, entries)
+	assert.Equal(t, ledgerWorkGateReview, entries[len(entries)-1].Type)
+	assert.Contains(t, entries[len(entries)-1].Body, "[s2] Validation: add e2e tests")
+}
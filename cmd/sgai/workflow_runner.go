@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/sandgardenhq/sgai/pkg/state"
 )
@@ -17,6 +18,10 @@ type workflowRunner struct {
 	goalPath         string
 	coord            *state.Coordinator
 	metadata         GoalMetadata
+	config           *projectConfig
+	fallback         *modelFallback
+	stuck            *stuckDetector
+	stopReason       string
 	wfState          state.Workflow
 	retroDir         string
 	paddedsgai       string
@@ -24,6 +29,9 @@ type workflowRunner struct {
 	logWriter        io.Writer
 	retroLogs        retroLogWriters
 	iterationCounter int
+	execute          agentExecutor
+	redactor         *redactor
+	wait             func(ctx context.Context, d time.Duration)
 }
 
 type retroLogWriters struct {
@@ -76,10 +84,18 @@ func (r *workflowRunner) runAgent(ctx context.Context) runResult {
 		log.Println("failed to reload GOAL.md frontmatter:", errReload)
 		return resultInterrupt
 	}
+	applyConfigDefaults(r.config, &r.metadata)
+	if r.fallback == nil || !r.fallback.sameChain(r.metadata.Model, r.metadata.FallbackModels) {
+		r.fallback = newModelFallback(r.metadata.Model, r.metadata.FallbackModels)
+	}
+	if r.stuck == nil {
+		r.stuck = newStuckDetector(r.dir)
+	}
+	r.stuck.configure(r.metadata.StuckLoop)
 
 	r.wfState = r.executeCoordinator(ctx)
 
-	if ctx.Err() != nil {
+	if ctx.Err() != nil || r.stopReason != "" {
 		return resultInterrupt
 	}
 
@@ -109,11 +125,16 @@ func (r *workflowRunner) executeCoordinator(ctx context.Context) state.Workflow
 		coord:            r.coord,
 		retrospectiveDir: r.retroDir,
 		goalAgents:       r.metadata.Agents,
+		agentModels:      r.metadata.Models,
 		paddedsgai:       r.paddedsgai,
 		mcpURL:           r.mcpURL,
 		logWriter:        r.logWriter,
 		stdoutLog:        r.retroLogs.stdout,
 		stderrLog:        r.retroLogs.stderr,
+		onGateFailure:    r.stuck.recordGateFailure,
+		secrets:          r.secretsConfig(),
+		userConfigDir:    defaultUserConfigDir(),
+		redactor:         r.outputRedactor(),
 	}
 	wfState := r.wfState
 	var capturedSessionID string
@@ -132,13 +153,18 @@ func (r *workflowRunner) executeCoordinator(ctx context.Context) state.Workflow
 		saveState(cfg.coord, wfState)
 		copyProjectManagementToRetrospective(cfg.dir, cfg.retrospectiveDir)
 
-		agentArgs := buildAgentArgs(cfg.agent, r.metadata.Model, capturedSessionID)
+		modelSpec := r.fallback.current()
+		agentArgs := buildAgentArgs(cfg.agent, modelSpec, capturedSessionID)
+		cfg.steeringNote = r.stuck.takeSteering()
 		agentMsg := buildAgentMessage(cfg, wfState, r.metadata)
 
-		newState, capturedSessionID, errExec := executeAgentProcess(ctx, cfg, agentArgs, agentMsg, prefix, outputCapture, wfState, r.metadata.Model)
+		newState, capturedSessionID, failure, errExec := r.executor()(ctx, cfg, agentArgs, agentMsg, prefix, outputCapture, wfState, modelSpec)
 		if errExec != nil {
+			r.handleAgentFailure(ctx, cfg, failure)
 			return *errExec
 		}
+		r.fallback.recordSuccess()
+		r.recordIterationCheckpoint(cfg.agent, prefix, newState)
 
 		if capturedSessionID == "" {
 			log.Println("opencode session id not captured; skipping usage export")
@@ -149,7 +175,13 @@ func (r *workflowRunner) executeCoordinator(ctx context.Context) state.Workflow
 
 		switch newState.Status {
 		case state.StatusComplete:
-			return handleCompleteStatus(ctx, cfg, newState, wfState, r.metadata)
+			completed := handleCompleteStatus(ctx, cfg, newState, wfState, r.metadata)
+			if completed.Status == state.StatusWorking {
+				if reason := r.stuck.observe(wfState, completed); reason != "" && r.reactToStuckLoop(ctx, cfg, &completed, reason, &capturedSessionID) {
+					r.stopReason = reason
+				}
+			}
+			return completed
 
 		case state.StatusWaitingForHuman:
 			wfState = handleWaitingForHumanStatus(cfg, newState)
@@ -162,6 +194,10 @@ func (r *workflowRunner) executeCoordinator(ctx context.Context) state.Workflow
 
 		case state.StatusWorking:
 			saveState(cfg.coord, newState)
+			if reason := r.stuck.observe(wfState, newState); reason != "" && r.reactToStuckLoop(ctx, cfg, &newState, reason, &capturedSessionID) {
+				r.stopReason = reason
+				return newState
+			}
 			consecutiveWorkingIterations = handleWorkingLoop(cfg, &capturedSessionID, consecutiveWorkingIterations)
 			wfState = newState
 			continue
@@ -172,9 +208,18 @@ func (r *workflowRunner) executeCoordinator(ctx context.Context) state.Workflow
 	}
 }
 
+func (r *workflowRunner) executor() agentExecutor {
+	if r.execute != nil {
+		return r.execute
+	}
+	return executeAgentProcess
+}
+
 func (r *workflowRunner) runContinuous(ctx context.Context, continuousPrompt string) {
 	goalPath := filepath.Join(r.dir, "GOAL.md")
 	stateJSONPath := filepath.Join(r.dir, ".sgai", "state.json")
+	cron := newCronScheduler(r.dir, cronSchedule{}, time.Now())
+	var cycleTrigger *state.Trigger
 
 	for {
 		if ctx.Err() != nil {
@@ -193,7 +238,8 @@ func (r *workflowRunner) runContinuous(ctx context.Context, continuousPrompt str
 			return
 		}
 
-		runContinuousModePrompt(ctx, r.dir, continuousPrompt, r.mcpURL, r.coord)
+		runContinuousModePrompt(ctx, r.dir, continuousPromptWithTrigger(continuousPrompt, cycleTrigger), r.mcpURL, r.coord)
+		clearConsumedTrigger(r.coord)
 
 		if ctx.Err() != nil {
 			return
@@ -205,10 +251,11 @@ func (r *workflowRunner) runContinuous(ctx context.Context, continuousPrompt str
 			return
 		}
 
-		autoDuration, cronExpr := readContinuousModeAutoCron(r.dir)
+		autoDuration, _ := readContinuousModeAutoCron(r.dir)
+		cron.reload()
 
-		trigger := watchForTrigger(ctx, r.dir, checksum, autoDuration, cronExpr)
-		if trigger == triggerNone {
+		trigger := watchForTrigger(ctx, r.dir, checksum, autoDuration, cron, readContinuousModeTriggers(r.dir))
+		if trigger.Kind == triggerNone {
 			return
 		}
 
@@ -218,6 +265,7 @@ func (r *workflowRunner) runContinuous(ctx context.Context, continuousPrompt str
 		}
 
 		resetWorkflowForNextCycle(r.coord)
+		cycleTrigger = recordTrigger(r.coord, trigger)
 	}
 }
 
@@ -245,6 +293,10 @@ func buildWorkflowRunner(dir string, mcpURL string, logWriter io.Writer, session
 		log.Fatalln(errValidate)
 	}
 
+	if errValidate := validateGoalModels(metadata); errValidate != nil {
+		log.Fatalln(errValidate)
+	}
+
 	applyConfigDefaults(projectConfig, &metadata)
 
 	if errInit := initializeWorkspaceDir(dir); errInit != nil {
@@ -304,6 +356,7 @@ func buildWorkflowRunner(dir string, mcpURL string, logWriter io.Writer, session
 			if errCopy := copyFinalStateToRetrospective(dir, retroDir); errCopy != nil {
 				log.Println("[sgai] warning: failed to copy final state:", errCopy)
 			}
+			seedKnowledgeFromRetrospective(defaultUserConfigDir(), dir, retroDir)
 		}
 	}
 
@@ -312,6 +365,7 @@ func buildWorkflowRunner(dir string, mcpURL string, logWriter io.Writer, session
 		freshState := state.Workflow{
 			Status:          state.StatusWorking,
 			InteractionMode: preservedMode,
+			Trigger:         pipelineTrigger(wfState.Trigger),
 		}
 		if errUpdate := coord.UpdateState(func(wf *state.Workflow) {
 			*wf = freshState
@@ -323,6 +377,22 @@ func buildWorkflowRunner(dir string, mcpURL string, logWriter io.Writer, session
 		wfState = coord.State()
 	}
 
+	var recorder *runRecorder
+	if path := recordingPath(dir, os.Getenv(recordEnvVar), time.Now()); path != "" {
+		var errRecord error
+		recorder, errRecord = startRunRecorder(dir, path, wfState, time.Now())
+		if errRecord != nil {
+			log.Println("failed to start recording:", errRecord)
+		} else {
+			log.Println("recording workflow run to", path)
+			closeRetro := cleanup
+			cleanup = func() {
+				recorder.close(dir)
+				closeRetro()
+			}
+		}
+	}
+
 	retroLogs := retroLogWriters{stdout: retroStdoutLog, stderr: retroStderrLog}
 	runner := &workflowRunner{
 		dir:        dir,
@@ -332,13 +402,36 @@ func buildWorkflowRunner(dir string, mcpURL string, logWriter io.Writer, session
 		wfState:    wfState,
 		retroDir:   retroDir,
 		paddedsgai: paddedsgai,
-		mcpURL:    mcpURL,
-		logWriter: logWriter,
-		retroLogs: retroLogs,
+		mcpURL:     mcpURL,
+		logWriter:  logWriter,
+		retroLogs:  retroLogs,
+		config:     projectConfig,
+		fallback:   newModelFallback(metadata.Model, metadata.FallbackModels),
+	}
+	if recorder != nil {
+		runner.execute = recorder.wrap(executeAgentProcess)
 	}
 	return runner, cleanup, true
 }
 
+func (r *workflowRunner) secretsConfig() *secretsConfig {
+	if r.config == nil {
+		return nil
+	}
+	return r.config.Secrets
+}
+
+func (r *workflowRunner) outputRedactor() *redactor {
+	if r.redactor == nil {
+		var config *redactionConfig
+		if r.config != nil {
+			config = r.config.Redaction
+		}
+		r.redactor = newRedactor(config)
+	}
+	return r.redactor
+}
+
 func delegatableAgents(agents []string) []string {
 	delegatable := make([]string, 0, len(agents))
 	for _, agent := range agents {
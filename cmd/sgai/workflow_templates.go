@@ -0,0 +1,209 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	templateSourceBuiltin   = "builtin"
+	templateSourceUser      = "user"
+	templateSourceLayer     = "layer"
+	templateSourceWorkspace = "workspace"
+)
+
+var (
+	errInvalidTemplateID    = errors.New("template id must be lowercase letters, digits, '-' or '_'")
+	errTemplateExists       = errors.New("template already exists")
+	errInvalidTemplateScope = errors.New("template scope must be workspace or user")
+
+	templateIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+)
+
+// templateFile is the on-disk YAML form of a workflow template.
+type templateFile struct {
+	ID             string          `json:"id,omitempty"`
+	Name           string          `json:"name"`
+	Description    string          `json:"description,omitempty"`
+	Icon           string          `json:"icon,omitempty"`
+	Agents         []string        `json:"agents,omitempty"`
+	Model          string          `json:"model,omitempty"`
+	CompletionGate string          `json:"completionGate,omitempty"`
+	Interactive    string          `json:"interactive,omitempty"`
+	Tasks          string          `json:"tasks,omitempty"`
+	Body           string          `json:"body,omitempty"`
+	TechStack      []techStackFile `json:"techStack,omitempty"`
+}
+
+type techStackFile struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type templateDir struct {
+	dir    string
+	source string
+}
+
+// templateDirs lists the template directories in increasing precedence: a
+// template in a later directory replaces one with the same ID.
+func templateDirs(userConfigDir, workspacePath string) []templateDir {
+	dirs := []templateDir{{filepath.Join(userConfigDir, "templates"), templateSourceUser}}
+	if workspacePath != "" {
+		dirs = append(dirs,
+			templateDir{filepath.Join(workspacePath, "sgai", "templates"), templateSourceLayer},
+			templateDir{filepath.Join(workspacePath, ".sgai", "templates"), templateSourceWorkspace},
+		)
+	}
+	return dirs
+}
+
+// loadWorkflowTemplates merges the built-in templates with the YAML templates
+// found in the user, layer and workspace template directories.
+func loadWorkflowTemplates(userConfigDir, workspacePath string) []workflowTemplate {
+	templates := workflowTemplates()
+	for i := range templates {
+		templates[i].Source = templateSourceBuiltin
+	}
+	for _, dir := range templateDirs(userConfigDir, workspacePath) {
+		for _, tmpl := range readTemplateDir(dir) {
+			idx := slices.IndexFunc(templates, func(existing workflowTemplate) bool { return existing.ID == tmpl.ID })
+			if idx >= 0 {
+				templates[idx] = tmpl
+				continue
+			}
+			templates = append(templates, tmpl)
+		}
+	}
+	return templates
+}
+
+func readTemplateDir(dir templateDir) []workflowTemplate {
+	entries, errRead := os.ReadDir(dir.dir)
+	if errRead != nil {
+		if !os.IsNotExist(errRead) {
+			log.Println("failed to read templates:", errRead)
+		}
+		return nil
+	}
+	var templates []workflowTemplate
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(dir.dir, entry.Name())
+		tmpl, errParse := parseTemplateFile(path, strings.TrimSuffix(entry.Name(), ext))
+		if errParse != nil {
+			log.Println("skipping template:", errParse)
+			continue
+		}
+		tmpl.Source = dir.source
+		templates = append(templates, tmpl)
+	}
+	return templates
+}
+
+func parseTemplateFile(path, defaultID string) (workflowTemplate, error) {
+	data, errRead := os.ReadFile(path)
+	if errRead != nil {
+		return workflowTemplate{}, errRead
+	}
+	var file templateFile
+	if errYAML := yaml.UnmarshalStrict(data, &file); errYAML != nil {
+		return workflowTemplate{}, fmt.Errorf("parsing %s: %w", path, errYAML)
+	}
+	if file.ID == "" {
+		file.ID = defaultID
+	}
+	if !templateIDPattern.MatchString(file.ID) {
+		return workflowTemplate{}, fmt.Errorf("%s: %w", path, errInvalidTemplateID)
+	}
+	if file.Name == "" {
+		file.Name = file.ID
+	}
+
+	tmpl := workflowTemplate{
+		ID:             file.ID,
+		Name:           file.Name,
+		Description:    file.Description,
+		Icon:           file.Icon,
+		Model:          file.Model,
+		CompletionGate: file.CompletionGate,
+		Interactive:    file.Interactive,
+		Tasks:          file.Tasks,
+		Body:           file.Body,
+	}
+	for _, agent := range file.Agents {
+		tmpl.Agents = append(tmpl.Agents, composerAgentConf{Name: agent, Selected: true})
+	}
+	for _, item := range file.TechStack {
+		tmpl.TechStack = append(tmpl.TechStack, techStackItem{ID: item.ID, Name: cmp.Or(item.Name, item.ID)})
+	}
+	return tmpl, nil
+}
+
+// templateTechStackItems extends the default tech stack with the items
+// declared by templates, skipping IDs that are already present.
+func templateTechStackItems(templates []workflowTemplate) []techStackItem {
+	items := defaultTechStackItems()
+	for _, tmpl := range templates {
+		for _, item := range tmpl.TechStack {
+			if !slices.ContainsFunc(items, func(existing techStackItem) bool { return existing.ID == item.ID }) {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// templateFromComposerState captures the composer state as a template file.
+func templateFromComposerState(st composerState, id, name, description, icon string) templateFile {
+	file := templateFile{
+		ID:             id,
+		Name:           cmp.Or(name, id),
+		Description:    description,
+		Icon:           icon,
+		Model:          st.Model,
+		CompletionGate: st.CompletionGate,
+		Interactive:    st.Interactive,
+		Tasks:          st.Tasks,
+		Body:           st.Description,
+	}
+	for _, agent := range activeComposerAgents(st.Agents) {
+		if agent.Selected {
+			file.Agents = append(file.Agents, agent.Name)
+		}
+	}
+	return file
+}
+
+func writeTemplateFile(dir string, file templateFile, overwrite bool) (string, error) {
+	if !templateIDPattern.MatchString(file.ID) {
+		return "", errInvalidTemplateID
+	}
+	path := filepath.Join(dir, file.ID+".yaml")
+	if _, errStat := os.Stat(path); errStat == nil && !overwrite {
+		return "", fmt.Errorf("%w: %s", errTemplateExists, path)
+	}
+	data, errYAML := yaml.Marshal(file)
+	if errYAML != nil {
+		return "", fmt.Errorf("encoding template: %w", errYAML)
+	}
+	if errMkdir := os.MkdirAll(dir, 0755); errMkdir != nil {
+		return "", fmt.Errorf("creating templates directory: %w", errMkdir)
+	}
+	if errWrite := os.WriteFile(path, data, 0644); errWrite != nil {
+		return "", fmt.Errorf("writing template: %w", errWrite)
+	}
+	return path, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestTemplate(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+}
+
+func findTemplate(templates []workflowTemplate, id string) (workflowTemplate, bool) {
+	idx := slices.IndexFunc(templates, func(tmpl workflowTemplate) bool { return tmpl.ID == id })
+	if idx < 0 {
+		return workflowTemplate{}, false
+	}
+	return templates[idx], true
+}
+
+func TestLoadWorkflowTemplatesPrecedence(t *testing.T) {
+	userDir := t.TempDir()
+	wsDir := t.TempDir()
+	writeTestTemplate(t, filepath.Join(userDir, "templates"), "api.yaml", "name: User API\nagents: [go]\n")
+	writeTestTemplate(t, filepath.Join(userDir, "templates"), "docs.yml", "name: Docs\nmodel: anthropic/claude-opus-4-6\n")
+	writeTestTemplate(t, filepath.Join(wsDir, "sgai", "templates"), "api.yaml", "name: Layer API\n")
+	writeTestTemplate(t, filepath.Join(wsDir, ".sgai", "templates"), "api.yaml", `name: Workspace API
+agents: [go, react]
+completionGate: make test
+interactive: "yes"
+tasks: "- [ ] scaffold handlers"
+body: Build the API.
+techStack:
+  - id: rust
+    name: Rust
+`)
+	writeTestTemplate(t, filepath.Join(wsDir, ".sgai", "templates"), "backend.yaml", "name: Team Go\n")
+	writeTestTemplate(t, filepath.Join(wsDir, ".sgai", "templates"), "notes.txt", "ignored")
+
+	templates := loadWorkflowTemplates(userDir, wsDir)
+
+	api, ok := findTemplate(templates, "api")
+	require.True(t, ok)
+	assert.Equal(t, "Workspace API", api.Name)
+	assert.Equal(t, templateSourceWorkspace, api.Source)
+	assert.Equal(t, []composerAgentConf{{Name: "go", Selected: true}, {Name: "react", Selected: true}}, api.Agents)
+	assert.Equal(t, "make test", api.CompletionGate)
+	assert.Equal(t, "yes", api.Interactive)
+	assert.Equal(t, "- [ ] scaffold handlers", api.Tasks)
+	assert.Equal(t, "Build the API.", api.Body)
+
+	docs, ok := findTemplate(templates, "docs")
+	require.True(t, ok)
+	assert.Equal(t, templateSourceUser, docs.Source)
+	assert.Equal(t, "anthropic/claude-opus-4-6", docs.Model)
+
+	backend, ok := findTemplate(templates, "backend")
+	require.True(t, ok)
+	assert.Equal(t, "Team Go", backend.Name, "workspace templates override built-ins")
+
+	custom, ok := findTemplate(templates, "custom")
+	require.True(t, ok)
+	assert.Equal(t, templateSourceBuiltin, custom.Source)
+
+	_, ok = findTemplate(loadWorkflowTemplates(userDir, ""), "backend")
+	require.True(t, ok)
+	assert.Len(t, loadWorkflowTemplates(userDir, ""), len(workflowTemplates())+2)
+
+	items := templateTechStackItems(templates)
+	assert.Len(t, items, len(defaultTechStackItems())+1)
+	assert.Equal(t, techStackItem{ID: "rust", Name: "Rust"}, items[len(items)-1])
+}
+
+func TestParseTemplateFile(t *testing.T) {
+	cases := []struct {
+		name     string
+		fileName string
+		content  string
+		wantID   string
+		wantName string
+		wantErr  bool
+	}{
+		{"idFromFileName", "go-api.yaml", "name: Go API\n", "go-api", "Go API", false},
+		{"explicitID", "whatever.yaml", "id: explicit\n", "explicit", "explicit", false},
+		{"unknownField", "bad.yaml", "name: Bad\nagnets: [go]\n", "", "", true},
+		{"invalidID", "Bad Name.yaml", "name: Bad\n", "", "", true},
+		{"malformed", "broken.yaml", "name: [\n", "", "", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestTemplate(t, dir, tc.fileName, tc.content)
+			path := filepath.Join(dir, tc.fileName)
+			tmpl, errParse := parseTemplateFile(path, tc.fileName[:len(tc.fileName)-len(filepath.Ext(tc.fileName))])
+			if tc.wantErr {
+				require.Error(t, errParse)
+				return
+			}
+			require.NoError(t, errParse)
+			assert.Equal(t, tc.wantID, tmpl.ID)
+			assert.Equal(t, tc.wantName, tmpl.Name)
+		})
+	}
+}
+
+func TestHandleAPISaveComposeTemplate(t *testing.T) {
+	server, rootDir := setupTestServer(t)
+	server.externalConfigDir = t.TempDir()
+	wsDir := setupTestWorkspace(t, rootDir, "test-ws")
+	server.composeDraftService(wsDir, composerState{
+		Description:    "Ship the feature.",
+		CompletionGate: "make test",
+		Interactive:    "auto",
+		Agents:         []composerAgentConf{{Name: "coordinator", Selected: true}, {Name: "go", Selected: true}, {Name: "react"}},
+		Model:          "openai/gpt-5.5 (xhigh)",
+		Tasks:          "- [ ] write tests",
+	}, wizardState{})
+
+	w := serveHTTP(server, http.MethodPost, "/api/v1/compose/templates?workspace=test-ws", `{"id":"team-go","name":"Team Go"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var entry apiComposeTemplateEntry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entry))
+	assert.Equal(t, "team-go", entry.ID)
+	assert.Equal(t, templateSourceWorkspace, entry.Source)
+	assert.Equal(t, []composerAgentConf{{Name: "go", Selected: true}}, entry.Agents)
+	assert.Equal(t, "make test", entry.CompletionGate)
+	assert.Equal(t, "auto", entry.Interactive)
+	assert.Equal(t, "Ship the feature.", entry.Body)
+	assert.FileExists(t, filepath.Join(wsDir, ".sgai", "templates", "team-go.yaml"))
+
+	w = serveHTTP(server, http.MethodPost, "/api/v1/compose/templates?workspace=test-ws", `{"id":"team-go"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = serveHTTP(server, http.MethodPost, "/api/v1/compose/templates?workspace=test-ws", `{"id":"team-go","overwrite":true}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = serveHTTP(server, http.MethodPost, "/api/v1/compose/templates?workspace=test-ws", `{"id":"Team Go"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serveHTTP(server, http.MethodPost, "/api/v1/compose/templates?workspace=test-ws", `{"id":"shared","scope":"global"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serveHTTP(server, http.MethodPost, "/api/v1/compose/templates?workspace=test-ws", `{"id":"shared","scope":"user"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.FileExists(t, filepath.Join(server.externalConfigDir, "templates", "shared.yaml"))
+
+	w = serveHTTP(server, http.MethodGet, "/api/v1/compose/templates?workspace=test-ws", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp apiComposeTemplatesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	ids := make([]string, 0, len(resp.Templates))
+	for _, tmpl := range resp.Templates {
+		ids = append(ids, tmpl.ID)
+	}
+	assert.Contains(t, ids, "team-go")
+	assert.Contains(t, ids, "shared")
+
+	w = serveHTTP(server, http.MethodGet, "/api/v1/compose/templates?workspace=missing", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestBuildGOALContentRoundTripsInteractive(t *testing.T) {
+	wsDir := t.TempDir()
+	content := buildGOALContent(composerState{Interactive: "auto", Agents: []composerAgentConf{{Name: "go", Selected: true}}})
+	assert.Contains(t, content, "interactive: auto\n")
+	require.NoError(t, os.WriteFile(filepath.Join(wsDir, "GOAL.md"), []byte(content), 0o644))
+
+	st := loadComposerStateFromDisk(wsDir)
+
+	assert.Equal(t, "auto", st.Interactive)
+}
@@ -60,6 +60,13 @@ type ProgressEntry struct {
 	Description string `json:"description"`
 }
 
+// Trigger describes the event that started a continuous-mode cycle.
+type Trigger struct {
+	Kind      string `json:"kind"`
+	Payload   string `json:"payload,omitempty"`
+	Timestamp string `json:"timestamp"`
+}
+
 // QuestionItem represents a single question in a multi-question batch.
 type QuestionItem struct {
 	Question    string   `json:"question"`
@@ -72,6 +79,42 @@ type QuestionItem struct {
 type MultiChoiceQuestion struct {
 	Questions  []QuestionItem `json:"questions"`
 	IsWorkGate bool           `json:"isWorkGate,omitempty"`
+	// Sections splits a work-gate summary into parts the human can comment on.
+	Sections []WorkGateSection `json:"sections,omitempty"`
+}
+
+// Work-gate review decisions.
+const (
+	WorkGateApprove             = "approve"
+	WorkGateApproveWithComments = "approve-with-comments"
+	WorkGateRequestChanges      = "request-changes"
+)
+
+// WorkGateSection is one reviewable part of a work-gate summary.
+type WorkGateSection struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// WorkGateComment is a reviewer comment attached to a work-gate section.
+type WorkGateComment struct {
+	Section string `json:"section"`
+	Title   string `json:"title,omitempty"`
+	Comment string `json:"comment"`
+}
+
+// WorkGateReview records the human's decision on a work-gate summary.
+type WorkGateReview struct {
+	Decision  string            `json:"decision"`
+	Comment   string            `json:"comment,omitempty"`
+	Comments  []WorkGateComment `json:"comments,omitempty"`
+	Timestamp string            `json:"timestamp,omitempty"`
+}
+
+// Approved reports whether the review lets implementation begin.
+func (r WorkGateReview) Approved() bool {
+	return r.Decision == WorkGateApprove || r.Decision == WorkGateApproveWithComments
 }
 
 // Workflow represents the complete workflow state for a sgai session.
@@ -88,6 +131,16 @@ type Workflow struct {
 
 	InteractionMode string `json:"interactionMode,omitempty"`
 
+	// Trigger records what woke the current continuous-mode cycle.
+	Trigger *Trigger `json:"trigger,omitempty"`
+
+	// WorkGateReviews is the history of work-gate decisions, oldest first.
+	WorkGateReviews []WorkGateReview `json:"workGateReviews,omitempty"`
+
+	// Redactions counts the credentials scrubbed from agent output during
+	// this session, keyed by detector name.
+	Redactions map[string]int `json:"redactions,omitempty"`
+
 	// Summary is a single-sentence summary of the project goal.
 	// Generated automatically when GOAL.md is saved or workspace starts,
 	// unless SummaryManual is true (indicating user has manually edited it).